@@ -0,0 +1,66 @@
+package build
+
+import (
+	"reflect"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cbuild "github.com/pip-services3-go/pip-services3-components-go/build"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+)
+
+/*
+Creates persistence components by their descriptors.
+Item types of created components are set by "item_type" configuration
+parameter with names registered in persistence.DefaultTypeRegistry.
+Until item type is configured, components store items as maps.
+
+See Factory
+See IdentifiableMemoryPersistence
+See IdentifiableFilePersistence
+
+Example
+
+    persistence.DefaultTypeRegistry.RegisterType(reflect.TypeOf(Order{}))
+
+    # Container configuration
+    - descriptor: "pip-services:persistence:file:orders:1.0"
+      item_type: "mypackage.Order"
+      path: "./data/orders.json"
+
+    # Loader and saver of memory persistence resolved by references
+    - descriptor: "pip-services:persister:json:orders:1.0"
+      path: "./data/orders.json"
+    - descriptor: "pip-services:persistence:memory:orders:1.0"
+      item_type: "mypackage.Order"
+      dependencies:
+        persister: "pip-services:persister:json:orders:1.0"
+*/
+type DefaultPersistenceFactory struct {
+	cbuild.Factory
+}
+
+var DefaultPersistenceFactoryDescriptor = refer.NewDescriptor("pip-services", "factory", "persistence", "default", "1.0")
+var MemoryPersistenceDescriptor = refer.NewDescriptor("pip-services", "persistence", "memory", "*", "1.0")
+var FilePersistenceDescriptor = refer.NewDescriptor("pip-services", "persistence", "file", "*", "1.0")
+var JsonFilePersisterDescriptor = refer.NewDescriptor("pip-services", "persister", "json", "*", "1.0")
+
+// Create a new instance of the factory.
+// Returns *DefaultPersistenceFactory
+func NewDefaultPersistenceFactory() *DefaultPersistenceFactory {
+	c := &DefaultPersistenceFactory{
+		Factory: *cbuild.NewFactory(),
+	}
+
+	mapType := reflect.TypeOf(map[string]interface{}{})
+	c.Register(MemoryPersistenceDescriptor, func(locator interface{}) interface{} {
+		return persistence.NewIdentifiableMemoryPersistence(mapType)
+	})
+	c.Register(FilePersistenceDescriptor, func(locator interface{}) interface{} {
+		return persistence.NewIdentifiableFilePersistence(mapType, nil)
+	})
+	c.Register(JsonFilePersisterDescriptor, func(locator interface{}) interface{} {
+		return persistence.NewJsonFilePersister(mapType, "")
+	})
+
+	return c
+}
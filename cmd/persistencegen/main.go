@@ -0,0 +1,39 @@
+/*
+Command persistencegen generates typed persistence wrappers for data structs.
+
+It is designed to be called by go generate from the package that declares the struct:
+
+  //go:generate go run github.com/pip-services3-go/pip-services3-data-go/cmd/persistencegen -type=MyData -tests
+
+The command writes mydata_persistence.go and, with -tests flag, mydata_persistence_test.go
+next to the source file.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pip-services3-go/pip-services3-data-go/codegen"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the struct type")
+	dir := flag.String("dir", ".", "directory with the package that declares the struct")
+	tests := flag.Bool("tests", false, "generate fixture tests")
+	flag.Parse()
+
+	if *typeName == "" {
+		fmt.Fprintln(os.Stderr, "persistencegen: -type flag is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	generator := codegen.NewGenerator(*typeName, *dir)
+	generator.Tests = *tests
+	if err := generator.Write(); err != nil {
+		fmt.Fprintln(os.Stderr, "persistencegen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Command pipdata maintains data files produced by JsonFilePersister, so operators don't hand-edit JSON:
+
+  pipdata validate ./data/orders.json
+  pipdata compact ./data/orders.json
+  pipdata convert -format ndjson -o - ./data/orders.json
+  pipdata diff ./backup/orders.json ./data/orders.json
+  pipdata anonymize -rule email=email -rule name=name -o ./data/orders.anon.json ./data/orders.json
+  pipdata query -filter status=active -sort -created -take 10 ./data/orders.json
+
+Use - as a file to read items from stdin or write them to stdout.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-data-go/datatool"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		datatool.Usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	tool := datatool.NewTool(os.Stdin, os.Stdout)
+	if err := tool.Run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "pipdata:", err)
+		if appErr, ok := err.(*errors.ApplicationError); ok && appErr.Category == errors.BadRequest &&
+			appErr.Code != "INVALID_DATA" {
+			datatool.Usage(os.Stderr)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,257 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+/*
+Generator of typed persistence wrappers for data structs.
+
+For a struct with an id field (a field named Id or tagged json:"id") it emits:
+
+  - <Type>Page with typed data items
+  - <Type>Filter to build FilterParams with typed values of struct fields
+  - <Type>FilterBuilder to build filter functions with conditions on typed fields,
+    like Where().Name().Equals("ABC").And().Age().GreaterThan(30)
+  - <Type>MemoryPersistence and <Type>FilePersistence with typed Create, GetOneById,
+    GetPageByFilter and other operations over IdentifiableMemoryPersistence
+  - optionally fixture tests for the generated persistence components
+
+Example
+
+  //go:generate go run github.com/pip-services3-go/pip-services3-data-go/cmd/persistencegen -type=MyData -tests
+  type MyData struct {
+      Id   string `json:"id"`
+      Name string `json:"name"`
+  }
+*/
+type Generator struct {
+	// Name of the struct type
+	Type string
+	// Directory with the package that declares the struct
+	Dir string
+	// Generate fixture tests
+	Tests bool
+}
+
+// Struct field exposed in the typed filter
+type generatorField struct {
+	Name    string
+	Key     string
+	Type    string
+	Ordered bool
+	Text    bool
+	// Name of the condition type of the field in the typed filter builder
+	Condition string
+}
+
+// Condition on fields of the same type in the typed filter builder
+type generatorCondition struct {
+	Name    string
+	Type    string
+	Ordered bool
+	Text    bool
+}
+
+// Parsed data struct passed to templates
+type generatorModel struct {
+	Package    string
+	Type       string
+	IdField    string
+	IdType     string
+	IdValue    string
+	Fields     []*generatorField
+	Conditions []*generatorCondition
+	Imports    []string
+	Kinds      []string
+}
+
+// Creates a new generator for the struct type.
+// Parameters:
+//   - typeName string
+//   name of the struct type
+//   - dir string
+//   directory with the package that declares the struct
+// Returns *Generator
+func NewGenerator(typeName string, dir string) *Generator {
+	return &Generator{Type: typeName, Dir: dir}
+}
+
+// Parses the package in the directory and finds the struct type
+func (c *Generator) parse() (*generatorModel, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Name.Name != c.Type {
+					continue
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					return nil, fmt.Errorf("type %s is not a struct", c.Type)
+				}
+				return c.newModel(file, structType)
+			}
+		}
+	}
+	return nil, fmt.Errorf("struct %s is not found in %s", c.Type, c.Dir)
+}
+
+// Collects id and filter fields of the struct
+func (c *Generator) newModel(file *ast.File, structType *ast.StructType) (*generatorModel, error) {
+	model := &generatorModel{Package: file.Name.Name, Type: c.Type, Kinds: []string{"Memory", "File"}}
+	for _, field := range structType.Fields.List {
+		key := ""
+		if field.Tag != nil {
+			tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+			key = strings.Split(tag.Get("json"), ",")[0]
+		}
+		fieldType := types.ExprString(field.Type)
+		for _, name := range field.Names {
+			if !name.IsExported() || key == "-" {
+				continue
+			}
+			fieldKey := key
+			if fieldKey == "" {
+				fieldKey = name.Name
+			}
+			if model.IdField == "" && (name.Name == "Id" || fieldKey == "id") {
+				model.IdField = name.Name
+				model.IdType = fieldType
+			}
+			if f := newGeneratorField(name.Name, fieldKey, fieldType); f != nil {
+				model.Fields = append(model.Fields, f)
+				model.addCondition(f)
+				if fieldType == "time.Time" {
+					model.Imports = []string{"time"}
+				}
+			}
+		}
+	}
+
+	switch model.IdType {
+	case "":
+		return nil, fmt.Errorf("struct %s has no id field", c.Type)
+	case "string":
+		model.IdValue = `"1"`
+	case "int", "int32", "int64", "uint", "uint32", "uint64":
+		model.IdValue = "1"
+	default:
+		return nil, fmt.Errorf("id field %s of struct %s has unsupported type %s", model.IdField, c.Type, model.IdType)
+	}
+	return model, nil
+}
+
+// Creates a filter field for supported field types or returns nil
+func newGeneratorField(name string, key string, fieldType string) *generatorField {
+	field := &generatorField{Name: name, Key: key, Type: fieldType}
+	// Conditions are named after the types: String, Int64, Time and so on
+	field.Condition = strings.ToUpper(fieldType[:1]) + fieldType[1:]
+	if fieldType == "time.Time" {
+		field.Condition = "Time"
+	}
+	switch fieldType {
+	case "string":
+		field.Ordered = true
+		field.Text = true
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "time.Time":
+		field.Ordered = true
+	case "bool":
+	default:
+		return nil
+	}
+	return field
+}
+
+// Adds the condition type for the type of the field unless it is already added
+func (c *generatorModel) addCondition(field *generatorField) {
+	for _, condition := range c.Conditions {
+		if condition.Type == field.Type {
+			return
+		}
+	}
+	c.Conditions = append(c.Conditions, &generatorCondition{
+		Name: field.Condition, Type: field.Type, Ordered: field.Ordered, Text: field.Text,
+	})
+}
+
+// Renders the template into formatted Go source code
+func render(text string, model *generatorModel) ([]byte, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err = tmpl.Execute(&buffer, model); err != nil {
+		return nil, err
+	}
+	return format.Source(buffer.Bytes())
+}
+
+// Generates source code of the typed persistence wrappers.
+// Returns []byte, []byte, error
+// source of persistence components, source of fixture tests or nil if tests are not requested, and error.
+func (c *Generator) Generate() (source []byte, tests []byte, err error) {
+	model, err := c.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	if source, err = render(persistenceTemplate, model); err != nil {
+		return nil, nil, err
+	}
+	if c.Tests {
+		if tests, err = render(testsTemplate, model); err != nil {
+			return nil, nil, err
+		}
+	}
+	return source, tests, nil
+}
+
+// Generates and writes the typed persistence wrappers into <type>_persistence.go
+// and fixture tests into <type>_persistence_test.go in the package directory.
+// Returns error or nil when files are written.
+func (c *Generator) Write() error {
+	source, tests, err := c.Generate()
+	if err != nil {
+		return err
+	}
+	base := filepath.Join(c.Dir, strings.ToLower(c.Type)+"_persistence")
+	if err = os.WriteFile(base+".go", source, 0644); err != nil {
+		return err
+	}
+	if tests != nil {
+		return os.WriteFile(base+"_test.go", tests, 0644)
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package codegen
+
+// Template of typed page, filters and persistence components
+const persistenceTemplate = `// Code generated by persistencegen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"reflect"
+{{range .Imports}}	"{{.}}"
+{{end}}
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+)
+
+// {{.Type}}Page is a page of {{.Type}} items.
+type {{.Type}}Page struct {
+	Total *int64 ` + "`json:\"total\"`" + `
+	Data  []{{.Type}} ` + "`json:\"data\"`" + `
+}
+
+// {{.Type}}Filter builds filter parameters for {{.Type}} items with typed values.
+type {{.Type}}Filter struct {
+	*cdata.FilterParams
+}
+
+// New{{.Type}}Filter creates an empty filter for {{.Type}} items.
+func New{{.Type}}Filter() *{{.Type}}Filter {
+	return &{{.Type}}Filter{cdata.NewEmptyFilterParams()}
+}
+{{range .Fields}}
+// {{.Name}}Equals selects items with {{.Name}} equal to the value.
+func (c *{{$.Type}}Filter) {{.Name}}Equals(value {{.Type}}) *{{$.Type}}Filter {
+	c.Put("{{.Key}}", value)
+	return c
+}
+
+// {{.Name}}NotEquals selects items with {{.Name}} not equal to the value.
+func (c *{{$.Type}}Filter) {{.Name}}NotEquals(value {{.Type}}) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_ne", value)
+	return c
+}
+{{if .Ordered}}
+// {{.Name}}GreaterThan selects items with {{.Name}} greater than the value.
+func (c *{{$.Type}}Filter) {{.Name}}GreaterThan(value {{.Type}}) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_gt", value)
+	return c
+}
+
+// {{.Name}}GreaterThanOrEqual selects items with {{.Name}} greater than or equal to the value.
+func (c *{{$.Type}}Filter) {{.Name}}GreaterThanOrEqual(value {{.Type}}) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_gte", value)
+	return c
+}
+
+// {{.Name}}LessThan selects items with {{.Name}} less than the value.
+func (c *{{$.Type}}Filter) {{.Name}}LessThan(value {{.Type}}) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_lt", value)
+	return c
+}
+
+// {{.Name}}LessThanOrEqual selects items with {{.Name}} less than or equal to the value.
+func (c *{{$.Type}}Filter) {{.Name}}LessThanOrEqual(value {{.Type}}) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_lte", value)
+	return c
+}
+{{end}}{{if .Text}}
+// {{.Name}}Contains selects items with {{.Name}} that contains the value.
+func (c *{{$.Type}}Filter) {{.Name}}Contains(value string) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_contains", value)
+	return c
+}
+
+// {{.Name}}StartsWith selects items with {{.Name}} that starts with the value.
+func (c *{{$.Type}}Filter) {{.Name}}StartsWith(value string) *{{$.Type}}Filter {
+	c.Put("{{.Key}}_starts", value)
+	return c
+}
+{{end}}{{end}}
+// {{.Type}}FilterBuilder builds filter functions for {{.Type}} items
+// with conditions on typed fields, for instance:
+//
+//   New{{.Type}}FilterBuilder().Where().{{(index .Fields 0).Name}}().Equals(value).Build()
+type {{.Type}}FilterBuilder struct {
+	builder *cpersist.FilterBuilder
+}
+
+// New{{.Type}}FilterBuilder creates a filter builder for {{.Type}} items.
+func New{{.Type}}FilterBuilder() *{{.Type}}FilterBuilder {
+	return &{{.Type}}FilterBuilder{cpersist.NewFilterBuilder(reflect.TypeOf({{.Type}}{}))}
+}
+
+// Where starts the first condition.
+func (c *{{.Type}}FilterBuilder) Where() *{{.Type}}FilterFields {
+	return &{{.Type}}FilterFields{c}
+}
+
+// And starts a condition joined with the previous ones using logical AND.
+func (c *{{.Type}}FilterBuilder) And() *{{.Type}}FilterFields {
+	return &{{.Type}}FilterFields{c}
+}
+
+// Or starts a new group of conditions joined with the previous groups using logical OR.
+func (c *{{.Type}}FilterBuilder) Or() *{{.Type}}FilterFields {
+	c.builder.Or()
+	return &{{.Type}}FilterFields{c}
+}
+
+// WithCollation sets a collation to compare strings in all conditions.
+func (c *{{.Type}}FilterBuilder) WithCollation(collation *cpersist.Collation) *{{.Type}}FilterBuilder {
+	c.builder.WithCollation(collation)
+	return c
+}
+
+// Build builds a filter function from the conditions.
+func (c *{{.Type}}FilterBuilder) Build() (func(interface{}) bool, error) {
+	return c.builder.Build()
+}
+
+// {{.Type}}FilterFields selects a field of {{.Type}} items for a condition.
+type {{.Type}}FilterFields struct {
+	builder *{{.Type}}FilterBuilder
+}
+{{range .Fields}}
+// {{.Name}} starts a condition on {{.Name}} field.
+func (c *{{$.Type}}FilterFields) {{.Name}}() *{{$.Type}}{{.Condition}}Field {
+	return &{{$.Type}}{{.Condition}}Field{c.builder, c.builder.builder.Where("{{.Name}}")}
+}
+{{end}}{{range .Conditions}}
+// {{$.Type}}{{.Name}}Field defines a condition on a field of {{.Type}} type.
+type {{$.Type}}{{.Name}}Field struct {
+	builder *{{$.Type}}FilterBuilder
+	field   *cpersist.FilterField
+}
+
+// Equals selects items with the field equal to the value.
+func (c *{{$.Type}}{{.Name}}Field) Equals(value {{.Type}}) *{{$.Type}}FilterBuilder {
+	c.field.Equals(value)
+	return c.builder
+}
+
+// NotEquals selects items with the field not equal to the value.
+func (c *{{$.Type}}{{.Name}}Field) NotEquals(value {{.Type}}) *{{$.Type}}FilterBuilder {
+	c.field.NotEquals(value)
+	return c.builder
+}
+
+// In selects items with the field equal to one of the values.
+func (c *{{$.Type}}{{.Name}}Field) In(values ...{{.Type}}) *{{$.Type}}FilterBuilder {
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+	c.field.In(items...)
+	return c.builder
+}
+{{if .Ordered}}
+// GreaterThan selects items with the field greater than the value.
+func (c *{{$.Type}}{{.Name}}Field) GreaterThan(value {{.Type}}) *{{$.Type}}FilterBuilder {
+	c.field.GreaterThan(value)
+	return c.builder
+}
+
+// GreaterThanOrEqual selects items with the field greater than or equal to the value.
+func (c *{{$.Type}}{{.Name}}Field) GreaterThanOrEqual(value {{.Type}}) *{{$.Type}}FilterBuilder {
+	c.field.GreaterThanOrEqual(value)
+	return c.builder
+}
+
+// LessThan selects items with the field less than the value.
+func (c *{{$.Type}}{{.Name}}Field) LessThan(value {{.Type}}) *{{$.Type}}FilterBuilder {
+	c.field.LessThan(value)
+	return c.builder
+}
+
+// LessThanOrEqual selects items with the field less than or equal to the value.
+func (c *{{$.Type}}{{.Name}}Field) LessThanOrEqual(value {{.Type}}) *{{$.Type}}FilterBuilder {
+	c.field.LessThanOrEqual(value)
+	return c.builder
+}
+{{end}}{{if .Text}}
+// Contains selects items with the field that contains the value.
+func (c *{{$.Type}}{{.Name}}Field) Contains(value string) *{{$.Type}}FilterBuilder {
+	c.field.Contains(value)
+	return c.builder
+}
+
+// StartsWith selects items with the field that starts with the value.
+func (c *{{$.Type}}{{.Name}}Field) StartsWith(value string) *{{$.Type}}FilterBuilder {
+	c.field.StartsWith(value)
+	return c.builder
+}
+{{end}}
+// IsEmpty selects items with the field that has zero value.
+func (c *{{$.Type}}{{.Name}}Field) IsEmpty() *{{$.Type}}FilterBuilder {
+	c.field.IsEmpty()
+	return c.builder
+}
+{{end}}
+// Converts a list of items into typed items
+func to{{.Type}}List(values []interface{}) []{{.Type}} {
+	items := make([]{{.Type}}, len(values))
+	for i, v := range values {
+		items[i], _ = v.({{.Type}})
+	}
+	return items
+}
+
+// Converts a list of typed ids into ids
+func from{{.Type}}Ids(ids []{{.IdType}}) []interface{} {
+	values := make([]interface{}, len(ids))
+	for i, v := range ids {
+		values[i] = v
+	}
+	return values
+}
+{{range $kind := .Kinds}}{{with $}}
+// {{.Type}}{{$kind}}Persistence is a typed {{if eq $kind "File"}}file{{else}}memory{{end}} persistence of {{.Type}} items.
+type {{.Type}}{{$kind}}Persistence struct {
+	cpersist.Identifiable{{$kind}}Persistence
+}
+{{if eq $kind "File"}}
+// New{{.Type}}FilePersistence creates a typed persistence of {{.Type}} items stored in the JSON file.
+func New{{.Type}}FilePersistence(path string) *{{.Type}}FilePersistence {
+	proto := reflect.TypeOf({{.Type}}{})
+	return &{{.Type}}FilePersistence{*cpersist.NewIdentifiableFilePersistence(proto, cpersist.NewJsonFilePersister(proto, path))}
+}
+{{else}}
+// New{{.Type}}MemoryPersistence creates a typed persistence of {{.Type}} items stored in memory.
+func New{{.Type}}MemoryPersistence() *{{.Type}}MemoryPersistence {
+	proto := reflect.TypeOf({{.Type}}{})
+	return &{{.Type}}MemoryPersistence{*cpersist.NewIdentifiableMemoryPersistence(proto)}
+}
+{{end}}
+// Create creates an item.
+func (c *{{.Type}}{{$kind}}Persistence) Create(correlationId string, item {{.Type}}) (result {{.Type}}, err error) {
+	value, err := c.IdentifiableMemoryPersistence.Create(correlationId, item)
+	result, _ = value.({{.Type}})
+	return result, err
+}
+
+// Update updates an item.
+func (c *{{.Type}}{{$kind}}Persistence) Update(correlationId string, item {{.Type}}) (result {{.Type}}, err error) {
+	value, err := c.IdentifiableMemoryPersistence.Update(correlationId, item)
+	result, _ = value.({{.Type}})
+	return result, err
+}
+
+// Set creates or updates an item.
+func (c *{{.Type}}{{$kind}}Persistence) Set(correlationId string, item {{.Type}}) (result {{.Type}}, err error) {
+	value, err := c.IdentifiableMemoryPersistence.Set(correlationId, item)
+	result, _ = value.({{.Type}})
+	return result, err
+}
+
+// UpdatePartially updates selected fields of an item.
+func (c *{{.Type}}{{$kind}}Persistence) UpdatePartially(correlationId string, id {{.IdType}}, data *cdata.AnyValueMap) (result {{.Type}}, err error) {
+	value, err := c.IdentifiableMemoryPersistence.UpdatePartially(correlationId, id, data)
+	result, _ = value.({{.Type}})
+	return result, err
+}
+
+// GetOneById gets an item by its id.
+func (c *{{.Type}}{{$kind}}Persistence) GetOneById(correlationId string, id {{.IdType}}) (result {{.Type}}, err error) {
+	value, err := c.IdentifiableMemoryPersistence.GetOneById(correlationId, id)
+	result, _ = value.({{.Type}})
+	return result, err
+}
+
+// GetListByIds gets a list of items by their ids.
+func (c *{{.Type}}{{$kind}}Persistence) GetListByIds(correlationId string, ids []{{.IdType}}) (items []{{.Type}}, err error) {
+	values, err := c.IdentifiableMemoryPersistence.GetListByIds(correlationId, from{{.Type}}Ids(ids))
+	return to{{.Type}}List(values), err
+}
+
+// GetPageByFilter gets a page of items by filter parameters.
+func (c *{{.Type}}{{$kind}}Persistence) GetPageByFilter(correlationId string, filter *cdata.FilterParams,
+	paging *cdata.PagingParams, sort *cdata.SortParams) (page *{{.Type}}Page, err error) {
+	sortFunc, err := c.ComposeSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.IdentifiableMemoryPersistence.GetPageByFilterParams(correlationId, filter, paging, sortFunc)
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Type}}Page{Total: values.Total, Data: to{{.Type}}List(values.Data)}, nil
+}
+
+// GetListByFilter gets a list of items by filter parameters.
+func (c *{{.Type}}{{$kind}}Persistence) GetListByFilter(correlationId string, filter *cdata.FilterParams,
+	sort *cdata.SortParams) (items []{{.Type}}, err error) {
+	sortFunc, err := c.ComposeSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.IdentifiableMemoryPersistence.GetListByFilterParams(correlationId, filter, sortFunc)
+	return to{{.Type}}List(values), err
+}
+
+// GetCountByFilter gets a number of items by filter parameters.
+func (c *{{.Type}}{{$kind}}Persistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
+	return c.IdentifiableMemoryPersistence.GetCountByFilterParams(correlationId, filter)
+}
+
+// DeleteById deletes an item by its id.
+func (c *{{.Type}}{{$kind}}Persistence) DeleteById(correlationId string, id {{.IdType}}) (result {{.Type}}, err error) {
+	value, err := c.IdentifiableMemoryPersistence.DeleteById(correlationId, id)
+	result, _ = value.({{.Type}})
+	return result, err
+}
+
+// DeleteByIds deletes items by their ids.
+func (c *{{.Type}}{{$kind}}Persistence) DeleteByIds(correlationId string, ids []{{.IdType}}) error {
+	return c.IdentifiableMemoryPersistence.DeleteByIds(correlationId, from{{.Type}}Ids(ids))
+}
+{{end}}{{end}}`
+
+// Template of fixture tests for generated persistence components
+const testsTemplate = `// Code generated by persistencegen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"path/filepath"
+	"testing"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// Runs CRUD operations over the typed persistence
+func test{{.Type}}Crud(t *testing.T, persistence interface {
+	Create(correlationId string, item {{.Type}}) ({{.Type}}, error)
+	GetOneById(correlationId string, id {{.IdType}}) ({{.Type}}, error)
+	GetPageByFilter(correlationId string, filter *cdata.FilterParams, paging *cdata.PagingParams, sort *cdata.SortParams) (*{{.Type}}Page, error)
+	DeleteById(correlationId string, id {{.IdType}}) ({{.Type}}, error)
+}) {
+	item := {{.Type}}{}
+	item.{{.IdField}} = {{.IdValue}}
+
+	created, err := persistence.Create("", item)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.{{.IdField}} != item.{{.IdField}} {
+		t.Fatalf("Create returned id %v instead of %v", created.{{.IdField}}, item.{{.IdField}})
+	}
+
+	found, err := persistence.GetOneById("", item.{{.IdField}})
+	if err != nil || found.{{.IdField}} != item.{{.IdField}} {
+		t.Fatalf("GetOneById failed: %v", err)
+	}
+
+	page, err := persistence.GetPageByFilter("", New{{.Type}}Filter().FilterParams, cdata.NewEmptyPagingParams(), nil)
+	if err != nil || len(page.Data) != 1 {
+		t.Fatalf("GetPageByFilter failed: %v", err)
+	}
+
+	if _, err = persistence.DeleteById("", item.{{.IdField}}); err != nil {
+		t.Fatalf("DeleteById failed: %v", err)
+	}
+	page, err = persistence.GetPageByFilter("", nil, nil, nil)
+	if err != nil || len(page.Data) != 0 {
+		t.Fatalf("Item is not deleted: %v", err)
+	}
+}
+
+func Test{{.Type}}MemoryPersistence(t *testing.T) {
+	persistence := New{{.Type}}MemoryPersistence()
+	if err := persistence.Open(""); err != nil {
+		t.Fatal(err)
+	}
+	defer persistence.Close("")
+	test{{.Type}}Crud(t, persistence)
+}
+
+func Test{{.Type}}FilePersistence(t *testing.T) {
+	persistence := New{{.Type}}FilePersistence(filepath.Join(t.TempDir(), "{{.Type}}.json"))
+	if err := persistence.Open(""); err != nil {
+		t.Fatal(err)
+	}
+	defer persistence.Close("")
+	test{{.Type}}Crud(t, persistence)
+}
+`
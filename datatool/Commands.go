@@ -0,0 +1,201 @@
+package datatool
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Repeated flag that collects all its values.
+*/
+type listFlag []string
+
+func (c *listFlag) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *listFlag) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+/*
+Command of the tool with its usage and a function that runs it with parsed flags.
+*/
+type command struct {
+	usage string
+	run   func(c *Tool, flags *flag.FlagSet, args []string) error
+}
+
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"validate": {usage: "validate <file>", run: runValidate},
+		"compact":  {usage: "compact [-o <file>] <file>", run: runCompact},
+		"convert":  {usage: "convert -format json|ndjson [-o <file>] [-indent <str>] <file>", run: runConvert},
+		"diff":     {usage: "diff <old file> <new file>", run: runDiff},
+		"anonymize": {usage: "anonymize -rule <field>=<rule> [-rule ...] [-o <file>] <file>",
+			run: runAnonymize},
+		"query": {usage: "query [-filter <key>=<value> ...] [-sort [-]<field> ...] [-skip n] [-take n] <file>",
+			run: runQuery},
+	}
+}
+
+// Writes usage of all commands.
+// Parameters:
+//   - writer io.Writer
+//   a writer of the usage
+func Usage(writer io.Writer) {
+	fmt.Fprintln(writer, "usage: pipdata <command> [flags] <files>")
+	fmt.Fprintln(writer, "commands:")
+	for _, name := range commandNames() {
+		fmt.Fprintln(writer, "  "+commands[name].usage)
+	}
+	fmt.Fprintln(writer, "use - as a file to read from stdin or write to stdout")
+}
+
+// Runs a command with its flags and arguments, for instance
+// []string{"convert", "-format", "ndjson", "-o", "-", "data.json"}.
+// Parameters:
+//   - args []string
+//   a command name followed by its flags and arguments
+// Returns error
+// BadRequestError for unknown commands or invalid arguments, or error of the command.
+func (c *Tool) Run(args []string) error {
+	if len(args) == 0 {
+		return errors.NewBadRequestError(c.CorrelationId, "NO_COMMAND", "Command is not specified")
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return errors.NewBadRequestError(c.CorrelationId, "UNKNOWN_COMMAND", "Unknown command "+args[0]).
+			WithDetails("command", args[0])
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return cmd.run(c, flags, args[1:])
+}
+
+// Parses flags and checks the number of remaining arguments
+func (c *Tool) parse(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, errors.NewBadRequestError(c.CorrelationId, "INVALID_FLAGS", err.Error()).
+			WithDetails("command", flags.Name())
+	}
+	if flags.NArg() != count {
+		return nil, errors.NewBadRequestError(c.CorrelationId, "INVALID_ARGUMENTS",
+			fmt.Sprintf("Usage: pipdata %s", commands[flags.Name()].usage)).
+			WithDetails("command", flags.Name())
+	}
+	return flags.Args(), nil
+}
+
+// Gets the output path: the input path by default
+func outputPath(output string, input string) string {
+	if output == "" {
+		return input
+	}
+	return output
+}
+
+func runValidate(c *Tool, flags *flag.FlagSet, args []string) error {
+	files, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	report, err := c.Validate(files[0])
+	if err != nil {
+		return err
+	}
+	for _, problem := range report.Problems {
+		fmt.Fprintln(c.Out, problem)
+	}
+	if !report.Valid() {
+		return errors.NewBadRequestError(c.CorrelationId, "INVALID_DATA",
+			fmt.Sprintf("Found %d problems in %d items", len(report.Problems), report.Items)).
+			WithDetails("problems", len(report.Problems))
+	}
+	fmt.Fprintf(c.Out, "%d items are valid\n", report.Items)
+	return nil
+}
+
+func runCompact(c *Tool, flags *flag.FlagSet, args []string) error {
+	output := flags.String("o", "", "output file, the input file by default")
+	files, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	_, err = c.Compact(files[0], outputPath(*output, files[0]))
+	return err
+}
+
+func runConvert(c *Tool, flags *flag.FlagSet, args []string) error {
+	output := flags.String("o", "", "output file, the input file by default")
+	format := flags.String("format", FormatJson, "output format: json or ndjson")
+	flags.StringVar(&c.Indent, "indent", c.Indent, "indent of items in json format")
+	files, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	_, err = c.Convert(files[0], outputPath(*output, files[0]), *format)
+	return err
+}
+
+func runDiff(c *Tool, flags *flag.FlagSet, args []string) error {
+	files, err := c.parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	_, err = c.Diff(files[0], files[1])
+	return err
+}
+
+func runAnonymize(c *Tool, flags *flag.FlagSet, args []string) error {
+	output := flags.String("o", "", "output file, the input file by default")
+	var rulePairs listFlag
+	flags.Var(&rulePairs, "rule", "anonymize rule as <field>=<rule>")
+	files, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	rules, err := parseRules(rulePairs)
+	if err != nil {
+		return err
+	}
+	_, err = c.Anonymize(files[0], outputPath(*output, files[0]), rules)
+	return err
+}
+
+func runQuery(c *Tool, flags *flag.FlagSet, args []string) error {
+	var filterPairs, sortFields listFlag
+	flags.Var(&filterPairs, "filter", "filter parameter as <key>=<value>")
+	flags.Var(&sortFields, "sort", "sort field, - prefix sorts in descending order")
+	skip := flags.Int64("skip", -1, "number of items to skip")
+	take := flags.Int64("take", -1, "number of items to return")
+	files, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	filter, err := parseFilter(filterPairs)
+	if err != nil {
+		return err
+	}
+	var paging *cdata.PagingParams
+	if *skip >= 0 || *take >= 0 {
+		paging = cdata.NewPagingParams(nil, nil, false)
+		if *skip >= 0 {
+			paging.Skip = skip
+		}
+		if *take >= 0 {
+			paging.Take = take
+		}
+	}
+	_, err = c.Query(files[0], filter, parseSort(sortFields), paging)
+	return err
+}
@@ -0,0 +1,402 @@
+package datatool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+)
+
+// Formats of data files
+const (
+	// JSON array of items written by JsonFilePersister
+	FormatJson = "json"
+	// Newline delimited JSON with one item per line
+	FormatNdjson = "ndjson"
+)
+
+/*
+Tool performs maintenance of data files produced by JsonFilePersister:
+validation, compaction, conversion between formats, diffs, anonymization and queries.
+Items are handled as maps, so the tool works with data files of any type.
+Path "-" reads items from In and writes them to Out.
+
+It is used by pipdata command, but can be embedded into other tools.
+
+Example
+
+  tool := datatool.NewTool(os.Stdin, os.Stdout)
+  report, err := tool.Validate("./data/orders.json")
+  if err == nil && !report.Valid() {
+      fmt.Println(report.Problems)
+  }
+*/
+type Tool struct {
+	// Reader of items for "-" path
+	In io.Reader
+	// Writer of results and items for "-" path
+	Out io.Writer
+	// Transaction id passed to persistence operations
+	CorrelationId string
+	// String to indent items in written JSON files, empty to write compact JSON
+	Indent string
+}
+
+/*
+Results of data file validation.
+*/
+type ValidationReport struct {
+	// Number of items in the file
+	Items int
+	// Descriptions of found problems
+	Problems []string
+}
+
+// Checks if no problems were found.
+// Returns bool
+func (c *ValidationReport) Valid() bool {
+	return len(c.Problems) == 0
+}
+
+// Creates a new tool.
+// Parameters:
+//   - in io.Reader
+//   a reader of items for "-" path
+//   - out io.Writer
+//   a writer of results
+// Returns *Tool
+func NewTool(in io.Reader, out io.Writer) *Tool {
+	return &Tool{In: in, Out: out, CorrelationId: "pipdata"}
+}
+
+// Creates a persister of items as maps
+func (c *Tool) persister(path string) *persistence.JsonFilePersister {
+	persister := persistence.NewJsonFilePersister(reflect.TypeOf(map[string]interface{}{}), path)
+	persister.Stdin = c.In
+	persister.Stdout = c.Out
+	persister.Indent = c.Indent
+	return persister
+}
+
+// Opens a file or In for "-" path
+func (c *Tool) open(path string) (io.ReadCloser, error) {
+	if path == persistence.StdioPath {
+		return io.NopCloser(c.In), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NewFileError(c.CorrelationId, "READ_FAILED", "Failed to read data file: "+path).WithCause(err)
+	}
+	return file, nil
+}
+
+// Reads items from JSON array or newline delimited JSON
+func (c *Tool) read(path string) ([]interface{}, error) {
+	source, err := c.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	reader := bufio.NewReader(source)
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return []interface{}{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewFileError(c.CorrelationId, "READ_FAILED", "Failed to read data file: "+path).WithCause(err)
+	}
+	if first == '[' {
+		items, err := c.persister(path).LoadFrom(c.CorrelationId, reader)
+		if items == nil && err == nil {
+			items = []interface{}{}
+		}
+		return items, err
+	}
+
+	items := []interface{}{}
+	decoder := json.NewDecoder(reader)
+	for {
+		var item interface{}
+		if err = decoder.Decode(&item); err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, errors.NewFileError(c.CorrelationId, "PARSE_FAILED",
+				fmt.Sprintf("Failed to parse item %d in %s", len(items), path)).WithCause(err)
+		}
+		items = append(items, item)
+	}
+}
+
+// Writes items in the format into a file or Out for "-" path
+func (c *Tool) write(path string, format string, items []interface{}) error {
+	switch format {
+	case "", FormatJson:
+		return c.persister(path).Save(c.CorrelationId, items)
+	case FormatNdjson:
+		writer := c.Out
+		if path != persistence.StdioPath {
+			file, err := os.Create(path)
+			if err != nil {
+				return errors.NewFileError(c.CorrelationId, "WRITE_FAILED", "Failed to write data file: "+path).WithCause(err)
+			}
+			defer file.Close()
+			writer = file
+		}
+		buffer := bufio.NewWriter(writer)
+		encoder := json.NewEncoder(buffer)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return errors.NewInternalError(c.CorrelationId, "CAN'T_CONVERT", "Failed convert to JSON").WithCause(err)
+			}
+		}
+		if err := buffer.Flush(); err != nil {
+			return errors.NewFileError(c.CorrelationId, "WRITE_FAILED", "Failed to write data file: "+path).WithCause(err)
+		}
+		return nil
+	default:
+		return errors.NewBadRequestError(c.CorrelationId, "UNKNOWN_FORMAT", "Unknown format "+format).
+			WithDetails("format", format)
+	}
+}
+
+// Skips leading white spaces and gets the first character without consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		ch, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if ch != ' ' && ch != '\t' && ch != '\r' && ch != '\n' {
+			return ch, reader.UnreadByte()
+		}
+	}
+}
+
+// Validates a data file: all items must be objects with unique not empty ids.
+// Parameters:
+//   - path string
+//   a path to the data file
+// Returns *ValidationReport, error
+// found problems or error if the file can't be read or parsed.
+func (c *Tool) Validate(path string) (*ValidationReport, error) {
+	items, err := c.read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Items: len(items), Problems: []string{}}
+	ids := map[string]int{}
+	for index, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			report.Problems = append(report.Problems, fmt.Sprintf("item %d is not an object", index))
+			continue
+		}
+		id := persistence.GetObjectId(fields)
+		if id == nil || id == "" {
+			report.Problems = append(report.Problems, fmt.Sprintf("item %d has no id", index))
+			continue
+		}
+		key := fmt.Sprint(id)
+		if previous, ok := ids[key]; ok {
+			report.Problems = append(report.Problems, fmt.Sprintf("item %d has the same id %s as item %d", index, key, previous))
+			continue
+		}
+		ids[key] = index
+	}
+	return report, nil
+}
+
+// Rewrites a data file as compact JSON without white spaces.
+// Parameters:
+//   - input string
+//   a path to the data file
+//   - output string
+//   a path to the compacted file, it can be the same as input
+// Returns int, error
+// number of written items or error.
+func (c *Tool) Compact(input string, output string) (int, error) {
+	items, err := c.read(input)
+	if err != nil {
+		return 0, err
+	}
+	indent := c.Indent
+	c.Indent = ""
+	defer func() { c.Indent = indent }()
+	return len(items), c.write(output, FormatJson, items)
+}
+
+// Converts a data file in JSON or newline delimited JSON into the format.
+// Parameters:
+//   - input string
+//   a path to the data file
+//   - output string
+//   a path to the converted file
+//   - format string
+//   a format of the converted file: json or ndjson
+// Returns int, error
+// number of written items or error.
+func (c *Tool) Convert(input string, output string, format string) (int, error) {
+	items, err := c.read(input)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), c.write(output, format, items)
+}
+
+// Compares two data files by item ids and writes differences to Out as JSON lines.
+// Parameters:
+//   - oldPath string
+//   a path to the old data file
+//   - newPath string
+//   a path to the new data file
+// Returns []*persistence.ItemDifference, error
+// differences or error.
+func (c *Tool) Diff(oldPath string, newPath string) ([]*persistence.ItemDifference, error) {
+	oldItems, err := c.read(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newItems, err := c.read(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	differences := persistence.DiffItems(oldItems, newItems)
+	encoder := json.NewEncoder(c.Out)
+	for _, difference := range differences {
+		line := map[string]interface{}{"id": difference.Id, "change": difference.Change}
+		if difference.Change == persistence.DiffUpdated {
+			line["fields"] = difference.Fields
+		}
+		if err = encoder.Encode(line); err != nil {
+			return nil, errors.NewInternalError(c.CorrelationId, "CAN'T_CONVERT", "Failed convert to JSON").WithCause(err)
+		}
+	}
+	return differences, nil
+}
+
+// Replaces personal data in a data file with fake values.
+// Parameters:
+//   - input string
+//   a path to the data file
+//   - output string
+//   a path to the anonymized file
+//   - rules persistence.AnonymizeRules
+//   anonymize rules for field paths
+// Returns int, error
+// number of written items or error.
+func (c *Tool) Anonymize(input string, output string, rules persistence.AnonymizeRules) (int, error) {
+	anonymizer, err := persistence.NewAnonymizer(rules)
+	if err != nil {
+		return 0, err
+	}
+	items, err := c.read(input)
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if items[i], err = anonymizer.Anonymize(item); err != nil {
+			return 0, err
+		}
+	}
+	return len(items), c.write(output, FormatJson, items)
+}
+
+// Selects items from a data file with filter, sort and paging parameters
+// and writes them to Out as JSON array.
+// Parameters:
+//   - path string
+//   a path to the data file
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters with field values
+//   - sort *cdata.SortParams
+//   (optional) sort fields
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+// Returns []interface{}, error
+// selected items or error.
+func (c *Tool) Query(path string, filter *cdata.FilterParams, sort *cdata.SortParams,
+	paging *cdata.PagingParams) ([]interface{}, error) {
+	items, err := c.read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := persistence.NewMemoryPersistence(reflect.TypeOf(map[string]interface{}{}))
+	memory.MaxPageSize = len(items) + 1
+	memory.CloneStrategy = persistence.CloneStrategyNone
+	memory.Items = items
+	sortFunc, err := memory.ComposeSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	page, err := memory.GetPageByFilterParams(c.CorrelationId, filter, paging, sortFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.persister(persistence.StdioPath).SaveTo(c.CorrelationId, c.Out, page.Data); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(c.Out)
+	return page.Data, nil
+}
+
+// Parses filter parameters from "key=value" pairs
+func parseFilter(pairs []string) (*cdata.FilterParams, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.NewBadRequestError("", "INVALID_FILTER", "Filter must be key=value: "+pair)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return cdata.NewFilterParams(values), nil
+}
+
+// Parses sort parameters from field names, "-" prefix sorts in descending order
+func parseSort(fields []string) *cdata.SortParams {
+	if len(fields) == 0 {
+		return nil
+	}
+	sortFields := make([]cdata.SortField, len(fields))
+	for i, field := range fields {
+		sortFields[i] = cdata.NewSortField(strings.TrimPrefix(field, "-"), !strings.HasPrefix(field, "-"))
+	}
+	return cdata.NewSortParams(sortFields)
+}
+
+// Parses anonymize rules from "field=rule" pairs
+func parseRules(pairs []string) (persistence.AnonymizeRules, error) {
+	rules := persistence.AnonymizeRules{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.NewBadRequestError("", "INVALID_RULE", "Rule must be field=rule: "+pair)
+		}
+		rules[parts[0]] = parts[1]
+	}
+	return rules, nil
+}
+
+// Gets sorted names of commands
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
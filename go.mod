@@ -6,5 +6,11 @@ require (
 	github.com/jinzhu/copier v0.2.8
 	github.com/pip-services3-go/pip-services3-commons-go v1.1.0
 	github.com/pip-services3-go/pip-services3-components-go v1.1.0
-	github.com/stretchr/testify v1.7.0
+	github.com/prometheus/client_golang v1.16.0
+	github.com/stretchr/testify v1.8.3
+	golang.org/x/net v0.11.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/text v0.13.0
+	google.golang.org/grpc v1.56.3
+	google.golang.org/protobuf v1.31.0
 )
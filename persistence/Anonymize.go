@@ -0,0 +1,194 @@
+package persistence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	mrand "math/rand"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Rules to replace field values with fake values in anonymized exports
+const (
+	// Replaces the value with a fake email like jane.smith42@example.com
+	AnonymizeEmail = "email"
+	// Replaces the value with a fake person name
+	AnonymizeName = "name"
+	// Replaces digits with random digits keeping other characters, for phones, cards or numbers
+	AnonymizeDigits = "digits"
+	// Replaces letters and digits with random ones keeping case, length and punctuation
+	AnonymizeMask = "mask"
+	// Replaces the value with a hex hash
+	AnonymizeHash = "hash"
+	// Clears the value
+	AnonymizeClear = "clear"
+)
+
+// AnonymizeRules maps field paths like "email" or "address.street" to anonymize rules.
+type AnonymizeRules map[string]string
+
+var anonymizeFirstNames = []string{"Alex", "Maria", "John", "Emma", "David", "Olivia", "Peter", "Sophia", "Daniel", "Anna"}
+var anonymizeLastNames = []string{"Smith", "Johnson", "Brown", "Taylor", "Miller", "Wilson", "Moore", "Clark", "Lewis", "Walker"}
+
+/*
+Anonymizer replaces configured fields of items with fake but format-valid values.
+Equal values of a field get equal fake values within one anonymizer,
+so references between items are preserved, while different anonymizers
+use different random salts and their results can't be matched.
+*/
+type Anonymizer struct {
+	rules AnonymizeRules
+	salt  string
+}
+
+// Creates a new anonymizer with a random salt.
+// Parameters:
+//   - rules AnonymizeRules
+//   anonymize rules for field paths
+// Returns *Anonymizer, error
+// the anonymizer or BadRequestError if some rules are unknown.
+func NewAnonymizer(rules AnonymizeRules) (*Anonymizer, error) {
+	for path, rule := range rules {
+		switch rule {
+		case AnonymizeEmail, AnonymizeName, AnonymizeDigits, AnonymizeMask, AnonymizeHash, AnonymizeClear:
+		default:
+			return nil, errors.NewBadRequestError("", "UNKNOWN_ANONYMIZE_RULE",
+				"Unknown anonymize rule "+rule+" for field "+path).
+				WithDetails("field", path).WithDetails("rule", rule)
+		}
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &Anonymizer{rules: rules, salt: hex.EncodeToString(salt)}, nil
+}
+
+// Creates a random generator seeded by the salted value
+func (c *Anonymizer) random(rule string, value string) *mrand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(c.salt + ":" + rule + ":" + value))
+	return mrand.New(mrand.NewSource(int64(hash.Sum64())))
+}
+
+// Generates a fake value for the original one
+func (c *Anonymizer) fake(rule string, value string) string {
+	random := c.random(rule, value)
+	switch rule {
+	case AnonymizeEmail:
+		first := anonymizeFirstNames[random.Intn(len(anonymizeFirstNames))]
+		last := anonymizeLastNames[random.Intn(len(anonymizeLastNames))]
+		return fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), random.Intn(1000))
+	case AnonymizeName:
+		first := anonymizeFirstNames[random.Intn(len(anonymizeFirstNames))]
+		last := anonymizeLastNames[random.Intn(len(anonymizeLastNames))]
+		return first + " " + last
+	case AnonymizeHash:
+		hash := fnv.New64a()
+		hash.Write([]byte(c.salt + ":" + value))
+		return fmt.Sprintf("%016x", hash.Sum64())
+	}
+
+	// Mask and digits rules keep the format of the value
+	result := []rune(value)
+	for i, r := range result {
+		switch {
+		case unicode.IsDigit(r):
+			// Leading digit stays non-zero to keep the number of digits
+			if i == 0 || !unicode.IsDigit(result[i-1]) {
+				result[i] = rune('1' + random.Intn(9))
+			} else {
+				result[i] = rune('0' + random.Intn(10))
+			}
+		case rule == AnonymizeMask && unicode.IsUpper(r):
+			result[i] = rune('A' + random.Intn(26))
+		case rule == AnonymizeMask && unicode.IsLower(r):
+			result[i] = rune('a' + random.Intn(26))
+		}
+	}
+	return string(result)
+}
+
+// Returns an anonymized copy of the item. The original item is not changed.
+// Fields that are not present in the item are skipped.
+// Parameters:
+//   - item interface{}
+//   an item to anonymize
+// Returns interface{}, error
+// anonymized item or error if a fake value can't be set.
+func (c *Anonymizer) Anonymize(item interface{}) (interface{}, error) {
+	result := item
+	for path, rule := range c.rules {
+		value := GetPathValue(result, path)
+		if value == nil {
+			continue
+		}
+		var fake interface{}
+		if rule == AnonymizeClear {
+			fake = reflect.Zero(reflect.TypeOf(value)).Interface()
+		} else {
+			fake = c.fake(rule, convert.StringConverter.ToString(value))
+		}
+		if err := SetPathValue(&result, path, fake); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Returns an anonymized copy of the item using a new anonymizer.
+// Parameters:
+//   - item interface{}
+//   an item to anonymize
+//   - rules AnonymizeRules
+//   anonymize rules for field paths
+// Returns interface{}, error
+// anonymized item or error.
+func Anonymize(item interface{}, rules AnonymizeRules) (interface{}, error) {
+	anonymizer, err := NewAnonymizer(rules)
+	if err != nil {
+		return nil, err
+	}
+	return anonymizer.Anonymize(item)
+}
+
+// Exports all items with anonymized fields into the saver, for instance
+// a JsonFilePersister, so production data can be shared with developers.
+// Stored items are not changed.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - saver ISaver
+//   a saver to write anonymized items
+//   - rules AnonymizeRules
+//   anonymize rules for field paths
+// Returns error or nil for success.
+func (c *MemoryPersistence) ExportAnonymized(correlationId string, saver ISaver, rules AnonymizeRules) error {
+	anonymizer, err := NewAnonymizer(rules)
+	if err != nil {
+		return wrapError(err, correlationId, "ANONYMIZE_FAILED", "Failed to create anonymizer")
+	}
+
+	c.rlock()
+	items := make([]interface{}, len(c.Items))
+	copy(items, c.Items)
+	c.runlock()
+
+	for i, item := range items {
+		if items[i], err = anonymizer.Anonymize(item); err != nil {
+			return wrapError(err, correlationId, "ANONYMIZE_FAILED", "Failed to anonymize item")
+		}
+	}
+
+	if err = saver.Save(correlationId, items); err != nil {
+		return err
+	}
+	c.Logger.Trace(correlationId, "Exported %d anonymized items", len(items))
+	return nil
+}
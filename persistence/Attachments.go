@@ -0,0 +1,329 @@
+package persistence
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Attachment is metadata of content stored by AttachmentFilePersistence.
+type Attachment struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	// Size of the content in bytes
+	Size int64 `json:"size"`
+	// Number of content chunks
+	Chunks     int       `json:"chunks"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+/*
+BlobFileStore keeps binary content in chunk files, one directory per blob.
+Content is written and read as streams, so large blobs never have to fit into memory.
+New content is written into a temporary directory and replaces the old one only when it's complete.
+*/
+type BlobFileStore struct {
+	// Directory where blobs are stored
+	Path string
+	// Maximum size of a chunk file in bytes
+	ChunkSize int
+	// Persister whose mode and owner are given to chunk files, chunk files are private when it's not set
+	permissions *JsonFilePersister
+}
+
+// Creates a new store of blobs.
+// Parameters:
+//   - path string
+//   a directory where blobs are stored
+// Returns *BlobFileStore
+func NewBlobFileStore(path string) *BlobFileStore {
+	return &BlobFileStore{Path: path, ChunkSize: 1024 * 1024}
+}
+
+// Gets a directory of the blob
+func (c *BlobFileStore) blobDir(correlationId string, id string) (string, error) {
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id {
+		return "", errors.NewBadRequestError(correlationId, "INVALID_BLOB_ID", "Invalid blob id "+id).
+			WithDetails("id", id)
+	}
+	return filepath.Join(c.Path, id), nil
+}
+
+// Writes content of a blob in chunks, replacing the old content.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   a blob id
+//   - content io.Reader
+//   a stream of content to write
+// Returns int64, int, error
+// size of the content, number of chunks or error.
+func (c *BlobFileStore) Write(correlationId string, id string, content io.Reader) (size int64, chunks int, err error) {
+	dir, err := c.blobDir(correlationId, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	writeError := func(err error) error {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write blob "+id).WithCause(err)
+	}
+
+	temp := dir + ".tmp"
+	os.RemoveAll(temp)
+	if err = os.MkdirAll(temp, 0755); err != nil {
+		return 0, 0, writeError(err)
+	}
+	defer os.RemoveAll(temp)
+
+	buffer := make([]byte, c.ChunkSize)
+	for {
+		n, readErr := io.ReadFull(content, buffer)
+		if n > 0 {
+			chunkPath := filepath.Join(temp, fmt.Sprintf("%06d.chunk", chunks))
+			if err = c.writeChunk(correlationId, chunkPath, buffer[:n]); err != nil {
+				return 0, 0, writeError(err)
+			}
+			size += int64(n)
+			chunks++
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return 0, 0, writeError(readErr)
+		}
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return 0, 0, writeError(err)
+	}
+	if err = os.Rename(temp, dir); err != nil {
+		return 0, 0, writeError(err)
+	}
+	return size, chunks, nil
+}
+
+// Writes a chunk file with the mode and owner of the data file
+func (c *BlobFileStore) writeChunk(correlationId string, path string, content []byte) error {
+	if c.permissions == nil {
+		return os.WriteFile(path, content, 0600)
+	}
+	return c.permissions.writeSideFile(correlationId, path, content)
+}
+
+// Opens a stream to read content of a blob.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   a blob id
+// Returns io.ReadCloser, error
+// a stream of content or NotFoundError if the blob doesn't exist.
+func (c *BlobFileStore) Read(correlationId string, id string) (io.ReadCloser, error) {
+	dir, err := c.blobDir(correlationId, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		return nil, errors.NewNotFoundError(correlationId, "BLOB_NOT_FOUND", "Blob "+id+" was not found").
+			WithDetails("id", id)
+	}
+	chunks, err := filepath.Glob(filepath.Join(dir, "*.chunk"))
+	if err != nil {
+		return nil, errors.NewFileError(correlationId, "READ_FAILED", "Failed to read blob "+id).WithCause(err)
+	}
+	sort.Strings(chunks)
+	return &chunkReader{chunks: chunks}, nil
+}
+
+// Deletes content of a blob. Missing blobs are ignored.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   a blob id
+// Returns error or nil for success.
+func (c *BlobFileStore) Delete(correlationId string, id string) error {
+	dir, err := c.blobDir(correlationId, id)
+	if err != nil {
+		return err
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return errors.NewFileError(correlationId, "DELETE_FAILED", "Failed to delete blob "+id).WithCause(err)
+	}
+	return nil
+}
+
+// Reads chunk files one by one as a single stream
+type chunkReader struct {
+	chunks  []string
+	current *os.File
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.chunks) == 0 {
+				return 0, io.EOF
+			}
+			file, err := os.Open(c.chunks[0])
+			if err != nil {
+				return 0, err
+			}
+			c.current = file
+			c.chunks = c.chunks[1:]
+		}
+		n, err := c.current.Read(p)
+		if err == io.EOF {
+			c.current.Close()
+			c.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	c.chunks = nil
+	if c.current != nil {
+		err := c.current.Close()
+		c.current = nil
+		return err
+	}
+	return nil
+}
+
+/*
+AttachmentFilePersistence stores attachment metadata in a JSON file and their content
+in a BlobFileStore. Metadata and content are linked by attachment id, and content
+is deleted together with metadata however the attachment is deleted.
+
+Configuration parameters
+
+- path:                    path to the file where attachment metadata is stored
+- content_path:            directory where attachment content is stored
+- options:
+    - chunk_size:          Maximum size of content chunk files in bytes (default: 1048576)
+
+Example
+
+    attachments := NewAttachmentFilePersistence("./data/attachments.json", "./data/attachments")
+    attachments.Open("123")
+
+    file, _ := os.Open("report.pdf")
+    attachment, err := attachments.CreateAttachment("123",
+        Attachment{Name: "report.pdf", ContentType: "application/pdf"}, file)
+
+    content, err := attachments.ReadContent("123", attachment.Id)
+    defer content.Close()
+    io.Copy(os.Stdout, content)
+
+    attachments.DeleteById("123", attachment.Id)
+*/
+type AttachmentFilePersistence struct {
+	IdentifiableFilePersistence
+	Content *BlobFileStore
+}
+
+// Creates a new persistence of attachments.
+// Parameters:
+//   - path string
+//   a path to the file where attachment metadata is stored
+//   - contentPath string
+//   a directory where attachment content is stored
+// Returns *AttachmentFilePersistence
+func NewAttachmentFilePersistence(path string, contentPath string) *AttachmentFilePersistence {
+	prototype := reflect.TypeOf(Attachment{})
+	c := &AttachmentFilePersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+		Content:                     NewBlobFileStore(contentPath),
+	}
+	c.Content.permissions = c.Persister
+	// Content is removed by all kinds of deletes
+	c.addChangeHandler(func(oldItem interface{}, newItem interface{}) {
+		if attachment, ok := oldItem.(Attachment); ok && newItem == nil {
+			if err := c.Content.Delete("", attachment.Id); err != nil {
+				c.Logger.Warn("", "Failed to delete content of attachment %s: %v", attachment.Id, err)
+			}
+		}
+	})
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *AttachmentFilePersistence) Configure(config *config.ConfigParams) {
+	c.IdentifiableFilePersistence.Configure(config)
+
+	c.lock()
+	c.Content.Path = config.GetAsStringWithDefault("content_path", c.Content.Path)
+	c.Content.ChunkSize = config.GetAsIntegerWithDefault("options.chunk_size", c.Content.ChunkSize)
+	c.unlock()
+}
+
+// Creates an attachment and writes its content.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - attachment Attachment
+//   metadata of the attachment, the id is generated if it's empty
+//   - content io.Reader
+//   a stream of the attachment content
+// Returns Attachment, error
+// created attachment with content size or error.
+func (c *AttachmentFilePersistence) CreateAttachment(correlationId string, attachment Attachment,
+	content io.Reader) (Attachment, error) {
+	var item interface{} = attachment
+	c.ids.generate(&item)
+	attachment, _ = item.(Attachment)
+	if attachment.CreateTime.IsZero() {
+		attachment.CreateTime = c.Clock.Now().UTC()
+	}
+
+	size, chunks, err := c.Content.Write(correlationId, attachment.Id, content)
+	if err != nil {
+		return Attachment{}, err
+	}
+	attachment.Size = size
+	attachment.Chunks = chunks
+
+	result, err := c.IdentifiableMemoryPersistence.Create(correlationId, attachment)
+	if err != nil {
+		c.Content.Delete(correlationId, attachment.Id)
+		return Attachment{}, err
+	}
+	attachment, _ = result.(Attachment)
+	return attachment, nil
+}
+
+// Opens a stream to read content of an attachment.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   an attachment id
+// Returns io.ReadCloser, error
+// a stream of content that must be closed or NotFoundError if the attachment doesn't exist.
+func (c *AttachmentFilePersistence) ReadContent(correlationId string, id string) (io.ReadCloser, error) {
+	c.rlock()
+	index := c.GetIndexById(id)
+	c.runlock()
+	if index < 0 {
+		return nil, errors.NewNotFoundError(correlationId, "ATTACHMENT_NOT_FOUND", "Attachment "+id+" was not found").
+			WithDetails("id", id)
+	}
+	return c.Content.Read(correlationId, id)
+}
@@ -0,0 +1,126 @@
+package persistence
+
+import (
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+)
+
+// Converts items into the prototype and sets them in one locked operation
+// with a single notification of change handlers and a single save.
+// When replace is false the items are added to the existing ones.
+// The prepare function, if set, is applied to every converted item.
+func (c *MemoryPersistence) setAll(correlationId string, items []interface{}, replace bool,
+	prepare func(items []interface{}, item *interface{})) error {
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return err
+	}
+
+	var newItems []interface{}
+	if replace {
+		newItems = make([]interface{}, 0, len(items))
+	} else {
+		newItems = make([]interface{}, len(c.Items), len(c.Items)+len(items))
+		copy(newItems, c.Items)
+	}
+	for _, item := range items {
+		newItem := c.cloneItem(item)
+		if newItem == nil {
+			c.unlock()
+			return newInvalidItemError(correlationId)
+		}
+		c.applyComputedFields(&newItem)
+		if prepare != nil {
+			prepare(newItems, &newItem)
+		}
+		if newItem != nil {
+			newItems = append(newItems, newItem)
+		}
+	}
+	if err := c.checkQuotas(correlationId, newItems); err != nil {
+		c.unlock()
+		return err
+	}
+
+	c.Items = newItems
+	c.notifyChange(nil, nil)
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Set %d items in bulk", len(items))
+
+	return c.save(correlationId)
+}
+
+// Replaces all data items in one locked operation. Change handlers and indexes
+// are rebuilt once and items are saved once, which is much faster
+// than deleting and creating items one by one.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - items []interface{}
+//   new data items
+// Returns error or nil for success.
+func (c *MemoryPersistence) ReplaceAll(correlationId string, items []interface{}) error {
+	return c.setAll(correlationId, items, true, nil)
+}
+
+// Adds data items in bulk in one locked operation with a single save.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - items []interface{}
+//   data items to add
+// Returns error or nil for success.
+func (c *MemoryPersistence) LoadAll(correlationId string, items []interface{}) error {
+	return c.setAll(correlationId, items, false, nil)
+}
+
+// Generates missing ids, normalizes ids and replaces items with the same ids
+func (c *IdentifiableMemoryPersistence) prepareBulkItem(items []interface{}, item *interface{}, indexes map[string]int) {
+	c.ids.generate(item)
+	c.normalizeItemId(item)
+	key := convert.StringConverter.ToString(c.ids.get(*item))
+	if index, ok := indexes[key]; ok {
+		items[index] = *item
+		*item = nil
+		return
+	}
+	indexes[key] = len(items)
+}
+
+// Replaces all data items in one locked operation. Missing ids are generated
+// and items with duplicated ids replace the previous ones.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - items []interface{}
+//   new data items
+// Returns error or nil for success.
+func (c *IdentifiableMemoryPersistence) ReplaceAll(correlationId string, items []interface{}) error {
+	indexes := map[string]int{}
+	return c.setAll(correlationId, items, true, func(items []interface{}, item *interface{}) {
+		c.prepareBulkItem(items, item, indexes)
+	})
+}
+
+// Adds data items in bulk in one locked operation with a single save.
+// Missing ids are generated and items with existing ids replace the stored ones.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - items []interface{}
+//   data items to add or replace
+// Returns error or nil for success.
+func (c *IdentifiableMemoryPersistence) LoadAll(correlationId string, items []interface{}) error {
+	var indexes map[string]int
+	return c.setAll(correlationId, items, false, func(items []interface{}, item *interface{}) {
+		if indexes == nil {
+			indexes = make(map[string]int, len(items))
+			for i, existing := range items {
+				indexes[convert.StringConverter.ToString(c.ids.get(existing))] = i
+			}
+		}
+		c.prepareBulkItem(items, item, indexes)
+	})
+}
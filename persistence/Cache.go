@@ -0,0 +1,206 @@
+package persistence
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// CacheEntry is a cached value stored by CacheFilePersistence.
+type CacheEntry struct {
+	// Key of the value
+	Id    string      `json:"id"`
+	Value interface{} `json:"value"`
+	// Time when the value expires
+	Expiration time.Time `json:"expiration"`
+}
+
+/*
+CacheFilePersistence is an ICache implementation that keeps cached values in a JSON file,
+so they survive restarts. Values are stored with timeouts, and when the cache grows
+over its maximum size expired values and then values that expire first are evicted.
+
+Values retrieved after a restart are decoded from JSON, so structs come back as maps.
+
+Configuration parameters
+
+- path:                    path to the file where cached values are stored
+- options:
+    - timeout:             Default timeout of cached values in milliseconds (default: 60000)
+    - max_size:            Maximum number of cached values, 0 for unlimited (default: 1000)
+
+Example
+
+    cache := NewCacheFilePersistence("./data/cache.json")
+    cache.Open("123")
+
+    cache.Store("123", "key1", "ABC", 10000)
+    value, err := cache.Retrieve("123", "key1")    // Result: "ABC"
+    cache.Remove("123", "key1")
+*/
+// implements ICache
+type CacheFilePersistence struct {
+	IdentifiableFilePersistence
+	// Default timeout of cached values in milliseconds
+	Timeout int64
+	// Maximum number of cached values, 0 for unlimited
+	MaxSize int
+}
+
+// Creates a new cache stored in a file.
+// Parameters:
+//   - path string
+//   a path to the file where cached values are stored
+// Returns *CacheFilePersistence
+func NewCacheFilePersistence(path string) *CacheFilePersistence {
+	prototype := reflect.TypeOf(CacheEntry{})
+	return &CacheFilePersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+		Timeout:                     60000,
+		MaxSize:                     1000,
+	}
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *CacheFilePersistence) Configure(config *config.ConfigParams) {
+	c.IdentifiableFilePersistence.Configure(config)
+
+	c.lock()
+	c.Timeout = config.GetAsLongWithDefault("options.timeout", c.Timeout)
+	c.MaxSize = config.GetAsIntegerWithDefault("options.max_size", c.MaxSize)
+	c.unlock()
+}
+
+// Retrieves a cached value by its key.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - key string
+//   a unique value key.
+// Returns interface{}, error
+// the cached value or nil if the value is missing or expired.
+func (c *CacheFilePersistence) Retrieve(correlationId string, key string) (interface{}, error) {
+	c.rlock()
+	defer c.runlock()
+
+	if index := c.GetIndexById(key); index >= 0 {
+		if entry, ok := c.Items[index].(CacheEntry); ok && entry.Expiration.After(c.Clock.Now()) {
+			return entry.Value, nil
+		}
+	}
+	return nil, nil
+}
+
+// Retrieves a cached value by its key into a reference object.
+// Values are converted through JSON, so structs can be restored after a restart.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - key string
+//   a unique value key.
+//   - result interface{}
+//   a pointer to the object to restore the value into.
+// Returns interface{}, error
+// the result object, nil if the value is missing or expired, or error if it can't be converted.
+func (c *CacheFilePersistence) RetrieveAs(correlationId string, key string, result interface{}) (interface{}, error) {
+	value, err := c.Retrieve(correlationId, key)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	buffer, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(buffer, result)
+	}
+	if err != nil {
+		return nil, errors.NewBadRequestError(correlationId, "CONVERSION_FAILED", "Failed to convert cached value "+key).
+			WithDetails("key", key).WithCause(err)
+	}
+	return result, nil
+}
+
+// Evicts expired values and values that expire first to keep the maximum size.
+// Must be called under write lock.
+func (c *CacheFilePersistence) evict(now time.Time) {
+	items := make([]interface{}, 0, len(c.Items))
+	for _, item := range c.Items {
+		if entry, ok := item.(CacheEntry); ok && !entry.Expiration.After(now) {
+			c.notifyChange(item, nil)
+			continue
+		}
+		items = append(items, item)
+	}
+	c.Items = items
+
+	for c.MaxSize > 0 && len(c.Items) > c.MaxSize {
+		first := 0
+		for i, item := range c.Items {
+			entry, _ := item.(CacheEntry)
+			if entry.Expiration.Before(c.Items[first].(CacheEntry).Expiration) {
+				first = i
+			}
+		}
+		c.notifyChange(c.Items[first], nil)
+		c.Items = append(c.Items[:first], c.Items[first+1:]...)
+	}
+}
+
+// Stores a value in the cache with expiration time.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - key string
+//   a unique value key.
+//   - value interface{}
+//   a value to store.
+//   - timeout int64
+//   expiration timeout in milliseconds, 0 for the default timeout.
+// Returns interface{}, error
+// the stored value or error.
+func (c *CacheFilePersistence) Store(correlationId string, key string, value interface{}, timeout int64) (interface{}, error) {
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	if timeout <= 0 {
+		timeout = c.Timeout
+	}
+	now := c.Clock.Now()
+	var newItem interface{} = CacheEntry{Id: key, Value: value, Expiration: now.Add(time.Duration(timeout) * time.Millisecond)}
+	if index := c.GetIndexById(key); index >= 0 {
+		c.notifyChange(c.Items[index], newItem)
+		c.Items[index] = newItem
+	} else {
+		c.Items = append(c.Items, newItem)
+		c.notifyChange(nil, newItem)
+	}
+	c.evict(now)
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Stored cached value %s", key)
+
+	if err := c.save(correlationId); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Removes a value from the cache by its key.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - key string
+//   a unique value key.
+// Returns error or nil for success.
+func (c *CacheFilePersistence) Remove(correlationId string, key string) error {
+	_, err := c.IdentifiableMemoryPersistence.DeleteById(correlationId, key)
+	return err
+}
@@ -0,0 +1,188 @@
+package persistence
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Creates an error for a panic raised by a user supplied callback.
+// Parameters:
+//   - correlationId string
+//   transaction id to trace execution through call chain.
+//   - callback string
+//   a kind of the callback: filter, sort or select
+//   - index int
+//   an index of the item that was processed by the callback
+//   - recovered interface{}
+//   a value returned by recover
+// Returns error
+// InternalError with CALLBACK_PANIC code.
+func newCallbackPanicError(correlationId string, callback string, index int, recovered interface{}) error {
+	return errors.NewInternalError(correlationId, "CALLBACK_PANIC",
+		fmt.Sprintf("%s function panicked on item %d: %v", callback, index, recovered)).
+		WithDetails("callback", callback).
+		WithDetails("index", index)
+}
+
+// Recovers from a panic in a user callback and converts it into an error.
+// Must be called directly by defer.
+func (c *MemoryPersistence) recoverCallback(correlationId string, callback string, index *int, err *error) {
+	if r := recover(); r != nil {
+		*err = newCallbackPanicError(correlationId, callback, *index, r)
+		c.Logger.Error(correlationId, *err, "Recovered from panic in %s function", callback)
+	}
+}
+
+// Selects items that match the filter function. Must be called under lock.
+// Large sets of items are evaluated by FilterParallelism goroutines,
+// and the original order of items is preserved.
+// Panics in the filter function are returned as errors, and scans that exceed
+// OperationTimeout are stopped.
+func (c *MemoryPersistence) filterItems(correlationId string, filterFunc func(interface{}) bool) ([]interface{}, error) {
+	return c.filterItemsOf(correlationId, c.Items, filterFunc)
+}
+
+// Selects the given items that match the filter function like filterItems. Must be called under lock.
+func (c *MemoryPersistence) filterItemsOf(correlationId string, items []interface{},
+	filterFunc func(interface{}) bool) ([]interface{}, error) {
+	workers := c.FilterParallelism
+	deadline := c.operationDeadline()
+	if workers <= 1 || len(items) < c.ParallelFilterThreshold {
+		return c.filterChunk(correlationId, filterFunc, items, 0, deadline)
+	}
+
+	chunkSize := (len(items) + workers - 1) / workers
+	chunks := make([][]interface{}, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunkSize
+		if start >= len(items) {
+			break
+		}
+		end := start + chunkSize
+		if end > len(items) {
+			end = len(items)
+		}
+		wg.Add(1)
+		go func(w int, start int, chunk []interface{}) {
+			defer wg.Done()
+			chunks[w], errs[w] = c.filterChunk(correlationId, filterFunc, chunk, start, deadline)
+		}(w, start, items[start:end])
+	}
+	wg.Wait()
+
+	var results []interface{}
+	for w, chunk := range chunks {
+		if errs[w] != nil {
+			return nil, errs[w]
+		}
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+// Selects items of a chunk that match the filter function.
+// Offset is an index of the first item in the chunk used in errors.
+func (c *MemoryPersistence) filterChunk(correlationId string, filterFunc func(interface{}) bool,
+	items []interface{}, offset int, deadline time.Time) (results []interface{}, err error) {
+	index := offset
+	defer c.recoverCallback(correlationId, "filter", &index, &err)
+
+	for i, v := range items {
+		index = offset + i
+		if i > 0 && i%deadlineCheckInterval == 0 {
+			if err = c.checkDeadline(correlationId, "filter", deadline); err != nil {
+				return nil, err
+			}
+		}
+		if filterFunc(v) {
+			results = append(results, v)
+		}
+	}
+	return results, nil
+}
+
+// Finds indexes of items that match the filter function. Must be called under lock.
+func (c *MemoryPersistence) matchItems(correlationId string, filterFunc func(interface{}) bool) (matches []bool, err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "filter", &index, &err)
+
+	deadline := c.operationDeadline()
+	matches = make([]bool, len(c.Items))
+	for i, v := range c.Items {
+		index = i
+		if i > 0 && i%deadlineCheckInterval == 0 {
+			if err = c.checkDeadline(correlationId, "filter", deadline); err != nil {
+				return nil, err
+			}
+		}
+		matches[i] = filterFunc(v)
+	}
+	return matches, nil
+}
+
+// Calls the found function for indexes of items that match the filter function
+// until it returns false. Must be called under lock.
+func (c *MemoryPersistence) findItems(correlationId string, filterFunc func(interface{}) bool,
+	found func(index int) bool) (err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "filter", &index, &err)
+
+	deadline := c.operationDeadline()
+	for i, v := range c.Items {
+		index = i
+		if i > 0 && i%deadlineCheckInterval == 0 {
+			if err = c.checkDeadline(correlationId, "filter", deadline); err != nil {
+				return err
+			}
+		}
+		if (filterFunc == nil || filterFunc(v)) && !found(i) {
+			break
+		}
+	}
+	return nil
+}
+
+// Sorts items in place using the sort function. Equal items keep their original order.
+// Panics in the sort function are returned as errors.
+func (c *MemoryPersistence) sortItems(correlationId string, items []interface{},
+	sortFunc func(a, b interface{}) bool) (err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "sort", &index, &err)
+
+	sort.Stable(trackingSorter{
+		sorter: sorter{items: items, compFunc: sortFunc},
+		index:  &index,
+	})
+	return nil
+}
+
+// Sorter that remembers an index of the last compared item
+type trackingSorter struct {
+	sorter
+	index *int
+}
+
+func (s trackingSorter) Less(i, j int) bool {
+	*s.index = i
+	return s.sorter.Less(i, j)
+}
+
+// Converts items in place using the select function.
+// Panics in the select function are returned as errors.
+func (c *MemoryPersistence) selectItems(correlationId string, items []interface{},
+	selectFunc func(in interface{}) (out interface{})) (err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "select", &index, &err)
+
+	for i, v := range items {
+		index = i
+		items[i] = selectFunc(v)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package persistence
+
+import "github.com/pip-services3-go/pip-services3-components-go/cache"
+
+// Compile-time checks that persistence components implement capability interfaces,
+// so service and controller layers can depend on the interfaces instead of concrete structs.
+var (
+	_ IGetter         = (*IdentifiableMemoryPersistence)(nil)
+	_ IWriter         = (*IdentifiableMemoryPersistence)(nil)
+	_ ISetter         = (*IdentifiableMemoryPersistence)(nil)
+	_ IPartialUpdater = (*IdentifiableMemoryPersistence)(nil)
+	_ IPatchUpdater   = (*IdentifiableMemoryPersistence)(nil)
+
+	_ IGetter         = (*IdentifiableFilePersistence)(nil)
+	_ IWriter         = (*IdentifiableFilePersistence)(nil)
+	_ ISetter         = (*IdentifiableFilePersistence)(nil)
+	_ IPartialUpdater = (*IdentifiableFilePersistence)(nil)
+
+	_ IGetter         = (*RateLimitedPersistence)(nil)
+	_ IWriter         = (*RateLimitedPersistence)(nil)
+	_ ISetter         = (*RateLimitedPersistence)(nil)
+	_ IPartialUpdater = (*RateLimitedPersistence)(nil)
+
+	_ IGetter         = (*DecoratedPersistence)(nil)
+	_ IWriter         = (*DecoratedPersistence)(nil)
+	_ ISetter         = (*DecoratedPersistence)(nil)
+	_ IPartialUpdater = (*DecoratedPersistence)(nil)
+
+	_ IGetter         = (*ChaosPersistence)(nil)
+	_ IWriter         = (*ChaosPersistence)(nil)
+	_ ISetter         = (*ChaosPersistence)(nil)
+	_ IPartialUpdater = (*ChaosPersistence)(nil)
+
+	_ cache.ICache = (*CacheFilePersistence)(nil)
+
+	_ ILoader        = (*JsonFilePersister)(nil)
+	_ ISaver         = (*JsonFilePersister)(nil)
+	_ IContextLoader = (*JsonFilePersister)(nil)
+	_ IContextSaver  = (*JsonFilePersister)(nil)
+	_ IStreamLoader  = (*JsonFilePersister)(nil)
+	_ IStreamSaver   = (*JsonFilePersister)(nil)
+
+	_ ILoader        = (*GrpcPersister)(nil)
+	_ ISaver         = (*GrpcPersister)(nil)
+	_ IContextLoader = (*GrpcPersister)(nil)
+	_ IContextSaver  = (*GrpcPersister)(nil)
+
+	_ ILoader = (*RemoteFilePersister)(nil)
+	_ ISaver  = (*RemoteFilePersister)(nil)
+
+	_ ILoader              = (*NetworkFilePersister)(nil)
+	_ ISaver               = (*NetworkFilePersister)(nil)
+	_ IModificationTracker = (*NetworkFilePersister)(nil)
+
+	_ ILoader = (*SqlPersister)(nil)
+	_ ISaver  = (*SqlPersister)(nil)
+
+	_ ILoader = (*KeyValuePersister)(nil)
+	_ ISaver  = (*KeyValuePersister)(nil)
+
+	_ IGetter         = (*UniversalIdentifiablePersistence)(nil)
+	_ IWriter         = (*UniversalIdentifiablePersistence)(nil)
+	_ ISetter         = (*UniversalIdentifiablePersistence)(nil)
+	_ IPartialUpdater = (*UniversalIdentifiablePersistence)(nil)
+)
@@ -0,0 +1,318 @@
+package persistence
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Kinds of latency distributions
+const (
+	// Every operation is delayed for the same time
+	LatencyFixed = "fixed"
+	// Delays are uniformly distributed between min and max
+	LatencyUniform = "uniform"
+	// Operations are delayed for min time, and some of them spike up to max time
+	LatencySpikes = "spikes"
+)
+
+/*
+LatencyProfile defines a distribution of simulated delays of operations.
+
+Profiles are written as strings:
+
+  - fixed:50               every operation takes 50ms
+  - uniform:10:100         delays are uniformly distributed from 10 to 100ms
+  - spikes:10:500:0.01     operations take 10ms, and 1% of them take 500ms
+*/
+type LatencyProfile struct {
+	Kind string
+	Min  time.Duration
+	Max  time.Duration
+	// Probability of spikes from 0 to 1
+	SpikeProbability float64
+}
+
+func newInvalidLatencyProfileError(correlationId string, profile string) error {
+	return errors.NewConfigError(correlationId, "INVALID_LATENCY_PROFILE", "Invalid latency profile "+profile).
+		WithDetails("profile", profile)
+}
+
+// Parses a latency profile like "fixed:50", "uniform:10:100" or "spikes:10:500:0.01".
+// Parameters:
+//   - value string
+//   a profile string with delays in milliseconds
+// Returns *LatencyProfile, error
+// parsed profile or ConfigError if the profile is invalid.
+func ParseLatencyProfile(value string) (*LatencyProfile, error) {
+	invalid := func() error { return newInvalidLatencyProfileError("", value) }
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	numbers := make([]float64, len(parts)-1)
+	for i, part := range parts[1:] {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || number < 0 {
+			return nil, invalid()
+		}
+		numbers[i] = number
+	}
+	millis := func(value float64) time.Duration { return time.Duration(value * float64(time.Millisecond)) }
+
+	profile := &LatencyProfile{Kind: parts[0]}
+	switch {
+	case profile.Kind == LatencyFixed && len(numbers) == 1:
+		profile.Min, profile.Max = millis(numbers[0]), millis(numbers[0])
+	case profile.Kind == LatencyUniform && len(numbers) == 2 && numbers[0] <= numbers[1]:
+		profile.Min, profile.Max = millis(numbers[0]), millis(numbers[1])
+	case profile.Kind == LatencySpikes && len(numbers) == 3 && numbers[2] <= 1:
+		profile.Min, profile.Max = millis(numbers[0]), millis(numbers[1])
+		profile.SpikeProbability = numbers[2]
+	default:
+		return nil, invalid()
+	}
+	return profile, nil
+}
+
+// Gets the next delay from the distribution.
+// Parameters:
+//   - random *rand.Rand
+//   a source of random numbers
+// Returns time.Duration
+func (c *LatencyProfile) Next(random *rand.Rand) time.Duration {
+	switch c.Kind {
+	case LatencyUniform:
+		return c.Min + time.Duration(random.Int63n(int64(c.Max-c.Min)+1))
+	case LatencySpikes:
+		if random.Float64() < c.SpikeProbability {
+			return c.Max
+		}
+	}
+	return c.Min
+}
+
+/*
+Decorator that simulates slow storage for performance testing.
+Operations of the decorated persistence are delayed according to latency
+profiles, so timeouts and SLOs can be tested against realistic latencies.
+
+Configuration parameters
+
+- options:
+    - latency:             Latency profile of all operations, see LatencyProfile (default: none)
+- latency:
+    - <operation>:         Latency profile of the operation: get_one_by_id, create, update, set, update_partially or delete_by_id
+
+Example
+
+    persistence := NewChaosPersistence(NewMyFilePersistence())
+    persistence.Configure(NewConfigParamsFromTuples(
+        "options.latency", "uniform:5:20",
+        "latency.create", "spikes:10:2000:0.01",
+    ))
+    item, err := persistence.GetOneById("123", "1")
+*/
+// implements IGetter, IWriter, ISetter, IPartialUpdater, IConfigurable
+type ChaosPersistence struct {
+	Persistence interface{}
+	// Latency profile of operations without own profiles
+	Latency *LatencyProfile
+	// Latency profiles of operations
+	Latencies map[string]*LatencyProfile
+	// Clock to wait for delays
+	Clock          IClock
+	lock           sync.Mutex
+	random         *rand.Rand
+	invalidProfile string
+}
+
+// Creates a new decorator that simulates latency.
+// Parameters:
+//   - persistence interface{}
+//   a decorated persistence that implements IGetter, IWriter, ISetter or IPartialUpdater
+// Returns *ChaosPersistence
+func NewChaosPersistence(persistence interface{}) *ChaosPersistence {
+	return &ChaosPersistence{
+		Persistence: persistence,
+		Latencies:   map[string]*LatencyProfile{},
+		Clock:       SystemClock,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Configures component by passing configuration parameters.
+// Invalid latency profiles make all operations fail with ConfigError.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *ChaosPersistence) Configure(config *config.ConfigParams) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.invalidProfile = ""
+	if value := config.GetAsString("options.latency"); value != "" {
+		if profile, err := ParseLatencyProfile(value); err == nil {
+			c.Latency = profile
+		} else {
+			c.invalidProfile = value
+		}
+	}
+	latencies := config.GetSection("latency")
+	for _, operation := range latencies.Keys() {
+		value := latencies.GetAsString(operation)
+		if profile, err := ParseLatencyProfile(value); err == nil {
+			c.Latencies[operation] = profile
+		} else {
+			c.invalidProfile = value
+		}
+	}
+}
+
+// Waits for the simulated latency of the operation
+func (c *ChaosPersistence) delay(correlationId string, operation string) error {
+	c.lock.Lock()
+	if c.invalidProfile != "" {
+		profile := c.invalidProfile
+		c.lock.Unlock()
+		return newInvalidLatencyProfileError(correlationId, profile)
+	}
+	profile, ok := c.Latencies[operation]
+	if !ok {
+		profile = c.Latency
+	}
+	var delay time.Duration
+	if profile != nil {
+		delay = profile.Next(c.random)
+	}
+	c.lock.Unlock()
+
+	if delay > 0 {
+		c.Clock.Sleep(delay)
+	}
+	return nil
+}
+
+func (c *ChaosPersistence) unsupported(correlationId string, operation string) error {
+	return errors.NewUnsupportedError(correlationId, "NOT_SUPPORTED",
+		"Operation "+operation+" is not supported by decorated persistence")
+}
+
+// Gets a data item by its unique id.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be retrieved.
+// Returns interface{}, error
+// data item or error.
+func (c *ChaosPersistence) GetOneById(correlationId string, id interface{}) (item interface{}, err error) {
+	getter, ok := c.Persistence.(IGetter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "GetOneById")
+	}
+	if err = c.delay(correlationId, "get_one_by_id"); err != nil {
+		return nil, err
+	}
+	return getter.GetOneById(correlationId, id)
+}
+
+// Creates a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be created.
+// Returns interface{}, error
+// created item or error.
+func (c *ChaosPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Create")
+	}
+	if err = c.delay(correlationId, "create"); err != nil {
+		return nil, err
+	}
+	return writer.Create(correlationId, item)
+}
+
+// Updates a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be updated.
+// Returns interface{}, error
+// updated item or error.
+func (c *ChaosPersistence) Update(correlationId string, item interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Update")
+	}
+	if err = c.delay(correlationId, "update"); err != nil {
+		return nil, err
+	}
+	return writer.Update(correlationId, item)
+}
+
+// Deleted a data item by it's unique id.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the item to be deleted
+// Returns interface{}, error
+// deleted item or error.
+func (c *ChaosPersistence) DeleteById(correlationId string, id interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "DeleteById")
+	}
+	if err = c.delay(correlationId, "delete_by_id"); err != nil {
+		return nil, err
+	}
+	return writer.DeleteById(correlationId, id)
+}
+
+// Sets a data item. If the data item exists it updates it, otherwise it create a new data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   a item to be set.
+// Returns interface{}, error
+// updated item or error.
+func (c *ChaosPersistence) Set(correlationId string, item interface{}) (result interface{}, err error) {
+	setter, ok := c.Persistence.(ISetter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Set")
+	}
+	if err = c.delay(correlationId, "set"); err != nil {
+		return nil, err
+	}
+	return setter.Set(correlationId, item)
+}
+
+// Updates only few selected fields in a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be updated.
+//   - data *cdata.AnyValueMap
+//   a map with fields to be updated.
+// Returns interface{}, error
+// updated item or error.
+func (c *ChaosPersistence) UpdatePartially(correlationId string, id interface{}, data *cdata.AnyValueMap) (result interface{}, err error) {
+	updater, ok := c.Persistence.(IPartialUpdater)
+	if !ok {
+		return nil, c.unsupported(correlationId, "UpdatePartially")
+	}
+	if err = c.delay(correlationId, "update_partially"); err != nil {
+		return nil, err
+	}
+	return updater.UpdatePartially(correlationId, id, data)
+}
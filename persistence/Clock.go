@@ -0,0 +1,160 @@
+package persistence
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+IClock provides current time, timers and delays to persistence components.
+Timestamps, TTLs, retention windows and autosave timers use the clock,
+so tests can replace it with ManualClock and advance time without sleeping.
+*/
+type IClock interface {
+	// Gets the current time
+	Now() time.Time
+	// Creates a ticker that sends ticks with the interval
+	NewTicker(interval time.Duration) IClockTicker
+	// Waits for the duration
+	Sleep(duration time.Duration)
+}
+
+// IClockTicker is a ticker created by IClock.
+type IClockTicker interface {
+	// Gets the channel to receive ticks
+	C() <-chan time.Time
+	// Stops the ticker
+	Stop()
+}
+
+// SystemClock is the clock that uses system time.
+var SystemClock IClock = &systemClock{}
+
+type systemClock struct{}
+
+func (c *systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *systemClock) NewTicker(interval time.Duration) IClockTicker {
+	return &systemTicker{ticker: time.NewTicker(interval)}
+}
+
+func (c *systemClock) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (c *systemTicker) C() <-chan time.Time {
+	return c.ticker.C
+}
+
+func (c *systemTicker) Stop() {
+	c.ticker.Stop()
+}
+
+/*
+ManualClock is a clock for deterministic tests. Its time changes only
+when Advance or Sleep are called, and tickers fire when the time passes their next tick.
+
+Example
+
+  clock := NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+  persistence := NewIdentifiableMemoryPersistenceWithOptions(reflect.TypeOf(MyData{}), WithClock(clock))
+  ...
+  clock.Advance(24 * time.Hour)
+*/
+type ManualClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	tickers []*manualTicker
+}
+
+// Creates a new manual clock.
+// Parameters:
+//   - now time.Time
+//   the initial time
+// Returns *ManualClock
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Gets the current time of the clock.
+// Returns time.Time
+func (c *ManualClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Creates a ticker that fires when the clock is advanced past its next tick.
+// Parameters:
+//   - interval time.Duration
+//   an interval between ticks
+// Returns IClockTicker
+func (c *ManualClock) NewTicker(interval time.Duration) IClockTicker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ticker := &manualTicker{clock: c, interval: interval, next: c.now.Add(interval), ch: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advances the clock instead of waiting.
+// Parameters:
+//   - duration time.Duration
+//   the duration to advance the clock
+func (c *ManualClock) Sleep(duration time.Duration) {
+	c.Advance(duration)
+}
+
+// Moves the clock forward and fires tickers that passed their next tick.
+// Like system tickers, slow receivers miss ticks.
+// Parameters:
+//   - duration time.Duration
+//   the duration to advance the clock
+func (c *ManualClock) Advance(duration time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(duration)
+	for _, ticker := range c.tickers {
+		if ticker.next.After(c.now) {
+			continue
+		}
+		select {
+		case ticker.ch <- c.now:
+		default:
+		}
+		for !ticker.next.After(c.now) {
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+	}
+}
+
+type manualTicker struct {
+	clock    *ManualClock
+	interval time.Duration
+	next     time.Time
+	ch       chan time.Time
+}
+
+func (c *manualTicker) C() <-chan time.Time {
+	return c.ch
+}
+
+func (c *manualTicker) Stop() {
+	c.clock.lock.Lock()
+	defer c.clock.lock.Unlock()
+
+	for i, ticker := range c.clock.tickers {
+		if ticker == c {
+			c.clock.tickers = append(c.clock.tickers[:i], c.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"reflect"
+)
+
+// Strategies to copy items when they are stored and returned
+const (
+	// Items are copied with nested pointers, maps and slices shared with the copy (default)
+	CloneStrategyShallow = "shallow"
+	// Items are copied together with all nested values
+	CloneStrategyDeep = "deep"
+	// Items of non-pointer prototypes, or all items in pointer storage mode, are stored and returned
+	// as is without reflection copy. Nested pointers, maps and slices must not be changed by callers
+	CloneStrategyNone = "none"
+)
+
+// Returns true if the item can be used as is under CloneStrategyNone
+func (c *MemoryPersistence) isSharedItem(item interface{}) bool {
+	if c.CloneStrategy != CloneStrategyNone || item == nil {
+		return false
+	}
+	itemType := reflect.TypeOf(item)
+	if c.StorageMode == StorageModePointer {
+		return itemType == c.Prototype
+	}
+	if itemType.Kind() == reflect.Ptr || itemType.Kind() == reflect.Map {
+		return false
+	}
+	return itemType == c.Prototype || c.Prototype.Kind() == reflect.Interface
+}
+
+// Copies an item to store it according to the clone strategy
+func (c *MemoryPersistence) cloneItem(item interface{}) interface{} {
+	if c.isSharedItem(item) {
+		return item
+	}
+	if c.StorageMode == StorageModePointer {
+		return cloneObjectForResult(item, c.Prototype, c.CloneStrategy == CloneStrategyDeep)
+	}
+	return cloneObject(item, c.Prototype, c.CloneStrategy == CloneStrategyDeep)
+}
+
+// Copies a stored item to return it according to the clone strategy
+func (c *MemoryPersistence) cloneResult(item interface{}) interface{} {
+	if c.isSharedItem(item) {
+		return item
+	}
+	return cloneObjectForResult(item, c.Prototype, c.CloneStrategy == CloneStrategyDeep)
+}
@@ -0,0 +1,162 @@
+package persistence
+
+import (
+	"reflect"
+	"time"
+)
+
+// Maximum depth of nested values walked by compaction
+const maxCompactionDepth = 32
+
+/*
+Results of memory compaction reported by Compact.
+*/
+type CompactionReport struct {
+	// Number of compacted items
+	Items int
+	// Number of distinct strings kept after interning
+	UniqueStrings int
+	// Number of duplicate strings replaced by shared copies
+	InternedStrings int
+	// Number of slices with unused capacity that were trimmed
+	TrimmedSlices int
+	// Estimated number of bytes that can be released by duplicate strings and unused slice capacity
+	SavedBytes int64
+	// Duration of the compaction
+	Duration time.Duration
+}
+
+/*
+State of a compaction pass that keeps interned strings.
+*/
+type compactor struct {
+	strings map[string]string
+	report  *CompactionReport
+}
+
+// Copies a value into a new settable value
+func settableCopy(value reflect.Value) reflect.Value {
+	result := reflect.New(value.Type()).Elem()
+	result.Set(value)
+	return result
+}
+
+// Interns strings and trims slices of a settable value. Shared values are never changed in place:
+// pointers, slices and maps are replaced with compacted copies.
+func (c *compactor) compact(value reflect.Value, depth int) {
+	if depth > maxCompactionDepth {
+		return
+	}
+	switch value.Kind() {
+	case reflect.String:
+		str := value.String()
+		if interned, ok := c.strings[str]; ok {
+			if str != "" {
+				c.report.InternedStrings++
+				c.report.SavedBytes += int64(len(str))
+			}
+			value.SetString(interned)
+		} else {
+			c.strings[str] = str
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if field := value.Field(i); field.CanSet() {
+				c.compact(field, depth+1)
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			c.compact(value.Index(i), depth+1)
+		}
+	case reflect.Slice:
+		if value.IsNil() {
+			return
+		}
+		if value.Cap() > value.Len() {
+			c.report.TrimmedSlices++
+			c.report.SavedBytes += int64(value.Cap()-value.Len()) * int64(value.Type().Elem().Size())
+		}
+		slice := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		reflect.Copy(slice, value)
+		for i := 0; i < slice.Len(); i++ {
+			c.compact(slice.Index(i), depth+1)
+		}
+		value.Set(slice)
+	case reflect.Map:
+		if value.IsNil() {
+			return
+		}
+		result := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := settableCopy(iter.Key())
+			c.compact(key, depth+1)
+			element := settableCopy(iter.Value())
+			c.compact(element, depth+1)
+			result.SetMapIndex(key, element)
+		}
+		value.Set(result)
+	case reflect.Ptr:
+		if value.IsNil() {
+			return
+		}
+		element := reflect.New(value.Type().Elem())
+		element.Elem().Set(value.Elem())
+		c.compact(element.Elem(), depth+1)
+		value.Set(element)
+	case reflect.Interface:
+		if value.IsNil() {
+			return
+		}
+		element := settableCopy(value.Elem())
+		c.compact(element, depth+1)
+		value.Set(element)
+	}
+}
+
+// Replaces items with compacted copies. Must be called under write lock.
+func (c *MemoryPersistence) compactItems(correlationId string) *CompactionReport {
+	start := time.Now()
+	report := &CompactionReport{Items: len(c.Items)}
+	state := &compactor{strings: map[string]string{}, report: report}
+
+	items := make([]interface{}, len(c.Items))
+	for i, item := range c.Items {
+		if item == nil {
+			continue
+		}
+		value := settableCopy(reflect.ValueOf(item))
+		state.compact(value, 0)
+		items[i] = value.Interface()
+	}
+	if cap(c.Items) > len(c.Items) {
+		report.SavedBytes += int64(cap(c.Items)-len(c.Items)) * int64(reflect.TypeOf(c.Items).Elem().Size())
+	}
+	c.Items = items
+
+	report.UniqueStrings = len(state.strings)
+	report.Duration = time.Since(start)
+	c.Logger.Debug(correlationId, "Compacted %d items: interned %d strings, trimmed %d slices, saved about %d bytes in %v",
+		report.Items, report.InternedStrings, report.TrimmedSlices, report.SavedBytes, report.Duration)
+	return report
+}
+
+// Reduces memory held by items, for instance after bulk loads of large string-heavy datasets.
+// Equal strings in all items are replaced with a single shared copy, and unused capacity
+// of slices is released. Items are replaced with compacted copies, so references held by callers
+// are not changed. Unexported fields are left as is.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns *CompactionReport, error
+// a report with estimated savings or error.
+func (c *MemoryPersistence) Compact(correlationId string) (*CompactionReport, error) {
+	c.lock()
+	defer c.unlock()
+
+	report := c.compactItems(correlationId)
+	// Indexes and views keep references to previous items, so they are rebuilt with compacted ones
+	c.notifyChange(nil, nil)
+	return report, nil
+}
@@ -0,0 +1,50 @@
+package persistence
+
+/*
+Computed field of persisted items. Its value is calculated from other
+fields of the item and stored in the item on every create and update,
+so filters and sorting can use denormalized values directly.
+*/
+type computedField struct {
+	name    string
+	compute func(item interface{}) interface{}
+}
+
+// Declares a computed field that is recalculated and stored in items on every create or update.
+// Values of the field in already stored items are recalculated immediately.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the field to store the computed value. The function must return a value of the field type
+//   - compute func(item interface{}) interface{}
+//   a function that calculates the field value from the item
+//
+// Example
+//
+//   persistence.AddComputedField("", "SearchName", func(item interface{}) interface{} {
+//       return strings.ToLower(item.(MyData).Name)
+//   })
+func (c *MemoryPersistence) AddComputedField(correlationId string, name string, compute func(item interface{}) interface{}) {
+	c.lock()
+	defer c.unlock()
+
+	field := &computedField{name: name, compute: compute}
+	c.computedFields = append(c.computedFields, field)
+
+	if len(c.Items) > 0 {
+		for i := range c.Items {
+			SetObjectProperty(&c.Items[i], field.name, field.compute(c.Items[i]))
+		}
+		c.notifyChange(nil, nil)
+	}
+
+	c.Logger.Trace(correlationId, "Added computed field %s", name)
+}
+
+// Calculates and stores values of all computed fields in the item
+func (c *MemoryPersistence) applyComputedFields(item *interface{}) {
+	for _, field := range c.computedFields {
+		SetObjectProperty(item, field.name, field.compute(*item))
+	}
+}
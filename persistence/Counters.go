@@ -0,0 +1,152 @@
+package persistence
+
+import (
+	"reflect"
+	"time"
+)
+
+// Counter is a named counter or gauge stored by CountersFilePersistence.
+type Counter struct {
+	// Name of the counter
+	Id string `json:"id"`
+	// Current value
+	Value float64 `json:"value"`
+	// Time of the last change
+	Time time.Time `json:"time"`
+}
+
+/*
+CountersFilePersistence durably stores named counters and gauges in a JSON file,
+so services can keep crash-safe counters without a database.
+Increment and Set change values atomically and save them before they return.
+
+Configuration parameters
+
+- path:                    path to the file where counters are stored
+- options:
+    - durability:          Durability of saved changes: none, flush or fsync (default: flush)
+
+Example
+
+    counters := NewCountersFilePersistence("./data/counters.json")
+    counters.Open("123")
+
+    counter, err := counters.Increment("123", "orders.created", 1)
+    counter, err = counters.Set("123", "queue.length", 42)
+    value, err := counters.GetValue("123", "orders.created")
+*/
+type CountersFilePersistence struct {
+	IdentifiableFilePersistence
+}
+
+// Creates a new persistence of counters.
+// Parameters:
+//   - path string
+//   a path to the file where counters are stored
+// Returns *CountersFilePersistence
+func NewCountersFilePersistence(path string) *CountersFilePersistence {
+	prototype := reflect.TypeOf(Counter{})
+	return &CountersFilePersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+	}
+}
+
+// Atomically changes a counter value and saves the change. Missing counters start from zero.
+func (c *CountersFilePersistence) change(correlationId string, name string, update func(value float64) float64) (result Counter, err error) {
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return result, err
+	}
+
+	var oldItem interface{}
+	result = Counter{Id: name}
+	index := c.GetIndexById(name)
+	if index >= 0 {
+		oldItem = c.Items[index]
+		result, _ = oldItem.(Counter)
+	}
+	result.Value = update(result.Value)
+	result.Time = c.Clock.Now().UTC()
+
+	var newItem interface{} = result
+	c.notifyChange(oldItem, newItem)
+	if index >= 0 {
+		c.Items[index] = newItem
+	} else {
+		c.Items = append(c.Items, newItem)
+	}
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Changed counter %s to %v", name, result.Value)
+
+	err = c.save(correlationId)
+	return result, err
+}
+
+// Atomically increments a counter. Missing counters are created with zero value.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the counter
+//   - delta float64
+//   a value to add, negative values decrement the counter
+// Returns Counter, error
+// changed counter or error.
+func (c *CountersFilePersistence) Increment(correlationId string, name string, delta float64) (Counter, error) {
+	return c.change(correlationId, name, func(value float64) float64 { return value + delta })
+}
+
+// Atomically sets a value of a gauge or resets a counter.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the gauge
+//   - value float64
+//   a new value
+// Returns Counter, error
+// changed gauge or error.
+func (c *CountersFilePersistence) Set(correlationId string, name string, value float64) (Counter, error) {
+	return c.change(correlationId, name, func(float64) float64 { return value })
+}
+
+// Gets a value of a counter.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the counter
+// Returns float64, error
+// the counter value or zero if the counter doesn't exist.
+func (c *CountersFilePersistence) GetValue(correlationId string, name string) (float64, error) {
+	c.rlock()
+	defer c.runlock()
+
+	if index := c.GetIndexById(name); index >= 0 {
+		counter, _ := c.Items[index].(Counter)
+		return counter.Value, nil
+	}
+	return 0, nil
+}
+
+// Gets values of all counters.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns map[string]float64, error
+// counter values by names.
+func (c *CountersFilePersistence) GetValues(correlationId string) (map[string]float64, error) {
+	c.rlock()
+	defer c.runlock()
+
+	values := make(map[string]float64, len(c.Items))
+	for _, item := range c.Items {
+		if counter, ok := item.(Counter); ok {
+			values[counter.Id] = counter.Value
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,307 @@
+package persistence
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+var generatorWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliet", "kilo", "lima", "mike", "november", "oscar", "papa", "quebec", "romeo"}
+
+// Options of a generated field parsed from its fake tag
+type fakeTag struct {
+	skip   bool
+	format string
+	oneOf  []string
+	min    *float64
+	max    *float64
+	length int
+}
+
+// Parses a tag like `fake:"min=1,max=10"`, `fake:"format=email"` or `fake:"oneof=new|done"`
+func parseFakeTag(tag string) (*fakeTag, error) {
+	result := &fakeTag{length: -1}
+	if tag == "-" {
+		result.skip = true
+		return result, nil
+	}
+	for _, option := range strings.Split(tag, ",") {
+		if option == "" {
+			continue
+		}
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid fake tag option %s", option)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch key {
+		case "format":
+			result.format = value
+		case "oneof":
+			result.oneOf = strings.Split(value, "|")
+		case "min", "max":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fake tag option %s", option)
+			}
+			if key == "min" {
+				result.min = &number
+			} else {
+				result.max = &number
+			}
+		case "len":
+			length, err := strconv.Atoi(value)
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid fake tag option %s", option)
+			}
+			result.length = length
+		default:
+			return nil, fmt.Errorf("unknown fake tag option %s", key)
+		}
+	}
+	return result, nil
+}
+
+/*
+DataGenerator fabricates random items of a prototype for load tests and demo environments.
+
+Generated values respect fake struct tags:
+
+  - fake:"-"                 the field is not generated
+  - fake:"min=1,max=100"     range of numbers, for time fields unix seconds
+  - fake:"len=5"             length of strings and slices
+  - fake:"oneof=new|done"    one of the listed values
+  - fake:"format=email"      format of strings: email, name, phone, uuid, word, sentence or url
+
+Example
+
+  type Order struct {
+      Id     string  `json:"id" fake:"format=uuid"`
+      Email  string  `json:"email" fake:"format=email"`
+      Amount float64 `json:"amount" fake:"min=1,max=500"`
+      Status string  `json:"status" fake:"oneof=new|paid|shipped"`
+  }
+
+  generator := NewDataGenerator(reflect.TypeOf(Order{}), 1)
+  err := generator.Seed("123", persistence, 10000)
+*/
+type DataGenerator struct {
+	Prototype reflect.Type
+	random    *rand.Rand
+	tags      map[reflect.Type][]*fakeTag
+}
+
+// Creates a new generator of items.
+// Parameters:
+//   - prototype reflect.Type
+//   type of generated items, panics when it is nil
+//   - seed int64
+//   seed of random values, equal seeds produce equal items
+// Returns *DataGenerator
+func NewDataGenerator(prototype reflect.Type, seed int64) *DataGenerator {
+	checkPrototype(prototype)
+	return &DataGenerator{
+		Prototype: prototype,
+		random:    rand.New(rand.NewSource(seed)),
+		tags:      map[reflect.Type][]*fakeTag{},
+	}
+}
+
+// Gets parsed fake tags of struct fields
+func (c *DataGenerator) structTags(typ reflect.Type) ([]*fakeTag, error) {
+	if tags, ok := c.tags[typ]; ok {
+		return tags, nil
+	}
+	tags := make([]*fakeTag, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		tag, err := parseFakeTag(typ.Field(i).Tag.Get("fake"))
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %v", typ.Name(), typ.Field(i).Name, err)
+		}
+		tags[i] = tag
+	}
+	c.tags[typ] = tags
+	return tags, nil
+}
+
+// Generates a random number in the tag range
+func (c *DataGenerator) number(tag *fakeTag, min float64, max float64) float64 {
+	if tag.min != nil {
+		min = *tag.min
+	}
+	if tag.max != nil {
+		max = *tag.max
+	}
+	if max <= min {
+		return min
+	}
+	return min + c.random.Float64()*(max-min)
+}
+
+// Generates a random string in the tag format
+func (c *DataGenerator) text(tag *fakeTag) string {
+	word := func() string { return generatorWords[c.random.Intn(len(generatorWords))] }
+	switch tag.format {
+	case "email":
+		return fmt.Sprintf("%s.%s%d@example.com", word(), word(), c.random.Intn(1000))
+	case "name":
+		return anonymizeFirstNames[c.random.Intn(len(anonymizeFirstNames))] + " " +
+			anonymizeLastNames[c.random.Intn(len(anonymizeLastNames))]
+	case "phone":
+		return fmt.Sprintf("+1 (%03d) %03d-%04d", 200+c.random.Intn(800), c.random.Intn(1000), c.random.Intn(10000))
+	case "uuid":
+		return fmt.Sprintf("%08x%08x%08x%08x", c.random.Uint32(), c.random.Uint32(), c.random.Uint32(), c.random.Uint32())
+	case "url":
+		return "https://" + word() + ".example.com/" + word()
+	case "sentence":
+		words := make([]string, 3+c.random.Intn(8))
+		for i := range words {
+			words[i] = word()
+		}
+		sentence := strings.Join(words, " ")
+		return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+	case "", "word":
+	default:
+		return ""
+	}
+	if tag.length < 0 {
+		return word()
+	}
+	letters := make([]byte, tag.length)
+	for i := range letters {
+		letters[i] = byte('a' + c.random.Intn(26))
+	}
+	return string(letters)
+}
+
+// Fills the value with random data
+func (c *DataGenerator) fill(val reflect.Value, tag *fakeTag, depth int) error {
+	if tag.skip {
+		return nil
+	}
+	if len(tag.oneOf) > 0 {
+		choice := tag.oneOf[c.random.Intn(len(tag.oneOf))]
+		typed, ok := toTypedValue(choice, val.Type())
+		if !ok {
+			return fmt.Errorf("value %s can't be converted to %s", choice, val.Type())
+		}
+		val.Set(typed)
+		return nil
+	}
+
+	switch val.Kind() {
+	case reflect.String:
+		val.SetString(c.text(tag))
+	case reflect.Bool:
+		val.SetBool(c.random.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val.SetInt(int64(c.number(tag, 0, 1000)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val.SetUint(uint64(c.number(tag, 0, 1000)))
+	case reflect.Float32, reflect.Float64:
+		val.SetFloat(c.number(tag, 0, 1000))
+	case reflect.Ptr:
+		if depth > 3 {
+			return nil
+		}
+		ptr := reflect.New(val.Type().Elem())
+		if err := c.fill(ptr.Elem(), tag, depth+1); err != nil {
+			return err
+		}
+		val.Set(ptr)
+	case reflect.Slice:
+		if depth > 3 {
+			return nil
+		}
+		length := tag.length
+		if length < 0 {
+			length = c.random.Intn(4)
+		}
+		slice := reflect.MakeSlice(val.Type(), length, length)
+		itemTag := &fakeTag{format: tag.format, min: tag.min, max: tag.max, length: -1}
+		for i := 0; i < length; i++ {
+			if err := c.fill(slice.Index(i), itemTag, depth+1); err != nil {
+				return err
+			}
+		}
+		val.Set(slice)
+	case reflect.Struct:
+		if val.Type() == reflect.TypeOf(time.Time{}) {
+			now := float64(time.Now().Unix())
+			seconds := c.number(tag, now-365*24*60*60, now)
+			val.Set(reflect.ValueOf(time.Unix(int64(seconds), 0).UTC()))
+			return nil
+		}
+		if depth > 3 {
+			return nil
+		}
+		tags, err := c.structTags(val.Type())
+		if err != nil {
+			return err
+		}
+		for i := 0; i < val.NumField(); i++ {
+			if val.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := c.fill(val.Field(i), tags[i], depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Generates a random item of the prototype.
+// Returns interface{}, error
+// generated item or ConfigError if fake tags are invalid.
+func (c *DataGenerator) Generate() (interface{}, error) {
+	ptr := reflect.New(c.Prototype)
+	if err := c.fill(ptr.Elem(), &fakeTag{length: -1}, 0); err != nil {
+		return nil, errors.NewConfigError("", "INVALID_FAKE_TAG",
+			"Invalid fake tags of "+c.Prototype.String()).WithCause(err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// Generates random items of the prototype.
+// Parameters:
+//   - count int
+//   number of items to generate
+// Returns []interface{}, error
+// generated items or ConfigError if fake tags are invalid.
+func (c *DataGenerator) GenerateItems(count int) ([]interface{}, error) {
+	items := make([]interface{}, count)
+	for i := range items {
+		item, err := c.Generate()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// Generates random items and adds them to the persistence in bulk.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - persistence interface{}
+//   a persistence with LoadAll method like MemoryPersistence or IdentifiableMemoryPersistence
+//   - count int
+//   number of items to generate
+// Returns error or nil for success.
+func (c *DataGenerator) Seed(correlationId string, persistence interface {
+	LoadAll(correlationId string, items []interface{}) error
+}, count int) error {
+	items, err := c.GenerateItems(count)
+	if err != nil {
+		return wrapError(err, correlationId, "INVALID_FAKE_TAG", "Invalid fake tags")
+	}
+	return persistence.LoadAll(correlationId, items)
+}
@@ -0,0 +1,35 @@
+package persistence
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Removes diacritics, so "Crème Brûlée" becomes "Creme Brulee".
+// Text is decomposed, combining marks are dropped and the rest is composed back,
+// so differently normalized forms of the same text become equal.
+func foldDiacritics(value string) string {
+	if isASCII(value) {
+		return value
+	}
+	folder := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(folder, value)
+	if err != nil {
+		return value
+	}
+	return result
+}
+
+// Checks if a string contains only ASCII characters, which have no diacritics
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+)
+
+// Kinds of changes between two sets of items
+const (
+	// Item exists only in the new set
+	DiffCreated = "created"
+	// Item exists in both sets with different fields
+	DiffUpdated = "updated"
+	// Item exists only in the old set
+	DiffDeleted = "deleted"
+)
+
+/*
+Difference of a single field between two versions of an item.
+*/
+type FieldDifference struct {
+	Field    string      `json:"field"`
+	OldValue interface{} `json:"old_value"`
+	NewValue interface{} `json:"new_value"`
+}
+
+/*
+Difference of an item between two sets of items.
+*/
+type ItemDifference struct {
+	Id      interface{}        `json:"id"`
+	Change  string             `json:"change"`
+	OldItem interface{}        `json:"old_item"`
+	NewItem interface{}        `json:"new_item"`
+	Fields  []*FieldDifference `json:"fields"`
+}
+
+// Gets fields of the item by their serialized names
+func itemFields(item interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	val := reflect.ValueOf(item)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return fields
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			fields[convert.StringConverter.ToString(key.Interface())] = val.MapIndex(key).Interface()
+		}
+	case reflect.Struct:
+		typ := val.Type()
+		for index := 0; index < typ.NumField(); index++ {
+			field := typ.Field(index)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			fields[name] = val.Field(index).Interface()
+		}
+	}
+	return fields
+}
+
+// Compares two versions of an item field by field.
+// Parameters:
+//   - oldItem interface{}
+//   an old version of the item
+//   - newItem interface{}
+//   a new version of the item
+// Returns []*FieldDifference
+// differences of fields sorted by field names, empty if items are equal.
+func DiffItemFields(oldItem interface{}, newItem interface{}) []*FieldDifference {
+	oldFields := itemFields(oldItem)
+	newFields := itemFields(newItem)
+
+	names := []string{}
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := []*FieldDifference{}
+	for _, name := range names {
+		oldValue := oldFields[name]
+		newValue := newFields[name]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			result = append(result, &FieldDifference{Field: name, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	return result
+}
+
+// Compares two sets of items matched by their ids.
+// Parameters:
+//   - oldItems []interface{}
+//   an old set of items
+//   - newItems []interface{}
+//   a new set of items
+// Returns []*ItemDifference
+// updated and deleted items in order of the old set followed by created items in order of the new set.
+func DiffItems(oldItems []interface{}, newItems []interface{}) []*ItemDifference {
+	return idAccessor{}.diffItems(oldItems, newItems)
+}
+
+// Compares two sets of items matched by ids of the accessor
+func (c idAccessor) diffItems(oldItems []interface{}, newItems []interface{}) []*ItemDifference {
+	newById := map[interface{}]interface{}{}
+	for _, item := range newItems {
+		newById[c.get(item)] = item
+	}
+
+	result := []*ItemDifference{}
+	oldIds := map[interface{}]bool{}
+	for _, oldItem := range oldItems {
+		id := c.get(oldItem)
+		oldIds[id] = true
+		newItem, ok := newById[id]
+		if !ok {
+			result = append(result, &ItemDifference{Id: id, Change: DiffDeleted, OldItem: oldItem})
+			continue
+		}
+		if fields := DiffItemFields(oldItem, newItem); len(fields) > 0 {
+			result = append(result, &ItemDifference{
+				Id: id, Change: DiffUpdated, OldItem: oldItem, NewItem: newItem, Fields: fields,
+			})
+		}
+	}
+	for _, newItem := range newItems {
+		id := c.get(newItem)
+		if !oldIds[id] {
+			result = append(result, &ItemDifference{Id: id, Change: DiffCreated, NewItem: newItem})
+		}
+	}
+	return result
+}
+
+// Compares items from two data sources, for instance two persistences
+// or a persistence and a file persister.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - oldSource ILoader
+//   a source of the old set of items
+//   - newSource ILoader
+//   a source of the new set of items
+// Returns []*ItemDifference, error
+// differences between the sources or error.
+func Diff(correlationId string, oldSource ILoader, newSource ILoader) ([]*ItemDifference, error) {
+	oldItems, err := oldSource.Load(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	newItems, err := newSource.Load(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	return DiffItems(oldItems, newItems), nil
+}
@@ -0,0 +1,60 @@
+package persistence
+
+import (
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Remembers state of loaded or saved data to detect changes made by other instances
+func (c *MemoryPersistence) rememberModified(correlationId string) {
+	if state, ok, err := c.currentDataState(correlationId); ok && err == nil {
+		c.lastState.Store(state)
+	}
+}
+
+// Reloads items if they were changed by another instance
+func (c *MemoryPersistence) reloadIfModified(correlationId string) error {
+	state, ok, err := c.currentDataState(correlationId)
+	if !ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if last, ok := c.lastState.Load().(dataState); ok && state.equal(last) {
+		return nil
+	}
+
+	c.lock()
+	defer c.unlock()
+	return c.load(correlationId)
+}
+
+// Executes a read-modify-write sequence under a distributed lock,
+// so it's safe when several instances share the same data.
+// Before the action items are reloaded if they were changed by another instance.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - key string
+//   a unique key of the lock
+//   - action func() error
+//   an action to execute while the lock is held
+// Returns error
+// an error of the action, ConflictError if the lock wasn't acquired on timeout, or nil for success.
+func (c *MemoryPersistence) WithLock(correlationId string, key string, action func() error) error {
+	if c.locker == nil {
+		return errors.NewConfigError(correlationId, "NO_LOCK", "Lock reference is required to execute actions under lock")
+	}
+
+	err := c.locker.AcquireLock(correlationId, key, c.LockTtl, c.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.locker.ReleaseLock(correlationId, key)
+	c.Logger.Trace(correlationId, "Acquired lock %s", key)
+
+	if err = c.reloadIfModified(correlationId); err != nil {
+		return err
+	}
+	return action()
+}
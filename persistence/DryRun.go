@@ -0,0 +1,84 @@
+package persistence
+
+import "github.com/pip-services3-go/pip-services3-commons-go/config"
+
+// Options that make the persistence save or coordinate with other instances,
+// they are disabled in copies used by dry runs
+var sandboxDisabledOptions = config.NewConfigParamsFromTuples(
+	"options.single_writer", false,
+	"options.background_save", false,
+	"options.autosave_interval", 0,
+	"options.persist_indexes", false,
+	"options.read_snapshot", false,
+)
+
+// Creates an in-memory copy of the persistence with the same items and write rules,
+// but without loader, saver and change handlers. The copy is configured with
+// the same configuration parameters, so it applies all configured options,
+// and gets validators, computed fields and other rules registered in code.
+// Must be called under read lock.
+func (c *IdentifiableMemoryPersistence) newSandbox() *IdentifiableMemoryPersistence {
+	sandbox := NewIdentifiableMemoryPersistence(c.Prototype)
+	sandbox.Logger = c.Logger
+	sandbox.Clock = c.Clock
+	sandbox.Items = make([]interface{}, len(c.Items))
+	for i, item := range c.Items {
+		sandbox.Items[i] = c.cloneItem(item)
+	}
+	// Items are copied first, so quotas, rollups and indexes are built from them
+	sandbox.Configure(c.configParams.Override(sandboxDisabledOptions))
+
+	// Rules that are set in code and not by configuration
+	sandbox.MaxPageSize = c.MaxPageSize
+	sandbox.ErrorOnNotFound = c.ErrorOnNotFound
+	sandbox.DuplicatePolicy = c.DuplicatePolicy
+	sandbox.IdNormalization = c.IdNormalization
+	sandbox.ReadOnly = c.ReadOnly
+	sandbox.ids = c.ids
+	sandbox.subtypes = c.subtypes
+	sandbox.computedFields = append(sandbox.computedFields, c.computedFields...)
+	sandbox.changeValidators = append(sandbox.changeValidators, c.changeValidators...)
+	sandbox.mergeHandlers = append(sandbox.mergeHandlers, c.mergeHandlers...)
+	if c.quota != nil && sandbox.quota != nil {
+		for key, limit := range c.quota.limits {
+			sandbox.quota.limits[key] = limit
+		}
+	}
+	return sandbox
+}
+
+// Executes mutations in dry-run mode. The action is called with a copy of the persistence
+// that applies the same configuration, change validators, id normalization, computed fields and quotas,
+// but its changes are never committed to this persistence or saved.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - action func(persistence *IdentifiableMemoryPersistence) error
+//   a function that performs Create, Update, Delete and other operations on the copy
+// Returns []*ItemDifference, error
+// changes that would be made by the action or error returned by the action.
+//
+// Example
+//
+//   changes, err := persistence.DryRun("123", func(p *IdentifiableMemoryPersistence) error {
+//       return p.DeleteByFilter("123", func(item interface{}) bool { return item.(MyData).Expired })
+//   })
+//   fmt.Println(len(changes)) // Number of items that would be deleted
+func (c *IdentifiableMemoryPersistence) DryRun(correlationId string,
+	action func(persistence *IdentifiableMemoryPersistence) error) ([]*ItemDifference, error) {
+	c.rlock()
+	oldItems := make([]interface{}, len(c.Items))
+	copy(oldItems, c.Items)
+	sandbox := c.newSandbox()
+	c.runlock()
+
+	if err := action(sandbox); err != nil {
+		return nil, err
+	}
+
+	sandbox.rlock()
+	defer sandbox.runlock()
+	changes := c.ids.diffItems(oldItems, sandbox.Items)
+	c.Logger.Trace(correlationId, "Dry run would change %d items", len(changes))
+	return changes, nil
+}
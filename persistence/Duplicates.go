@@ -0,0 +1,189 @@
+package persistence
+
+import (
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Finds groups of items that share the same key.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - keyFunc func(interface{}) string
+//   a function that calculates a key of the item, items with empty keys are skipped
+// Returns [][]interface{}, error
+// groups of two or more duplicated items in order of their first occurrence or error.
+func (c *IdentifiableMemoryPersistence) FindDuplicates(correlationId string,
+	keyFunc func(interface{}) string) (groups [][]interface{}, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	index := 0
+	defer c.recoverCallback(correlationId, "key", &index, &err)
+
+	deadline := c.operationDeadline()
+	positions := map[string]int{}
+	all := [][]interface{}{}
+	for i, item := range c.Items {
+		index = i
+		if i > 0 && i%deadlineCheckInterval == 0 {
+			if err = c.checkDeadline(correlationId, "key", deadline); err != nil {
+				return nil, err
+			}
+		}
+		key := keyFunc(item)
+		if key == "" {
+			continue
+		}
+		position, ok := positions[key]
+		if !ok {
+			position = len(all)
+			positions[key] = position
+			all = append(all, []interface{}{})
+		}
+		all[position] = append(all[position], c.cloneResult(item))
+	}
+
+	groups = [][]interface{}{}
+	for _, group := range all {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Found %d groups of duplicates", len(groups))
+	return groups, nil
+}
+
+// Adds a handler that is called after items are merged.
+// Handlers are used to reassign references from merged items to the target item.
+// Parameters:
+//   - handler func(correlationId string, targetId interface{}, mergedIds []interface{}) error
+//   a handler that receives id of the resulting item and ids of removed items
+func (c *IdentifiableMemoryPersistence) AddMergeHandler(
+	handler func(correlationId string, targetId interface{}, mergedIds []interface{}) error) {
+	c.lock()
+	defer c.unlock()
+
+	c.mergeHandlers = append(c.mergeHandlers, handler)
+}
+
+// Merges items into one. The merged item keeps id of the first item,
+// the rest of items are deleted and merge handlers are called to reassign references.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - ids []interface{}
+//   ids of items to merge, the first id is kept
+//   - mergeFunc func(items []interface{}) interface{}
+//   a function that combines items in order of ids into a single item
+// Returns interface{}, error
+// the merged item or error. When a merge handler fails the merged items are still saved.
+func (c *IdentifiableMemoryPersistence) MergeItems(correlationId string, ids []interface{},
+	mergeFunc func(items []interface{}) interface{}) (result interface{}, err error) {
+	if len(ids) == 0 {
+		return nil, errors.NewBadRequestError(correlationId, "NO_IDS", "Ids of items to merge are not set")
+	}
+
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	indexes := make([]int, len(ids))
+	items := make([]interface{}, len(ids))
+	for i, id := range ids {
+		indexes[i] = c.GetIndexById(id)
+		if indexes[i] < 0 {
+			c.unlock()
+			return nil, errors.NewNotFoundError(correlationId, "NOT_FOUND",
+				"Item "+convert.StringConverter.ToString(id)+" was not found").WithDetails("id", id)
+		}
+		items[i] = c.cloneResult(c.Items[indexes[i]])
+	}
+
+	merged, err := c.mergeWith(correlationId, mergeFunc, items)
+	if err != nil {
+		c.unlock()
+		return nil, err
+	}
+	newItem := c.cloneItem(merged)
+	if newItem == nil {
+		c.unlock()
+		return nil, newInvalidItemError(correlationId)
+	}
+	c.ids.set(&newItem, c.ids.get(c.Items[indexes[0]]))
+	c.applyComputedFields(&newItem)
+
+	// All changes are checked before any of them is made
+	removed := map[int]bool{}
+	for _, index := range indexes[1:] {
+		if index != indexes[0] {
+			removed[index] = true
+		}
+	}
+	// The merged item takes a place of one of merged items in quotas
+	err = c.checkQuota(correlationId, c.Items[indexes[0]], newItem)
+	for _, index := range indexes[1:] {
+		if err != nil && c.checkQuota(correlationId, c.Items[index], newItem) == nil {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = c.validateChange(correlationId, c.Items[indexes[0]], newItem)
+	}
+	for _, index := range indexes[1:] {
+		if err == nil && removed[index] {
+			err = c.validateChange(correlationId, c.Items[index], nil)
+		}
+	}
+	if err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	c.notifyChange(c.Items[indexes[0]], newItem)
+	c.Items[indexes[0]] = newItem
+
+	if len(removed) > 0 {
+		kept := make([]interface{}, 0, len(c.Items)-len(removed))
+		for index, item := range c.Items {
+			if removed[index] {
+				c.notifyChange(item, nil)
+			} else {
+				kept = append(kept, item)
+			}
+		}
+		c.Items = kept
+	}
+	handlers := c.mergeHandlers
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Merged %d items into %s", len(ids), ids[0])
+
+	for _, handler := range handlers {
+		if err = handler(correlationId, ids[0], ids[1:]); err != nil {
+			break
+		}
+	}
+
+	// Items are already merged, so they are saved even when a handler fails
+	errsav := c.save(correlationId)
+	if err != nil {
+		return nil, err
+	}
+
+	result = c.cloneResult(newItem)
+	return result, errsav
+}
+
+// Calls the merge function. Panics in the function are returned as errors.
+func (c *IdentifiableMemoryPersistence) mergeWith(correlationId string,
+	mergeFunc func(items []interface{}) interface{}, items []interface{}) (result interface{}, err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "merge", &index, &err)
+
+	return mergeFunc(items), nil
+}
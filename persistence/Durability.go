@@ -0,0 +1,49 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// Durability levels of saved data
+const (
+	// Changes are kept in memory and written only on Flush and Close
+	DurabilityNone = "none"
+	// Changes are written to the data file after every change (default)
+	DurabilityFlush = "flush"
+	// Changes are written and synced to the disk after every change
+	DurabilityFsync = "fsync"
+)
+
+// Syncs the directory to make renamed files durable.
+// Errors are ignored because some platforms don't support syncing directories.
+func syncDir(path string) {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	dir.Sync()
+	dir.Close()
+}
+
+// Checks if items must be saved on every change
+func (c *MemoryPersistence) saveOnChange() bool {
+	if c.Durability == DurabilityNone {
+		atomic.StoreInt32(&c.unsaved, 1)
+		return false
+	}
+	return true
+}
+
+// Saves items that were changed since the last save in durability mode "none"
+func (c *MemoryPersistence) saveUnsaved(correlationId string) error {
+	if atomic.SwapInt32(&c.unsaved, 0) == 0 {
+		return nil
+	}
+	err := c.saveNow(correlationId)
+	if err != nil {
+		atomic.StoreInt32(&c.unsaved, 1)
+	}
+	return err
+}
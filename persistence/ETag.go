@@ -0,0 +1,107 @@
+package persistence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Computes ETag of a data item as a hash of its JSON serialization.
+// Equal items always have the same ETag, so it can be used for conditional operations
+// by clients that don't track version fields.
+// Parameters:
+//   - item interface{}
+//   a data item
+// Returns string
+// quoted ETag like "1f3870be274f6c49b3e31a0c6728957f" or empty string if the item can't be serialized.
+func ComputeETag(item interface{}) string {
+	if item == nil {
+		return ""
+	}
+	buffer, err := json.Marshal(item)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(buffer)
+	return "\"" + hex.EncodeToString(hash[:16]) + "\""
+}
+
+// Removes weak prefix and quotes from ETag
+func normalizeETag(etag string) string {
+	etag = strings.TrimSpace(etag)
+	etag = strings.TrimPrefix(etag, "W/")
+	return strings.Trim(etag, "\"")
+}
+
+// Checks If-Match condition. Empty ETag skips the check and "*" matches any item.
+// The condition may list several ETags separated by commas.
+func checkETag(correlationId string, id interface{}, item interface{}, etag string) error {
+	if etag == "" {
+		return nil
+	}
+	current := normalizeETag(ComputeETag(item))
+	for _, expected := range strings.Split(etag, ",") {
+		expected = normalizeETag(expected)
+		if expected == "*" || expected == current {
+			return nil
+		}
+	}
+	return errors.NewConflictError(correlationId, "PRECONDITION_FAILED", "Item was changed since it was read").
+		WithStatus(http.StatusPreconditionFailed).
+		WithDetails("id", id).
+		WithDetails("etag", ComputeETag(item))
+}
+
+// Gets a data item by its unique id together with its ETag.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item.
+// Returns interface{}, string, error
+// data item, its ETag and error.
+func (c *IdentifiableMemoryPersistence) GetOneByIdWithETag(correlationId string, id interface{}) (result interface{}, etag string, err error) {
+	result, err = c.GetOneById(correlationId, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, ComputeETag(result), nil
+}
+
+// Updates a data item only when its current ETag matches If-Match condition.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be updated.
+//   - etag string
+//   expected ETag, a list of ETags separated by commas or "*" to match any item.
+// Returns interface{}, error
+// updated item, ConflictError with status 412 when ETag doesn't match or other error.
+func (c *IdentifiableMemoryPersistence) UpdateIfMatch(correlationId string, item interface{}, etag string) (result interface{}, err error) {
+	if etag == "" {
+		return nil, errors.NewBadRequestError(correlationId, "NO_ETAG", "ETag is not set")
+	}
+	return c.update(correlationId, item, etag)
+}
+
+// Deletes a data item only when its current ETag matches If-Match condition.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the item to be deleted.
+//   - etag string
+//   expected ETag, a list of ETags separated by commas or "*" to match any item.
+// Returns interface{}, error
+// deleted item, ConflictError with status 412 when ETag doesn't match or other error.
+func (c *IdentifiableMemoryPersistence) DeleteByIdIfMatch(correlationId string, id interface{}, etag string) (result interface{}, err error) {
+	if etag == "" {
+		return nil, errors.NewBadRequestError(correlationId, "NO_ETAG", "ETag is not set")
+	}
+	return c.deleteById(correlationId, id, etag)
+}
@@ -0,0 +1,123 @@
+package persistence
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Name of the struct field of type map[string]interface{} that keeps fields not defined in the struct
+const ExtraFieldName = "Extra"
+
+// Gets the Extra field of the struct type
+func findExtraField(typ reflect.Type) (reflect.StructField, bool) {
+	if typ == nil {
+		return reflect.StructField{}, false
+	}
+	typ = valueType(typ)
+	if typ.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	field, ok := typ.FieldByName(ExtraFieldName)
+	if !ok || field.Type != reflect.TypeOf(map[string]interface{}{}) {
+		return reflect.StructField{}, false
+	}
+	return field, true
+}
+
+// Checks if the struct type keeps extra fields
+func hasExtraField(typ reflect.Type) bool {
+	_, ok := findExtraField(typ)
+	return ok
+}
+
+// Gets a value of the extra field by name ignoring case
+func getExtraValue(obj interface{}, name string) (interface{}, bool) {
+	val := reflect.ValueOf(obj)
+	for val.IsValid() && (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) {
+		if val.IsNil() {
+			return nil, false
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil, false
+	}
+	field, ok := findExtraField(val.Type())
+	if !ok {
+		return nil, false
+	}
+	extra, _ := val.FieldByIndex(field.Index).Interface().(map[string]interface{})
+	if value, ok := extra[name]; ok {
+		return value, true
+	}
+	name = strings.ToLower(name)
+	for key, value := range extra {
+		if strings.ToLower(key) == name {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Sets fields that are not defined in the struct into its Extra field
+func setExtraFields(value reflect.Value, field reflect.StructField, fields map[string]interface{}) {
+	if len(fields) == 0 {
+		return
+	}
+	value.FieldByIndex(field.Index).Set(reflect.ValueOf(fields))
+}
+
+// Merges Extra fields of the original items into serialized items.
+// Extra fields never override fields defined in the struct.
+func encodeExtraFields(originals []interface{}, items []interface{}) []interface{} {
+	var result []interface{}
+	for i, original := range originals {
+		val := reflect.ValueOf(original)
+		if !val.IsValid() {
+			continue
+		}
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				continue
+			}
+			val = val.Elem()
+		}
+		field, ok := findExtraField(val.Type())
+		if !ok {
+			continue
+		}
+		extra, _ := val.FieldByIndex(field.Index).Interface().(map[string]interface{})
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(extra) == 0 && jsonName == "-" {
+			continue
+		}
+
+		value, ok := items[i].(map[string]interface{})
+		if !ok {
+			buffer, err := json.Marshal(items[i])
+			if err != nil || json.Unmarshal(buffer, &value) != nil {
+				continue
+			}
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		delete(value, jsonName)
+		for key, fieldValue := range extra {
+			if _, ok := value[key]; !ok {
+				value[key] = fieldValue
+			}
+		}
+
+		if result == nil {
+			result = make([]interface{}, len(items))
+			copy(result, items)
+		}
+		result[i] = value
+	}
+	if result == nil {
+		return items
+	}
+	return result
+}
@@ -0,0 +1,370 @@
+package persistence
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Segment of a path to a nested field, for instance, "address" or "tags[0]".
+*/
+type fieldPathSegment struct {
+	name  string
+	index int
+}
+
+// Checks if the name is a path to a nested field
+func isFieldPath(name string) bool {
+	return strings.ContainsAny(name, ".[")
+}
+
+// Parses a path like "address.city" or "items[0].name" into segments
+func parseFieldPath(path string) ([]fieldPathSegment, error) {
+	segments := []fieldPathSegment{}
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		indexes := []int{}
+		if start := strings.Index(part, "["); start >= 0 {
+			name = part[:start]
+			rest := part[start:]
+			for len(rest) > 0 {
+				end := strings.Index(rest, "]")
+				if rest[0] != '[' || end < 0 {
+					return nil, errors.NewBadRequestError("", "INVALID_PATH", "Field path "+path+" is not valid").
+						WithDetails("path", path)
+				}
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil || index < 0 {
+					return nil, errors.NewBadRequestError("", "INVALID_PATH", "Field path "+path+" has invalid index").
+						WithDetails("path", path)
+				}
+				indexes = append(indexes, index)
+				rest = rest[end+1:]
+			}
+		}
+		if name == "" && (len(segments) > 0 || len(indexes) == 0) {
+			return nil, errors.NewBadRequestError("", "INVALID_PATH", "Field path "+path+" is not valid").
+				WithDetails("path", path)
+		}
+		if name != "" {
+			segments = append(segments, fieldPathSegment{name: name, index: -1})
+		}
+		for _, index := range indexes {
+			segments = append(segments, fieldPathSegment{index: index})
+		}
+	}
+	return segments, nil
+}
+
+// Finds a key in the map by name ignoring case
+func findMapKey(val reflect.Value, name string) (reflect.Value, bool) {
+	lowerName := strings.ToLower(name)
+	for _, key := range val.MapKeys() {
+		if strings.ToLower(convert.StringConverter.ToString(key.Interface())) == lowerName {
+			return key, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Gets a value of the nested field specified by path like "address.city" or "tags[0]".
+// Struct fields are matched by names or json tags, map keys are matched ignoring case.
+// Parameters:
+//   - obj interface{}
+//   an object to read the value from
+//   - path string
+//   a path to the field
+// Returns interface{}
+// the field value or nil if the path doesn't exist
+func GetPathValue(obj interface{}, path string) interface{} {
+	segments, err := parseFieldPath(path)
+	if err != nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(obj)
+	for _, segment := range segments {
+		for val.IsValid() && (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) {
+			if val.IsNil() {
+				return nil
+			}
+			val = val.Elem()
+		}
+		if !val.IsValid() {
+			return nil
+		}
+
+		if segment.index >= 0 {
+			if (val.Kind() != reflect.Slice && val.Kind() != reflect.Array) || segment.index >= val.Len() {
+				return nil
+			}
+			val = val.Index(segment.index)
+			continue
+		}
+
+		switch val.Kind() {
+		case reflect.Struct:
+			field, ok := findField(val.Type(), segment.name)
+			if !ok {
+				extra, ok := getExtraValue(val.Interface(), segment.name)
+				if !ok {
+					return nil
+				}
+				val = reflect.ValueOf(extra)
+				continue
+			}
+			val = val.FieldByIndex(field.Index)
+		case reflect.Map:
+			key, ok := findMapKey(val, segment.name)
+			if !ok {
+				return nil
+			}
+			val = val.MapIndex(key)
+		default:
+			return nil
+		}
+	}
+
+	if !val.IsValid() || !val.CanInterface() {
+		return nil
+	}
+	return val.Interface()
+}
+
+// Gets type of the nested field of the prototype specified by path.
+// Returns false if the path doesn't exist in structs. Paths through maps and interfaces
+// can't be checked and return nil type with true.
+func findPathType(prototype reflect.Type, path string) (reflect.Type, bool) {
+	segments, err := parseFieldPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	typ := prototype
+	for _, segment := range segments {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		switch {
+		case typ.Kind() == reflect.Interface:
+			return nil, true
+		case segment.index >= 0:
+			if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+				return nil, false
+			}
+			typ = typ.Elem()
+		case typ.Kind() == reflect.Struct:
+			field, ok := findField(typ, segment.name)
+			if !ok && hasExtraField(typ) {
+				return nil, true
+			}
+			if !ok {
+				return nil, false
+			}
+			typ = field.Type
+		case typ.Kind() == reflect.Map:
+			typ = typ.Elem()
+		default:
+			return nil, false
+		}
+	}
+	return typ, true
+}
+
+// Converts a value to the type of the target
+func toTypedValue(value interface{}, typ reflect.Type) (reflect.Value, bool) {
+	if value == nil {
+		return reflect.Zero(typ), true
+	}
+	val := reflect.ValueOf(value)
+	if val.Type().AssignableTo(typ) {
+		return val, true
+	}
+	if val.Type().ConvertibleTo(typ) && val.Kind() != reflect.String && typ.Kind() != reflect.String {
+		return val.Convert(typ), true
+	}
+	if val.Kind() == reflect.String && typ.Kind() == reflect.String {
+		return val.Convert(typ), true
+	}
+	return reflect.Value{}, false
+}
+
+// Returns a copy of the value with the nested field set
+func setPathValue(val reflect.Value, typ reflect.Type, segments []fieldPathSegment, value interface{}, path string) (reflect.Value, error) {
+	if len(segments) == 0 {
+		result, ok := toTypedValue(value, typ)
+		if !ok {
+			return val, errors.NewBadRequestError("", "INVALID_VALUE", "Value can't be assigned to field "+path).
+				WithDetails("path", path)
+		}
+		return result, nil
+	}
+	if !val.IsValid() {
+		val = reflect.Zero(typ)
+	}
+	notFound := func() (reflect.Value, error) {
+		return val, errors.NewBadRequestError("", "INVALID_PATH", "Field path "+path+" doesn't exist").
+			WithDetails("path", path)
+	}
+
+	segment := segments[0]
+	switch typ.Kind() {
+	case reflect.Ptr:
+		result := reflect.New(typ.Elem())
+		if !val.IsNil() {
+			result.Elem().Set(val.Elem())
+		}
+		elem, err := setPathValue(result.Elem(), typ.Elem(), segments, value, path)
+		if err != nil {
+			return val, err
+		}
+		result.Elem().Set(elem)
+		return result, nil
+	case reflect.Interface:
+		if val.IsNil() {
+			if segment.index >= 0 {
+				return notFound()
+			}
+			val = reflect.ValueOf(map[string]interface{}{})
+		} else {
+			val = val.Elem()
+		}
+		result, err := setPathValue(val, val.Type(), segments, value, path)
+		if err != nil {
+			return val, err
+		}
+		wrapped := reflect.New(typ).Elem()
+		wrapped.Set(result)
+		return wrapped, nil
+	case reflect.Struct:
+		if segment.index >= 0 {
+			return notFound()
+		}
+		field, ok := findField(typ, segment.name)
+		if !ok {
+			return notFound()
+		}
+		result := reflect.New(typ).Elem()
+		result.Set(val)
+		fieldValue, err := setPathValue(result.FieldByIndex(field.Index), field.Type, segments[1:], value, path)
+		if err != nil {
+			return val, err
+		}
+		result.FieldByIndex(field.Index).Set(fieldValue)
+		return result, nil
+	case reflect.Map:
+		if segment.index >= 0 {
+			return notFound()
+		}
+		result := reflect.MakeMap(typ)
+		if !val.IsNil() {
+			for _, key := range val.MapKeys() {
+				result.SetMapIndex(key, val.MapIndex(key))
+			}
+		}
+		key, ok := findMapKey(result, segment.name)
+		if !ok {
+			key = reflect.ValueOf(segment.name)
+			if !key.Type().ConvertibleTo(typ.Key()) {
+				return notFound()
+			}
+			key = key.Convert(typ.Key())
+		}
+		elem, err := setPathValue(result.MapIndex(key), typ.Elem(), segments[1:], value, path)
+		if err != nil {
+			return val, err
+		}
+		result.SetMapIndex(key, elem)
+		return result, nil
+	case reflect.Slice:
+		if segment.index < 0 || segment.index > val.Len() {
+			return notFound()
+		}
+		length := val.Len()
+		if segment.index == length {
+			length++
+		}
+		result := reflect.MakeSlice(typ, length, length)
+		reflect.Copy(result, val)
+		elem, err := setPathValue(result.Index(segment.index), typ.Elem(), segments[1:], value, path)
+		if err != nil {
+			return val, err
+		}
+		result.Index(segment.index).Set(elem)
+		return result, nil
+	}
+	return notFound()
+}
+
+// Sets a value of the nested field specified by path like "address.city" or "tags[0]".
+// Nested structs, maps and slices are copied, so the original object is not changed.
+// Missing maps and pointers on the path are created, index equal to the slice length appends an element.
+// Parameters:
+//   - obj *interface{}
+//   a pointer on object to set the value
+//   - path string
+//   a path to the field
+//   - value interface{}
+//   a new value of the field
+// Returns error
+// BadRequestError if the path doesn't exist or the value has wrong type, nil for success.
+func SetPathValue(obj *interface{}, path string, value interface{}) error {
+	segments, err := parseFieldPath(path)
+	if err != nil {
+		return err
+	}
+	if *obj == nil {
+		return errors.NewBadRequestError("", "INVALID_PATH", "Field path "+path+" doesn't exist").
+			WithDetails("path", path)
+	}
+
+	val := reflect.ValueOf(*obj)
+	result, err := setPathValue(val, val.Type(), segments, value, path)
+	if err != nil {
+		return err
+	}
+	*obj = result.Interface()
+	return nil
+}
+
+// Creates a projection function that selects values of fields specified by names or paths.
+// The projection returns a map with the paths as keys.
+// Parameters:
+//   - paths ...string
+//   names or paths of fields to select
+// Returns func(interface{}) interface{}
+// projection function to use with GetPageByFilter or GetListByFilter
+func SelectFields(paths ...string) func(interface{}) interface{} {
+	return func(item interface{}) interface{} {
+		result := make(map[string]interface{}, len(paths))
+		for _, path := range paths {
+			result[path] = GetPathValue(item, path)
+		}
+		return result
+	}
+}
+
+// Creates a sort function that orders items by a field specified by name or path.
+// Parameters:
+//   - path string
+//   a name or path of the field to sort by
+//   - ascending bool
+//   true to sort in ascending order and false in descending
+// Returns func(a, b interface{}) bool
+// sort function to use with GetPageByFilter or GetListByFilter
+func SortByField(path string, ascending bool) func(a, b interface{}) bool {
+	return func(a, b interface{}) bool {
+		result, ok := compareOrdered(GetPathValue(a, path), GetPathValue(b, path))
+		if !ok {
+			return false
+		}
+		if ascending {
+			return result < 0
+		}
+		return result > 0
+	}
+}
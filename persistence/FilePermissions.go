@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Parses file mode bits in octal notation like "0600"
+func parseFileMode(value string, defaultValue os.FileMode) os.FileMode {
+	if value == "" {
+		return defaultValue
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return defaultValue
+	}
+	return os.FileMode(mode) & os.ModePerm
+}
+
+// Reads file permission options
+func (c *JsonFilePersister) configurePermissions(config *config.ConfigParams) {
+	c.FileMode = parseFileMode(config.GetAsString("options.file_mode"), c.FileMode)
+	c.DirMode = parseFileMode(config.GetAsString("options.dir_mode"), c.DirMode)
+	c.FileOwner = config.GetAsStringWithDefault("options.file_owner", c.FileOwner)
+	c.FileGroup = config.GetAsStringWithDefault("options.file_group", c.FileGroup)
+}
+
+// Gets mode of created data files. When the mode is not configured
+// files get the mode of the existing data file or 0600.
+func (c *JsonFilePersister) fileMode() os.FileMode {
+	if c.FileMode != 0 {
+		return c.FileMode
+	}
+	if info, err := os.Stat(c.path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0600
+}
+
+// Writes a file next to the data file, like stored indexes or item versions,
+// with the same mode and owner as the data file
+func (c *JsonFilePersister) writeSideFile(correlationId string, path string, content []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, c.fileMode())
+	if err != nil {
+		return err
+	}
+	if err = c.applyPermissions(correlationId, file); err == nil {
+		_, err = file.Write(content)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Creates a temporary file with a unique name next to the data file, so concurrent writers
+// don't share it. Temporary files are readable only by the owner, so when the mode is not configured
+// they get the mode of the data file they replace.
+func (c *JsonFilePersister) createTempFile(correlationId string) (*os.File, error) {
+	file, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return nil, errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+c.path).WithCause(err)
+	}
+	if c.FileMode == 0 {
+		if info, err := os.Stat(c.path); err == nil {
+			if err = file.Chmod(info.Mode().Perm()); err != nil {
+				file.Close()
+				os.Remove(file.Name())
+				return nil, errors.NewFileError(correlationId, "CHMOD_FAILED", "Failed to set mode of data file: "+c.path).WithCause(err)
+			}
+		}
+	}
+	return file, nil
+}
+
+// Creates missing directories of the file
+func (c *JsonFilePersister) createDir(correlationId string, path string) error {
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return nil
+	}
+	mode := c.DirMode
+	if mode == 0 {
+		mode = 0755
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to create directory: "+dir).WithCause(err)
+	}
+	return nil
+}
+
+// Resolves user or group id by a number or a name
+func lookupId(value string, lookup func(name string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, nil
+	}
+	id, err := lookup(value)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(id)
+}
+
+// Sets configured mode and owner of the written file
+func (c *JsonFilePersister) applyPermissions(correlationId string, file *os.File) error {
+	if c.FileMode != 0 {
+		// Unlike creation mode, explicit change of mode is not limited by umask
+		if err := file.Chmod(c.FileMode); err != nil {
+			return errors.NewFileError(correlationId, "CHMOD_FAILED", "Failed to set mode of data file: "+c.path).WithCause(err)
+		}
+	}
+	if c.FileOwner == "" && c.FileGroup == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	var err error
+	if c.FileOwner != "" {
+		uid, err = lookupId(c.FileOwner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+	}
+	if err == nil && c.FileGroup != "" {
+		gid, err = lookupId(c.FileGroup, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+	}
+	if err == nil {
+		err = file.Chown(uid, gid)
+	}
+	if err != nil {
+		return errors.NewFileError(correlationId, "CHOWN_FAILED", "Failed to set owner of data file: "+c.path).WithCause(err)
+	}
+	return nil
+}
@@ -20,11 +20,28 @@ see JsonFilePersister
 
 Configuration parameters
 
-  - path - path to the file where data is stored
+  - path - path to the file where data is stored, "-" to read items from stdin and write them to stdout
+  - options:
+      - save_chunk_size:     Size of chunks written to the data file in bytes (default: 65536)
+      - load_parallelism:    Number of goroutines that unmarshal items of the data file preserving their order (default: 1)
+      - read_buffer_size:    Size of the buffer to read the data file in bytes (default: 65536)
+      - write_buffer_size:   Size of the buffer to write the data file in bytes, the same as save_chunk_size
+      - indent:              String to indent items in the data file, empty to write compact JSON (default: empty)
+      - escape_html:         Escape <, > and & characters in strings written to the data file (default: true)
+      - file_mode:           Mode bits of the data file in octal notation like 0600 (default: mode of the replaced file or 0600)
+      - dir_mode:            Mode bits of created directories like 0700 (default: 0755 limited by umask)
+      - file_owner:          User name or id of the data file owner on Unix
+      - file_group:          Group name or id of the data file on Unix
+
+Other configuration parameters of MemoryPersistence are supported, see MemoryPersistence.
 
 References
 
 - *:logger:*:*:1.0  (optional) ILogger components to pass log messages
+- *:lock:*:*:1.0    (optional) ILock component for WithLock and to coordinate the writer in single writer mode
+- *:persister:*:*:1.0 (optional) ILoader and ISaver component configured in dependencies.persister instead of the default JsonFilePersister
+- *:loader:*:*:1.0  (optional) ILoader component configured in dependencies.loader
+- *:saver:*:*:1.0   (optional) ISaver component configured in dependencies.saver
 
 Example
   type MyJsonFilePersistence struct {
@@ -63,6 +80,7 @@ type FilePersistence struct {
 }
 
 // Creates a new instance of the persistence.
+//  - prototype    type of contained data, panics when it is nil
 //  - persister    (optional) a persister component that loads and saves data from/to flat file.
 // Return *FilePersistence
 // Pointer on new FilePersistence instance
@@ -79,8 +97,24 @@ func NewFilePersistence(prototype reflect.Type, persister *JsonFilePersister) *F
 	return c
 }
 
+// Creates a new instance of the persistence with JsonFilePersister for the data file.
+// Parameters:
+//  - prototype reflect.Type
+//   type of contained data
+//  - path string
+//   a path to the data file
+// Return *FilePersistence, error
+// created FilePersistence or ConfigError when the prototype or the path is not set.
+func NewFilePersistenceFromPath(prototype reflect.Type, path string) (*FilePersistence, error) {
+	if err := validatePrototypeAndPath(prototype, path); err != nil {
+		return nil, err
+	}
+	return NewFilePersistence(prototype, NewJsonFilePersister(prototype, path)), nil
+}
+
 // Configures component by passing configuration parameters.
 //  - config    configuration parameters to be set.
 func (c *FilePersistence) Configure(conf *config.ConfigParams) {
-	c.Persister.Configure(conf)
+	c.MemoryPersistence.Configure(conf)
+	c.reconfigurePersister(c.Persister, conf)
 }
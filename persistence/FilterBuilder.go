@@ -0,0 +1,268 @@
+package persistence
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+FilterBuilder composes filter functions for MemoryPersistence from conditions
+bound to fields of the persistence prototype. Field names are checked against
+the prototype when the filter is built, and struct field indexes are resolved once,
+so the resulting predicate doesn't search fields by names for every item.
+
+Conditions joined with And are evaluated together, Or starts a new group of conditions.
+Strings are compared by their bytes unless a collation is set with WithCollation.
+
+Typed builders with a method per field are generated on top of FilterBuilder
+by cmd/persistencegen, so field names and value types are checked by the compiler:
+
+  filter, err := NewMyDataFilterBuilder().
+      Where().Name().Equals("ABC").
+      And().Age().GreaterThan(30).
+      Build()
+
+Example
+
+  filter, err := NewFilterBuilder(reflect.TypeOf(MyData{})).
+      Where("Name").Equals("ABC").
+      And().Where("Age").GreaterThan(30).
+      Build()
+  if err == nil {
+      page, err := persistence.GetPageByFilter("123", filter, nil, nil, nil)
+      ...
+  }
+*/
+type FilterBuilder struct {
+	prototype reflect.Type
+	collation *Collation
+	groups    [][]*filterCondition
+	err       error
+}
+
+type filterCondition struct {
+	name     string
+	index    []int
+	operator string
+	value    interface{}
+	matches  func(value interface{}) bool
+}
+
+// Creates a new instance of the filter builder.
+// Parameters:
+//  - prototype reflect.Type
+//  type of items to be filtered
+// Return *FilterBuilder
+// created filter builder
+func NewFilterBuilder(prototype reflect.Type) *FilterBuilder {
+	return &FilterBuilder{
+		prototype: prototype,
+		groups:    [][]*filterCondition{{}},
+	}
+}
+
+// Starts a condition for a field specified by its name, json tag or path to a nested field like "address.city".
+// Parameters:
+//  - name string
+//  a name of the field
+// Return *FilterField
+// field to set the condition
+func (c *FilterBuilder) Where(name string) *FilterField {
+	field := &FilterField{builder: c, name: name}
+
+	if c.prototype != nil {
+		typ := c.prototype
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Struct && isFieldPath(name) {
+			if _, ok := findPathType(typ, name); !ok && c.err == nil {
+				c.err = errors.NewBadRequestError("", "UNKNOWN_FIELD",
+					"Field "+name+" is not defined in "+typ.Name()).WithDetails("field", name)
+			}
+		} else if typ.Kind() == reflect.Struct {
+			structField, ok := findField(typ, name)
+			if !ok && c.err == nil && !hasExtraField(typ) {
+				c.err = errors.NewBadRequestError("", "UNKNOWN_FIELD",
+					"Field "+name+" is not defined in "+typ.Name()).WithDetails("field", name)
+			}
+			field.index = structField.Index
+		}
+	}
+	return field
+}
+
+// Sets a collation to compare strings in all conditions of the filter,
+// for instance, to match names ignoring letter case and diacritics.
+// Parameters:
+//  - collation *Collation
+//  (optional) collation to compare strings, nil to compare strings by bytes
+// Return *FilterBuilder
+// the same builder
+func (c *FilterBuilder) WithCollation(collation *Collation) *FilterBuilder {
+	c.collation = collation
+	return c
+}
+
+// Joins the next condition with the previous ones using logical AND.
+// Return *FilterBuilder
+// the same builder
+func (c *FilterBuilder) And() *FilterBuilder {
+	return c
+}
+
+// Starts a new group of conditions joined with the previous groups using logical OR.
+// Return *FilterBuilder
+// the same builder
+func (c *FilterBuilder) Or() *FilterBuilder {
+	c.groups = append(c.groups, []*filterCondition{})
+	return c
+}
+
+func (c *FilterBuilder) add(condition *filterCondition) *FilterBuilder {
+	last := len(c.groups) - 1
+	c.groups[last] = append(c.groups[last], condition)
+	return c
+}
+
+// Gets non-empty groups of conditions joined with logical OR
+func (c *FilterBuilder) conditionGroups() [][]*filterCondition {
+	groups := make([][]*filterCondition, 0, len(c.groups))
+	for _, group := range c.groups {
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Builds a filter function from the defined conditions.
+// Return func(interface{}) bool, error
+// the filter function or error if some fields are not defined in the prototype
+func (c *FilterBuilder) Build() (func(interface{}) bool, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	groups := c.conditionGroups()
+	if len(groups) == 0 {
+		return func(item interface{}) bool { return true }, nil
+	}
+
+	return func(item interface{}) bool {
+		for _, group := range groups {
+			matched := true
+			for _, condition := range group {
+				value := getFieldValue(item, condition.index, condition.name)
+				if !condition.matches(value) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+/*
+FilterField defines a condition for a single field in FilterBuilder.
+*/
+type FilterField struct {
+	builder *FilterBuilder
+	name    string
+	index   []int
+}
+
+func (c *FilterField) is(operator string, value interface{}, matches func(value interface{}) bool) *FilterBuilder {
+	return c.builder.add(&filterCondition{
+		name: c.name, index: c.index, operator: operator, value: value, matches: matches,
+	})
+}
+
+func (c *FilterField) compare(operator string, expected interface{}, accept func(result int) bool) *FilterBuilder {
+	return c.is(operator, expected, func(value interface{}) bool {
+		result, ok := compareCollated(value, expected, c.builder.collation)
+		return ok && accept(result)
+	})
+}
+
+// Field value is equal to the given value.
+func (c *FilterField) Equals(value interface{}) *FilterBuilder {
+	return c.compare("eq", value, func(result int) bool { return result == 0 })
+}
+
+// Field value is not equal to the given value.
+func (c *FilterField) NotEquals(value interface{}) *FilterBuilder {
+	return c.is("ne", value, func(actual interface{}) bool {
+		result, ok := compareCollated(actual, value, c.builder.collation)
+		return !ok || result != 0
+	})
+}
+
+// Field value is greater than the given value.
+func (c *FilterField) GreaterThan(value interface{}) *FilterBuilder {
+	return c.compare("gt", value, func(result int) bool { return result > 0 })
+}
+
+// Field value is greater than or equal to the given value.
+func (c *FilterField) GreaterThanOrEqual(value interface{}) *FilterBuilder {
+	return c.compare("gte", value, func(result int) bool { return result >= 0 })
+}
+
+// Field value is less than the given value.
+func (c *FilterField) LessThan(value interface{}) *FilterBuilder {
+	return c.compare("lt", value, func(result int) bool { return result < 0 })
+}
+
+// Field value is less than or equal to the given value.
+func (c *FilterField) LessThanOrEqual(value interface{}) *FilterBuilder {
+	return c.compare("lte", value, func(result int) bool { return result <= 0 })
+}
+
+// Field value is equal to one of the given values.
+func (c *FilterField) In(values ...interface{}) *FilterBuilder {
+	return c.is("in", values, func(actual interface{}) bool {
+		for _, value := range values {
+			if result, ok := compareCollated(actual, value, c.builder.collation); ok && result == 0 {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Field value converted to string contains the given substring.
+func (c *FilterField) Contains(value string) *FilterBuilder {
+	return c.is("contains", value, func(actual interface{}) bool {
+		collation := c.builder.collation
+		return actual != nil && strings.Contains(collation.Normalize(convert.StringConverter.ToString(actual)),
+			collation.Normalize(value))
+	})
+}
+
+// Field value converted to string starts with the given prefix.
+func (c *FilterField) StartsWith(value string) *FilterBuilder {
+	return c.is("starts", value, func(actual interface{}) bool {
+		collation := c.builder.collation
+		return actual != nil && strings.HasPrefix(collation.Normalize(convert.StringConverter.ToString(actual)),
+			collation.Normalize(value))
+	})
+}
+
+// Field value is nil or has zero value of its type.
+func (c *FilterField) IsEmpty() *FilterBuilder {
+	return c.is("empty", nil, func(actual interface{}) bool {
+		return actual == nil || reflect.ValueOf(actual).IsZero()
+	})
+}
+
+// Field value matches the given function.
+func (c *FilterField) Matches(matches func(value interface{}) bool) *FilterBuilder {
+	return c.is("custom", nil, matches)
+}
@@ -0,0 +1,170 @@
+package persistence
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// Operator suffixes recognized by ComposeFilter
+var filterOperators = []string{"_gte", "_gt", "_lte", "_lt", "_ne", "_in", "_contains", "_starts"}
+
+/*
+ComposeFilter converts FilterParams into a filter function by mapping
+filter keys to fields of the prototype. Keys are matched with field names
+or json tags (case insensitive). Values are converted to the field types.
+
+Comparison operators are encoded as key suffixes:
+
+  - name=ABC            field is equal to the value
+  - name_ne=ABC         field is not equal to the value
+  - age_gt=30           field is greater than the value
+  - age_gte=30          field is greater than or equal to the value
+  - age_lt=30           field is less than the value
+  - age_lte=30          field is less than or equal to the value
+  - status_in=new,done  field is equal to one of comma separated values
+  - name_contains=AB    field contains the value
+  - name_starts=AB      field starts with the value
+
+Parameters:
+  - prototype reflect.Type
+  type of items to be filtered
+  - filter *cdata.FilterParams
+  (optional) filter parameters
+Returns func(interface{}) bool, error
+filter function or BadRequestError if some keys don't match prototype fields.
+
+Example
+
+  filter, err := ComposeFilter(reflect.TypeOf(MyData{}),
+      cdata.NewFilterParamsFromTuples("name", "ABC", "age_gte", 30))
+*/
+func ComposeFilter(prototype reflect.Type, filter *cdata.FilterParams) (func(interface{}) bool, error) {
+	return composeFilterBuilder(prototype, filter, nil).Build()
+}
+
+// Converts FilterParams into a filter function for items of the persistence
+// using the collation set in options.collation to compare strings.
+// Parameters:
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters
+// Returns func(interface{}) bool, error
+// filter function or BadRequestError if some keys don't match prototype fields.
+func (c *MemoryPersistence) ComposeFilter(filter *cdata.FilterParams) (func(interface{}) bool, error) {
+	return composeFilterBuilder(c.Prototype, filter, c.Collation).Build()
+}
+
+// composeFilterBuilder fills FilterBuilder with conditions defined by filter parameters
+func composeFilterBuilder(prototype reflect.Type, filter *cdata.FilterParams, collation *Collation) *FilterBuilder {
+	builder := NewFilterBuilder(prototype).WithCollation(collation)
+	if filter == nil {
+		return builder
+	}
+
+	for _, key := range filter.Keys() {
+		value := filter.GetAsString(key)
+		name, operator := splitFilterKey(prototype, key)
+		field := builder.Where(name)
+		fieldType := filterFieldType(prototype, name)
+
+		switch operator {
+		case "_gte":
+			field.GreaterThanOrEqual(toFieldValue(value, fieldType))
+		case "_gt":
+			field.GreaterThan(toFieldValue(value, fieldType))
+		case "_lte":
+			field.LessThanOrEqual(toFieldValue(value, fieldType))
+		case "_lt":
+			field.LessThan(toFieldValue(value, fieldType))
+		case "_ne":
+			field.NotEquals(toFieldValue(value, fieldType))
+		case "_in":
+			values := []interface{}{}
+			for _, v := range strings.Split(value, ",") {
+				values = append(values, toFieldValue(strings.TrimSpace(v), fieldType))
+			}
+			field.In(values...)
+		case "_contains":
+			field.Contains(value)
+		case "_starts":
+			field.StartsWith(value)
+		default:
+			field.Equals(toFieldValue(value, fieldType))
+		}
+	}
+
+	return builder
+}
+
+// splitFilterKey separates operator suffix from the field name.
+// The suffix is recognized only when the rest of the key matches a field
+func splitFilterKey(prototype reflect.Type, key string) (string, string) {
+	for _, operator := range filterOperators {
+		if strings.HasSuffix(key, operator) {
+			name := key[:len(key)-len(operator)]
+			if filterFieldType(prototype, name) != nil || !isStructType(prototype) || hasExtraField(prototype) {
+				return name, operator
+			}
+		}
+	}
+	return key, ""
+}
+
+func isStructType(typ reflect.Type) bool {
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ != nil && typ.Kind() == reflect.Struct
+}
+
+// filterFieldType gets type of the prototype field or nil if it is not found
+func filterFieldType(prototype reflect.Type, name string) reflect.Type {
+	if !isStructType(prototype) {
+		return nil
+	}
+	if isFieldPath(name) {
+		typ, _ := findPathType(prototype, name)
+		return typ
+	}
+	field, ok := findField(prototype, name)
+	if !ok {
+		return nil
+	}
+	return field.Type
+}
+
+// toFieldValue converts a string filter value into the type of the field
+func toFieldValue(value string, typ reflect.Type) interface{} {
+	if typ == nil {
+		return value
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		if result := convert.DateTimeConverter.ToNullableDateTime(value); result != nil {
+			return *result
+		}
+		return value
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		if result := convert.BooleanConverter.ToNullableBoolean(value); result != nil {
+			return *result
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if result := convert.LongConverter.ToNullableLong(value); result != nil {
+			return *result
+		}
+	case reflect.Float32, reflect.Float64:
+		if result := convert.DoubleConverter.ToNullableDouble(value); result != nil {
+			return *result
+		}
+	}
+	return value
+}
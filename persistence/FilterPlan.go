@@ -0,0 +1,205 @@
+package persistence
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// Maximum number of items used to estimate selectivity of filter predicates
+const filterPlanSampleSize = 1000
+
+// Index of time partitions by the timestamp field used by queries by FilterParams
+const IndexTimePartitions = "time_partitions"
+
+/*
+FilterPlan describes how MemoryPersistence executes a filter
+composed from FilterParams. It is returned by ExplainFilter
+to help diagnosing slow queries on large datasets.
+*/
+type FilterPlan struct {
+	// Total number of items in the persistence
+	TotalItems int64 `json:"total_items"`
+	// Estimated number of items scanned to execute the filter
+	ScannedItems int64 `json:"scanned_items"`
+	// Estimated number of items that match the filter
+	MatchedItems int64 `json:"matched_items"`
+	// True if an index is used to select items instead of full scan
+	IndexUsed bool `json:"index_used"`
+	// Name of the used index, for instance IndexTimePartitions
+	Index string `json:"index"`
+	// Predicates in the recommended evaluation order
+	Predicates []*FilterPredicatePlan `json:"predicates"`
+}
+
+/*
+FilterPredicatePlan describes a single predicate in FilterPlan.
+*/
+type FilterPredicatePlan struct {
+	// Group of predicates joined with AND. Groups are joined with OR
+	Group int `json:"group"`
+	// Name of the filtered field
+	Field string `json:"field"`
+	// Comparison operator
+	Operator string `json:"operator"`
+	// Compared value
+	Value interface{} `json:"value"`
+	// Estimated fraction of items that match the predicate, from 0 to 1
+	Selectivity float64 `json:"selectivity"`
+}
+
+// Explains how items are selected by the given filter parameters.
+// Filters that limit the timestamp field from below select items by time partitions
+// when partitions are enabled, other filters scan all items.
+// Selectivity of predicates is estimated on a sample of items,
+// and predicates inside each group are ordered from the most selective ones.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters
+// Returns *FilterPlan, error
+// execution plan or error if the filter is invalid.
+func (c *MemoryPersistence) ExplainFilter(correlationId string, filter *cdata.FilterParams) (plan *FilterPlan, err error) {
+	builder := composeFilterBuilder(c.Prototype, filter, c.Collation)
+	filterFunc, err := builder.Build()
+	if err != nil {
+		return nil, wrapError(err, correlationId, "INVALID_FILTER", "Invalid filter")
+	}
+
+	c.rlock()
+	defer c.runlock()
+
+	candidates, index := c.candidateItems(builder.conditionGroups())
+	sample := candidates
+	if len(sample) > filterPlanSampleSize {
+		sample = sample[:filterPlanSampleSize]
+	}
+
+	plan = &FilterPlan{
+		TotalItems:   int64(len(c.Items)),
+		ScannedItems: int64(len(candidates)),
+		IndexUsed:    index != "",
+		Index:        index,
+		Predicates:   []*FilterPredicatePlan{},
+	}
+
+	matched, err := c.filterItemsOf(correlationId, sample, filterFunc)
+	if err != nil {
+		return nil, err
+	}
+	if len(sample) > 0 {
+		plan.MatchedItems = int64(float64(len(matched)) / float64(len(sample)) * float64(len(candidates)))
+	}
+
+	for group, conditions := range builder.conditionGroups() {
+		predicates := make([]*FilterPredicatePlan, len(conditions))
+		for i, condition := range conditions {
+			selectivity, err := c.estimateSelectivity(correlationId, condition, sample)
+			if err != nil {
+				return nil, err
+			}
+			predicates[i] = &FilterPredicatePlan{
+				Group:       group,
+				Field:       condition.name,
+				Operator:    condition.operator,
+				Value:       condition.value,
+				Selectivity: selectivity,
+			}
+		}
+		sort.SliceStable(predicates, func(i, j int) bool {
+			return predicates[i].Selectivity < predicates[j].Selectivity
+		})
+		plan.Predicates = append(plan.Predicates, predicates...)
+	}
+
+	c.Logger.Trace(correlationId, "Explained filter with %d predicates", len(plan.Predicates))
+	return plan, nil
+}
+
+// estimateSelectivity calculates fraction of sample items that match the condition
+func (c *MemoryPersistence) estimateSelectivity(correlationId string, condition *filterCondition,
+	sample []interface{}) (float64, error) {
+	if len(sample) == 0 {
+		return 1, nil
+	}
+	matched, err := c.filterItemsOf(correlationId, sample, func(item interface{}) bool {
+		return condition.matches(getFieldValue(item, condition.index, condition.name))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return float64(len(matched)) / float64(len(sample)), nil
+}
+
+// Selects items that shall be scanned by the filter with the given conditions.
+// Returns items selected by an index and the name of the index, or all items and empty name.
+// Must be called under read lock.
+func (c *MemoryPersistence) candidateItems(conditions [][]*filterCondition) ([]interface{}, string) {
+	if items := c.selectByTimePartitions(conditions); items != nil {
+		return items, IndexTimePartitions
+	}
+	return c.Items, ""
+}
+
+// Selects items from time partitions when the filter is a single group of conditions
+// that limits the timestamp field from below. Items without timestamps can match only
+// upper limits, so filters without lower limit are not selected by partitions.
+// Returns items in their original order, or nil when partitions can't be used.
+func (c *MemoryPersistence) selectByTimePartitions(conditions [][]*filterCondition) []interface{} {
+	if c.partitionIndex == nil || c.TimestampField == "" || len(conditions) != 1 {
+		return nil
+	}
+
+	var from, to time.Time
+	for _, condition := range conditions[0] {
+		if !c.isTimestampField(condition.name) {
+			continue
+		}
+		value := convert.DateTimeConverter.ToNullableDateTime(condition.value)
+		if value == nil {
+			continue
+		}
+		if condition.operator == "eq" || condition.operator == "gt" || condition.operator == "gte" {
+			if from.IsZero() || value.After(from) {
+				from = *value
+			}
+		}
+		if condition.operator == "eq" || condition.operator == "lt" || condition.operator == "lte" {
+			if to.IsZero() || value.Before(to) {
+				to = *value
+			}
+		}
+	}
+	if from.IsZero() {
+		return nil
+	}
+
+	partitions, _ := c.partitionIndex.find(c, from, to)
+	positions := []int{}
+	for _, partition := range partitions {
+		positions = append(positions, partition.positions...)
+	}
+	sort.Ints(positions)
+	items := make([]interface{}, len(positions))
+	for i, position := range positions {
+		items[i] = c.Items[position]
+	}
+	return items
+}
+
+// Checks if the filtered field is the timestamp field by names or json names of the fields
+func (c *MemoryPersistence) isTimestampField(name string) bool {
+	if strings.EqualFold(name, c.TimestampField) {
+		return true
+	}
+	field, ok := findField(c.Prototype, name)
+	if !ok {
+		return false
+	}
+	timestampField, ok := findField(c.Prototype, c.TimestampField)
+	return ok && field.Name == timestampField.Name
+}
@@ -0,0 +1,252 @@
+package persistence
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Client of a remote file server used by RemoteFilePersister.
+*/
+type IRemoteFileClient interface {
+	// Opens a remote file for reading, returns an error matched by os.IsNotExist when it doesn't exist.
+	Retrieve(path string) (io.ReadCloser, error)
+
+	// Writes content of the reader into a remote file.
+	Store(path string, reader io.Reader) error
+
+	// Renames a remote file replacing the target file.
+	Rename(from string, to string) error
+
+	// Closes the connection.
+	Close() error
+}
+
+/*
+Component that connects to remote file servers of a protocol, for instance an adapter
+over github.com/pkg/sftp client, referenced by RemoteFilePersister as *:file-dialer:<protocol>:*:1.0.
+*/
+type IRemoteFileDialer interface {
+	// Connects to a server with host, port and credentials resolved from connection and credential parameters.
+	Dial(host string, port int, username string, password string, timeout time.Duration) (IRemoteFileClient, error)
+}
+
+/*
+Function that connects to a remote file server.
+Host, port and credentials are resolved from connection and credential parameters.
+*/
+type RemoteFileDialer func(host string, port int, username string, password string,
+	timeout time.Duration) (IRemoteFileClient, error)
+
+// Connects to a server by calling the function, so functions can be referenced as IRemoteFileDialer.
+func (d RemoteFileDialer) Dial(host string, port int, username string, password string,
+	timeout time.Duration) (IRemoteFileClient, error) {
+	return d(host, port, username, password, timeout)
+}
+
+/*
+Minimal FTP client over standard library with explicit TLS (FTPS):
+the control connection is upgraded by AUTH TLS, and data connections are
+protected by PROT P. Data connections are opened in passive mode.
+*/
+type ftpClient struct {
+	conn      *textproto.Conn
+	host      string
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+// Connects to FTP server, upgrades the connection to TLS when tlsConfig is set and logs in
+func dialFtp(host string, port int, username string, password string, timeout time.Duration,
+	tlsConfig *tls.Config) (*ftpClient, error) {
+	raw, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &ftpClient{conn: textproto.NewConn(raw), host: host, timeout: timeout}
+	if _, _, err = c.conn.ReadResponse(220); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		if _, err = c.command(234, "AUTH TLS"); err != nil {
+			c.conn.Close()
+			return nil, err
+		}
+		c.tlsConfig = tlsConfig.Clone()
+		if c.tlsConfig.ServerName == "" {
+			c.tlsConfig.ServerName = host
+		}
+		if c.tlsConfig.ClientSessionCache == nil {
+			// Servers often require data connections to resume the session of the control connection
+			c.tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+		}
+		secured := tls.Client(raw, c.tlsConfig)
+		if err = secured.Handshake(); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		c.conn = textproto.NewConn(secured)
+	}
+
+	if username == "" {
+		username = "anonymous"
+	}
+	code, err := c.command(0, "USER %s", username)
+	if err == nil && code == 331 {
+		_, err = c.command(230, "PASS %s", password)
+	} else if err == nil && code != 230 {
+		err = &textproto.Error{Code: code, Msg: "login failed"}
+	}
+	if err == nil && c.tlsConfig != nil {
+		if _, err = c.command(200, "PBSZ 0"); err == nil {
+			_, err = c.command(200, "PROT P")
+		}
+	}
+	if err == nil {
+		_, err = c.command(200, "TYPE I")
+	}
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Sends a command and reads the response, 0 code accepts any positive response
+func (c *ftpClient) command(expectCode int, format string, args ...interface{}) (int, error) {
+	if err := c.conn.PrintfLine(format, args...); err != nil {
+		return 0, err
+	}
+	code, message, err := c.conn.ReadResponse(expectCode)
+	if err == nil && expectCode == 0 && code >= 400 {
+		err = &textproto.Error{Code: code, Msg: message}
+	}
+	return code, err
+}
+
+// Opens a passive data connection, EPSV is tried first and PASV is used when it is not supported
+func (c *ftpClient) openData() (net.Conn, error) {
+	port := 0
+	if err := c.conn.PrintfLine("EPSV"); err != nil {
+		return nil, err
+	}
+	code, message, err := c.conn.ReadResponse(0)
+	if err != nil {
+		return nil, err
+	}
+	if code == 229 {
+		// 229 Entering Extended Passive Mode (|||port|)
+		start := strings.Index(message, "(|||")
+		end := strings.LastIndex(message, "|)")
+		if start >= 0 && end > start+4 {
+			port, _ = strconv.Atoi(message[start+4 : end])
+		}
+	}
+	if port == 0 {
+		if _, message, err = c.pasv(); err != nil {
+			return nil, err
+		}
+		// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+		start := strings.Index(message, "(")
+		end := strings.LastIndex(message, ")")
+		parts := []string{}
+		if start >= 0 && end > start {
+			parts = strings.Split(message[start+1:end], ",")
+		}
+		if len(parts) != 6 {
+			return nil, fmt.Errorf("invalid passive mode response: %s", message)
+		}
+		high, _ := strconv.Atoi(parts[4])
+		low, _ := strconv.Atoi(parts[5])
+		port = high<<8 | low
+	}
+
+	// The host of the control connection is used, as servers behind NAT report their internal addresses
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.host, strconv.Itoa(port)), c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.tlsConfig != nil {
+		return tls.Client(conn, c.tlsConfig), nil
+	}
+	return conn, nil
+}
+
+func (c *ftpClient) pasv() (int, string, error) {
+	if err := c.conn.PrintfLine("PASV"); err != nil {
+		return 0, "", err
+	}
+	return c.conn.ReadResponse(227)
+}
+
+/*
+Reader of a data connection that reads the transfer response when it is closed.
+*/
+type ftpDataReader struct {
+	net.Conn
+	client *ftpClient
+}
+
+func (c *ftpDataReader) Close() error {
+	err := c.Conn.Close()
+	if _, _, respErr := c.client.conn.ReadResponse(2); err == nil {
+		err = respErr
+	}
+	return err
+}
+
+func (c *ftpClient) Retrieve(path string) (io.ReadCloser, error) {
+	data, err := c.openData()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = c.command(1, "RETR %s", path); err != nil {
+		data.Close()
+		if protoErr, ok := err.(*textproto.Error); ok && protoErr.Code == 550 {
+			return nil, &os.PathError{Op: "retrieve", Path: path, Err: os.ErrNotExist}
+		}
+		return nil, err
+	}
+	return &ftpDataReader{Conn: data, client: c}, nil
+}
+
+func (c *ftpClient) Store(path string, reader io.Reader) error {
+	data, err := c.openData()
+	if err != nil {
+		return err
+	}
+	if _, err = c.command(1, "STOR %s", path); err != nil {
+		data.Close()
+		return err
+	}
+	_, err = io.Copy(data, reader)
+	if closeErr := data.Close(); err == nil {
+		err = closeErr
+	}
+	if _, _, respErr := c.conn.ReadResponse(2); err == nil {
+		err = respErr
+	}
+	return err
+}
+
+func (c *ftpClient) Rename(from string, to string) error {
+	if _, err := c.command(350, "RNFR %s", from); err != nil {
+		return err
+	}
+	_, err := c.command(250, "RNTO %s", to)
+	return err
+}
+
+func (c *ftpClient) Close() error {
+	c.command(0, "QUIT")
+	return c.conn.Close()
+}
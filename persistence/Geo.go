@@ -0,0 +1,451 @@
+package persistence
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// Mean radius of the Earth in meters
+const EarthRadius = 6371000.0
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+/*
+Geographic point defined by latitude and longitude in degrees.
+*/
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Creates a new geographic point.
+// Parameters:
+//   - latitude float64
+//   a latitude in degrees
+//   - longitude float64
+//   a longitude in degrees
+// Returns GeoPoint
+func NewGeoPoint(latitude float64, longitude float64) GeoPoint {
+	return GeoPoint{Latitude: latitude, Longitude: longitude}
+}
+
+/*
+Geographic bounding box defined by its south-west and north-east corners.
+When MinLongitude is greater than MaxLongitude the box crosses the antimeridian.
+*/
+type GeoBoundingBox struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// Creates a new bounding box from its south-west and north-east corners.
+// Parameters:
+//   - southWest GeoPoint
+//   a south-west corner of the box
+//   - northEast GeoPoint
+//   a north-east corner of the box
+// Returns GeoBoundingBox
+func NewGeoBoundingBox(southWest GeoPoint, northEast GeoPoint) GeoBoundingBox {
+	return GeoBoundingBox{
+		MinLatitude:  southWest.Latitude,
+		MinLongitude: southWest.Longitude,
+		MaxLatitude:  northEast.Latitude,
+		MaxLongitude: northEast.Longitude,
+	}
+}
+
+// Calculates a bounding box that contains a circle with the given center and radius.
+// Parameters:
+//   - center GeoPoint
+//   a center of the circle
+//   - radius float64
+//   a radius of the circle in meters
+// Returns GeoBoundingBox
+func NewGeoBoundingBoxAround(center GeoPoint, radius float64) GeoBoundingBox {
+	latDelta := radius / EarthRadius * 180 / math.Pi
+	box := GeoBoundingBox{
+		MinLatitude:  math.Max(center.Latitude-latDelta, -90),
+		MaxLatitude:  math.Min(center.Latitude+latDelta, 90),
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+	cos := math.Cos(center.Latitude * math.Pi / 180)
+	if box.MinLatitude > -90 && box.MaxLatitude < 90 && cos > 0 {
+		lonDelta := latDelta / cos
+		if lonDelta < 180 {
+			box.MinLongitude = normalizeLongitude(center.Longitude - lonDelta)
+			box.MaxLongitude = normalizeLongitude(center.Longitude + lonDelta)
+		}
+	}
+	return box
+}
+
+// Checks if the bounding box contains a point.
+// Parameters:
+//   - point GeoPoint
+//   a point to check
+// Returns true if the point is inside the box or on its border
+func (c GeoBoundingBox) Contains(point GeoPoint) bool {
+	if point.Latitude < c.MinLatitude || point.Latitude > c.MaxLatitude {
+		return false
+	}
+	if c.MinLongitude <= c.MaxLongitude {
+		return point.Longitude >= c.MinLongitude && point.Longitude <= c.MaxLongitude
+	}
+	return point.Longitude >= c.MinLongitude || point.Longitude <= c.MaxLongitude
+}
+
+// Calculates great-circle distance between two points using haversine formula.
+// Parameters:
+//   - point1 GeoPoint
+//   the first point
+//   - point2 GeoPoint
+//   the second point
+// Returns float64
+// distance in meters
+func GeoDistance(point1 GeoPoint, point2 GeoPoint) float64 {
+	lat1 := point1.Latitude * math.Pi / 180
+	lat2 := point2.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (point2.Longitude - point1.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Encodes a point into a geohash string.
+// Parameters:
+//   - point GeoPoint
+//   a point to encode
+//   - precision int
+//   a number of characters in the result
+// Returns string
+// geohash of the point
+func GeoHash(point GeoPoint, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	even := true
+	bit := 0
+	ch := 0
+	for len(hash) < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if point.Longitude >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch = ch << 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if point.Latitude >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch = ch << 1
+				maxLat = mid
+			}
+		}
+		even = !even
+		bit++
+		if bit == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bit = 0
+			ch = 0
+		}
+	}
+	return string(hash)
+}
+
+// Calculates size of a geohash cell in degrees for the given precision
+func geohashCellSize(precision int) (latSize float64, lonSize float64) {
+	bits := 5 * precision
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+func normalizeLongitude(longitude float64) float64 {
+	for longitude > 180 {
+		longitude -= 360
+	}
+	for longitude < -180 {
+		longitude += 360
+	}
+	return longitude
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+	val := reflect.ValueOf(value)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return 0, false
+		}
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint()), true
+	}
+	return 0, false
+}
+
+/*
+Spatial index that groups items by geohash cells of fixed precision.
+The index is rebuilt lazily on the first query after items change.
+*/
+type geoIndex struct {
+	lock      sync.Mutex
+	precision int
+	dirty     bool
+	cells     map[string][]interface{}
+}
+
+func (c *geoIndex) invalidate(oldItem interface{}, newItem interface{}) {
+	c.lock.Lock()
+	c.dirty = true
+	c.lock.Unlock()
+}
+
+// Groups all items into cells. Must be called under index lock.
+func (c *geoIndex) rebuild(persistence *MemoryPersistence) {
+	c.cells = map[string][]interface{}{}
+	for _, item := range persistence.Items {
+		if point, ok := persistence.GetItemLocation(item); ok {
+			hash := GeoHash(point, c.precision)
+			c.cells[hash] = append(c.cells[hash], item)
+		}
+	}
+	c.dirty = false
+}
+
+// Finds items in cells that overlap the bounding box.
+// Returns false if the box covers too many cells to use the index.
+func (c *geoIndex) find(persistence *MemoryPersistence, box GeoBoundingBox) ([]interface{}, bool) {
+	if c.precision <= 0 || box.MinLongitude > box.MaxLongitude {
+		return nil, false
+	}
+	latSize, lonSize := geohashCellSize(c.precision)
+	latCells := math.Floor(box.MaxLatitude/latSize) - math.Floor(box.MinLatitude/latSize) + 1
+	lonCells := math.Floor(box.MaxLongitude/lonSize) - math.Floor(box.MinLongitude/lonSize) + 1
+	if latCells*lonCells > float64(len(persistence.Items)) {
+		return nil, false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.dirty || c.cells == nil {
+		c.rebuild(persistence)
+	}
+
+	items := []interface{}{}
+	visited := map[string]bool{}
+	for lat := box.MinLatitude; lat < box.MaxLatitude+latSize; lat += latSize {
+		for lon := box.MinLongitude; lon < box.MaxLongitude+lonSize; lon += lonSize {
+			hash := GeoHash(GeoPoint{
+				Latitude:  math.Min(lat, box.MaxLatitude),
+				Longitude: math.Min(lon, box.MaxLongitude),
+			}, c.precision)
+			if !visited[hash] {
+				visited[hash] = true
+				items = append(items, c.cells[hash]...)
+			}
+		}
+	}
+	return items, true
+}
+
+// Enables or disables spatial index for items
+func (c *MemoryPersistence) setGeoIndexPrecision(precision int) {
+	if precision <= 0 {
+		if c.geoIndex != nil {
+			c.geoIndex.precision = 0
+		}
+		return
+	}
+	if c.geoIndex == nil {
+		c.geoIndex = &geoIndex{}
+		c.addChangeHandler(c.geoIndex.invalidate)
+	}
+	c.geoIndex.lock.Lock()
+	c.geoIndex.precision = precision
+	c.geoIndex.dirty = true
+	c.geoIndex.lock.Unlock()
+}
+
+// Gets location of the item from its latitude and longitude fields.
+// Parameters:
+//   - item interface{}
+//   an item to get location from
+// Returns GeoPoint, bool
+// the item location and true if both fields are present and numeric
+func (c *MemoryPersistence) GetItemLocation(item interface{}) (GeoPoint, bool) {
+	var index []int
+	if field, ok := findField(reflect.TypeOf(item), c.LatitudeField); ok {
+		index = field.Index
+	}
+	lat, ok := toFloat(getFieldValue(item, index, c.LatitudeField))
+	if !ok {
+		return GeoPoint{}, false
+	}
+	index = nil
+	if field, ok := findField(reflect.TypeOf(item), c.LongitudeField); ok {
+		index = field.Index
+	}
+	lon, ok := toFloat(getFieldValue(item, index, c.LongitudeField))
+	if !ok {
+		return GeoPoint{}, false
+	}
+	return GeoPoint{Latitude: lat, Longitude: lon}, true
+}
+
+// Selects items inside the box using spatial index when it is enabled
+func (c *MemoryPersistence) findInBoundingBox(box GeoBoundingBox) []interface{} {
+	candidates := c.Items
+	if c.geoIndex != nil {
+		if items, ok := c.geoIndex.find(c, box); ok {
+			candidates = items
+		}
+	}
+
+	items := []interface{}{}
+	for _, item := range candidates {
+		if point, ok := c.GetItemLocation(item); ok && box.Contains(point) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Extracts a page of result items and clones them
+func (c *MemoryPersistence) extractPage(items []interface{}, paging *cdata.PagingParams) *cdata.DataPage {
+	if paging == nil {
+		paging = cdata.NewEmptyPagingParams()
+	}
+	skip := paging.GetSkip(-1)
+	take := paging.GetTake((int64)(c.MaxPageSize))
+	var total int64
+	if paging.Total {
+		total = (int64)(len(items))
+	}
+	if skip > 0 {
+		if skip >= (int64)(len(items)) {
+			skip = (int64)(len(items))
+		}
+		items = items[skip:]
+	}
+	if (int64)(len(items)) >= take {
+		items = items[:take]
+	}
+
+	results := make([]interface{}, len(items))
+	for i, item := range items {
+		results[i] = c.cloneResult(item)
+	}
+	return cdata.NewDataPage(&total, results)
+}
+
+// Gets a page of items located inside a bounding box.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - box GeoBoundingBox
+//   a bounding box to search in
+//   - filterFunc func(interface{}) bool
+//   (optional) an additional filter function
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+// Returns *cdata.DataPage, error
+// data page or error.
+func (c *MemoryPersistence) GetPageByBoundingBox(correlationId string, box GeoBoundingBox,
+	filterFunc func(interface{}) bool, paging *cdata.PagingParams) (page *cdata.DataPage, err error) {
+	if err = c.checkPaging(correlationId, paging); err != nil {
+		return nil, err
+	}
+	c.rlock()
+	defer c.runlock()
+
+	items := c.findInBoundingBox(box)
+	if filterFunc != nil {
+		if items, err = c.filterItemsOf(correlationId, items, filterFunc); err != nil {
+			return nil, err
+		}
+	}
+
+	page = c.extractPage(items, paging)
+	c.Logger.Trace(correlationId, "Retrieved %d items in bounding box", len(page.Data))
+	return page, nil
+}
+
+// Gets a page of items located within a radius from a point, sorted by distance from the nearest.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - point GeoPoint
+//   a center point
+//   - radius float64
+//   a search radius in meters
+//   - filterFunc func(interface{}) bool
+//   (optional) an additional filter function
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+// Returns *cdata.DataPage, error
+// data page or error.
+func (c *MemoryPersistence) GetPageByNear(correlationId string, point GeoPoint, radius float64,
+	filterFunc func(interface{}) bool, paging *cdata.PagingParams) (page *cdata.DataPage, err error) {
+	if err = c.checkPaging(correlationId, paging); err != nil {
+		return nil, err
+	}
+	c.rlock()
+	defer c.runlock()
+
+	candidates := c.findInBoundingBox(NewGeoBoundingBoxAround(point, radius))
+	if filterFunc != nil {
+		if candidates, err = c.filterItemsOf(correlationId, candidates, filterFunc); err != nil {
+			return nil, err
+		}
+	}
+	items := make([]interface{}, 0, len(candidates))
+	distances := make(map[int]float64, len(candidates))
+	for _, item := range candidates {
+		location, _ := c.GetItemLocation(item)
+		distance := GeoDistance(point, location)
+		if distance <= radius {
+			distances[len(items)] = distance
+			items = append(items, item)
+		}
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distances[order[i]] < distances[order[j]]
+	})
+	sorted := make([]interface{}, len(items))
+	for i, position := range order {
+		sorted[i] = items[position]
+	}
+
+	page = c.extractPage(sorted, paging)
+	c.Logger.Trace(correlationId, "Retrieved %d items near %v", len(page.Data), point)
+	return page, nil
+}
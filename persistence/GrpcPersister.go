@@ -0,0 +1,212 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-data-go/protos"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+/*
+Persistence component that fetches and pushes a dataset through DatasetService gRPC service
+defined in protos/dataset.proto, so memory persistence can be seeded from data services
+and checkpointed to them. Items are encoded as protobuf DatasetItem messages
+with fields in google.protobuf.Struct by their serialized JSON names.
+
+The client is generated into protos package by protoc-gen-go and protoc-gen-go-grpc,
+and is created over a gRPC connection opened by the application.
+
+Example
+
+  conn, _ := grpc.Dial("data-service:8090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+  persister := NewGrpcPersister(protos.NewDatasetServiceClient(conn), "orders")
+  persistence := NewMemoryPersistence(reflect.TypeOf(Order{}))
+  persistence.Loader = persister
+  persistence.Saver = persister
+  persistence.Open("123")
+  ...
+  persistence.Close("123")
+  conn.Close()
+*/
+type GrpcPersister struct {
+	client protos.DatasetServiceClient
+	// Name of the dataset in the service
+	Dataset string
+	// Number of items sent in a push request (default: 1000)
+	BatchSize int
+	// Timeout of fetch and push calls, 0 to wait without timeout (default: 0)
+	Timeout time.Duration
+}
+
+// Creates a new instance of the persister.
+// Parameters:
+//   - client protos.DatasetServiceClient
+//   a client of DatasetService
+//   - dataset string
+//   a name of the dataset
+// Returns *GrpcPersister
+func NewGrpcPersister(client protos.DatasetServiceClient, dataset string) *GrpcPersister {
+	return &GrpcPersister{
+		client:    client,
+		Dataset:   dataset,
+		BatchSize: 1000,
+	}
+}
+
+// Gets a context with timeout of the call
+func (c *GrpcPersister) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout > 0 {
+		return context.WithTimeout(ctx, c.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Loads data items from the dataset.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items or error.
+func (c *GrpcPersister) Load(correlationId string) (data []interface{}, err error) {
+	return c.LoadWithContext(context.Background(), correlationId)
+}
+
+// Loads data items from the dataset. Items are returned as maps of fields
+// that memory persistence converts into its prototype.
+// Parameters:
+//   - ctx context.Context
+//   a context to cancel loading.
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items, ConnectionError when the call fails to start or InvocationError when it is broken.
+func (c *GrpcPersister) LoadWithContext(ctx context.Context, correlationId string) (data []interface{}, err error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	stream, err := c.client.Fetch(ctx, &protos.FetchRequest{CorrelationId: correlationId, Dataset: c.Dataset})
+	if err != nil {
+		return nil, errors.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to fetch dataset "+c.Dataset).
+			WithDetails("dataset", c.Dataset).WithCause(err)
+	}
+
+	data = []interface{}{}
+	for {
+		if err = checkCancelled(ctx, correlationId); err != nil {
+			return nil, err
+		}
+		item, err := stream.Recv()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, errors.NewInvocationError(correlationId, "FETCH_FAILED", "Failed to fetch dataset "+c.Dataset).
+				WithDetails("dataset", c.Dataset).WithCause(err)
+		}
+		data = append(data, item.GetFields().AsMap())
+	}
+}
+
+// Converts an item into a dataset item with fields by their serialized names
+func (c *GrpcPersister) toDatasetItem(correlationId string, item interface{}) (*protos.DatasetItem, error) {
+	buffer, err := json.Marshal(item)
+	if err != nil {
+		return nil, errors.NewInternalError(correlationId, "CAN'T_CONVERT", "Failed convert to JSON").WithCause(err)
+	}
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(buffer, &fields); err != nil {
+		return nil, errors.NewBadRequestError(correlationId, "INVALID_ITEM", "Pushed item must be an object").
+			WithDetails("dataset", c.Dataset).WithCause(err)
+	}
+	structFields, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, errors.NewBadRequestError(correlationId, "INVALID_ITEM", "Failed to encode pushed item").
+			WithDetails("dataset", c.Dataset).WithCause(err)
+	}
+	result := &protos.DatasetItem{Fields: structFields}
+	if id := GetObjectId(item); id != nil {
+		result.Id = fmt.Sprint(id)
+	}
+	return result, nil
+}
+
+// Saves given data items into the dataset.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface{}
+//   list of data items to save
+// Returns error
+func (c *GrpcPersister) Save(correlationId string, items []interface{}) error {
+	return c.SaveWithContext(context.Background(), correlationId, items)
+}
+
+// Replaces all items of the dataset with given data items.
+// Items are pushed in batches of BatchSize, and the first batch is always sent,
+// so pushing no items clears the dataset.
+// Parameters:
+//   - ctx context.Context
+//   a context to cancel saving.
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface{}
+//   list of data items to save
+// Returns error
+// ConnectionError when the call fails to start, InvocationError when it is broken
+// or the service stored a different number of items.
+func (c *GrpcPersister) SaveWithContext(ctx context.Context, correlationId string, items []interface{}) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	stream, err := c.client.Push(ctx)
+	if err != nil {
+		return errors.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to push dataset "+c.Dataset).
+			WithDetails("dataset", c.Dataset).WithCause(err)
+	}
+	pushFailed := func(cause error) error {
+		return errors.NewInvocationError(correlationId, "PUSH_FAILED", "Failed to push dataset "+c.Dataset).
+			WithDetails("dataset", c.Dataset).WithCause(cause)
+	}
+
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	for start := 0; start == 0 || start < len(items); start += batchSize {
+		if err = checkCancelled(ctx, correlationId); err != nil {
+			return err
+		}
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		request := &protos.PushRequest{CorrelationId: correlationId, Dataset: c.Dataset,
+			Items: make([]*protos.DatasetItem, 0, end-start)}
+		for _, item := range items[start:end] {
+			datasetItem, err := c.toDatasetItem(correlationId, item)
+			if err != nil {
+				return err
+			}
+			request.Items = append(request.Items, datasetItem)
+		}
+		if err = stream.Send(request); err != nil {
+			return pushFailed(err)
+		}
+	}
+
+	reply, err := stream.CloseAndRecv()
+	if err != nil {
+		return pushFailed(err)
+	}
+	if reply.Count != int64(len(items)) {
+		return errors.NewInvocationError(correlationId, "PUSH_INCOMPLETE",
+			fmt.Sprintf("Dataset %s stored %d of %d items", c.Dataset, reply.Count, len(items))).
+			WithDetails("dataset", c.Dataset).WithDetails("count", reply.Count)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package persistence
+
+import (
+	"strconv"
+	"time"
+)
+
+/*
+Bucket of a histogram. It counts items which values are
+within [From, To) range. Nil From or To means unbounded range.
+*/
+type HistogramBucket struct {
+	Key   string      `json:"key"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	Count int64       `json:"count"`
+}
+
+// Checks if the value falls into the bucket
+func (c *HistogramBucket) contains(value interface{}) bool {
+	if c.From != nil {
+		if result, ok := compareOrdered(value, c.From); !ok || result < 0 {
+			return false
+		}
+	}
+	if c.To != nil {
+		if result, ok := compareOrdered(value, c.To); !ok || result >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Creates buckets for numeric ranges of equal width.
+// Parameters:
+//   - start float64
+//   a start of the first bucket
+//   - width float64
+//   a width of each bucket
+//   - count int
+//   a number of buckets
+// Returns []*HistogramBucket
+func NewNumericBuckets(start float64, width float64, count int) []*HistogramBucket {
+	buckets := make([]*HistogramBucket, count)
+	for i := range buckets {
+		from := start + width*float64(i)
+		buckets[i] = &HistogramBucket{
+			Key:  strconv.FormatFloat(from, 'f', -1, 64),
+			From: from,
+			To:   from + width,
+		}
+	}
+	return buckets
+}
+
+// Creates buckets for time intervals of equal duration.
+// Parameters:
+//   - start time.Time
+//   a start of the first bucket
+//   - interval time.Duration
+//   a duration of each bucket
+//   - count int
+//   a number of buckets
+// Returns []*HistogramBucket
+func NewTimeBuckets(start time.Time, interval time.Duration, count int) []*HistogramBucket {
+	buckets := make([]*HistogramBucket, count)
+	for i := range buckets {
+		from := start.Add(interval * time.Duration(i))
+		buckets[i] = &HistogramBucket{
+			Key:  from.Format(time.RFC3339),
+			From: from,
+			To:   from.Add(interval),
+		}
+	}
+	return buckets
+}
+
+// Gets number of items retrieved by a filter in each bucket.
+// Values which don't fall into any bucket are not counted.
+// When buckets overlap a value is counted in the first matching bucket.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function to filter items
+//   - fieldSelector func(interface{}) interface{}
+//   a function that returns a value to put into buckets
+//   - buckets []*HistogramBucket
+//   buckets to count values in
+// Returns []*HistogramBucket, error
+// copies of the buckets with calculated counts or error.
+func (c *MemoryPersistence) GetHistogramByFilter(correlationId string, filterFunc func(interface{}) bool,
+	fieldSelector func(interface{}) interface{}, buckets []*HistogramBucket) (histogram []*HistogramBucket, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	histogram = make([]*HistogramBucket, len(buckets))
+	for i, bucket := range buckets {
+		histogram[i] = &HistogramBucket{Key: bucket.Key, From: bucket.From, To: bucket.To}
+	}
+
+	items := c.Items
+	if filterFunc != nil {
+		if items, err = c.filterItems(correlationId, filterFunc); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range items {
+		value := fieldSelector(item)
+		if value == nil {
+			continue
+		}
+		for _, bucket := range histogram {
+			if bucket.contains(value) {
+				bucket.Count++
+				break
+			}
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Calculated histogram of %d items in %d buckets", len(items), len(histogram))
+	return histogram, nil
+}
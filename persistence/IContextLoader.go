@@ -0,0 +1,21 @@
+package persistence
+
+import "context"
+
+/*
+  Interface for data processing components that load data items
+  and can be cancelled through a context.
+*/
+type IContextLoader interface {
+	ILoader
+
+	// Loads data items.
+	// Parameters:
+	//   - ctx context.Context
+	//   a context to cancel loading.
+	//   - correlation_id string
+	//   transaction id to trace execution through call chain.
+	// Retruns []interface{}, error
+	// a list of data items or error.
+	LoadWithContext(ctx context.Context, correlation_id string) (items []interface{}, err error)
+}
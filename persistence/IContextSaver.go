@@ -0,0 +1,22 @@
+package persistence
+
+import "context"
+
+/*
+  Interface for data processing components that save data items
+  and can be cancelled through a context.
+*/
+type IContextSaver interface {
+	ISaver
+
+	// Saves given data items.
+	// Parameters:
+	//  - ctx context.Context
+	//  a context to cancel saving.
+	//  - correlation_id string
+	//  transaction id to trace execution through call chain.
+	//  - items []interface{}
+	//  a list of items to save.
+	// Retuirns error or nil for success.
+	SaveWithContext(ctx context.Context, correlation_id string, items []interface{}) error
+}
@@ -0,0 +1,19 @@
+package persistence
+
+/*
+  Interface for data processing components to update data items by JSON Patch or merge patch.
+*/
+type IPatchUpdater interface {
+
+	// Updates a data item by JSON Patch (RFC 6902) operations or merge patch (RFC 7386).
+	// Parameters:
+	//   - correlation_id string
+	//   transaction id to trace execution through call chain.
+	//   - id interface{}
+	//   an id of data item to be updated.
+	//   - patch interface{}
+	//   an array of patch operations or an object with merge patch.
+	// Returns interface{}, error
+	// updated item or error.
+	UpdateByPatch(correlation_id string, id interface{}, patch interface{}) (item interface{}, err error)
+}
@@ -0,0 +1,21 @@
+package persistence
+
+import "io"
+
+/*
+  Interface for data processing components that load data items
+  from streams like sockets, pipes, HTTP bodies or decompressing readers.
+*/
+type IStreamLoader interface {
+	ILoader
+
+	// Loads data items from a reader.
+	// Parameters:
+	//   - correlation_id string
+	//   transaction id to trace execution through call chain.
+	//   - reader io.Reader
+	//   a reader of serialized data items
+	// Retruns []interface{}, error
+	// a list of data items or error.
+	LoadFrom(correlation_id string, reader io.Reader) (items []interface{}, err error)
+}
@@ -0,0 +1,22 @@
+package persistence
+
+import "io"
+
+/*
+  Interface for data processing components that save data items
+  to streams like sockets, pipes, HTTP responses or compressing writers.
+*/
+type IStreamSaver interface {
+	ISaver
+
+	// Saves given data items to a writer.
+	// Parameters:
+	//  - correlation_id string
+	//  transaction id to trace execution through call chain.
+	//  - writer io.Writer
+	//  a writer of serialized data items.
+	//  - items []interface{}
+	//  a list of items to save.
+	// Retuirns error or nil for success.
+	SaveTo(correlation_id string, writer io.Writer, items []interface{}) error
+}
@@ -0,0 +1,22 @@
+package persistence
+
+import (
+	"time"
+)
+
+/*
+  Interface for data processing components that keep tombstones of deleted data items,
+  so incremental sync clients can learn about deletions.
+*/
+type ITraceable interface {
+
+	// Gets tombstones of data items deleted since a given time.
+	// Parameters:
+	//   - correlation_id string
+	//   (optional) transaction id to trace execution through call chain.
+	//   - since time.Time
+	//   a time to get deletions after.
+	// Returns []*Tombstone, error
+	// tombstones ordered by deletion time or error.
+	GetDeletedSince(correlation_id string, since time.Time) (tombstones []*Tombstone, err error)
+}
@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Default name of the id field
+const defaultIdField = "Id"
+
+// Names of id fields of struct types, found by `pip:"id"` tags or set by RegisterIdField
+var idFields sync.Map
+
+// Accessor of item ids. The zero value uses id fields of item types,
+// an accessor of a persistence uses the id field set by its configuration for items of its type.
+type idAccessor struct {
+	typ   reflect.Type
+	field string
+	// Key of the id in items loaded as maps
+	key string
+}
+
+// Creates an accessor that uses the field of the struct type, or the id fields of item types when the field is empty
+func newIdAccessor(prototype reflect.Type, field string) (idAccessor, error) {
+	if field == "" {
+		return idAccessor{}, nil
+	}
+	typ, name, err := resolveIdField(prototype, field)
+	if err != nil {
+		return idAccessor{}, err
+	}
+	structField, _ := typ.FieldByName(name)
+	key := strings.Split(structField.Tag.Get("json"), ",")[0]
+	if key == "" || key == "-" {
+		key = name
+	}
+	return idAccessor{typ: typ, field: name, key: key}, nil
+}
+
+// Gets the name of the field that keeps id of the item
+func (c idAccessor) fieldName(item interface{}) string {
+	if c.field != "" {
+		if itemStructType(reflect.TypeOf(item)) == c.typ {
+			return c.field
+		}
+		if reflect.ValueOf(item).Kind() == reflect.Map {
+			return c.key
+		}
+	}
+	return idFieldName(item)
+}
+
+// Gets id of the item
+func (c idAccessor) get(item interface{}) interface{} {
+	return GetProperty(item, c.fieldName(item))
+}
+
+// Sets id of the item
+func (c idAccessor) set(item *interface{}, id interface{}) {
+	SetObjectProperty(item, c.fieldName(*item), id)
+}
+
+// Generates a new id of the item when it's empty, panics when the item doesn't have id field
+func (c idAccessor) generate(item *interface{}) {
+	id := c.get(*item)
+	if id == nil {
+		panic("Id field doesn't exist")
+	}
+	if reflect.ValueOf(id).IsZero() {
+		c.set(item, cdata.IdGenerator.NextLong())
+	}
+}
+
+// Gets the struct type of the item, or nil for other kinds of items
+func itemStructType(typ reflect.Type) reflect.Type {
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+	return typ
+}
+
+// Gets the name of the field that keeps id of the item: a field tagged with `pip:"id"`,
+// a field registered by RegisterIdField or Id field by default
+func idFieldName(item interface{}) string {
+	typ := itemStructType(reflect.TypeOf(item))
+	if typ == nil {
+		return defaultIdField
+	}
+	if name, ok := idFields.Load(typ); ok {
+		return name.(string)
+	}
+
+	name := defaultIdField
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		for _, option := range strings.Split(field.Tag.Get("pip"), ",") {
+			if option == "id" && field.PkgPath == "" {
+				name = field.Name
+			}
+		}
+	}
+	actual, _ := idFields.LoadOrStore(typ, name)
+	return actual.(string)
+}
+
+// Sets the field that keeps ids of items of the struct type for all persistence components,
+// so third-party structs without Id field can be persisted without wrapper types.
+// It overrides `pip:"id"` tags. To set the field for one persistence use options.id_field configuration parameter.
+// Parameters:
+//   - prototype reflect.Type
+//   a struct type or a pointer to it
+//   - field string
+//   a name of a public field or its json name
+// Returns error
+// ConfigError when the type is not a struct or doesn't have the field.
+func RegisterIdField(prototype reflect.Type, field string) error {
+	typ, name, err := resolveIdField(prototype, field)
+	if err != nil {
+		return err
+	}
+	idFields.Store(typ, name)
+	return nil
+}
+
+// Finds the struct type and the name of its field by the name or json name of the field
+func resolveIdField(prototype reflect.Type, field string) (reflect.Type, string, error) {
+	typ := itemStructType(prototype)
+	if typ == nil {
+		return nil, "", errors.NewConfigError("", "INVALID_ID_FIELD", "Id field can be set only for struct types").
+			WithDetails("type", fmt.Sprint(prototype))
+	}
+	structField, ok := findField(typ, field)
+	if !ok {
+		return nil, "", errors.NewConfigError("", "INVALID_ID_FIELD", "Type "+typ.String()+" doesn't have field "+field).
+			WithDetails("type", typ.String()).WithDetails("field", field)
+	}
+	return typ, structField.Name, nil
+}
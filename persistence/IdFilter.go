@@ -0,0 +1,153 @@
+package persistence
+
+import (
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+)
+
+// Default number of ids the id filter is sized for before it grows
+const defaultIdFilterCapacity = 10000
+
+// False positive rate of the id filter at its capacity
+const idFilterFalsePositiveRate = 0.01
+
+/*
+Bloom filter of item ids. It answers whether an id may exist or definitely doesn't exist.
+Bits are only set, never cleared, so deleted ids remain possible until the filter is rebuilt.
+The filter bits are read and updated with atomic operations, while the filter itself
+is replaced under the persistence lock, so lookups are made under the lock as well.
+*/
+type idBloomFilter struct {
+	bits     []uint64
+	hashes   int
+	capacity int
+	count    int64
+}
+
+// Creates a filter sized for the given number of ids
+func newIdBloomFilter(capacity int) *idBloomFilter {
+	if capacity < defaultIdFilterCapacity {
+		capacity = defaultIdFilterCapacity
+	}
+	size := int(math.Ceil(-float64(capacity) * math.Log(idFilterFalsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Round(float64(size) / float64(capacity) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &idBloomFilter{
+		bits:     make([]uint64, (size+63)/64),
+		hashes:   hashes,
+		capacity: capacity,
+	}
+}
+
+// Calculates two hashes of the id used to derive positions of its bits
+func idFilterHashes(id string) (uint64, uint64) {
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	h1 := hash.Sum64()
+	h2 := (h1 >> 33) | (h1 << 31)
+	return h1, h2 | 1
+}
+
+func (c *idBloomFilter) add(id string) {
+	h1, h2 := idFilterHashes(id)
+	size := uint64(len(c.bits)) * 64
+	for i := 0; i < c.hashes; i++ {
+		position := (h1 + uint64(i)*h2) % size
+		word := &c.bits[position/64]
+		mask := uint64(1) << (position % 64)
+		for {
+			value := atomic.LoadUint64(word)
+			if value&mask != 0 || atomic.CompareAndSwapUint64(word, value, value|mask) {
+				break
+			}
+		}
+	}
+	atomic.AddInt64(&c.count, 1)
+}
+
+func (c *idBloomFilter) mightContain(id string) bool {
+	h1, h2 := idFilterHashes(id)
+	size := uint64(len(c.bits)) * 64
+	for i := 0; i < c.hashes; i++ {
+		position := (h1 + uint64(i)*h2) % size
+		if atomic.LoadUint64(&c.bits[position/64])&(uint64(1)<<(position%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Gets a key of the id in the filter
+func idFilterKey(id interface{}) string {
+	return convert.StringConverter.ToString(id)
+}
+
+// Creates a filter with ids of all items. Must be called under lock.
+func (c *MemoryPersistence) newIdFilter(extra int) *idBloomFilter {
+	filter := newIdBloomFilter(2 * (len(c.Items) + extra))
+	for _, item := range c.Items {
+		if id := c.ids.get(item); id != nil {
+			filter.add(idFilterKey(id))
+		}
+	}
+	return filter
+}
+
+// Gets the id filter or nil when it is disabled
+func (c *MemoryPersistence) getIdFilter() *idBloomFilter {
+	filter, _ := c.idFilter.Load().(*idBloomFilter)
+	return filter
+}
+
+// Enables or disables the id filter. Must be called under lock.
+func (c *MemoryPersistence) setIdFilter(enabled bool) {
+	if !enabled {
+		c.idFilter.Store((*idBloomFilter)(nil))
+		return
+	}
+	if !c.idFilterHandler {
+		c.addChangeHandler(c.updateIdFilter)
+		c.idFilterHandler = true
+	}
+	if c.getIdFilter() == nil {
+		c.idFilter.Store(c.newIdFilter(0))
+	}
+}
+
+// Adds ids of created and changed items to the filter. Reloaded items and filters
+// filled over their capacity are rebuilt, which also drops ids of deleted items.
+// Called under write lock.
+func (c *MemoryPersistence) updateIdFilter(oldItem interface{}, newItem interface{}) {
+	filter := c.getIdFilter()
+	if filter == nil {
+		return
+	}
+	if oldItem == nil && newItem == nil {
+		c.idFilter.Store(c.newIdFilter(0))
+		return
+	}
+	if newItem == nil {
+		return
+	}
+	id := c.ids.get(newItem)
+	if id == nil || (oldItem != nil && CompareValues(c.ids.get(oldItem), id)) {
+		return
+	}
+	if atomic.LoadInt64(&filter.count) >= int64(filter.capacity) {
+		filter = c.newIdFilter(1)
+		c.idFilter.Store(filter)
+	}
+	filter.add(idFilterKey(id))
+}
+
+// Checks if an item with the id definitely doesn't exist according to the id filter.
+// Must be called under lock, returns false when the filter is disabled.
+func (c *MemoryPersistence) isAbsentId(id interface{}) bool {
+	filter := c.getIdFilter()
+	return filter != nil && id != nil && !filter.mightContain(idFilterKey(id))
+}
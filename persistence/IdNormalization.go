@@ -0,0 +1,90 @@
+package persistence
+
+import (
+	"strings"
+)
+
+// Normalizations of string ids
+const (
+	// Removes leading and trailing spaces
+	IdNormalizeTrim = "trim"
+	// Converts ids to lower case
+	IdNormalizeLowercase = "lowercase"
+	// Converts UUIDs to canonical lower case form with hyphens
+	IdNormalizeUuid = "uuid"
+)
+
+// Converts UUID in any common form to canonical form, other values are returned as is
+func toCanonicalUuid(value string) string {
+	hex := strings.TrimPrefix(strings.ToLower(value), "urn:uuid:")
+	hex = strings.Trim(hex, "{}")
+	hex = strings.Replace(hex, "-", "", -1)
+	if len(hex) != 32 {
+		return value
+	}
+	for _, ch := range hex {
+		if (ch < '0' || ch > '9') && (ch < 'a' || ch > 'f') {
+			return value
+		}
+	}
+	return hex[0:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:32]
+}
+
+// Normalizes an id according to configured normalizations.
+// Only string ids are normalized, other ids are returned as is.
+// Parameters:
+//   - id interface{}
+//   an id to normalize
+// Returns interface{}
+// normalized id
+func (c *IdentifiableMemoryPersistence) NormalizeId(id interface{}) interface{} {
+	value, ok := id.(string)
+	if !ok || len(c.IdNormalization) == 0 {
+		return id
+	}
+	for _, normalization := range c.IdNormalization {
+		switch normalization {
+		case IdNormalizeTrim:
+			value = strings.TrimSpace(value)
+		case IdNormalizeLowercase:
+			value = strings.ToLower(value)
+		case IdNormalizeUuid:
+			value = toCanonicalUuid(value)
+		}
+	}
+	return value
+}
+
+// Normalizes ids in a list
+func (c *IdentifiableMemoryPersistence) normalizeIds(ids []interface{}) []interface{} {
+	if len(c.IdNormalization) == 0 {
+		return ids
+	}
+	result := make([]interface{}, len(ids))
+	for i, id := range ids {
+		result[i] = c.NormalizeId(id)
+	}
+	return result
+}
+
+// Normalizes id of the item
+func (c *IdentifiableMemoryPersistence) normalizeItemId(item *interface{}) {
+	if len(c.IdNormalization) == 0 {
+		return
+	}
+	id := c.ids.get(*item)
+	if normalized := c.NormalizeId(id); normalized != id {
+		c.ids.set(item, normalized)
+	}
+}
+
+// Parses list of normalizations from configuration
+func parseIdNormalization(value string) []string {
+	result := []string{}
+	for _, normalization := range strings.Split(value, ",") {
+		if normalization = strings.ToLower(strings.TrimSpace(normalization)); normalization != "" {
+			result = append(result, normalization)
+		}
+	}
+	return result
+}
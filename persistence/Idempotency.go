@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Record of a create request processed with an idempotency key.
+*/
+type idempotencyRecord struct {
+	item    interface{}
+	expires time.Time
+}
+
+/*
+Collection of processed idempotency keys that expire after TTL.
+*/
+type idempotencyStore struct {
+	lock    sync.Mutex
+	records map[string]*idempotencyRecord
+	swept   time.Time
+}
+
+// Removes expired records, must be called under lock
+func (c *idempotencyStore) sweep(now time.Time) {
+	for key, record := range c.records {
+		if !record.expires.After(now) {
+			delete(c.records, key)
+		}
+	}
+	c.swept = now
+}
+
+// Creates a data item once per idempotency key. When a request with the same key
+// is sent again within TTL the originally created item is returned instead of creating a duplicate.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - idempotencyKey string
+//   a unique key of the request generated by the client, empty key disables deduplication
+//   - item interface{}
+//   an item to be created.
+// Returns interface{}, error
+// created or originally created item or error.
+func (c *IdentifiableMemoryPersistence) CreateIdempotent(correlationId string, idempotencyKey string,
+	item interface{}) (result interface{}, err error) {
+	if idempotencyKey == "" {
+		return c.Create(correlationId, item)
+	}
+
+	store := c.idempotency
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := c.Clock.Now()
+	if now.Sub(store.swept) > time.Duration(c.IdempotencyTtl)*time.Millisecond {
+		store.sweep(now)
+	}
+
+	if record, ok := store.records[idempotencyKey]; ok && record.expires.After(now) {
+		c.Logger.Trace(correlationId, "Returned item created with idempotency key %s", idempotencyKey)
+		return c.cloneResult(record.item), nil
+	}
+
+	result, err = c.Create(correlationId, item)
+	if err != nil {
+		return nil, err
+	}
+	store.records[idempotencyKey] = &idempotencyRecord{
+		item:    c.cloneItem(result),
+		expires: now.Add(time.Duration(c.IdempotencyTtl) * time.Millisecond),
+	}
+	return result, nil
+}
@@ -24,13 +24,18 @@ See MemoryPersistence
 
 Configuration parameters
 
-  - path:                    path to the file where data is stored
-  - options:
-      - max_page_size:       Maximum number of items returned in a single page (default: 100)
+  - path:                    path to the file where data is stored, "-" to read items from stdin and write them to stdout
+
+Options of the data file are the same as in FilePersistence, see FilePersistence.
+Other configuration parameters of IdentifiableMemoryPersistence are supported, see IdentifiableMemoryPersistence.
 
  References
 
 - *:logger:*:*:1.0      (optional)  ILogger components to pass log messages
+- *:lock:*:*:1.0        (optional) ILock component for WithLock and to coordinate the writer in single writer mode
+- *:persister:*:*:1.0   (optional) ILoader and ISaver component configured in dependencies.persister instead of the default JsonFilePersister
+- *:loader:*:*:1.0      (optional) ILoader component configured in dependencies.loader
+- *:saver:*:*:1.0       (optional) ISaver component configured in dependencies.saver
 
 Examples
   type MyFilePersistence  struct {
@@ -43,9 +48,9 @@ Examples
   		return mfp
       }
   
-      func composeFilter(filter cdata.FilterParams)(func (item interface{})bool) {
-  		if &filter == nil {
-  			filter = NewFilterParams()
+      func composeFilter(filter *cdata.FilterParams)(func (item interface{})bool) {
+  		if filter == nil {
+  			filter = cdata.NewEmptyFilterParams()
   		}
           name := filter.GetAsNullableString("name");
           return func (item interface) bool {
@@ -89,7 +94,7 @@ type IdentifiableFilePersistence struct {
 // Creates a new instance of the persistence.
 // Parameters:
 //   - prototype reflect.Type
-//   type of contained data
+//   type of contained data, panics when it is nil
 //   - persister    (optional) a persister component that loads and saves data from/to flat file.
 // Return *IdentifiableFilePersistence
 // pointer on new IdentifiableFilePersistence
@@ -105,10 +110,32 @@ func NewIdentifiableFilePersistence(prototype reflect.Type, persister *JsonFileP
 	return c
 }
 
+// Creates a new instance of the persistence with JsonFilePersister for the data file.
+// Parameters:
+//   - prototype reflect.Type
+//   type of contained data
+//   - path string
+//   a path to the data file
+// Return *IdentifiableFilePersistence, error
+// created IdentifiableFilePersistence or ConfigError when the prototype or the path is not set.
+func NewIdentifiableFilePersistenceFromPath(prototype reflect.Type, path string) (*IdentifiableFilePersistence, error) {
+	if err := validatePrototypeAndPath(prototype, path); err != nil {
+		return nil, err
+	}
+	return NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)), nil
+}
+
 // Configures component by passing configuration parameters.
 // Parameters:
 //   - config    configuration parameters to be set.
 func (c *IdentifiableFilePersistence) Configure(config *config.ConfigParams) {
-	c.Configure(config)
-	c.Persister.Configure(config)
+	c.IdentifiableMemoryPersistence.Configure(config)
+	c.reconfigurePersister(c.Persister, config)
+
+	// Keep item versions next to the data file
+	if c.versions != nil && c.Loader == c.Persister {
+		persister := &versionsFilePersister{persister: c.Persister, persistence: &c.IdentifiableMemoryPersistence}
+		c.Loader = persister
+		c.Saver = persister
+	}
 }
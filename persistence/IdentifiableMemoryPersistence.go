@@ -2,9 +2,12 @@ package persistence
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
 	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
 	refl "github.com/pip-services3-go/pip-services3-commons-go/reflect"
 	"github.com/pip-services3-go/pip-services3-components-go/log"
 )
@@ -12,7 +15,8 @@ import (
 /*
 Abstract persistence component that stores data in memory
 and implements a number of CRUD operations over data items with unique ids.
-The data items must have Id field.
+The data items must have Id field, a field tagged with `pip:"id"`
+or a field set by options.id_field.
 
 In basic scenarios child structs shall only override GetPageByFilter,
 GetListByFilter or DeleteByFilter operations with specific filter function.
@@ -26,21 +30,37 @@ See MemoryPersistence
 
 Configuration parameters
 
+All configuration parameters of MemoryPersistence are supported, see MemoryPersistence.
+Additional options:
+
 - options:
-    - max_page_size:       Maximum number of items returned in a single page (default: 100)
+    - not_found_error:     Return NotFoundError instead of nil result when item is not found (default: false)
+    - duplicate_policy:    Action on create of item with existing id: reject, overwrite or generate (default: reject)
+    - replica_id:          Id of the replica to track item versions for MergeFrom (default: none)
+    - merge_strategy:      Resolution of concurrent changes on merge: lww or field (default: lww)
+    - omit_empty_updates:  Skip zero values in UpdatePartially instead of clearing the fields (default: false)
+    - idempotency_ttl:     Time to keep idempotency keys of created items in milliseconds (default: 86400000)
+    - id_normalization:    Comma-separated normalizations of string ids on write and lookup: trim, lowercase, uuid (default: none)
+    - id_field:            Name or json name of the struct field with item ids for structs without Id field (default: Id or the field tagged with pip:"id")
+    - id_filter:           Keep a bloom filter of ids so lookups of missing ids return without scanning items (default: false)
+    - tombstone_window:    Time to keep tombstones of deleted items for GetDeletedSince in milliseconds, 0 to disable (default: 0)
 
  References
 
 - *:logger:*:*:1.0     (optional) ILogger components to pass log messages
+- *:lock:*:*:1.0       (optional) ILock component for WithLock and to coordinate the writer in single writer mode
+- *:persister:*:*:1.0  (optional) ILoader and ISaver component configured in dependencies.persister
+- *:loader:*:*:1.0     (optional) ILoader component configured in dependencies.loader
+- *:saver:*:*:1.0      (optional) ISaver component configured in dependencies.saver
 
  Examples
 
   type MyMemoryPersistence struct{
   	IdentifiableMemoryPersistence
   }
-      func composeFilter(filter: FilterParams) (func (item interface{}) bool ) {
-          if &filter == nil {
-  			filter = NewFilterParams()
+      func composeFilter(filter *cdata.FilterParams) (func (item interface{}) bool ) {
+          if filter == nil {
+  			filter = cdata.NewEmptyFilterParams()
   		}
           name := filter.getAsNullableString("Name");
           return func(item interface{}) bool {
@@ -74,11 +94,41 @@ Configuration parameters
   	...
 
 */
-// extends MemoryPersistence  implements IConfigurable, IWriter, IGetter, ISetter
+// extends MemoryPersistence  implements IConfigurable, IWriter, IGetter, ISetter, IPartialUpdater, IPatchUpdater, ITraceable
 type IdentifiableMemoryPersistence struct {
 	MemoryPersistence
+	ErrorOnNotFound bool
+	DuplicatePolicy string
+	mergeHandlers   []func(correlationId string, targetId interface{}, mergedIds []interface{}) error
+	// Skip zero values in UpdatePartially instead of clearing the fields
+	OmitEmptyUpdates bool
+	// Id of the replica used to track versions of items for offline sync
+	ReplicaId string
+	// Strategy to resolve concurrent changes on merge: lww or field
+	MergeStrategy string
+	versions      map[string]*ItemVersion
+	merging       bool
+	lastTimestamp int64
+	// Time to keep idempotency keys of created items in milliseconds
+	IdempotencyTtl int64
+	idempotency    *idempotencyStore
+	// Normalizations applied to string ids on write and lookup: trim, lowercase, uuid
+	IdNormalization []string
+	// Time to keep tombstones of deleted items in milliseconds, 0 to disable
+	TombstoneWindow int64
+	tombstones      *tombstoneStore
 }
 
+// Policies that define how Create handles items with already existing ids
+const (
+	// Create fails with ConflictError
+	DuplicatePolicyReject = "reject"
+	// Existing item is replaced with the new one
+	DuplicatePolicyOverwrite = "overwrite"
+	// The new item gets a newly generated id
+	DuplicatePolicyGenerate = "generate"
+)
+
 // Creates a new empty instance of the persistence.
 // Parameters:
 //  - prototype reflect.Type
@@ -90,6 +140,10 @@ func NewIdentifiableMemoryPersistence(prototype reflect.Type) (c *IdentifiableMe
 	c.MemoryPersistence = *NewMemoryPersistence(prototype)
 	c.Logger = log.NewCompositeLogger()
 	c.MaxPageSize = 100
+	c.DuplicatePolicy = DuplicatePolicyReject
+	c.MergeStrategy = MergeStrategyLastWriterWins
+	c.IdempotencyTtl = 24 * 60 * 60 * 1000
+	c.idempotency = &idempotencyStore{records: map[string]*idempotencyRecord{}}
 	return c
 }
 
@@ -98,7 +152,43 @@ func NewIdentifiableMemoryPersistence(prototype reflect.Type) (c *IdentifiableMe
 //  - config  *config.ConfigParams
 //  configuration parameters to be set.
 func (c *IdentifiableMemoryPersistence) Configure(config *config.ConfigParams) {
-	c.MaxPageSize = config.GetAsIntegerWithDefault("options.max_page_size", c.MaxPageSize)
+	c.MemoryPersistence.Configure(config)
+
+	// The id field is set only for this instance, RegisterIdField sets it for all instances
+	if idField := config.GetAsString("options.id_field"); idField != "" {
+		ids, err := newIdAccessor(c.Prototype, idField)
+		if err != nil {
+			c.Logger.Error("", err, "Failed to set id field")
+		} else {
+			c.lock()
+			c.ids = ids
+			c.unlock()
+		}
+	}
+
+	c.lock()
+	c.ErrorOnNotFound = config.GetAsBooleanWithDefault("options.not_found_error", c.ErrorOnNotFound)
+	c.DuplicatePolicy = config.GetAsStringWithDefault("options.duplicate_policy", c.DuplicatePolicy)
+	c.MergeStrategy = config.GetAsStringWithDefault("options.merge_strategy", c.MergeStrategy)
+	c.OmitEmptyUpdates = config.GetAsBooleanWithDefault("options.omit_empty_updates", c.OmitEmptyUpdates)
+	c.IdempotencyTtl = config.GetAsLongWithDefault("options.idempotency_ttl", c.IdempotencyTtl)
+	if normalization := config.GetAsNullableString("options.id_normalization"); normalization != nil && *normalization != "" {
+		c.IdNormalization = parseIdNormalization(*normalization)
+	}
+	c.setReplicaId(config.GetAsStringWithDefault("options.replica_id", c.ReplicaId))
+	c.setTombstoneWindow(config.GetAsLongWithDefault("options.tombstone_window", c.TombstoneWindow))
+	c.setIdFilter(config.GetAsBooleanWithDefault("options.id_filter", c.getIdFilter() != nil))
+	c.unlock()
+}
+
+// Returns error for missing item according to the configured not found policy.
+// Returns NotFoundError if ErrorOnNotFound is set and nil otherwise
+func (c *IdentifiableMemoryPersistence) notFound(correlationId string, id interface{}) error {
+	if !c.ErrorOnNotFound {
+		return nil
+	}
+	return errors.NewNotFoundError(correlationId, "NOT_FOUND",
+		"Item "+convert.StringConverter.ToString(id)+" was not found").WithDetails("id", id)
 }
 
 // Gets a list of data items retrieved by given unique ids.
@@ -110,11 +200,15 @@ func (c *IdentifiableMemoryPersistence) Configure(config *config.ConfigParams) {
 // Returns  []interface{}, error
 // data list or error.
 func (c *IdentifiableMemoryPersistence) GetListByIds(correlationId string, ids []interface{}) (result []interface{}, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "get_list_by_ids", start, ids, err) }(time.Now())
+	}
+	ids = c.normalizeIds(ids)
 	filter := func(item interface{}) bool {
 		exist := false
-		id := GetObjectId(item)
+		id := c.ids.get(item)
 		for _, v := range ids {
-			vId := refl.ObjectReader.GetValue(v)
+			vId := c.NormalizeId(refl.ObjectReader.GetValue(v))
 			if CompareValues(id, vId) {
 				exist = true
 				break
@@ -134,12 +228,22 @@ func (c *IdentifiableMemoryPersistence) GetListByIds(correlationId string, ids [
 // Returns:  interface{}, error
 // data item or error.
 func (c *IdentifiableMemoryPersistence) GetOneById(correlationId string, id interface{}) (result interface{}, err error) {
-	c.Lock.RLock()
-	defer c.Lock.RUnlock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "get_one_by_id", start, id, err) }(time.Now())
+	}
+	id = c.NormalizeId(id)
+
+	c.rlock()
+	defer c.runlock()
+
+	if c.isAbsentId(id) {
+		c.Logger.Trace(correlationId, "Cannot find item by %s", id)
+		return nil, c.notFound(correlationId, id)
+	}
 
 	var items []interface{}
 	for _, v := range c.Items {
-		vId := GetObjectId(v)
+		vId := c.ids.get(v)
 		if CompareValues(vId, id) {
 			items = append(items, v)
 		}
@@ -148,22 +252,42 @@ func (c *IdentifiableMemoryPersistence) GetOneById(correlationId string, id inte
 	var item interface{} = nil
 	if len(items) > 0 {
 		//item = CloneObject(items[0])
-		item = CloneObjectForResult(items[0], c.Prototype)
+		item = c.cloneResult(items[0])
 	}
 	if item != nil {
 		c.Logger.Trace(correlationId, "Retrieved item %s", id)
 	} else {
 		c.Logger.Trace(correlationId, "Cannot find item by %s", id)
+		err = c.notFound(correlationId, id)
 	}
 	return item, err
 }
 
+// Checks if a data item with the given id exists.
+// When options.id_filter is enabled, missing ids are usually detected without scanning items.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the data item
+// Returns bool, error
+// true if the item exists or error.
+func (c *IdentifiableMemoryPersistence) Exists(correlationId string, id interface{}) (bool, error) {
+	c.rlock()
+	defer c.runlock()
+	return c.GetIndexById(id) >= 0, nil
+}
+
 // Get index by "Id" field
 // return index number
 func (c *IdentifiableMemoryPersistence) GetIndexById(id interface{}) int {
+	id = c.NormalizeId(id)
+	if c.isAbsentId(id) {
+		return -1
+	}
 	var index int = -1
 	for i, v := range c.Items {
-		vId := GetObjectId(v)
+		vId := c.ids.get(v)
 		if CompareValues(vId, id) {
 			index = i
 			break
@@ -181,18 +305,65 @@ func (c *IdentifiableMemoryPersistence) GetIndexById(id interface{}) int {
 // Returns:  interface{}, error
 // created item or error.
 func (c *IdentifiableMemoryPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) {
+			c.logOperation(correlationId, "create", start, c.operationItemId(result, item), err)
+		}(time.Now())
+	}
+	c.lock()
 
-	newItem := CloneObject(item, c.Prototype)
-	GenerateObjectId(&newItem)
-	id := GetObjectId(newItem)
-	c.Items = append(c.Items, newItem)
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	newItem := c.cloneItem(item)
+	if newItem == nil {
+		c.unlock()
+		return nil, newInvalidItemError(correlationId)
+	}
+	c.ids.generate(&newItem)
+	c.normalizeItemId(&newItem)
+	c.applyComputedFields(&newItem)
+	id := c.ids.get(newItem)
+
+	index := c.GetIndexById(id)
+	if index >= 0 && c.DuplicatePolicy == DuplicatePolicyGenerate {
+		c.ids.set(&newItem, cdata.IdGenerator.NextLong())
+		id = c.ids.get(newItem)
+		index = c.GetIndexById(id)
+	}
+
+	var oldItem interface{}
+	if index >= 0 {
+		oldItem = c.Items[index]
+	}
+	if err = c.checkQuota(correlationId, oldItem, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+	if err = c.validateChange(correlationId, oldItem, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	if index < 0 {
+		c.Items = append(c.Items, newItem)
+		c.notifyChange(nil, newItem)
+	} else if c.DuplicatePolicy == DuplicatePolicyOverwrite {
+		c.notifyChange(c.Items[index], newItem)
+		c.Items[index] = newItem
+	} else {
+		c.unlock()
+		return nil, errors.NewConflictError(correlationId, "DUPLICATE_ID",
+			"Item "+convert.StringConverter.ToString(id)+" already exists").WithDetails("id", id)
+	}
 
-	c.Lock.Unlock()
+	c.unlock()
 	c.Logger.Trace(correlationId, "Created item %s", id)
 
-	errsave := c.Save(correlationId)
-	result = CloneObjectForResult(newItem, c.Prototype)
+	errsave := c.save(correlationId)
+	result = c.cloneResult(newItem)
 
 	return result, errsave
 }
@@ -207,25 +378,54 @@ func (c *IdentifiableMemoryPersistence) Create(correlationId string, item interf
 // Returns:  interface{}, error
 // updated item or error.
 func (c *IdentifiableMemoryPersistence) Set(correlationId string, item interface{}) (result interface{}, err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "set", start, c.ids.get(item), err) }(time.Now())
+	}
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
 
-	newItem := CloneObject(item, c.Prototype)
-	GenerateObjectId(&newItem)
+	newItem := c.cloneItem(item)
+	if newItem == nil {
+		c.unlock()
+		return nil, newInvalidItemError(correlationId)
+	}
+	c.ids.generate(&newItem)
+	c.normalizeItemId(&newItem)
+	c.applyComputedFields(&newItem)
 
-	id := GetObjectId(item)
+	id := c.ids.get(item)
 	index := c.GetIndexById(id)
+	var oldItem interface{}
+	if index >= 0 {
+		oldItem = c.Items[index]
+	}
+	if err = c.checkQuota(correlationId, oldItem, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+	if err = c.validateChange(correlationId, oldItem, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
 	if index < 0 {
 		c.Items = append(c.Items, newItem)
+		c.notifyChange(nil, newItem)
 	} else {
+		c.notifyChange(c.Items[index], newItem)
 		c.Items[index] = newItem
 	}
 
-	c.Lock.Unlock()
+	c.unlock()
 	c.Logger.Trace(correlationId, "Set item %s", id)
 
-	errsav := c.Save(correlationId)
+	errsav := c.save(correlationId)
 
-	result = CloneObjectForResult(newItem, c.Prototype)
+	result = c.cloneResult(newItem)
 	return result, errsav
 }
 
@@ -238,28 +438,58 @@ func (c *IdentifiableMemoryPersistence) Set(correlationId string, item interface
 // Returns:   interface{}, error
 // updated item or error.
 func (c *IdentifiableMemoryPersistence) Update(correlationId string, item interface{}) (result interface{}, err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "update", start, c.ids.get(item), err) }(time.Now())
+	}
+	return c.update(correlationId, item, "")
+}
+
+// Updates a data item when its current ETag matches the expected one. Empty ETag skips the check.
+func (c *IdentifiableMemoryPersistence) update(correlationId string, item interface{}, etag string) (result interface{}, err error) {
+	c.lock()
 
-	id := GetObjectId(item)
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	id := c.ids.get(item)
 	index := c.GetIndexById(id)
 	if index < 0 {
 		c.Logger.Trace(correlationId, "Item %s was not found", id)
-		c.Lock.Unlock()
-		return nil, nil
+		c.unlock()
+		return nil, c.notFound(correlationId, id)
+	}
+	if err = checkETag(correlationId, id, c.Items[index], etag); err != nil {
+		c.unlock()
+		return nil, err
 	}
-	newItem := CloneObject(item, c.Prototype)
+	newItem := c.cloneItem(item)
+	if newItem == nil {
+		c.unlock()
+		return nil, newInvalidItemError(correlationId)
+	}
+	c.normalizeItemId(&newItem)
+	c.applyComputedFields(&newItem)
+	if err = c.validateChange(correlationId, c.Items[index], newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+	c.notifyChange(c.Items[index], newItem)
 	c.Items[index] = newItem
 
-	c.Lock.Unlock()
+	c.unlock()
 	c.Logger.Trace(correlationId, "Updated item %s", id)
 
-	errsave := c.Save(correlationId)
+	errsave := c.save(correlationId)
 
-	result = CloneObjectForResult(newItem, c.Prototype)
+	result = c.cloneResult(newItem)
 	return result, errsave
 }
 
 // Updates only few selectFuncected fields in a data item.
+// Fields with zero values are cleared unless OmitEmptyUpdates is set, use UpdatePartiallyWithMask
+// to explicitly choose the fields to set.
 // Parameters:
 //   - correlation_id string
 //   (optional) transaction id to trace execution through call chain.
@@ -270,35 +500,71 @@ func (c *IdentifiableMemoryPersistence) Update(correlationId string, item interf
 // Returns: interface{}, error
 // updated item or error.
 func (c *IdentifiableMemoryPersistence) UpdatePartially(correlationId string, id interface{}, data *cdata.AnyValueMap) (result interface{}, err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "update_partially", start, id, err) }(time.Now())
+	}
+
+	values := data.Value()
+	if c.OmitEmptyUpdates {
+		values = omitEmptyValues(values)
+	}
+	return c.updatePartially(correlationId, id, values)
+}
+
+// Sets values of fields specified by names or paths in a data item
+func (c *IdentifiableMemoryPersistence) updatePartially(correlationId string, id interface{},
+	data map[string]interface{}) (result interface{}, err error) {
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
 
 	index := c.GetIndexById(id)
 	if index < 0 {
 		c.Logger.Trace(correlationId, "Item %s was not found", id)
-		c.Lock.Unlock()
-		return nil, nil
+		c.unlock()
+		return nil, c.notFound(correlationId, id)
 	}
 
-	newItem := CloneObject(c.Items[index], c.Prototype)
+	newItem := c.copyStoredItem(c.Items[index])
+
+	// Nested fields are set by paths like "address.city"
+	values := map[string]interface{}{}
+	for key, value := range data {
+		if !isFieldPath(key) {
+			values[key] = value
+		} else if err = SetPathValue(&newItem, key, value); err != nil {
+			c.unlock()
+			return nil, err
+		}
+	}
 
-	if reflect.ValueOf(newItem).Kind() == reflect.Map {
-		refl.ObjectWriter.SetProperties(newItem, data.Value())
+	if kind := reflect.ValueOf(newItem).Kind(); kind == reflect.Map || kind == reflect.Ptr {
+		refl.ObjectWriter.SetProperties(newItem, values)
 	} else {
 		objPointer := reflect.New(reflect.TypeOf(newItem))
 		objPointer.Elem().Set(reflect.ValueOf(newItem))
 		intPointer := objPointer.Interface()
-		refl.ObjectWriter.SetProperties(intPointer, data.Value())
+		refl.ObjectWriter.SetProperties(intPointer, values)
 		newItem = reflect.ValueOf(intPointer).Elem().Interface()
 	}
+	c.applyComputedFields(&newItem)
+	if err = c.validateChange(correlationId, c.Items[index], newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
 
+	c.notifyChange(c.Items[index], newItem)
 	c.Items[index] = newItem
 
-	c.Lock.Unlock()
+	c.unlock()
 	c.Logger.Trace(correlationId, "Partially updated item %s", id)
 
-	errsave := c.Save(correlationId)
+	errsave := c.save(correlationId)
 
-	result = CloneObjectForResult(newItem, c.Prototype)
+	result = c.cloneResult(newItem)
 	return result, errsave
 }
 
@@ -311,16 +577,34 @@ func (c *IdentifiableMemoryPersistence) UpdatePartially(correlationId string, id
 // Retruns:  interface{}, error
 // deleted item or error.
 func (c *IdentifiableMemoryPersistence) DeleteById(correlationId string, id interface{}) (result interface{}, err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "delete_by_id", start, id, err) }(time.Now())
+	}
+	return c.deleteById(correlationId, id, "")
+}
+
+// Deletes a data item when its current ETag matches the expected one. Empty ETag skips the check.
+func (c *IdentifiableMemoryPersistence) deleteById(correlationId string, id interface{}, etag string) (result interface{}, err error) {
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
 
 	index := c.GetIndexById(id)
 	if index < 0 {
 		c.Logger.Trace(correlationId, "Item %s was not found", id)
-		c.Lock.Unlock()
-		return nil, nil
+		c.unlock()
+		return nil, c.notFound(correlationId, id)
+	}
+	if err = checkETag(correlationId, id, c.Items[index], etag); err != nil {
+		c.unlock()
+		return nil, err
 	}
 
 	oldItem := c.Items[index]
+	c.notifyChange(oldItem, nil)
 
 	if index == len(c.Items) {
 		c.Items = c.Items[:index-1]
@@ -328,12 +612,12 @@ func (c *IdentifiableMemoryPersistence) DeleteById(correlationId string, id inte
 		c.Items = append(c.Items[:index], c.Items[index+1:]...)
 	}
 
-	c.Lock.Unlock()
+	c.unlock()
 	c.Logger.Trace(correlationId, "Deleted item by %s", id)
 
-	errsave := c.Save(correlationId)
+	errsave := c.save(correlationId)
 	//result = CloneObject(oldItem)
-	result = CloneObjectForResult(oldItem, c.Prototype)
+	result = c.cloneResult(oldItem)
 	return result, errsave
 }
 
@@ -346,17 +630,69 @@ func (c *IdentifiableMemoryPersistence) DeleteById(correlationId string, id inte
 // Returns: error
 // error or null for success.
 func (c *IdentifiableMemoryPersistence) DeleteByIds(correlationId string, ids []interface{}) (err error) {
-	filterFunc := func(item interface{}) bool {
-		exist := false
-		itemId := GetObjectId(item)
-		for _, v := range ids {
-			if CompareValues(v, itemId) {
-				exist = true
-				break
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "delete_by_ids", start, ids, err) }(time.Now())
+	}
+	_, err = c.deleteByIds(correlationId, ids)
+	return err
+}
+
+// Deletes multiple data items by their unique ids and reports ids that were not found.
+// All found items are deleted under a single lock and saved once.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+//   - ids []interface{}
+//   ids of data items to be deleted.
+// Returns: []interface{}, error
+// ids that were not found or error.
+func (c *IdentifiableMemoryPersistence) DeleteByIdsEx(correlationId string, ids []interface{}) (missing []interface{}, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "delete_by_ids_ex", start, ids, err) }(time.Now())
+	}
+	return c.deleteByIds(correlationId, ids)
+}
+
+func (c *IdentifiableMemoryPersistence) deleteByIds(correlationId string, ids []interface{}) (missing []interface{}, err error) {
+	ids = c.normalizeIds(ids)
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	found := make([]bool, len(ids))
+	items := make([]interface{}, 0, len(c.Items))
+	for _, item := range c.Items {
+		itemId := c.ids.get(item)
+		matched := false
+		for i, id := range ids {
+			if CompareValues(id, itemId) {
+				found[i] = true
+				matched = true
 			}
 		}
-		return exist
+		if matched {
+			c.notifyChange(item, nil)
+		} else {
+			items = append(items, item)
+		}
+	}
+	deleted := len(c.Items) - len(items)
+	c.Items = items
+	c.unlock()
+
+	missing = []interface{}{}
+	for i, id := range ids {
+		if !found[i] {
+			missing = append(missing, id)
+		}
+	}
+	if deleted == 0 {
+		return missing, nil
 	}
 
-	return c.DeleteByFilter(correlationId, filterFunc)
+	c.Logger.Trace(correlationId, "Deleted %d items by ids", deleted)
+	return missing, c.save(correlationId)
 }
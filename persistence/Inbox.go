@@ -0,0 +1,241 @@
+package persistence
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Statuses of inbox messages
+const (
+	// Message waits for processing
+	InboxPending = "pending"
+	// Message was processed and acknowledged
+	InboxProcessed = "processed"
+	// Message processing failed
+	InboxFailed = "failed"
+)
+
+// InboxMessage is an incoming message stored by InboxPersistence.
+type InboxMessage struct {
+	// Unique id of the message used to skip duplicates
+	Id      string      `json:"id"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+	// Processing status: pending, processed or failed
+	Status string `json:"status"`
+	// Number of times the message was fetched for processing
+	Attempts int `json:"attempts"`
+	// Error of the last failed attempt
+	Error string `json:"error"`
+	// Time until the message is fetched by a consumer
+	LockedUntil time.Time `json:"locked_until"`
+	ReceiveTime time.Time `json:"receive_time"`
+	ProcessTime time.Time `json:"process_time"`
+}
+
+/*
+InboxPersistence stores incoming messages in a JSON file for at-least-once processing pipelines.
+Duplicate messages are skipped by ids, fetched messages are redelivered if they are not
+acknowledged before the lock timeout, and failed messages can be reprocessed.
+
+Configuration parameters
+
+- path:                    path to the file where messages are stored
+
+Example
+
+    inbox := NewInboxPersistence("./data/inbox.json")
+    inbox.Open("123")
+    inbox.Receive("123", InboxMessage{Id: event.Id, Type: "order_created", Payload: event})
+
+    messages, err := inbox.FetchPending("123", 100, 60000)
+    for _, message := range messages {
+        if err := handle(message); err != nil {
+            inbox.Fail("123", message.Id, err)
+        } else {
+            inbox.Ack("123", message.Id)
+        }
+    }
+    ...
+    count, err := inbox.ReprocessFailed("123")
+*/
+type InboxPersistence struct {
+	IdentifiableFilePersistence
+}
+
+// Creates a new persistence of inbox messages.
+// Parameters:
+//   - path string
+//   a path to the file where messages are stored
+// Returns *InboxPersistence
+func NewInboxPersistence(path string) *InboxPersistence {
+	prototype := reflect.TypeOf(InboxMessage{})
+	return &InboxPersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+	}
+}
+
+// Atomically changes messages selected by the change function and saves them.
+// The change function returns false for messages that are not changed.
+func (c *InboxPersistence) change(correlationId string, change func(message *InboxMessage, now time.Time) bool) ([]InboxMessage, error) {
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	now := c.Clock.Now().UTC()
+	changed := []InboxMessage{}
+	for index, item := range c.Items {
+		message, ok := item.(InboxMessage)
+		if !ok || !change(&message, now) {
+			continue
+		}
+		var newItem interface{} = message
+		c.notifyChange(item, newItem)
+		c.Items[index] = newItem
+		changed = append(changed, message)
+	}
+
+	c.unlock()
+	if len(changed) == 0 {
+		return changed, nil
+	}
+	if err := c.save(correlationId); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// Changes a single message by its id
+func (c *InboxPersistence) changeById(correlationId string, id string, change func(message *InboxMessage, now time.Time)) error {
+	changed, err := c.change(correlationId, func(message *InboxMessage, now time.Time) bool {
+		if message.Id != id {
+			return false
+		}
+		change(message, now)
+		return true
+	})
+	if err == nil && len(changed) == 0 {
+		err = errors.NewNotFoundError(correlationId, "MESSAGE_NOT_FOUND", "Message "+id+" was not found").
+			WithDetails("id", id)
+	}
+	return err
+}
+
+// Stores an incoming message as pending. Messages with already received ids are skipped.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - message InboxMessage
+//   a message to store, the id is generated if it's empty
+// Returns bool, error
+// true if the message was stored or false if it is a duplicate.
+func (c *InboxPersistence) Receive(correlationId string, message InboxMessage) (bool, error) {
+	var item interface{} = message
+	c.ids.generate(&item)
+	message, _ = item.(InboxMessage)
+	message.Status = InboxPending
+	message.Attempts = 0
+	message.Error = ""
+	message.LockedUntil = time.Time{}
+	message.ReceiveTime = c.Clock.Now().UTC()
+
+	c.lock()
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return false, err
+	}
+	if c.GetIndexById(message.Id) >= 0 {
+		c.unlock()
+		c.Logger.Trace(correlationId, "Skipped duplicate message %s", message.Id)
+		return false, nil
+	}
+	item = message
+	c.Items = append(c.Items, item)
+	c.notifyChange(nil, item)
+	c.unlock()
+
+	return true, c.save(correlationId)
+}
+
+// Fetches pending messages for processing and increments their attempts.
+// Fetched messages are locked and delivered again if they are not acknowledged before the lock expires.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - maxCount int
+//   a maximum number of messages to fetch
+//   - lockTimeout int64
+//   time in milliseconds to lock fetched messages
+// Returns []InboxMessage, error
+// fetched messages in the order they were received or error.
+func (c *InboxPersistence) FetchPending(correlationId string, maxCount int, lockTimeout int64) ([]InboxMessage, error) {
+	count := 0
+	return c.change(correlationId, func(message *InboxMessage, now time.Time) bool {
+		if count >= maxCount || message.Status != InboxPending || message.LockedUntil.After(now) {
+			return false
+		}
+		count++
+		message.Attempts++
+		message.LockedUntil = now.Add(time.Duration(lockTimeout) * time.Millisecond)
+		return true
+	})
+}
+
+// Acknowledges a processed message.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   an id of the message
+// Returns error or nil for success.
+func (c *InboxPersistence) Ack(correlationId string, id string) error {
+	return c.changeById(correlationId, id, func(message *InboxMessage, now time.Time) {
+		message.Status = InboxProcessed
+		message.Error = ""
+		message.LockedUntil = time.Time{}
+		message.ProcessTime = now
+	})
+}
+
+// Marks a message as failed.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   an id of the message
+//   - cause error
+//   an error of the processing
+// Returns error or nil for success.
+func (c *InboxPersistence) Fail(correlationId string, id string, cause error) error {
+	return c.changeById(correlationId, id, func(message *InboxMessage, now time.Time) {
+		message.Status = InboxFailed
+		message.Error = ""
+		if cause != nil {
+			message.Error = cause.Error()
+		}
+		message.LockedUntil = time.Time{}
+		message.ProcessTime = now
+	})
+}
+
+// Returns all failed messages to pending status, so they are fetched again.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns int, error
+// number of returned messages or error.
+func (c *InboxPersistence) ReprocessFailed(correlationId string) (int, error) {
+	changed, err := c.change(correlationId, func(message *InboxMessage, now time.Time) bool {
+		if message.Status != InboxFailed {
+			return false
+		}
+		message.Status = InboxPending
+		return true
+	})
+	return len(changed), err
+}
@@ -0,0 +1,220 @@
+package persistence
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Indexes stored next to the data file. Items are referenced by positions in the data file,
+so the indexes are valid only for the data file with the same checksum.
+*/
+type persistedIndexes struct {
+	Checksum   string               `json:"checksum"`
+	Count      int                  `json:"count"`
+	Geo        *persistedGeoIndex   `json:"geo,omitempty"`
+	Partitions *persistedPartitions `json:"partitions,omitempty"`
+}
+
+type persistedGeoIndex struct {
+	LatitudeField  string           `json:"latitude_field"`
+	LongitudeField string           `json:"longitude_field"`
+	Precision      int              `json:"precision"`
+	Cells          map[string][]int `json:"cells"`
+}
+
+type persistedPartitions struct {
+	TimestampField string                `json:"timestamp_field"`
+	Period         int64                 `json:"period"`
+	Partitions     []*persistedPartition `json:"partitions"`
+}
+
+type persistedPartition struct {
+	Start   int64     `json:"start"`
+	MinTime time.Time `json:"min_time"`
+	MaxTime time.Time `json:"max_time"`
+	Items   []int     `json:"items"`
+}
+
+// Gets paths of the data file and the indexes file or empty strings
+// when items are not stored by JsonFilePersister
+func (c *MemoryPersistence) indexFilePaths() (string, string) {
+	persister, ok := c.Loader.(*JsonFilePersister)
+	if !ok || persister.Path() == "" || persister.Path() == StdioPath {
+		return "", ""
+	}
+	return persister.Path(), persister.Path() + ".indexes"
+}
+
+// Writes spatial and time indexes next to the data file. Must be called under lock
+// after items were saved, so the checksum matches the data file.
+func (c *MemoryPersistence) storeIndexes(correlationId string) error {
+	dataPath, indexPath := c.indexFilePaths()
+	if dataPath == "" || (c.geoIndex == nil && c.partitionIndex == nil) {
+		return nil
+	}
+
+	checksum, err := fileChecksum(dataPath)
+	if err != nil {
+		return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+dataPath).WithCause(err)
+	}
+	indexes := &persistedIndexes{Checksum: hex.EncodeToString(checksum), Count: len(c.Items)}
+	if c.geoIndex != nil {
+		indexes.Geo = &persistedGeoIndex{
+			LatitudeField:  c.LatitudeField,
+			LongitudeField: c.LongitudeField,
+			Precision:      c.geoIndex.precision,
+			Cells:          map[string][]int{},
+		}
+		for i, item := range c.Items {
+			if point, ok := c.GetItemLocation(item); ok {
+				hash := GeoHash(point, c.geoIndex.precision)
+				indexes.Geo.Cells[hash] = append(indexes.Geo.Cells[hash], i)
+			}
+		}
+	}
+	if c.partitionIndex != nil {
+		period := c.partitionIndex.period
+		indexes.Partitions = &persistedPartitions{TimestampField: c.TimestampField, Period: period}
+		byStart := map[int64]*persistedPartition{}
+		for i, item := range c.Items {
+			timestamp, ok := c.GetItemTimestamp(item)
+			if !ok {
+				continue
+			}
+			start := timestamp.UnixNano() / int64(time.Millisecond)
+			start = start - ((start%period)+period)%period
+			partition, ok := byStart[start]
+			if !ok {
+				partition = &persistedPartition{Start: start, MinTime: timestamp, MaxTime: timestamp}
+				byStart[start] = partition
+				indexes.Partitions.Partitions = append(indexes.Partitions.Partitions, partition)
+			}
+			if timestamp.Before(partition.MinTime) {
+				partition.MinTime = timestamp
+			}
+			if timestamp.After(partition.MaxTime) {
+				partition.MaxTime = timestamp
+			}
+			partition.Items = append(partition.Items, i)
+		}
+		sort.Slice(indexes.Partitions.Partitions, func(i, j int) bool {
+			return indexes.Partitions.Partitions[i].Start < indexes.Partitions.Partitions[j].Start
+		})
+	}
+
+	buffer, err := json.Marshal(indexes)
+	if err != nil {
+		return errors.NewInternalError(correlationId, "CAN'T_CONVERT", "Failed convert to JSON").WithCause(err)
+	}
+	if err = c.Loader.(*JsonFilePersister).writeSideFile(correlationId, indexPath, buffer); err != nil {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write indexes file: "+indexPath).WithCause(err)
+	}
+	c.Logger.Trace(correlationId, "Stored indexes to %s", indexPath)
+	return nil
+}
+
+// Restores spatial and time indexes from the file next to the data file.
+// Indexes are skipped and rebuilt lazily when the file is missing, the checksum
+// doesn't match the data file or index definitions were changed. Must be called under lock.
+// Returns true if the indexes were restored.
+func (c *MemoryPersistence) restoreIndexes(correlationId string) bool {
+	dataPath, indexPath := c.indexFilePaths()
+	if dataPath == "" || (c.geoIndex == nil && c.partitionIndex == nil) {
+		return false
+	}
+
+	buffer, err := ioutil.ReadFile(indexPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.Logger.Warn(correlationId, "Failed to read indexes file %s: %v", indexPath, err)
+		}
+		return false
+	}
+	indexes := &persistedIndexes{}
+	if err = json.Unmarshal(buffer, indexes); err != nil {
+		c.Logger.Warn(correlationId, "Failed to parse indexes file %s: %v", indexPath, err)
+		return false
+	}
+	checksum, err := fileChecksum(dataPath)
+	if err != nil || hex.EncodeToString(checksum) != indexes.Checksum || indexes.Count != len(c.Items) {
+		c.Logger.Debug(correlationId, "Indexes in %s are outdated and will be rebuilt", indexPath)
+		return false
+	}
+
+	if c.geoIndex != nil {
+		geo := indexes.Geo
+		if geo == nil || geo.Precision != c.geoIndex.precision ||
+			geo.LatitudeField != c.LatitudeField || geo.LongitudeField != c.LongitudeField {
+			return false
+		}
+		for _, positions := range geo.Cells {
+			if !validPositions(positions, len(c.Items)) {
+				return false
+			}
+		}
+	}
+	if c.partitionIndex != nil {
+		partitions := indexes.Partitions
+		if partitions == nil || partitions.Period != c.partitionIndex.period ||
+			partitions.TimestampField != c.TimestampField {
+			return false
+		}
+		for _, partition := range partitions.Partitions {
+			if !validPositions(partition.Items, len(c.Items)) {
+				return false
+			}
+		}
+	}
+
+	if index := c.geoIndex; index != nil {
+		index.lock.Lock()
+		index.cells = make(map[string][]interface{}, len(indexes.Geo.Cells))
+		for hash, positions := range indexes.Geo.Cells {
+			index.cells[hash] = c.itemsAt(positions)
+		}
+		index.dirty = false
+		index.lock.Unlock()
+	}
+	if index := c.partitionIndex; index != nil {
+		index.lock.Lock()
+		index.partitions = make([]*timePartition, len(indexes.Partitions.Partitions))
+		for i, partition := range indexes.Partitions.Partitions {
+			index.partitions[i] = &timePartition{
+				start:   partition.Start,
+				minTime: partition.MinTime,
+				maxTime: partition.MaxTime,
+				items:   c.itemsAt(partition.Items),
+			}
+		}
+		index.dirty = false
+		index.lock.Unlock()
+	}
+	c.Logger.Debug(correlationId, "Restored indexes from %s", indexPath)
+	return true
+}
+
+// Checks that all positions point to existing items
+func validPositions(positions []int, count int) bool {
+	for _, position := range positions {
+		if position < 0 || position >= count {
+			return false
+		}
+	}
+	return true
+}
+
+// Gets items at the given positions
+func (c *MemoryPersistence) itemsAt(positions []int) []interface{} {
+	items := make([]interface{}, len(positions))
+	for i, position := range positions {
+		items[i] = c.Items[position]
+	}
+	return items
+}
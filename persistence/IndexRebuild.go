@@ -0,0 +1,180 @@
+package persistence
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Progress of a background index rebuild reported to IndexProgressCallback.
+*/
+type IndexRebuildProgress struct {
+	// Name of the index that was rebuilt: geo, partitions, view:<name>, rollup:<name>,
+	// read_snapshot, id_filter or query_cache
+	Index string
+	// Number of rebuilt indexes
+	Rebuilt int
+	// Total number of indexes to rebuild
+	Total int
+	// True when all indexes are rebuilt
+	Done bool
+}
+
+/*
+IndexRebuild is a handle of indexes rebuilt in background by RebuildIndexes.
+*/
+type IndexRebuild struct {
+	lock     sync.Mutex
+	progress IndexRebuildProgress
+	done     chan struct{}
+}
+
+// Gets the latest progress of the rebuild.
+// Returns IndexRebuildProgress
+func (c *IndexRebuild) Progress() IndexRebuildProgress {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.progress
+}
+
+// Waits until all indexes are rebuilt.
+func (c *IndexRebuild) Wait() {
+	<-c.done
+}
+
+// Gets a channel that is closed when all indexes are rebuilt.
+// Returns <-chan struct{}
+func (c *IndexRebuild) Done() <-chan struct{} {
+	return c.done
+}
+
+// Collects names and rebuild functions of all indexes. Must be called under lock.
+func (c *MemoryPersistence) indexRebuilders() ([]string, []func()) {
+	names := []string{}
+	rebuilders := []func(){}
+	add := func(name string, rebuild func()) {
+		names = append(names, name)
+		rebuilders = append(rebuilders, rebuild)
+	}
+
+	if index := c.geoIndex; index != nil {
+		add("geo", func() {
+			index.lock.Lock()
+			defer index.lock.Unlock()
+			index.rebuild(c)
+		})
+	}
+	if index := c.partitionIndex; index != nil {
+		add("partitions", func() {
+			index.lock.Lock()
+			defer index.lock.Unlock()
+			index.rebuild(c)
+		})
+	}
+
+	viewNames := make([]string, 0, len(c.views))
+	for name := range c.views {
+		viewNames = append(viewNames, name)
+	}
+	sort.Strings(viewNames)
+	for _, name := range viewNames {
+		name := name
+		add("view:"+name, func() {
+			if view, ok := c.views[name]; ok {
+				c.rebuildView("", view)
+			}
+		})
+	}
+
+	rollupNames := make([]string, 0, len(c.rollups))
+	for name := range c.rollups {
+		rollupNames = append(rollupNames, name)
+	}
+	sort.Strings(rollupNames)
+	for _, name := range rollupNames {
+		name := name
+		add("rollup:"+name, func() {
+			if rollup, ok := c.rollups[name]; ok {
+				rollup.rebuild(c.Items)
+			}
+		})
+	}
+
+	if c.getReadSnapshots() != nil {
+		add("read_snapshot", func() {
+			if snapshots := c.getReadSnapshots(); snapshots != nil {
+				snapshots.current.Store(c.newReadSnapshot(c.copyItems(), snapshots.fields))
+			}
+		})
+	}
+	if c.getIdFilter() != nil {
+		add("id_filter", func() {
+			if c.getIdFilter() != nil {
+				c.idFilter.Store(c.newIdFilter(0))
+			}
+		})
+	}
+	if c.queryCache != nil {
+		add("query_cache", func() {
+			if c.queryCache != nil {
+				c.queryCache.invalidate()
+			}
+		})
+	}
+	return names, rebuilders
+}
+
+// Rebuilds spatial and time indexes, materialized views, rollups, read snapshots,
+// the id filter and the query cache in background, for example after bulk imports that bypass change handlers
+// or after index definitions were reconfigured.
+// Indexes are rebuilt one by one under write lock, and the lock is released between them,
+// so other operations are not blocked for the whole rebuild.
+// Progress is reported to IndexProgressCallback after every rebuilt index.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns *IndexRebuild
+// a handle to wait for the rebuild and get its progress.
+func (c *MemoryPersistence) RebuildIndexes(correlationId string) *IndexRebuild {
+	c.rlock()
+	names, rebuilders := c.indexRebuilders()
+	c.runlock()
+
+	rebuild := &IndexRebuild{
+		progress: IndexRebuildProgress{Total: len(names)},
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(rebuild.done)
+		start := time.Now()
+		for i, name := range names {
+			c.lock()
+			rebuilders[i]()
+			c.unlock()
+
+			rebuild.lock.Lock()
+			rebuild.progress.Index = name
+			rebuild.progress.Rebuilt = i + 1
+			rebuild.progress.Done = i+1 == len(names)
+			progress := rebuild.progress
+			rebuild.lock.Unlock()
+			c.reportIndexProgress(correlationId, progress)
+		}
+		if len(names) == 0 {
+			rebuild.lock.Lock()
+			rebuild.progress.Done = true
+			rebuild.lock.Unlock()
+			c.reportIndexProgress(correlationId, rebuild.Progress())
+		}
+		c.Logger.Trace(correlationId, "Rebuilt %d indexes in %v", len(names), time.Since(start))
+	}()
+	return rebuild
+}
+
+// Reports progress of index rebuild when the callback is set
+func (c *MemoryPersistence) reportIndexProgress(correlationId string, progress IndexRebuildProgress) {
+	if c.IndexProgressCallback != nil {
+		c.IndexProgressCallback(correlationId, progress)
+	}
+}
@@ -0,0 +1,179 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Job is a scheduled task stored by JobsFilePersistence.
+type Job struct {
+	Id string `json:"id"`
+	// Type of the job that tells workers what to do
+	Type string `json:"type"`
+	// Custom parameters of the job
+	Params map[string]interface{} `json:"params"`
+	// Time when the job is due
+	NextRun time.Time `json:"next_run"`
+	// Interval of recurring jobs in milliseconds, 0 for one-time jobs
+	Interval int64 `json:"interval"`
+	// Time until the job is claimed by a worker
+	LockedUntil time.Time `json:"locked_until"`
+	// Time when the job was completed last time
+	LastRun time.Time `json:"last_run"`
+}
+
+/*
+JobsFilePersistence stores scheduled jobs in a JSON file for simple cron-like workers
+without external queues. Workers claim due jobs with FetchDue and report them with CompleteJob.
+Claimed jobs are locked for the lock timeout, and jobs of failed workers are fetched again
+when their locks expire.
+
+Configuration parameters
+
+- path:                    path to the file where jobs are stored
+
+Example
+
+    jobs := NewJobsFilePersistence("./data/jobs.json")
+    jobs.Open("123")
+    jobs.Create("123", Job{Type: "cleanup", NextRun: time.Now(), Interval: 3600000})
+
+    due, err := jobs.FetchDue("123", time.Now(), 60000)
+    for _, job := range due {
+        runJob(job)
+        jobs.CompleteJob("123", job.Id)
+    }
+*/
+type JobsFilePersistence struct {
+	IdentifiableFilePersistence
+}
+
+// Creates a new persistence of jobs.
+// Parameters:
+//   - path string
+//   a path to the file where jobs are stored
+// Returns *JobsFilePersistence
+func NewJobsFilePersistence(path string) *JobsFilePersistence {
+	prototype := reflect.TypeOf(Job{})
+	return &JobsFilePersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+	}
+}
+
+// Atomically claims jobs that are due and not claimed by other workers.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - now time.Time
+//   the current time
+//   - lockTimeout int64
+//   time in milliseconds to lock claimed jobs
+// Returns []Job, error
+// claimed jobs ordered by due time or error.
+func (c *JobsFilePersistence) FetchDue(correlationId string, now time.Time, lockTimeout int64) ([]Job, error) {
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	due := []Job{}
+	lockedUntil := now.Add(time.Duration(lockTimeout) * time.Millisecond)
+	for index, item := range c.Items {
+		job, ok := item.(Job)
+		if !ok || job.NextRun.After(now) || job.LockedUntil.After(now) {
+			continue
+		}
+		job.LockedUntil = lockedUntil
+		var newItem interface{} = job
+		c.notifyChange(item, newItem)
+		c.Items[index] = newItem
+		due = append(due, job)
+	}
+
+	c.unlock()
+	if len(due) == 0 {
+		return due, nil
+	}
+	c.Logger.Trace(correlationId, "Claimed %d due jobs", len(due))
+
+	if err := c.save(correlationId); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextRun.Before(due[j].NextRun) })
+	return due, nil
+}
+
+// Completes a claimed job. One-time jobs are deleted, and recurring jobs
+// are unlocked and scheduled for the next interval after the current time.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   an id of the job
+// Returns error or nil for success.
+func (c *JobsFilePersistence) CompleteJob(correlationId string, id string) error {
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return err
+	}
+
+	index := c.GetIndexById(id)
+	if index < 0 {
+		c.unlock()
+		return errors.NewNotFoundError(correlationId, "JOB_NOT_FOUND", "Job "+id+" was not found").
+			WithDetails("id", id)
+	}
+
+	oldItem := c.Items[index]
+	job, _ := oldItem.(Job)
+	now := c.Clock.Now().UTC()
+	if job.Interval <= 0 {
+		c.notifyChange(oldItem, nil)
+		c.Items = append(c.Items[:index], c.Items[index+1:]...)
+	} else {
+		interval := time.Duration(job.Interval) * time.Millisecond
+		if !job.NextRun.After(now) {
+			// Missed runs are skipped
+			job.NextRun = job.NextRun.Add((now.Sub(job.NextRun)/interval + 1) * interval)
+		}
+		job.LockedUntil = time.Time{}
+		job.LastRun = now
+		var newItem interface{} = job
+		c.notifyChange(oldItem, newItem)
+		c.Items[index] = newItem
+	}
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Completed job %s", id)
+
+	return c.save(correlationId)
+}
+
+// Gets jobs by filter parameters.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters like "type"
+// Returns []Job, error
+// jobs or error.
+func (c *JobsFilePersistence) GetJobs(correlationId string, filter *cdata.FilterParams) ([]Job, error) {
+	items, err := c.GetListByFilterParams(correlationId, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, len(items))
+	for i, item := range items {
+		jobs[i], _ = item.(Job)
+	}
+	return jobs, nil
+}
@@ -1,9 +1,16 @@
 package persistence
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/pip-services3-go/pip-services3-commons-go/config"
 	"github.com/pip-services3-go/pip-services3-commons-go/convert"
@@ -15,9 +22,25 @@ Persistence component that loads and saves data from/to flat file.
 
 It is used by FilePersistence, but can be useful on its own.
 
+With "-" path items are read from stdin and written to stdout, so small tools built
+on persistences can transform data in Unix pipelines. Stdin is read only once, and every save
+writes all items again, so set durability to none to write them only on Close.
+
  Configuration parameters
 
-  - path:          path to the file where data is stored
+  - path:          path to the file where data is stored, "-" to read items from stdin and write them to stdout
+  - options:
+      - save_chunk_size: maximum size of chunks written to the file in bytes, 0 to write whole buffers (default: 0)
+      - load_parallelism: number of goroutines that unmarshal loaded items preserving their order (default: 1)
+      - read_buffer_size: size of the buffer to read the file in bytes (default: 65536)
+      - write_buffer_size: size of the buffer to encode items before they are written to the file in bytes (default: 65536)
+      - indent:          string to indent items in the written file, empty to write compact JSON (default: empty)
+      - escape_html:     escape <, > and & characters in written strings (default: true)
+      - durability:      fsync to sync the file to the disk after writing
+      - file_mode:       mode bits of the file in octal notation like 0600 (default: mode of the replaced file or 0600)
+      - dir_mode:        mode bits of created directories like 0700 (default: 0755 limited by umask)
+      - file_owner:      user name or id of the file owner on Unix
+      - file_group:      group name or id of the file on Unix
 
  Example
 
@@ -34,8 +57,43 @@ It is used by FilePersistence, but can be useful on its own.
 type JsonFilePersister struct {
 	path      string
 	Prototype reflect.Type
+	// Callback to report progress of long load and save operations
+	ProgressCallback func(correlationId string, progress PersisterProgress)
+	// Number of items between progress reports (default: 1000)
+	ProgressInterval int
+	// Maximum size of chunks written to the data file in bytes, 0 to write whole buffers
+	ChunkSize int
+	// Size of the buffer to encode items before they are written to the data file in bytes (default: 65536)
+	WriteBufferSize int
+	// Durability level, the data file is synced to the disk when it is "fsync"
+	Durability string
+	// Mode bits of the data file (default: mode of the replaced file or 0600)
+	FileMode os.FileMode
+	// Mode bits of created directories (default: 0755 limited by umask)
+	DirMode os.FileMode
+	// User name or id of the data file owner on Unix
+	FileOwner string
+	// Group name or id of the data file on Unix
+	FileGroup string
+	// Size of the buffer to read the data file in bytes (default: 65536)
+	ReadBufferSize int
+	// String to indent items in the data file, empty to write compact JSON
+	Indent string
+	// Write <, > and & characters in strings as is instead of escaping them
+	DisableHtmlEscape bool
+	// Number of goroutines that unmarshal loaded items, 0 or 1 to unmarshal them sequentially
+	LoadParallelism int
+	// Reader used instead of the data file when the path is "-" (default: os.Stdin)
+	Stdin io.Reader
+	// Writer used instead of the data file when the path is "-" (default: os.Stdout)
+	Stdout io.Writer
+	// Number of items in the last loaded file used to preallocate the list of items
+	loadedCount int64
 }
 
+// Path of the data file to read items from stdin and write them to stdout
+const StdioPath = "-"
+
 // Creates a new instance of the persistence.
 // Parameters:
 //  - path  string
@@ -51,6 +109,22 @@ func (c *JsonFilePersister) Path() string {
 	return c.path
 }
 
+// Gets the reader of items for "-" path
+func (c *JsonFilePersister) stdin() io.Reader {
+	if c.Stdin != nil {
+		return c.Stdin
+	}
+	return os.Stdin
+}
+
+// Gets the writer of items for "-" path
+func (c *JsonFilePersister) stdout() io.Writer {
+	if c.Stdout != nil {
+		return c.Stdout
+	}
+	return os.Stdout
+}
+
 // Sets the file path where data is stored.
 // Parameters:
 //  - value  string
@@ -65,6 +139,16 @@ func (c *JsonFilePersister) SetPath(value string) {
 //  parameters to be set.
 func (c *JsonFilePersister) Configure(config *config.ConfigParams) {
 	c.path = config.GetAsStringWithDefault("path", c.path)
+	c.ChunkSize = config.GetAsIntegerWithDefault("options.save_chunk_size", c.ChunkSize)
+	c.LoadParallelism = config.GetAsIntegerWithDefault("options.load_parallelism", c.LoadParallelism)
+	c.ReadBufferSize = config.GetAsIntegerWithDefault("options.read_buffer_size", c.ReadBufferSize)
+	c.WriteBufferSize = config.GetAsIntegerWithDefault("options.write_buffer_size", c.WriteBufferSize)
+	if indent := config.GetAsNullableString("options.indent"); indent != nil {
+		c.Indent = *indent
+	}
+	c.DisableHtmlEscape = !config.GetAsBooleanWithDefault("options.escape_html", !c.DisableHtmlEscape)
+	c.Durability = config.GetAsStringWithDefault("options.durability", c.Durability)
+	c.configurePermissions(config)
 }
 
 // Loads data items from external JSON file.
@@ -74,38 +158,188 @@ func (c *JsonFilePersister) Configure(config *config.ConfigParams) {
 // Returns []interface{}, error
 // loaded items or error.
 func (c *JsonFilePersister) Load(correlation_id string) (data []interface{}, err error) {
+	return c.LoadWithContext(context.Background(), correlation_id)
+}
+
+// Loads data items from external JSON file reporting progress to ProgressCallback.
+// Items are decoded one by one, so loading can be cancelled through the context.
+// Parameters:
+//  - ctx context.Context
+//  a context to cancel loading.
+//  - correlation_id  string
+//  transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items, InvalidStateError if loading was cancelled or other error.
+func (c *JsonFilePersister) LoadWithContext(ctx context.Context, correlation_id string) (data []interface{}, err error) {
 	if c.path == "" {
 		data = nil
-		err = errors.NewConfigError("", "NO_PATH", "Data file path is not set")
+		err = errors.NewConfigError(correlation_id, "NO_PATH", "Data file path is not set")
 		return data, err
 	}
 
-	_, fserr := os.Stat(c.path)
+	if c.path == StdioPath {
+		parseError := func(cause error) error {
+			return errors.NewBadRequestError(correlation_id, "PARSE_FAILED", "Failed to parse data items from stdin").WithCause(cause)
+		}
+		return c.loadFrom(ctx, correlation_id, c.stdin(), 0, parseError)
+	}
+
+	info, fserr := os.Stat(c.path)
 	if os.IsNotExist(fserr) {
 		data = nil
 		err = nil
 		return data, err
 	}
 
-	jsonStr, jsonerr := ioutil.ReadFile(c.path)
+	file, jsonerr := os.Open(c.path)
 	if jsonerr != nil {
 		err = errors.NewFileError(correlation_id, "READ_FAILED", "Failed to read data file: "+c.path).WithCause(jsonerr)
 		data = nil
 		return data, err
 	}
+	defer file.Close()
 
-	if len(jsonStr) == 0 {
+	if info.Size() == 0 {
 		return nil, nil
 	}
 
-	list, err := convert.FromJson((string)(jsonStr))
+	parseError := func(cause error) error {
+		return errors.NewFileError(correlation_id, "PARSE_FAILED", "Failed to parse data file: "+c.path).WithCause(cause)
+	}
+	return c.loadFrom(ctx, correlation_id, file, info.Size(), parseError)
+}
+
+// Loads data items from a reader with JSON array of items, for instance a socket,
+// a pipe, an HTTP body or a decompressing reader. The reader is not closed.
+// Parameters:
+//  - correlation_id  string
+//  transaction id to trace execution through call chain.
+//  - reader io.Reader
+//  a reader of JSON data
+// Returns []interface{}, error
+// loaded items or error.
+func (c *JsonFilePersister) LoadFrom(correlation_id string, reader io.Reader) (data []interface{}, err error) {
+	parseError := func(cause error) error {
+		return errors.NewBadRequestError(correlation_id, "PARSE_FAILED", "Failed to parse data items").WithCause(cause)
+	}
+	return c.loadFrom(context.Background(), correlation_id, reader, 0, parseError)
+}
+
+// Decodes items from a JSON array one by one. Other JSON values are converted as a whole.
+// Total bytes are reported in progress when they are known.
+func (c *JsonFilePersister) loadFrom(ctx context.Context, correlation_id string, source io.Reader, totalBytes int64,
+	parseError func(cause error) error) (data []interface{}, err error) {
+	readBufferSize := c.ReadBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = 64 * 1024
+	}
+	buffered := bufio.NewReaderSize(source, readBufferSize)
+	first, jsonerr := peekJsonStart(buffered)
+	if jsonerr == io.EOF {
+		return nil, nil
+	}
+	if jsonerr != nil {
+		return nil, errors.NewFileError(correlation_id, "READ_FAILED", "Failed to read data items").WithCause(jsonerr)
+	}
+	if first != '[' {
+		// Not an array, the whole value is converted the same way as before
+		return loadValue(buffered, parseError)
+	}
+
+	progress := &PersisterProgress{Operation: PersisterOperationLoad, TotalBytes: totalBytes}
+	reader := &countingReader{reader: buffered}
+	decoder := json.NewDecoder(reader)
+	if _, jsonerr = decoder.Token(); jsonerr != nil {
+		return nil, parseError(jsonerr)
+	}
+
+	if c.LoadParallelism > 1 {
+		if data, err = c.decodeItemsParallel(ctx, correlation_id, decoder, reader, progress, parseError); err != nil {
+			return nil, err
+		}
+	} else {
+		data = make([]interface{}, 0, atomic.LoadInt64(&c.loadedCount))
+		for decoder.More() {
+			if err = checkCancelled(ctx, correlation_id); err != nil {
+				return nil, err
+			}
+			var item interface{}
+			if jsonerr = decoder.Decode(&item); jsonerr != nil {
+				return nil, parseError(jsonerr)
+			}
+			data = append(data, item)
+			progress.Items++
+			progress.Bytes = reader.count
+			c.reportProgress(correlation_id, progress, false)
+		}
+	}
+	if _, jsonerr = decoder.Token(); jsonerr != nil {
+		return nil, parseError(jsonerr)
+	}
+
+	atomic.StoreInt64(&c.loadedCount, int64(len(data)))
+	progress.Bytes = reader.count
+	progress.Done = true
+	c.reportProgress(correlation_id, progress, true)
+	return data, nil
+}
+
+// Skips leading white spaces and gets the first character of JSON value without consuming it
+func peekJsonStart(reader *bufio.Reader) (byte, error) {
+	for {
+		ch, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if ch != ' ' && ch != '\t' && ch != '\r' && ch != '\n' {
+			return ch, reader.UnreadByte()
+		}
+	}
+}
+
+// Loads data that doesn't contain an array of items
+func loadValue(reader io.Reader, parseError func(cause error) error) (data []interface{}, err error) {
+	jsonStr, jsonerr := ioutil.ReadAll(reader)
+	if jsonerr != nil {
+		return nil, parseError(jsonerr)
+	}
+	list, jsonerr := convert.FromJson((string)(jsonStr))
+	if jsonerr != nil {
+		return nil, parseError(jsonerr)
+	}
 	if list == nil {
-		data = nil
-		return data, err
+		return nil, nil
+	}
+	return convert.ArrayConverter.ListToArray(list), nil
+}
+
+// Gets the time when the data file was last modified.
+// Parameters:
+//   - correlationId string
+//   transaction id to trace execution through call chain.
+// Returns time.Time, error
+// modification time, zero time if the file doesn't exist, or error.
+func (c *JsonFilePersister) LastModified(correlationId string) (time.Time, error) {
+	info, err := c.dataFileInfo(correlationId)
+	if err != nil || info == nil {
+		return time.Time{}, err
 	}
-	data = convert.ArrayConverter.ListToArray(list)
-	err = nil
-	return data, err
+	return info.ModTime(), nil
+}
+
+// Gets information about the data file, nil if the file doesn't exist
+func (c *JsonFilePersister) dataFileInfo(correlationId string) (os.FileInfo, error) {
+	if c.path == StdioPath {
+		return nil, nil
+	}
+	info, err := os.Stat(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+c.path).WithCause(err)
+	}
+	return info, nil
 }
 
 // Saves given data items to external JSON file.
@@ -117,15 +351,181 @@ func (c *JsonFilePersister) Load(correlation_id string) (data []interface{}, err
 //  Retruns error
 //  error or nil for success.
 func (c *JsonFilePersister) Save(correlationId string, items []interface{}) error {
-	json, jsonerr := convert.ToJson(items)
-	if jsonerr != nil {
-		err := errors.NewInternalError(correlationId, "CAN'T_CONVERT", "Failed convert to JSON")
+	return c.SaveWithContext(context.Background(), correlationId, items)
+}
+
+// Saves given data items to external JSON file reporting progress to ProgressCallback.
+// Items are encoded one by one and written in chunks into a temporary file
+// that replaces the data file at the end, so the whole JSON document is never kept in memory
+// and saving can be cancelled through the context without damaging the data file.
+// Parameters:
+//   - ctx context.Context
+//   a context to cancel saving.
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface[]
+//   list of data items to save
+//  Retruns error
+//  InvalidStateError if saving was cancelled, other error or nil for success.
+func (c *JsonFilePersister) SaveWithContext(ctx context.Context, correlationId string, items []interface{}) error {
+	if c.path == "" {
+		return errors.NewConfigError(correlationId, "NO_PATH", "Data file path is not set")
+	}
+
+	progress := &PersisterProgress{Operation: PersisterOperationSave}
+	if c.path == StdioPath {
+		return c.SaveTo(correlationId, c.stdout(), items)
+	}
+
+	// Items are written into a temporary file which replaces the data file when all items are written
+	if err := c.createDir(correlationId, c.path); err != nil {
 		return err
 	}
-	werr := ioutil.WriteFile(c.path, ([]byte)(json), 0777)
-	if werr != nil {
-		err := errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+c.path).WithCause(werr)
+	file, err := c.createTempFile(correlationId)
+	if err != nil {
 		return err
 	}
+	tempPath := file.Name()
+	err = c.applyPermissions(correlationId, file)
+	if err == nil {
+		err = c.saveTo(ctx, correlationId, file, items, progress)
+	}
+	if err == nil && c.Durability == DurabilityFsync {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, c.path)
+	}
+	if err == nil && c.Durability == DurabilityFsync {
+		syncDir(c.path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		if _, ok := err.(*errors.ApplicationError); ok {
+			return err
+		}
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+c.path).WithCause(err)
+	}
+
+	progress.Done = true
+	c.reportProgress(correlationId, progress, true)
+	return nil
+}
+
+// Saves data items as JSON array to a writer, for instance a socket, a pipe,
+// an HTTP response or a compressing writer. The writer is not closed.
+// Parameters:
+//   - correlationId string
+//   transaction id to trace execution through call chain.
+//   - writer io.Writer
+//   a writer of JSON data
+//   - items []interface[]
+//   list of data items to save
+//  Retruns error
+//  error or nil for success.
+func (c *JsonFilePersister) SaveTo(correlationId string, writer io.Writer, items []interface{}) error {
+	progress := &PersisterProgress{Operation: PersisterOperationSave}
+	err := c.saveTo(context.Background(), correlationId, writer, items, progress)
+	if err != nil {
+		if _, ok := err.(*errors.ApplicationError); ok {
+			return err
+		}
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data items").WithCause(err)
+	}
+
+	progress.Done = true
+	c.reportProgress(correlationId, progress, true)
 	return nil
 }
+
+// Writes items through a buffer of WriteBufferSize in chunks of ChunkSize and flushes it
+func (c *JsonFilePersister) saveTo(ctx context.Context, correlationId string, writer io.Writer,
+	items []interface{}, progress *PersisterProgress) error {
+	bufferSize := c.WriteBufferSize
+	if bufferSize <= 0 {
+		bufferSize = 64 * 1024
+	}
+	counter := &countingWriter{writer: writer}
+	var output io.Writer = counter
+	if c.ChunkSize > 0 {
+		output = &chunkedWriter{writer: counter, size: c.ChunkSize}
+	}
+	buffer := bufio.NewWriterSize(output, bufferSize)
+	err := c.writeItems(ctx, correlationId, buffer, items, progress)
+	if err == nil {
+		err = buffer.Flush()
+	}
+	progress.Bytes = counter.count
+	return err
+}
+
+// Writer that splits writes into chunks of limited size
+type chunkedWriter struct {
+	writer io.Writer
+	size   int
+}
+
+func (c *chunkedWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > c.size {
+			chunk = chunk[:c.size]
+		}
+		written, err := c.writer.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// Encodes items one by one into the writer
+func (c *JsonFilePersister) writeItems(ctx context.Context, correlationId string, buffer *bufio.Writer,
+	items []interface{}, progress *PersisterProgress) error {
+	if items == nil {
+		return checkCancelled(ctx, correlationId)
+	}
+
+	separator := ","
+	if c.Indent != "" {
+		separator = ",\n" + c.Indent
+		buffer.WriteString("[\n" + c.Indent)
+	} else {
+		buffer.WriteByte('[')
+	}
+	value := &bytes.Buffer{}
+	encoder := json.NewEncoder(value)
+	encoder.SetEscapeHTML(!c.DisableHtmlEscape)
+	encoder.SetIndent(c.Indent, c.Indent)
+	for i, item := range items {
+		if err := checkCancelled(ctx, correlationId); err != nil {
+			return err
+		}
+		value.Reset()
+		if jsonerr := encoder.Encode(item); jsonerr != nil {
+			return errors.NewInternalError(correlationId, "CAN'T_CONVERT", "Failed convert to JSON").WithCause(jsonerr)
+		}
+		// Encoder ends every value with a new line
+		value.Truncate(value.Len() - 1)
+		if i > 0 {
+			buffer.WriteString(separator)
+		}
+		if _, err := buffer.Write(value.Bytes()); err != nil {
+			return err
+		}
+		progress.Items++
+		progress.Bytes += int64(value.Len()) + 1
+		c.reportProgress(correlationId, progress, false)
+	}
+	if c.Indent != "" {
+		buffer.WriteString("\n]")
+	} else {
+		buffer.WriteByte(']')
+	}
+	return checkCancelled(ctx, correlationId)
+}
@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"encoding/json"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-components-go/cache"
+)
+
+/*
+Persistence component that loads and saves data items as one JSON value
+in a key-value store exposed as ICache, for instance Redis or Memcached caches,
+so memory persistence can keep items in shared key-value stores.
+
+The stores can evict values, so they fit data that can be restored,
+or stores configured to keep values without eviction.
+All items are loaded when the persistence is opened. To keep every item under its own key
+and load items on first access use LazyKeyValuePersistence.
+
+Example
+
+  persister := NewKeyValuePersister(redisCache, "orders")
+  persistence := NewIdentifiableMemoryPersistence(reflect.TypeOf(Order{}))
+  persistence.Loader = persister
+  persistence.Saver = persister
+  persistence.Open("123")
+*/
+// implements ILoader, ISaver
+type KeyValuePersister struct {
+	// Store that keeps the items
+	Cache cache.ICache
+	// Key of the value with items
+	Key string
+	// Timeout of the stored value in milliseconds, 0 to use the store default
+	Timeout int64
+}
+
+// Creates a new instance of the persister.
+// Parameters:
+//   - store cache.ICache
+//   a key-value store that keeps the items
+//   - key string
+//   a key of the value with items
+// Returns *KeyValuePersister
+func NewKeyValuePersister(store cache.ICache, key string) *KeyValuePersister {
+	return &KeyValuePersister{
+		Cache: store,
+		Key:   key,
+	}
+}
+
+// Loads data items from the store.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items as maps, nil when the key is not set, or ConnectionError when the store fails.
+func (c *KeyValuePersister) Load(correlationId string) ([]interface{}, error) {
+	if c.Cache == nil {
+		return nil, errors.NewConfigError(correlationId, "NO_STORE", "Key-value store is not set")
+	}
+	value, err := c.Cache.Retrieve(correlationId, c.Key)
+	if err != nil {
+		return nil, errors.NewConnectionError(correlationId, "READ_FAILED", "Failed to read items by key "+c.Key).
+			WithDetails("key", c.Key).WithCause(err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	// Stores keep values as they are or as serialized strings
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		if data, err = json.Marshal(v); err != nil {
+			return nil, errors.NewBadRequestError(correlationId, "PARSE_FAILED", "Failed to parse items by key "+c.Key).
+				WithDetails("key", c.Key).WithCause(err)
+		}
+	}
+	var items []interface{}
+	if err = json.Unmarshal(data, &items); err != nil {
+		return nil, errors.NewBadRequestError(correlationId, "PARSE_FAILED", "Failed to parse items by key "+c.Key).
+			WithDetails("key", c.Key).WithCause(err)
+	}
+	return items, nil
+}
+
+// Saves data items to the store as one JSON string.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface{}
+//   list of data items to save
+// Returns error
+// ConnectionError when the store fails.
+func (c *KeyValuePersister) Save(correlationId string, items []interface{}) error {
+	if c.Cache == nil {
+		return errors.NewConfigError(correlationId, "NO_STORE", "Key-value store is not set")
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return errors.NewBadRequestError(correlationId, "INVALID_ITEM", "Failed to serialize items").
+			WithDetails("key", c.Key).WithCause(err)
+	}
+	if _, err = c.Cache.Store(correlationId, c.Key, string(data), c.Timeout); err != nil {
+		return errors.NewConnectionError(correlationId, "WRITE_FAILED", "Failed to write items by key "+c.Key).
+			WithDetails("key", c.Key).WithCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+package persistence
+
+import (
+	"container/list"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+	"github.com/pip-services3-go/pip-services3-components-go/cache"
+	"github.com/pip-services3-go/pip-services3-components-go/log"
+)
+
+/*
+Identifiable persistence component that keeps every item under its own key in a key-value store
+exposed as ICache and loads items on first access instead of loading all items when it is opened,
+so the startup cost doesn't depend on the size of the dataset. Recently used items are kept
+in memory and the least recently used items are evicted when there are more than MaxCachedItems.
+Changes are written to the store right away.
+
+Key-value stores don't list their keys, so items are accessed only by their ids.
+For queries by filters use IdentifiableMemoryPersistence with KeyValuePersister.
+
+Configuration parameters
+
+- options:
+    - prefix:              Prefix of the keys of items (default: lowercase name of the item type)
+    - max_cached_items:    Maximum number of items kept in memory (default: 1000)
+    - timeout:             Timeout of stored items in milliseconds, 0 to use the store default (default: 0)
+
+References
+
+- *:logger:*:*:1.0    ILogger components to pass log messages
+- *:cache:*:*:1.0     ICache component with key-value store, used when Cache is not set
+
+Example
+
+    persistence := NewLazyKeyValuePersistence(reflect.TypeOf(MyData{}), redisCache)
+    persistence.Open("123") // Nothing is loaded
+
+    item, err := persistence.GetOneById("123", "1") // Loads the item by key mydata:1
+*/
+// implements IConfigurable, IReferenceable, IOpenable
+type LazyKeyValuePersistence struct {
+	// Type of contained data
+	Prototype reflect.Type
+	// Store that keeps the items
+	Cache cache.ICache
+	// Prefix of the keys of items
+	Prefix string
+	// Maximum number of items kept in memory
+	MaxCachedItems int
+	// Timeout of stored items in milliseconds, 0 to use the store default
+	Timeout int64
+	Logger  *log.CompositeLogger
+
+	lock   sync.Mutex
+	recent *list.List
+	cached map[string]*list.Element
+	opened bool
+}
+
+// Item kept in memory
+type lazyCachedItem struct {
+	key  string
+	item interface{}
+}
+
+// Creates a new instance of the persistence.
+// Parameters:
+//   - prototype reflect.Type
+//   type of contained data, panics when it is nil
+//   - store cache.ICache
+//   (optional) a key-value store that keeps the items, when nil it is resolved from references
+// Returns *LazyKeyValuePersistence
+func NewLazyKeyValuePersistence(prototype reflect.Type, store cache.ICache) *LazyKeyValuePersistence {
+	checkPrototype(prototype)
+	c := &LazyKeyValuePersistence{
+		Prototype:      prototype,
+		Cache:          store,
+		MaxCachedItems: 1000,
+		Logger:         log.NewCompositeLogger(),
+		recent:         list.New(),
+		cached:         map[string]*list.Element{},
+	}
+	c.Prefix = "items"
+	if typ := itemStructType(prototype); typ != nil && typ.Name() != "" {
+		c.Prefix = strings.ToLower(typ.Name())
+	}
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//   - config  *config.ConfigParams
+//   configuration parameters to be set.
+func (c *LazyKeyValuePersistence) Configure(config *config.ConfigParams) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.Prefix = config.GetAsStringWithDefault("options.prefix", c.Prefix)
+	c.MaxCachedItems = config.GetAsIntegerWithDefault("options.max_cached_items", c.MaxCachedItems)
+	c.Timeout = config.GetAsLongWithDefault("options.timeout", c.Timeout)
+}
+
+// Sets references to dependent components.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the component dependencies.
+func (c *LazyKeyValuePersistence) SetReferences(references refer.IReferences) {
+	c.Logger.SetReferences(references)
+
+	if c.Cache == nil {
+		if store, ok := references.GetOneOptional(refer.NewDescriptor("*", "cache", "*", "*", "1.0")).(cache.ICache); ok {
+			c.Cache = store
+		}
+	}
+}
+
+// Checks if the component is opened.
+// Returns bool
+// true if the component has been opened and false otherwise.
+func (c *LazyKeyValuePersistence) IsOpen() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.opened
+}
+
+// Opens the component. Items are not loaded until they are accessed.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+// ReferenceError when the key-value store is not set or referenced.
+func (c *LazyKeyValuePersistence) Open(correlationId string) error {
+	if c.Cache == nil {
+		return refer.NewReferenceError(correlationId, refer.NewDescriptor("*", "cache", "*", "*", "1.0"))
+	}
+
+	c.lock.Lock()
+	c.opened = true
+	c.lock.Unlock()
+
+	c.Logger.Trace(correlationId, "Opened lazy persistence with items by keys %s:<id>", c.Prefix)
+	return nil
+}
+
+// Closes the component and evicts all items kept in memory.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+func (c *LazyKeyValuePersistence) Close(correlationId string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.recent.Init()
+	c.cached = map[string]*list.Element{}
+	c.opened = false
+	return nil
+}
+
+// Gets the number of items kept in memory.
+// Returns int
+func (c *LazyKeyValuePersistence) CachedCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.recent.Len()
+}
+
+// Gets the key of an item in the store
+func (c *LazyKeyValuePersistence) itemKey(id interface{}) string {
+	return c.Prefix + ":" + convert.StringConverter.ToString(id)
+}
+
+// Gets an item kept in memory and marks it as recently used
+func (c *LazyKeyValuePersistence) getCached(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.cached[key]; ok {
+		c.recent.MoveToFront(element)
+		return element.Value.(*lazyCachedItem).item, true
+	}
+	return nil, false
+}
+
+// Keeps an item in memory and evicts the least recently used items over the limit
+func (c *LazyKeyValuePersistence) putCached(key string, item interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.cached[key]; ok {
+		element.Value.(*lazyCachedItem).item = item
+		c.recent.MoveToFront(element)
+	} else {
+		c.cached[key] = c.recent.PushFront(&lazyCachedItem{key: key, item: item})
+	}
+	for c.MaxCachedItems > 0 && c.recent.Len() > c.MaxCachedItems {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.cached, oldest.Value.(*lazyCachedItem).key)
+	}
+}
+
+// Removes an item from memory
+func (c *LazyKeyValuePersistence) removeCached(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.cached[key]; ok {
+		c.recent.Remove(element)
+		delete(c.cached, key)
+	}
+}
+
+// Reads an item from memory or from the store, nil when it is missing
+func (c *LazyKeyValuePersistence) load(correlationId string, key string) (interface{}, error) {
+	if item, ok := c.getCached(key); ok {
+		return item, nil
+	}
+	if c.Cache == nil {
+		return nil, errors.NewConfigError(correlationId, "NO_STORE", "Key-value store is not set")
+	}
+
+	value, err := c.Cache.Retrieve(correlationId, key)
+	if err != nil {
+		return nil, errors.NewConnectionError(correlationId, "READ_FAILED", "Failed to read item by key "+key).
+			WithDetails("key", key).WithCause(err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	// Stores keep values as they are or as serialized strings
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		data, err = json.Marshal(v)
+	}
+	prototype := c.Prototype
+	if prototype.Kind() == reflect.Ptr {
+		prototype = prototype.Elem()
+	}
+	result := reflect.New(prototype)
+	if err == nil {
+		err = json.Unmarshal(data, result.Interface())
+	}
+	if err != nil {
+		return nil, errors.NewBadRequestError(correlationId, "PARSE_FAILED", "Failed to parse item by key "+key).
+			WithDetails("key", key).WithCause(err)
+	}
+
+	item := result.Elem().Interface()
+	c.putCached(key, item)
+	c.Logger.Trace(correlationId, "Loaded item by key %s", key)
+	return item, nil
+}
+
+// Writes an item to the store and keeps it in memory
+func (c *LazyKeyValuePersistence) store(correlationId string, key string, item interface{}) error {
+	if c.Cache == nil {
+		return errors.NewConfigError(correlationId, "NO_STORE", "Key-value store is not set")
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return errors.NewBadRequestError(correlationId, "INVALID_ITEM", "Failed to serialize item").
+			WithDetails("key", key).WithCause(err)
+	}
+	if _, err = c.Cache.Store(correlationId, key, string(data), c.Timeout); err != nil {
+		// The stored value is unknown, so it is read again on the next access
+		c.removeCached(key)
+		return errors.NewConnectionError(correlationId, "WRITE_FAILED", "Failed to write item by key "+key).
+			WithDetails("key", key).WithCause(err)
+	}
+	c.putCached(key, item)
+	return nil
+}
+
+// Gets a data item by its unique id, loading it from the store on first access.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be retrieved.
+// Returns interface{}, error
+// data item or nil when it is not found.
+func (c *LazyKeyValuePersistence) GetOneById(correlationId string, id interface{}) (interface{}, error) {
+	item, err := c.load(correlationId, c.itemKey(id))
+	if item == nil || err != nil {
+		return nil, err
+	}
+	return CloneObjectForResult(item, c.Prototype), nil
+}
+
+// Creates a data item, generating its id when it is empty.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be created.
+// Returns interface{}, error
+// created item or error.
+func (c *LazyKeyValuePersistence) Create(correlationId string, item interface{}) (interface{}, error) {
+	newItem := CloneObject(item, c.Prototype)
+	if newItem == nil {
+		return nil, newInvalidItemError(correlationId)
+	}
+	GenerateObjectId(&newItem)
+	id := GetObjectId(newItem)
+	if err := c.store(correlationId, c.itemKey(id), newItem); err != nil {
+		return nil, err
+	}
+	c.Logger.Trace(correlationId, "Created item %s", id)
+	return CloneObjectForResult(newItem, c.Prototype), nil
+}
+
+// Sets a data item. If the item exists it is updated, otherwise it is created.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be set.
+// Returns interface{}, error
+// set item or error.
+func (c *LazyKeyValuePersistence) Set(correlationId string, item interface{}) (interface{}, error) {
+	newItem, err := c.Create(correlationId, item)
+	if err == nil {
+		c.Logger.Trace(correlationId, "Set item %s", GetObjectId(newItem))
+	}
+	return newItem, err
+}
+
+// Updates a data item if it exists in the store.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be updated.
+// Returns interface{}, error
+// updated item or nil when it is not found.
+func (c *LazyKeyValuePersistence) Update(correlationId string, item interface{}) (interface{}, error) {
+	id := GetObjectId(item)
+	key := c.itemKey(id)
+	current, err := c.load(correlationId, key)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		c.Logger.Trace(correlationId, "Item %s was not found", id)
+		return nil, nil
+	}
+	newItem := CloneObject(item, c.Prototype)
+	if newItem == nil {
+		return nil, newInvalidItemError(correlationId)
+	}
+	if err = c.store(correlationId, key, newItem); err != nil {
+		return nil, err
+	}
+	c.Logger.Trace(correlationId, "Updated item %s", id)
+	return CloneObjectForResult(newItem, c.Prototype), nil
+}
+
+// Deletes a data item by its unique id.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the item to be deleted
+// Returns interface{}, error
+// deleted item or nil when it is not found.
+func (c *LazyKeyValuePersistence) DeleteById(correlationId string, id interface{}) (interface{}, error) {
+	key := c.itemKey(id)
+	item, err := c.load(correlationId, key)
+	if item == nil || err != nil {
+		return nil, err
+	}
+	if err = c.Cache.Remove(correlationId, key); err != nil {
+		return nil, errors.NewConnectionError(correlationId, "WRITE_FAILED", "Failed to remove item by key "+key).
+			WithDetails("key", key).WithCause(err)
+	}
+	c.removeCached(key)
+	c.Logger.Trace(correlationId, "Deleted item %s", id)
+	return CloneObjectForResult(item, c.Prototype), nil
+}
@@ -0,0 +1,225 @@
+package persistence
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Lease is a named lease stored by LeasePersistence.
+type Lease struct {
+	// Name of the lease
+	Id string `json:"id"`
+	// Owner who holds the lease
+	Owner string `json:"owner"`
+	// Time when the lease was acquired
+	AcquireTime time.Time `json:"acquire_time"`
+	// Time when the lease expires unless it is renewed
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+/*
+LeasePersistence stores named leases with owners and expiration times in a JSON file.
+It gives simple coordination for file-backed deployments, for instance, to elect
+an instance that runs scheduled jobs. Expired leases can be acquired by other owners.
+
+Before every operation leases are reloaded if the file was changed by another instance.
+When a lock component is referenced, operations also run under the distributed lock.
+
+Configuration parameters
+
+- path:                    path to the file where leases are stored
+
+References
+
+- *:lock:*:*:1.0           (optional) ILock component to change leases under a distributed lock
+
+Example
+
+    leases := NewLeasePersistence("./data/leases.json")
+    leases.Open("123")
+
+    lease, err := leases.AcquireLease("123", "scheduler", "instance1", 30000)
+    if err == nil {
+        lease, err = leases.RenewLease("123", "scheduler", "instance1", 30000)
+        ...
+        leases.ReleaseLease("123", "scheduler", "instance1")
+    }
+*/
+type LeasePersistence struct {
+	IdentifiableFilePersistence
+}
+
+// Creates a new persistence of leases.
+// Parameters:
+//   - path string
+//   a path to the file where leases are stored
+// Returns *LeasePersistence
+func NewLeasePersistence(path string) *LeasePersistence {
+	prototype := reflect.TypeOf(Lease{})
+	return &LeasePersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+	}
+}
+
+func newLeaseHeldError(correlationId string, lease Lease) error {
+	return errors.NewConflictError(correlationId, "LEASE_HELD", "Lease "+lease.Id+" is held by "+lease.Owner).
+		WithDetails("name", lease.Id).
+		WithDetails("owner", lease.Owner)
+}
+
+// Atomically changes a lease and saves the change.
+// The update function gets the current lease, which is nil if it's missing or expired,
+// and returns a new lease, or nil to remove the lease.
+func (c *LeasePersistence) change(correlationId string, name string,
+	update func(current *Lease, now time.Time) (*Lease, error)) (result *Lease, err error) {
+	action := func() error {
+		c.lock()
+
+		if err = c.checkWritable(correlationId); err != nil {
+			c.unlock()
+			return err
+		}
+
+		now := c.Clock.Now().UTC()
+		var oldItem interface{}
+		var current *Lease
+		index := c.GetIndexById(name)
+		if index >= 0 {
+			oldItem = c.Items[index]
+			if lease, ok := oldItem.(Lease); ok && lease.ExpireTime.After(now) {
+				current = &lease
+			}
+		}
+
+		if result, err = update(current, now); err != nil {
+			c.unlock()
+			return err
+		}
+
+		switch {
+		case result != nil && index >= 0:
+			var newItem interface{} = *result
+			c.notifyChange(oldItem, newItem)
+			c.Items[index] = newItem
+		case result != nil:
+			var newItem interface{} = *result
+			c.notifyChange(nil, newItem)
+			c.Items = append(c.Items, newItem)
+		case index >= 0:
+			c.notifyChange(oldItem, nil)
+			c.Items = append(c.Items[:index], c.Items[index+1:]...)
+		}
+
+		c.unlock()
+		return c.save(correlationId)
+	}
+
+	if c.locker != nil {
+		err = c.WithLock(correlationId, "lease:"+name, action)
+	} else if err = c.reloadIfModified(correlationId); err == nil {
+		err = action()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Acquires a lease for the owner. The owner that already holds the lease extends it.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the lease
+//   - owner string
+//   an owner who acquires the lease
+//   - ttl int64
+//   time to live of the lease in milliseconds
+// Returns *Lease, error
+// the acquired lease or ConflictError if the lease is held by another owner.
+func (c *LeasePersistence) AcquireLease(correlationId string, name string, owner string, ttl int64) (*Lease, error) {
+	return c.change(correlationId, name, func(current *Lease, now time.Time) (*Lease, error) {
+		if current != nil && current.Owner != owner {
+			return nil, newLeaseHeldError(correlationId, *current)
+		}
+		lease := &Lease{Id: name, Owner: owner, AcquireTime: now}
+		if current != nil {
+			lease.AcquireTime = current.AcquireTime
+		}
+		lease.ExpireTime = now.Add(time.Duration(ttl) * time.Millisecond)
+		return lease, nil
+	})
+}
+
+// Renews a lease held by the owner.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the lease
+//   - owner string
+//   an owner who holds the lease
+//   - ttl int64
+//   new time to live of the lease in milliseconds
+// Returns *Lease, error
+// the renewed lease or ConflictError if the lease expired or is held by another owner.
+func (c *LeasePersistence) RenewLease(correlationId string, name string, owner string, ttl int64) (*Lease, error) {
+	return c.change(correlationId, name, func(current *Lease, now time.Time) (*Lease, error) {
+		if current == nil {
+			return nil, errors.NewConflictError(correlationId, "LEASE_EXPIRED", "Lease "+name+" is expired").
+				WithDetails("name", name)
+		}
+		if current.Owner != owner {
+			return nil, newLeaseHeldError(correlationId, *current)
+		}
+		lease := *current
+		lease.ExpireTime = now.Add(time.Duration(ttl) * time.Millisecond)
+		return &lease, nil
+	})
+}
+
+// Releases a lease held by the owner. Missing and expired leases are ignored.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the lease
+//   - owner string
+//   an owner who holds the lease
+// Returns error
+// ConflictError if the lease is held by another owner or nil for success.
+func (c *LeasePersistence) ReleaseLease(correlationId string, name string, owner string) error {
+	_, err := c.change(correlationId, name, func(current *Lease, now time.Time) (*Lease, error) {
+		if current != nil && current.Owner != owner {
+			return nil, newLeaseHeldError(correlationId, *current)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// Gets an active lease by its name.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the lease
+// Returns *Lease, error
+// the lease or nil if it's missing or expired.
+func (c *LeasePersistence) GetLease(correlationId string, name string) (*Lease, error) {
+	if err := c.reloadIfModified(correlationId); err != nil {
+		return nil, err
+	}
+
+	c.rlock()
+	defer c.runlock()
+
+	if index := c.GetIndexById(name); index >= 0 {
+		if lease, ok := c.Items[index].(Lease); ok && lease.ExpireTime.After(c.Clock.Now()) {
+			return &lease, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Policies for loaded data items that cannot be converted to the prototype
+const (
+	// Loading stops with BadRequestError and no items are changed
+	LoadErrorsAbort = "abort"
+	// Invalid items are skipped with a warning in the log
+	LoadErrorsSkip = "skip"
+	// Valid items are loaded and the persistence is opened,
+	// but Open returns BadRequestError with the list of skipped items
+	LoadErrorsFailOpen = "fail_open"
+)
+
+// Creates an error for a loaded data item that cannot be converted to the prototype.
+// Parameters:
+//   - correlationId string
+//   transaction id to trace execution through call chain.
+//   - index int
+//   an index of the item in the loaded data
+//   - cause error
+//   an error of the conversion
+// Returns error
+// BadRequestError with INVALID_DATA_ITEM code.
+func newInvalidDataItemError(correlationId string, index int, cause error) error {
+	return errors.NewBadRequestError(correlationId, "INVALID_DATA_ITEM",
+		fmt.Sprintf("Data item %d cannot be converted to persistence data type", index)).
+		WithDetails("index", index).
+		WithCause(cause)
+}
+
+// Creates an error for loaded data items skipped by fail_open policy
+func newInvalidDataItemsError(correlationId string, indexes []int) error {
+	return errors.NewBadRequestError(correlationId, "INVALID_DATA_ITEMS",
+		fmt.Sprintf("%d data items cannot be converted to persistence data type", len(indexes))).
+		WithDetails("indexes", indexes)
+}
+
+// Checks if the error was returned by load under fail_open policy
+func isInvalidDataItemsError(err error) bool {
+	appErr, ok := err.(*errors.ApplicationError)
+	return ok && appErr.Code == "INVALID_DATA_ITEMS"
+}
+
+// Handles a loaded data item that cannot be converted to the prototype
+// according to LoadErrors policy.
+// Returns error to abort loading or nil to skip the item.
+func (c *MemoryPersistence) rejectDataItem(correlationId string, index int, cause error, rejected *[]int) error {
+	err := newInvalidDataItemError(correlationId, index, cause)
+	switch c.LoadErrors {
+	case LoadErrorsSkip, LoadErrorsFailOpen:
+		c.Logger.Warn(correlationId, "Skipped data item %d: %v", index, cause)
+		*rejected = append(*rejected, index)
+		return nil
+	default:
+		return err
+	}
+}
@@ -0,0 +1,77 @@
+package persistence
+
+// Modes that define how loaded items are combined with items in memory
+const (
+	// Loaded items replace all items in memory
+	LoadModeReplace = "replace"
+	// Loaded items are merged with items in memory by ids, the newest timestamp wins
+	LoadModeMerge = "merge"
+)
+
+// Merges loaded items with current items by ids. Must be called under lock.
+// An item in memory is kept when its timestamp in TimestampField is newer than the loaded one,
+// and items that exist only in memory are kept, so recent writes are not lost.
+// Parameters:
+//   - loaded []interface{}
+//   freshly loaded items
+// Returns []interface{}
+// merged items.
+func (c *MemoryPersistence) mergeLoaded(loaded []interface{}) []interface{} {
+	current := make(map[string]interface{}, len(c.Items))
+	for _, item := range c.Items {
+		if id := c.ids.get(item); id != nil {
+			current[toIdKey(id)] = item
+		}
+	}
+
+	merged := make([]interface{}, 0, len(loaded)+len(c.Items))
+	for _, item := range loaded {
+		id := c.ids.get(item)
+		if id == nil {
+			merged = append(merged, item)
+			continue
+		}
+		key := toIdKey(id)
+		if existing, ok := current[key]; ok {
+			delete(current, key)
+			if c.isNewerItem(existing, item) {
+				item = existing
+			}
+		}
+		merged = append(merged, item)
+	}
+
+	// Keep items that were created in memory and are not saved yet
+	for _, item := range c.Items {
+		if id := c.ids.get(item); id != nil {
+			if _, ok := current[toIdKey(id)]; ok {
+				merged = append(merged, item)
+			}
+		}
+	}
+	return merged
+}
+
+// Checks if the item has a newer timestamp than another one
+func (c *MemoryPersistence) isNewerItem(item interface{}, other interface{}) bool {
+	timestamp, ok := c.GetItemTimestamp(item)
+	if !ok {
+		return false
+	}
+	otherTimestamp, ok := c.GetItemTimestamp(other)
+	return !ok || timestamp.After(otherTimestamp)
+}
+
+// Reloads items from the loader. In merge load mode loaded items are
+// merged with items in memory, so externally edited data can be picked up
+// without losing recent writes.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *MemoryPersistence) Reload(correlationId string) error {
+	c.lock()
+	defer c.unlock()
+
+	return c.load(correlationId)
+}
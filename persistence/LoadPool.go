@@ -0,0 +1,122 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+)
+
+// Maximum capacity of buffers returned to the pool, larger buffers are left to the garbage collector
+const maxPooledBufferSize = 1024 * 1024
+
+/*
+Statistics of buffers reused while converting loaded items.
+The difference between Gets and Allocations is the number of reused buffers.
+*/
+type LoadPoolStats struct {
+	// Number of buffers taken from the pool
+	Gets int64
+	// Number of buffers allocated because the pool was empty
+	Allocations int64
+	// Number of buffers returned to the pool
+	Puts int64
+}
+
+/*
+Pool of buffers with counters of gets, allocations and puts.
+*/
+type bufferPool struct {
+	pool        sync.Pool
+	gets        int64
+	allocations int64
+	puts        int64
+}
+
+func newBufferPool() *bufferPool {
+	c := &bufferPool{}
+	c.pool.New = func() interface{} {
+		atomic.AddInt64(&c.allocations, 1)
+		return &bytes.Buffer{}
+	}
+	return c
+}
+
+func (c *bufferPool) get() *bytes.Buffer {
+	atomic.AddInt64(&c.gets, 1)
+	buffer := c.pool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+func (c *bufferPool) put(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBufferSize {
+		return
+	}
+	atomic.AddInt64(&c.puts, 1)
+	c.pool.Put(buffer)
+}
+
+func (c *bufferPool) stats() LoadPoolStats {
+	return LoadPoolStats{
+		Gets:        atomic.LoadInt64(&c.gets),
+		Allocations: atomic.LoadInt64(&c.allocations),
+		Puts:        atomic.LoadInt64(&c.puts),
+	}
+}
+
+// Buffers shared by all persistences to convert loaded items into their types
+var loadBuffers = newBufferPool()
+
+// Converts a loaded item into the value through JSON using a pooled buffer
+func convertLoadedItem(item map[string]interface{}, value interface{}) error {
+	buffer := loadBuffers.get()
+	defer loadBuffers.put(buffer)
+
+	if err := json.NewEncoder(buffer).Encode(item); err != nil {
+		return err
+	}
+	return json.Unmarshal(buffer.Bytes(), value)
+}
+
+// Converts a loaded item into a map by its serialized field names.
+// Maps are converted as they are, while structs are converted through JSON,
+// so json tags and fields with their own encoding like time.Time are kept.
+// Returns error if the item is not an object.
+func toLoadedMap(item interface{}) (map[string]interface{}, error) {
+	value := reflect.ValueOf(item)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, fmt.Errorf("data item is nil")
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Map:
+		return convert.MapConverter.ToMap(item), nil
+	case reflect.Struct:
+		buffer := loadBuffers.get()
+		defer loadBuffers.put(buffer)
+
+		if err := json.NewEncoder(buffer).Encode(item); err != nil {
+			return nil, err
+		}
+		result := map[string]interface{}{}
+		if err := json.Unmarshal(buffer.Bytes(), &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("data item of type %T is not an object", item)
+	}
+}
+
+// Gets statistics of buffers reused to convert loaded items since the process start.
+// Returns LoadPoolStats
+func GetLoadPoolStats() LoadPoolStats {
+	return loadBuffers.stats()
+}
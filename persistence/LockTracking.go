@@ -0,0 +1,145 @@
+package persistence
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-components-go/log"
+)
+
+/*
+Tracking of the persistence lock that detects long locks to diagnose stuck services.
+When a hold threshold is set, it logs a warning with the stack of the goroutine that took
+the write lock when the lock is held longer than the threshold or when other goroutines
+wait for the lock longer than the threshold. Only locks taken by the persistence itself
+are tracked, locks taken directly through MemoryPersistence.Lock are not.
+*/
+type lockTracker struct {
+	// Hold threshold in nanoseconds, 0 to disable tracking
+	threshold int64
+	info      sync.Mutex
+	logger    *log.CompositeLogger
+	holder    []uintptr
+	lockedAt  time.Time
+}
+
+// Sets a hold threshold in milliseconds, 0 to disable tracking, and a logger for warnings
+func (m *lockTracker) track(threshold int64, logger *log.CompositeLogger) {
+	m.info.Lock()
+	m.logger = logger
+	m.info.Unlock()
+	atomic.StoreInt64(&m.threshold, int64(time.Duration(threshold)*time.Millisecond))
+}
+
+// Gets the hold threshold in milliseconds
+func (m *lockTracker) holdThreshold() int64 {
+	return int64(time.Duration(atomic.LoadInt64(&m.threshold)) / time.Millisecond)
+}
+
+// Locks for writing and remembers the caller stack
+func (m *lockTracker) lock(mutex *sync.RWMutex) {
+	threshold := time.Duration(atomic.LoadInt64(&m.threshold))
+	if threshold <= 0 {
+		mutex.Lock()
+		return
+	}
+
+	timer := time.AfterFunc(threshold, func() { m.reportWaiting("write", threshold) })
+	mutex.Lock()
+	timer.Stop()
+
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	m.info.Lock()
+	m.holder = pcs[:n]
+	m.lockedAt = time.Now()
+	m.info.Unlock()
+}
+
+// Unlocks for writing and reports the hold time when it exceeds the threshold
+func (m *lockTracker) unlock(mutex *sync.RWMutex) {
+	m.info.Lock()
+	holder, lockedAt, logger := m.holder, m.lockedAt, m.logger
+	m.holder, m.lockedAt = nil, time.Time{}
+	m.info.Unlock()
+	mutex.Unlock()
+
+	threshold := time.Duration(atomic.LoadInt64(&m.threshold))
+	if threshold <= 0 || lockedAt.IsZero() || logger == nil {
+		return
+	}
+	if held := time.Since(lockedAt); held > threshold {
+		logger.Warn("", "Write lock was held for %v by %s", held, formatLockStack(holder))
+	}
+}
+
+// Locks for reading and reports when readers wait for the write lock longer than the threshold
+func (m *lockTracker) rlock(mutex *sync.RWMutex) {
+	threshold := time.Duration(atomic.LoadInt64(&m.threshold))
+	if threshold <= 0 {
+		mutex.RLock()
+		return
+	}
+
+	timer := time.AfterFunc(threshold, func() { m.reportWaiting("read", threshold) })
+	mutex.RLock()
+	timer.Stop()
+}
+
+// Locks the persistence for writing
+func (c *MemoryPersistence) lock() {
+	c.lockTracking.lock(&c.Lock)
+}
+
+// Unlocks the persistence for writing
+func (c *MemoryPersistence) unlock() {
+	c.lockTracking.unlock(&c.Lock)
+}
+
+// Locks the persistence for reading
+func (c *MemoryPersistence) rlock() {
+	c.lockTracking.rlock(&c.Lock)
+}
+
+// Unlocks the persistence for reading
+func (c *MemoryPersistence) runlock() {
+	c.Lock.RUnlock()
+}
+
+// Reports a lock that is still waited for after the threshold
+func (m *lockTracker) reportWaiting(kind string, threshold time.Duration) {
+	m.info.Lock()
+	holder, lockedAt, logger := m.holder, m.lockedAt, m.logger
+	m.info.Unlock()
+
+	if logger == nil {
+		return
+	}
+	if lockedAt.IsZero() {
+		logger.Warn("", "Waiting for %s lock longer than %v, the lock is held by readers or outside of the persistence", kind, threshold)
+		return
+	}
+	logger.Warn("", "Waiting for %s lock longer than %v, write lock is held for %v by %s",
+		kind, threshold, time.Since(lockedAt), formatLockStack(holder))
+}
+
+// Formats a stack of the lock holder starting from the function that took the lock
+func formatLockStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return "unknown caller"
+	}
+	lines := []string{}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		lines = append(lines, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+	return strings.Join(lines, "\n    ")
+}
@@ -0,0 +1,209 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Materialized view over items in MemoryPersistence. It keeps filtered,
+sorted and projected items and is updated incrementally on every change,
+so reading a view doesn't require scanning all items.
+When a callback of the view panics the view is marked as failed
+and is not maintained until it is rebuilt from all items.
+Views are rebuilt when items are loaded and when items changed directly in Items are saved.
+*/
+type materializedView struct {
+	ids        *idAccessor
+	filterFunc func(interface{}) bool
+	sortFunc   func(a, b interface{}) bool
+	selectFunc func(in interface{}) (out interface{})
+	sources    []interface{}
+	values     []interface{}
+	// Error of a callback that left the view outdated
+	err error
+}
+
+// Recalculates the view from all items. Must be called under write lock.
+// Panics in callbacks are returned as errors and mark the view as failed.
+func (c *MemoryPersistence) rebuildView(correlationId string, view *materializedView) (err error) {
+	sources := append(make([]interface{}, 0, len(c.Items)), c.Items...)
+	if view.filterFunc != nil {
+		sources, err = c.filterItems(correlationId, view.filterFunc)
+	}
+	if err == nil && view.sortFunc != nil {
+		err = c.sortItems(correlationId, sources, view.sortFunc)
+	}
+	values := append(make([]interface{}, 0, len(sources)), sources...)
+	if err == nil && view.selectFunc != nil {
+		err = c.selectItems(correlationId, values, view.selectFunc)
+	}
+	if err != nil {
+		view.err = err
+		return err
+	}
+	view.sources, view.values, view.err = sources, values, nil
+	return nil
+}
+
+// Removes an item from the view
+func (c *materializedView) remove(item interface{}) {
+	for i, source := range c.sources {
+		if c.ids.isSameItem(source, item) {
+			c.sources = append(c.sources[:i], c.sources[i+1:]...)
+			c.values = append(c.values[:i], c.values[i+1:]...)
+			return
+		}
+	}
+}
+
+// Inserts an item into the view keeping the sort order. Must be called under write lock.
+// Panics in callbacks are returned as errors and mark the view as failed.
+func (c *MemoryPersistence) insertIntoView(view *materializedView, item interface{}) (err error) {
+	index := len(view.sources)
+	defer func() {
+		if view.err = err; err != nil {
+			view.sources, view.values = nil, nil
+		}
+	}()
+	defer c.recoverCallback("", "view", &index, &err)
+
+	if view.filterFunc != nil && !view.filterFunc(item) {
+		return nil
+	}
+	if view.sortFunc != nil {
+		index = sort.Search(len(view.sources), func(i int) bool {
+			return view.sortFunc(item, view.sources[i])
+		})
+	}
+	value := item
+	if view.selectFunc != nil {
+		value = view.selectFunc(item)
+	}
+	view.sources = append(view.sources, nil)
+	copy(view.sources[index+1:], view.sources[index:])
+	view.sources[index] = item
+	view.values = append(view.values, nil)
+	copy(view.values[index+1:], view.values[index:])
+	view.values[index] = value
+	return nil
+}
+
+// isSameItem checks if two items represent the same record.
+// Items with ids are compared by ids, other items are compared by content.
+func (c idAccessor) isSameItem(item1 interface{}, item2 interface{}) bool {
+	id1 := c.get(item1)
+	id2 := c.get(item2)
+	if id1 != nil && id2 != nil {
+		return CompareValues(id1, id2)
+	}
+	return reflect.DeepEqual(item1, item2)
+}
+
+// Updates all registered views. Called under write lock.
+// Failed views are skipped until all items are reloaded or saved.
+func (c *MemoryPersistence) updateViews(oldItem interface{}, newItem interface{}) {
+	for _, view := range c.views {
+		if oldItem == nil && newItem == nil {
+			c.rebuildView("", view)
+			continue
+		}
+		if view.err != nil {
+			continue
+		}
+		if oldItem != nil {
+			view.remove(oldItem)
+		}
+		if newItem != nil {
+			c.insertIntoView(view, newItem)
+		}
+	}
+}
+
+// Registers a named view over items. The view is maintained on every change
+// and can be read by GetView without scanning all items.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a unique name of the view
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function to select items
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) sorting compare function func Less (a, b interface{}) bool  see sort.Interface Less function
+//   - selectFunc func(in interface{}) (out interface{})
+//   (optional) projection parameters
+// Returns error or nil for success, the view is not registered when its callbacks panic.
+func (c *MemoryPersistence) RegisterView(correlationId string, name string, filterFunc func(interface{}) bool,
+	sortFunc func(a, b interface{}) bool, selectFunc func(in interface{}) (out interface{})) error {
+	c.lock()
+	defer c.unlock()
+
+	if c.views == nil {
+		c.views = map[string]*materializedView{}
+		c.addChangeHandler(c.updateViews)
+	}
+
+	view := &materializedView{
+		ids:        &c.ids,
+		filterFunc: filterFunc,
+		sortFunc:   sortFunc,
+		selectFunc: selectFunc,
+	}
+	if err := c.rebuildView(correlationId, view); err != nil {
+		return err
+	}
+	c.views[name] = view
+
+	c.Logger.Trace(correlationId, "Registered view %s with %d items", name, len(view.values))
+	return nil
+}
+
+// Removes a previously registered view.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the view
+func (c *MemoryPersistence) UnregisterView(correlationId string, name string) {
+	c.lock()
+	defer c.unlock()
+
+	delete(c.views, name)
+	c.Logger.Trace(correlationId, "Unregistered view %s", name)
+}
+
+// Gets a page of items from a registered view.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the view
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+// Returns *cdata.DataPage, error
+// data page, NotFoundError if the view is not registered or InvalidStateError if its callbacks panicked.
+func (c *MemoryPersistence) GetView(correlationId string, name string, paging *cdata.PagingParams) (page *cdata.DataPage, err error) {
+	if err = c.checkPaging(correlationId, paging); err != nil {
+		return nil, err
+	}
+	c.rlock()
+	defer c.runlock()
+
+	view, ok := c.views[name]
+	if !ok {
+		return nil, errors.NewNotFoundError(correlationId, "VIEW_NOT_FOUND", "View "+name+" is not registered").
+			WithDetails("view", name)
+	}
+	if view.err != nil {
+		return nil, errors.NewInvalidStateError(correlationId, "VIEW_FAILED", "View "+name+" failed and is outdated").
+			WithDetails("view", name).WithCause(view.err)
+	}
+
+	page = c.extractPage(view.values, paging)
+	c.Logger.Trace(correlationId, "Retrieved %d items from view %s", len(page.Data), name)
+	return page, nil
+}
@@ -1,16 +1,17 @@
 package persistence
 
 import (
-	"encoding/json"
 	"math/rand"
 	"reflect"
-	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
 	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
 	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+	"github.com/pip-services3-go/pip-services3-components-go/lock"
 	"github.com/pip-services3-go/pip-services3-components-go/log"
 )
 
@@ -26,9 +27,72 @@ The component supports loading and saving items from another data source.
 That allows to use it as a base struct for file and other types
 of persistence components that cache all data in memory.
 
+Structs with Extra map[string]interface{} field (usually tagged json:"-") keep there
+loaded fields that are not defined in the struct. The fields are written back on save
+and can be used in filters like regular fields.
+
+Configuration parameters
+
+- item_type:               (optional) name of the item type registered in DefaultTypeRegistry
+- options:
+    - max_page_size:       Maximum number of items returned in a single page
+    - max_skip:            Maximum number of items to skip in page queries, larger skips fail with BadRequestError, 0 for unlimited (default: 0)
+    - max_take:            Maximum number of items to take in page queries, larger takes fail with BadRequestError, 0 for unlimited (default: 0)
+    - multiple_error:      Return ConflictError from GetOneByFilter when more than one item matches the filter (default: false)
+    - operation_timeout:   Timeout of loads, saves and filter scans in milliseconds, loaders and savers that don't return in time are abandoned, 0 for unlimited (default: 0)
+    - long_lock_threshold: Time in milliseconds after which locks held or waited for are logged as warnings with the stack of the holder, 0 to disable (default: 0)
+    - filter_parallelism:  Number of goroutines used to evaluate filters (default: 1)
+    - parallel_filter_threshold: Minimal number of items to evaluate filters in parallel (default: 10000)
+    - query_cache_size:    Maximum number of cached results of queries by FilterParams, 0 to disable (default: 0)
+    - read_snapshot:       Publish immutable snapshots of items for GetReadSnapshot that are read without locks (default: false)
+    - read_snapshot_interval: Minimal interval to refresh read snapshots in milliseconds, 0 to refresh on every change (default: 0)
+    - read_snapshot_indexes: Comma-separated fields indexed in read snapshots
+    - latitude_field:      Name of the item field with latitude for geospatial queries (default: Latitude)
+    - longitude_field:     Name of the item field with longitude for geospatial queries (default: Longitude)
+    - geo_index_precision: Geohash precision of the spatial index, 0 to disable (default: 0)
+    - timestamp_field:     Name of the item field with timestamp to partition items by time for date range queries and FilterParams queries with lower limits of the timestamp
+    - partition_period:    Period of time partitions in milliseconds (default: 86400000)
+    - persist_indexes:     Store spatial and time indexes next to the data file on close and restore them at open when the data file checksum matches (default: false)
+    - single_writer:       Allow changes only in the instance that holds the writer lock (default: false)
+    - writer_lock_key:     Key of the writer lock (default: data file path or item type)
+    - writer_lock_ttl:     Timeout of the writer lock in milliseconds (default: 30000)
+    - writer_check_interval: Interval to renew the writer lock or reload items in milliseconds (default: 10000)
+    - lock_ttl:            Time to live of locks acquired by WithLock in milliseconds (default: 30000)
+    - lock_timeout:        Timeout to acquire locks by WithLock in milliseconds (default: 10000)
+    - read_only:           Reject all changes with InvalidStateError (default: false)
+    - quota_field:         Name of the item field to count quotas by, for instance, tenant or owner id
+    - quota_max_items:     Maximum number of items per value of the quota field, 0 for unlimited (default: 0)
+    - type_field:          Name of the discriminator field with names of registered subtypes (default: type)
+    - unknown_fields:      Policy for loaded fields not defined in the item type: drop, warn or fail (default: drop)
+    - preserve_unknown_fields: Keep unknown fields of loaded items and write them back on save (default: false)
+    - background_save:         Save items in a background writer that coalesces successive saves (default: false)
+    - max_pending_saves:       Maximum number of unsaved changes before writes block in background save mode, 0 for unlimited (default: 0)
+    - durability:              Durability of saves: none to write only on Flush and Close, flush to write on every change, fsync to also sync to the disk (default: flush)
+    - log_operations:          Write debug logs with operation, id, duration and result of every operation (default: false)
+    - load_errors:             Policy for loaded items that cannot be converted: abort, skip or fail_open (default: abort)
+    - load_mode:               Mode to combine loaded items with items in memory: replace or merge by ids where the newest timestamp wins (default: replace)
+    - compact_on_load:         Intern strings and trim unused slice capacity of loaded items to reduce memory (default: false)
+    - autosave_interval:       Interval to save unsaved changes in background in milliseconds, 0 to disable (default: 0)
+    - collation:               Comma-separated flags of string comparison in composed sorts and filters: ignore_case, ignore_diacritics, numeric (default: none)
+    - clone_strategy:          Strategy to copy stored and returned items: shallow, deep or none to skip copying of non-pointer items (default: shallow)
+    - storage_mode:            Mode to keep items in memory: value, or pointer to share stored items without copies under clone_strategy none, requires a pointer to struct prototype (default: value)
+- quotas:
+    - <key>:               Maximum number of items for a specific value of the quota field
+- rollups:
+    - <name>.group_by:     Field to group items of the rollup by, see RegisterRollup
+    - <name>.sum:          Comma-separated numeric fields to sum in the rollup
+- dependencies:
+    - persister:           (optional) Descriptor of ILoader and ISaver component to load and save items, for instance *:persister:json:orders:1.0
+    - loader:              (optional) Descriptor of ILoader component to load items
+    - saver:               (optional) Descriptor of ISaver component to save items
+
 References
 
 - *:logger:*:*:1.0    ILogger components to pass log messages
+- *:lock:*:*:1.0      (optional) ILock component for WithLock and to coordinate the writer in single writer mode
+- *:persister:*:*:1.0 (optional) ILoader and ISaver component configured in dependencies.persister
+- *:loader:*:*:1.0    (optional) ILoader component configured in dependencies.loader
+- *:saver:*:*:1.0     (optional) ISaver component configured in dependencies.saver
 
 Example
 
@@ -56,7 +120,7 @@ Example
     item, err := persistence.GetByName("123", "ABC")
     fmt.Println(item)   // Result: { name: "ABC" }
 */
-// implements IReferenceable, IOpenable, ICleanable
+// implements IReconfigurable, IReferenceable, IOpenable, ICleanable
 type MemoryPersistence struct {
 	Logger      *log.CompositeLogger
 	Items       []interface{}
@@ -64,33 +128,268 @@ type MemoryPersistence struct {
 	Saver       ISaver
 	opened      bool
 	Prototype   reflect.Type
+	ids         idAccessor
 	Lock        sync.RWMutex
 	MaxPageSize int
+	paused      bool
+	pendingSave int32
+	// Return ConflictError from GetOneByFilter when more than one item matches
+	ErrorOnMultiple bool
+	// Timeout of loads, saves and filter scans in milliseconds, 0 for unlimited
+	OperationTimeout int64
+	persisterCalls   persisterGuard
+	lockTracking     lockTracker
+	// Number of goroutines used to evaluate filters
+	FilterParallelism int
+	// Minimal number of items to evaluate filters in parallel
+	ParallelFilterThreshold int
+	queryCache              *queryCache
+	changeHandlers          []func(oldItem interface{}, newItem interface{})
+	changeValidators        []func(correlationId string, oldItem interface{}, newItem interface{}) error
+	views                   map[string]*materializedView
+	readSnapshots           atomic.Value
+	readSnapshotHandler     bool
+	idFilter                atomic.Value
+	idFilterHandler         bool
+	rollups                 map[string]*rollup
+	computedFields          []*computedField
+	// Name of the item field with latitude used by geospatial queries
+	LatitudeField string
+	// Name of the item field with longitude used by geospatial queries
+	LongitudeField string
+	geoIndex       *geoIndex
+	// Name of the item field with timestamp used to partition items by time
+	TimestampField string
+	partitionIndex *timePartitionIndex
+	// Store spatial and time indexes next to the data file to restore them at Open
+	PersistIndexes bool
+	writer         *singleWriter
+	locker         lock.ILock
+	// Time to live of distributed locks acquired by WithLock in milliseconds
+	LockTtl int64
+	// Timeout to acquire distributed locks by WithLock in milliseconds
+	LockTimeout int64
+	lastState   atomic.Value
+	quota       *itemQuota
+	subtypes    *itemSubtypes
+	// Policy for loaded fields that are not defined in the prototype: drop, warn or fail
+	UnknownFields string
+	unknownFields *unknownFieldStore
+	saveQueue     *saveQueue
+	// Handler of errors of saves in background, by default errors are logged
+	SaveErrorHandler func(correlationId string, err error)
+	// Durability level of saves: none, flush or fsync
+	Durability string
+	unsaved    int32
+	metrics    *PersistenceMetrics
+	// Write structured debug logs for every operation
+	LogOperations bool
+	// Policy for loaded items that cannot be converted: abort, skip or fail_open
+	LoadErrors string
+	// Mode to combine loaded items with items in memory: replace or merge
+	LoadMode string
+	// Intern strings and trim slices of loaded items to reduce memory
+	CompactOnLoad bool
+	// Collation to compare strings in sort functions composed by ComposeSort
+	Collation *Collation
+	// Maximum number of items to skip in page queries, 0 for unlimited
+	MaxSkip int64
+	// Maximum number of items to take in page queries, 0 for unlimited
+	MaxTake int64
+	// Rejects all changes when set
+	ReadOnly bool
+	// Interval to save unsaved changes in background in milliseconds, 0 to disable
+	AutosaveInterval int64
+	autosave         *autosaver
+	// Clock used for timestamps, TTLs, retention and autosave timers
+	Clock IClock
+	// Callback to report progress of indexes rebuilt by RebuildIndexes
+	IndexProgressCallback func(correlationId string, progress IndexRebuildProgress)
+	// Strategy to copy stored and returned items: shallow, deep or none
+	CloneStrategy string
+	// Mode to keep items in memory: value or pointer
+	StorageMode string
+	// Resolver of loader and saver components configured in dependencies section
+	dependencyResolver *refer.DependencyResolver
+	// Configuration parameters set by all calls of Configure
+	configParams *config.ConfigParams
 }
 
 // Creates a new instance of the MemoryPersistence
 // Parameters:
 //  - prototype reflect.Type
-//   type of contained data
+//   type of contained data, panics when it is nil
 // Return *MemoryPersistence
 // a MemoryPersistence
 func NewMemoryPersistence(prototype reflect.Type) *MemoryPersistence {
-	if prototype == nil {
-		panic("Prototype cannot be nil")
-	}
+	checkPrototype(prototype)
 	c := &MemoryPersistence{}
 	c.Prototype = prototype
 	c.Logger = log.NewCompositeLogger()
 	c.Items = make([]interface{}, 0, 10)
+	c.FilterParallelism = 1
+	c.ParallelFilterThreshold = 10000
+	c.LatitudeField = "Latitude"
+	c.LongitudeField = "Longitude"
+	c.LockTtl = 30000
+	c.LockTimeout = 10000
+	c.CloneStrategy = CloneStrategyShallow
+	c.StorageMode = StorageModeValue
+	c.Clock = SystemClock
+	c.dependencyResolver = refer.NewDependencyResolver()
+	c.configParams = config.NewEmptyConfigParams()
 	return c
 }
 
+// Configures component by passing configuration parameters.
+// The component can be reconfigured at runtime, changes are applied under lock.
+// Background components of the opened persistence, the writer coordination, the background writer
+// and the autosave timer, are stopped when they are switched off or replaced, and started with new options.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *MemoryPersistence) Configure(config *config.ConfigParams) {
+	c.lock()
+	c.configParams = c.configParams.Override(config)
+	if itemType := config.GetAsString("item_type"); itemType != "" {
+		c.setItemType(itemType)
+	}
+	maxPageSize, maxSkip, maxTake := c.MaxPageSize, c.MaxSkip, c.MaxTake
+	c.MaxPageSize = config.GetAsIntegerWithDefault("options.max_page_size", c.MaxPageSize)
+	c.MaxSkip = config.GetAsLongWithDefault("options.max_skip", c.MaxSkip)
+	c.MaxTake = config.GetAsLongWithDefault("options.max_take", c.MaxTake)
+	c.ErrorOnMultiple = config.GetAsBooleanWithDefault("options.multiple_error", c.ErrorOnMultiple)
+	c.OperationTimeout = config.GetAsLongWithDefault("options.operation_timeout", c.OperationTimeout)
+	c.lockTracking.track(config.GetAsLongWithDefault("options.long_lock_threshold", c.lockTracking.holdThreshold()), c.Logger)
+	c.FilterParallelism = config.GetAsIntegerWithDefault("options.filter_parallelism", c.FilterParallelism)
+	c.ParallelFilterThreshold = config.GetAsIntegerWithDefault("options.parallel_filter_threshold", c.ParallelFilterThreshold)
+
+	cacheSize := 0
+	if c.queryCache != nil {
+		cacheSize = c.queryCache.maxSize
+	}
+	cacheSize = config.GetAsIntegerWithDefault("options.query_cache_size", cacheSize)
+	if cacheSize <= 0 {
+		c.queryCache = nil
+	} else if c.queryCache == nil || c.queryCache.maxSize != cacheSize {
+		c.queryCache = newQueryCache(cacheSize)
+	} else if c.MaxPageSize != maxPageSize || c.MaxSkip != maxSkip || c.MaxTake != maxTake {
+		// Cached pages were limited by previous paging options
+		c.queryCache.invalidate()
+	}
+
+	c.LatitudeField = config.GetAsStringWithDefault("options.latitude_field", c.LatitudeField)
+	c.LongitudeField = config.GetAsStringWithDefault("options.longitude_field", c.LongitudeField)
+	geoPrecision := 0
+	if c.geoIndex != nil {
+		geoPrecision = c.geoIndex.precision
+	}
+	geoPrecision = config.GetAsIntegerWithDefault("options.geo_index_precision", geoPrecision)
+	c.TimestampField = config.GetAsStringWithDefault("options.timestamp_field", c.TimestampField)
+	c.PersistIndexes = config.GetAsBooleanWithDefault("options.persist_indexes", c.PersistIndexes)
+	c.LockTtl = config.GetAsLongWithDefault("options.lock_ttl", c.LockTtl)
+	c.LockTimeout = config.GetAsLongWithDefault("options.lock_timeout", c.LockTimeout)
+	c.ReadOnly = config.GetAsBooleanWithDefault("options.read_only", c.ReadOnly)
+	c.CloneStrategy = config.GetAsStringWithDefault("options.clone_strategy", c.CloneStrategy)
+	c.StorageMode = config.GetAsStringWithDefault("options.storage_mode", c.StorageMode)
+	c.dependencyResolver.Configure(config)
+	c.unlock()
+
+	if typeField := config.GetAsString("options.type_field"); typeField != "" {
+		c.lock()
+		if c.subtypes == nil {
+			c.subtypes = &itemSubtypes{byName: map[string]reflect.Type{}, byType: map[reflect.Type]string{}}
+		}
+		c.subtypes.field = typeField
+		c.unlock()
+	}
+	partitionPeriod := int64(24 * 60 * 60 * 1000)
+	if c.partitionIndex != nil {
+		partitionPeriod = c.partitionIndex.period
+	}
+	partitionPeriod = config.GetAsLongWithDefault("options.partition_period", partitionPeriod)
+	if partitionPeriod <= 0 {
+		partitionPeriod = 24 * 60 * 60 * 1000
+	}
+
+	quotaField := ""
+	var quotaMaxItems int64
+	if c.quota != nil {
+		quotaField = c.quota.field
+		quotaMaxItems = c.quota.maxItems
+	}
+	quotaField = config.GetAsStringWithDefault("options.quota_field", quotaField)
+	quotaMaxItems = config.GetAsLongWithDefault("options.quota_max_items", quotaMaxItems)
+
+	c.lock()
+	c.setQuotaField(quotaField)
+	if c.quota != nil {
+		c.quota.maxItems = quotaMaxItems
+		quotas := config.GetSection("quotas")
+		for _, key := range quotas.Keys() {
+			c.quota.limits[key] = quotas.GetAsLongWithDefault(key, 0)
+		}
+	}
+	c.configureWriter(config)
+	maxPendingSaves := int64(0)
+	if c.saveQueue != nil {
+		maxPendingSaves = c.saveQueue.maxPending
+	}
+	detachedQueue := c.setBackgroundSave(config.GetAsBooleanWithDefault("options.background_save", c.saveQueue != nil),
+		config.GetAsLongWithDefault("options.max_pending_saves", maxPendingSaves))
+	c.Durability = config.GetAsStringWithDefault("options.durability", c.Durability)
+	c.LogOperations = config.GetAsBooleanWithDefault("options.log_operations", c.LogOperations)
+	c.LoadErrors = config.GetAsStringWithDefault("options.load_errors", c.LoadErrors)
+	c.LoadMode = config.GetAsStringWithDefault("options.load_mode", c.LoadMode)
+	c.CompactOnLoad = config.GetAsBooleanWithDefault("options.compact_on_load", c.CompactOnLoad)
+	detachedAutosave := c.setAutosaveInterval(config.GetAsLongWithDefault("options.autosave_interval", c.AutosaveInterval))
+	if collation := config.GetAsNullableString("options.collation"); collation != nil {
+		c.Collation = ParseCollation(*collation)
+		// Cached results of filters depend on the collation
+		if c.queryCache != nil {
+			c.queryCache.invalidate()
+		}
+	}
+	c.setUnknownFields(config.GetAsStringWithDefault("options.unknown_fields", c.UnknownFields),
+		config.GetAsBooleanWithDefault("options.preserve_unknown_fields", c.unknownFields != nil))
+	c.configureRollups(config.GetSection("rollups"))
+	if snapshots := c.getReadSnapshots(); config.GetAsBooleanWithDefault("options.read_snapshot", snapshots != nil) {
+		interval, fields := int64(0), []string{}
+		if snapshots != nil {
+			interval, fields = snapshots.interval, snapshots.fields
+		}
+		if indexes := config.GetAsNullableString("options.read_snapshot_indexes"); indexes != nil {
+			fields = parseSnapshotIndexes(*indexes)
+		}
+		c.setReadSnapshot(true, config.GetAsLongWithDefault("options.read_snapshot_interval", interval), fields)
+	} else if snapshots != nil {
+		c.setReadSnapshot(false, 0, nil)
+	}
+	c.setGeoIndexPrecision(geoPrecision)
+	if c.TimestampField != "" {
+		c.setPartitionPeriod(partitionPeriod)
+	}
+	c.unlock()
+
+	if detachedQueue != nil {
+		detachedQueue.close()
+	}
+	if detachedAutosave != nil {
+		detachedAutosave.close()
+	}
+}
+
 //  Sets references to dependent components.
 //  Parameters:
 //   - references refer.IReferences
 //   references to locate the component dependencies.
 func (c *MemoryPersistence) SetReferences(references refer.IReferences) {
 	c.Logger.SetReferences(references)
+
+	if l, ok := references.GetOneOptional(refer.NewDescriptor("*", "lock", "*", "*", "*")).(lock.ILock); ok {
+		c.locker = l
+	}
+	c.resolvePersister(references)
 }
 
 //  Checks if the component is opened.
@@ -105,12 +404,28 @@ func (c *MemoryPersistence) IsOpen() bool {
 //   (optional) transaction id to trace execution through call chain.
 // Returns  error or null no errors occured.
 func (c *MemoryPersistence) Open(correlationId string) error {
-	c.Lock.Lock()
-	defer c.Lock.Unlock()
+	c.lock()
+	defer c.unlock()
 
+	if err := c.checkStorageMode(correlationId); err != nil {
+		return err
+	}
 	err := c.load(correlationId)
+	var loadErr error
+	if isInvalidDataItemsError(err) {
+		// Opens with valid items but still reports skipped ones
+		loadErr, err = err, nil
+	}
+	if err == nil && c.writer != nil {
+		err = c.startWriter(correlationId)
+	}
 	if err == nil {
+		if c.saveQueue != nil {
+			c.saveQueue.start()
+		}
+		c.startAutosave()
 		c.opened = true
+		err = loadErr
 	}
 	return err
 }
@@ -120,22 +435,79 @@ func (c *MemoryPersistence) load(correlationId string) error {
 		return nil
 	}
 
-	items, err := c.Loader.Load(correlationId)
-	if err == nil && items != nil {
-		c.Items = items
-		c.Items = make([]interface{}, len(items))
-		for i, v := range items {
-			item := convert.MapConverter.ToNullableMap(v)
-			jsonMarshalStr, errJson := json.Marshal(item)
-			if errJson != nil {
-				panic("MemoryPersistence.Load Error can't convert from Json to type")
+	start := time.Now()
+	items, err := c.loadWithTimeout(correlationId)
+	c.metrics.observe(PersisterOperationLoad, time.Since(start), err)
+	if err != nil {
+		return wrapError(err, correlationId, "LOAD_FAILED", "Failed to load data items")
+	}
+	c.rememberModified(correlationId)
+	if items != nil {
+		var preserved map[string]map[string]interface{}
+		if c.unknownFields != nil {
+			preserved = map[string]map[string]interface{}{}
+		}
+		loaded := make([]interface{}, 0, len(items))
+		rejected := []int{}
+		for index, v := range items {
+			item, errMap := toLoadedMap(v)
+			if errMap != nil {
+				if err = c.rejectDataItem(correlationId, index, errMap, &rejected); err != nil {
+					return err
+				}
+				continue
+			}
+			prototype := c.Prototype
+			if prototype.Kind() == reflect.Ptr {
+				prototype = prototype.Elem()
 			}
-			value := reflect.New(c.Prototype).Interface()
-			json.Unmarshal(jsonMarshalStr, value)
-			c.Items[i] = reflect.ValueOf(value).Elem().Interface() // load value
+			if c.subtypes != nil {
+				if subtype, ok := c.subtypes.resolve(item); ok {
+					prototype = subtype
+				} else if prototype.Kind() == reflect.Interface {
+					c.Logger.Warn(correlationId, "Skipped item with unknown type %v", item[c.subtypes.field])
+					continue
+				}
+			}
+			value := reflect.New(prototype).Interface()
+			if errJson := convertLoadedItem(item, value); errJson != nil {
+				if err = c.rejectDataItem(correlationId, index, errJson, &rejected); err != nil {
+					return err
+				}
+				continue
+			}
+			if extraField, ok := findExtraField(prototype); ok {
+				// Fields not defined in the struct are kept in its Extra field
+				fields := findUnknownFields(prototype, item)
+				if c.subtypes != nil {
+					delete(fields, c.subtypes.field)
+				}
+				setExtraFields(reflect.ValueOf(value).Elem(), extraField, fields)
+			} else if err = c.checkUnknownFields(correlationId, prototype, item, preserved); err != nil {
+				return err
+			}
+			loaded = append(loaded, c.toStoredItem(reflect.ValueOf(value).Elem().Interface())) // load value
+			c.applyComputedFields(&loaded[len(loaded)-1])
+		}
+		if c.LoadMode == LoadModeMerge {
+			loaded = c.mergeLoaded(loaded)
+		}
+		c.Items = loaded
+		if c.CompactOnLoad {
+			c.compactItems(correlationId)
+		}
+		c.notifyChange(nil, nil)
+		if c.PersistIndexes && c.LoadMode != LoadModeMerge && len(rejected) == 0 {
+			c.restoreIndexes(correlationId)
+		}
+		if preserved != nil {
+			c.unknownFields.fields = preserved
 		}
 		length := len(c.Items)
 		c.Logger.Trace(correlationId, "Loaded %d items", length)
+		if len(rejected) > 0 && c.LoadErrors == LoadErrorsFailOpen {
+			err = newInvalidDataItemsError(correlationId, rejected)
+		}
 	}
 	return err
 }
@@ -146,30 +518,237 @@ func (c *MemoryPersistence) load(correlationId string) error {
 //  (optional) transaction id to trace execution through call chain.
 // Retruns: error or nil if no errors occured.
 func (c *MemoryPersistence) Close(correlationId string) error {
-	err := c.Save(correlationId)
+	c.lock()
+	queue := c.saveQueue
+	autosave := c.detachAutosave()
+	c.unlock()
+	if queue != nil {
+		queue.close()
+	}
+	if autosave != nil {
+		autosave.close()
+	}
+
+	// Close always writes items regardless of the durability level
+	atomic.StoreInt32(&c.unsaved, 0)
+	err := c.saveNow(correlationId)
+	if err == nil && c.PersistIndexes {
+		c.rlock()
+		if indexErr := c.storeIndexes(correlationId); indexErr != nil {
+			c.Logger.Warn(correlationId, "Failed to store indexes: %v", indexErr)
+		}
+		c.runlock()
+	}
+	if c.writer != nil {
+		c.lock()
+		c.stopWriter(correlationId)
+		c.unlock()
+	}
 	c.opened = false
 	return err
 }
 
+// Pauses the component for maintenance. While paused all write operations
+// are rejected with InvalidStateError, reads keep working and nothing is saved
+// to the external data source, so data files can be safely copied or swapped.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil no errors occured.
+func (c *MemoryPersistence) Pause(correlationId string) error {
+	c.lock()
+	defer c.unlock()
+
+	c.paused = true
+	c.Logger.Info(correlationId, "Paused persistence")
+	return nil
+}
+
+// Resumes the component after Pause. If some changes were made right before
+// the pause and were not saved, they are saved now.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil no errors occured.
+func (c *MemoryPersistence) Resume(correlationId string) error {
+	c.lock()
+	c.paused = false
+	c.unlock()
+
+	c.Logger.Info(correlationId, "Resumed persistence")
+
+	if atomic.SwapInt32(&c.pendingSave, 0) != 0 {
+		return c.save(correlationId)
+	}
+	return nil
+}
+
+// Checks if the component is paused.
+// Returns true if the component has been paused and false otherwise.
+func (c *MemoryPersistence) IsPaused() bool {
+	c.rlock()
+	defer c.runlock()
+
+	return c.paused
+}
+
+// Adds a handler that is notified about every change of items.
+// Must be called under write lock.
+func (c *MemoryPersistence) addChangeHandler(handler func(oldItem interface{}, newItem interface{})) {
+	c.changeHandlers = append(c.changeHandlers, handler)
+}
+
+// Notifies change handlers about a changed item. Must be called under write lock.
+// Nil old item means that the item was created, nil new item means that it was deleted,
+// and both nil items mean that all items were replaced.
+func (c *MemoryPersistence) notifyChange(oldItem interface{}, newItem interface{}) {
+	// Cached queries are invalidated before readers can see the change,
+	// so results calculated from previous items are not cached after it
+	if c.queryCache != nil {
+		c.queryCache.invalidate()
+	}
+	for _, handler := range c.changeHandlers {
+		handler(oldItem, newItem)
+	}
+}
+
+// Adds a validator that can reject changes of items made by write operations.
+// Must be called under write lock.
+func (c *MemoryPersistence) addChangeValidator(validator func(correlationId string, oldItem interface{}, newItem interface{}) error) {
+	c.changeValidators = append(c.changeValidators, validator)
+}
+
+// Checks a change of an item by validators. Must be called under write lock.
+// Nil old item means that the item is created.
+func (c *MemoryPersistence) validateChange(correlationId string, oldItem interface{}, newItem interface{}) error {
+	for _, validator := range c.changeValidators {
+		if err := validator(correlationId, oldItem, newItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks if write operations are allowed. Must be called under write lock.
+func (c *MemoryPersistence) checkWritable(correlationId string) error {
+	if c.ReadOnly {
+		return errors.NewInvalidStateError(correlationId, "READ_ONLY", "Persistence is configured as read-only")
+	}
+	if c.paused {
+		return errors.NewInvalidStateError(correlationId, "PAUSED", "Persistence is paused for maintenance")
+	}
+	if c.writer != nil && c.writer.stop != nil && !c.holdsWriter() {
+		return errors.NewInvalidStateError(correlationId, "READ_ONLY", "Persistence is read-only because another instance is the writer")
+	}
+	return nil
+}
+
+// Gets a snapshot of all stored items.
+// It allows to use the persistence as ILoader, for instance, to compare or copy data.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// copies of all items or error.
+func (c *MemoryPersistence) Load(correlationId string) (items []interface{}, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	items = make([]interface{}, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = c.cloneResult(item)
+	}
+	return items, nil
+}
+
 // Saves items to external data source using configured saver component.
+// Items can be changed directly in Items before the call, so the id filter, the query cache
+// and materialized views that are not notified about such changes are rebuilt before saving.
 // Parameters:
 //   - correlationId string
 //   (optional) transaction id to trace execution through call chain.
 // Return error or null for success.
 func (c *MemoryPersistence) Save(correlationId string) error {
-	c.Lock.RLock()
-	defer c.Lock.RUnlock()
+	c.lock()
+	c.syncDirectChanges()
+	c.unlock()
+
+	return c.save(correlationId)
+}
+
+// Rebuilds state derived from items that is not updated when Items are changed directly.
+// Must be called under write lock.
+func (c *MemoryPersistence) syncDirectChanges() {
+	if c.getIdFilter() != nil {
+		c.idFilter.Store(c.newIdFilter(0))
+	}
+	if c.queryCache != nil {
+		c.queryCache.invalidate()
+	}
+	for _, view := range c.views {
+		c.rebuildView("", view)
+	}
+}
+
+// Saves items after changes that were already reported to change handlers
+func (c *MemoryPersistence) save(correlationId string) error {
+	c.rlock()
+	queue := c.saveQueue
+	c.runlock()
+
+	if !c.saveOnChange() {
+		return nil
+	}
+	if queue != nil && queue.running() {
+		return queue.request(correlationId)
+	}
+	return c.saveNow(correlationId)
+}
 
+// Saves items to external data source on the caller's goroutine
+func (c *MemoryPersistence) saveNow(correlationId string) error {
+	c.rlock()
+	defer c.runlock()
+
+	return c.saveItems(correlationId)
+}
+
+// Saves items to external data source. Must be called under lock.
+func (c *MemoryPersistence) saveItems(correlationId string) error {
 	if c.Saver == nil {
 		return nil
 	}
 
-	err := c.Saver.Save(correlationId, c.Items)
-	if err == nil {
-		length := len(c.Items)
-		c.Logger.Trace(correlationId, "Saved %d items", length)
+	// Read-only instances in single writer mode must not overwrite data saved by the writer
+	if c.writer != nil && c.writer.stop != nil && !c.holdsWriter() {
+		c.Logger.Trace(correlationId, "Skipped save because another instance is the writer")
+		return nil
 	}
-	return err
+
+	if c.paused {
+		atomic.StoreInt32(&c.pendingSave, 1)
+		c.Logger.Trace(correlationId, "Postponed save until persistence is resumed")
+		return nil
+	}
+
+	items := c.Items
+	if c.subtypes != nil {
+		items = c.subtypes.encode(items)
+	}
+	items = encodeExtraFields(c.Items, items)
+	if c.unknownFields != nil {
+		items = c.unknownFields.encode(items)
+	}
+	start := time.Now()
+	err := c.saveWithTimeout(correlationId, items)
+	c.metrics.observe(PersisterOperationSave, time.Since(start), err)
+	if err != nil {
+		return wrapError(err, correlationId, "SAVE_FAILED", "Failed to save data items")
+	}
+	c.rememberModified(correlationId)
+	length := len(c.Items)
+	c.Logger.Trace(correlationId, "Saved %d items", length)
+	return nil
 }
 
 // Clears component state.
@@ -178,13 +757,19 @@ func (c *MemoryPersistence) Save(correlationId string) error {
 //  (optional) transaction id to trace execution through call chain.
 //  Returns error or null no errors occured.
 func (c *MemoryPersistence) Clear(correlationId string) error {
-	c.Lock.Lock()
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return err
+	}
 
 	c.Items = make([]interface{}, 0, 5)
+	c.notifyChange(nil, nil)
 	c.Logger.Trace(correlationId, "Cleared items")
 
-	c.Lock.Unlock()
-	return c.Save(correlationId)
+	c.unlock()
+	return c.save(correlationId)
 }
 
 // Gets a page of data items retrieved by a given filter and sorted according to sort parameters.
@@ -205,17 +790,31 @@ func (c *MemoryPersistence) Clear(correlationId string) error {
 // data page or error.
 func (c *MemoryPersistence) GetPageByFilter(correlationId string, filterFunc func(interface{}) bool,
 	paging *cdata.PagingParams, sortFunc func(a, b interface{}) bool, selectFunc func(in interface{}) (out interface{})) (page *cdata.DataPage, err error) {
-	c.Lock.RLock()
-	defer c.Lock.RUnlock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "get_page_by_filter", start, nil, err) }(time.Now())
+	}
+	page, err = c.getPageByFilter(correlationId, filterFunc, nil, paging, sortFunc, selectFunc, nil)
+	return page, err
+}
+
+// Gets a page of data items and optionally collects execution metadata.
+// Conditions of the filter, when they are known, allow to select items by an index
+func (c *MemoryPersistence) getPageByFilter(correlationId string, filterFunc func(interface{}) bool,
+	conditions [][]*filterCondition, paging *cdata.PagingParams, sortFunc func(a, b interface{}) bool,
+	selectFunc func(in interface{}) (out interface{}), metadata *QueryMetadata) (page *cdata.DataPage, err error) {
+	if err = c.checkPaging(correlationId, paging); err != nil {
+		return nil, err
+	}
+	c.rlock()
+	defer c.runlock()
 
 	var items []interface{}
+	candidates, index := c.candidateItems(conditions)
 
 	// Apply filtering
 	if filterFunc != nil {
-		for _, v := range c.Items {
-			if filterFunc(v) {
-				items = append(items, v)
-			}
+		if items, err = c.filterItemsOf(correlationId, candidates, filterFunc); err != nil {
+			return nil, err
 		}
 	} else {
 		items = make([]interface{}, len(c.Items))
@@ -224,8 +823,9 @@ func (c *MemoryPersistence) GetPageByFilter(correlationId string, filterFunc fun
 
 	// Apply sorting
 	if sortFunc != nil {
-		localSort := sorter{items: items, compFunc: sortFunc}
-		sort.Sort(localSort)
+		if err = c.sortItems(correlationId, items, sortFunc); err != nil {
+			return nil, err
+		}
 	}
 
 	// Extract a page
@@ -238,6 +838,7 @@ func (c *MemoryPersistence) GetPageByFilter(correlationId string, filterFunc fun
 	if paging.Total {
 		total = (int64)(len(items))
 	}
+	matched := int64(len(items))
 	if skip > 0 {
 		len := (int64)(len(items))
 		if skip >= len {
@@ -245,14 +846,22 @@ func (c *MemoryPersistence) GetPageByFilter(correlationId string, filterFunc fun
 		}
 		items = items[skip:]
 	}
+	if metadata != nil {
+		metadata.ScannedItems = int64(len(candidates))
+		metadata.IndexUsed = index != ""
+		metadata.Index = index
+		metadata.MatchedItems = matched
+		metadata.TakeTruncated = paging.Take != nil && *paging.Take > take
+		metadata.HasMore = (int64)(len(items)) > take
+	}
 	if (int64)(len(items)) >= take {
 		items = items[:take]
 	}
 
 	// Get projection
 	if selectFunc != nil {
-		for i, v := range items {
-			items[i] = selectFunc(v)
+		if err = c.selectItems(correlationId, items, selectFunc); err != nil {
+			return nil, err
 		}
 	}
 
@@ -260,7 +869,7 @@ func (c *MemoryPersistence) GetPageByFilter(correlationId string, filterFunc fun
 	// W!
 	for i := 0; i < len(items); i++ {
 		//items[i] = CloneObject(items[i])
-		items[i] = CloneObjectForResult(items[i], c.Prototype)
+		items[i] = c.cloneResult(items[i])
 	}
 
 	page = cdata.NewDataPage(&total, items)
@@ -283,15 +892,24 @@ func (c *MemoryPersistence) GetPageByFilter(correlationId string, filterFunc fun
 // array of items and error
 func (c *MemoryPersistence) GetListByFilter(correlationId string, filterFunc func(interface{}) bool,
 	sortFunc func(a, b interface{}) bool, selectFunc func(in interface{}) (out interface{})) (results []interface{}, err error) {
-	c.Lock.RLock()
-	defer c.Lock.RUnlock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "get_list_by_filter", start, nil, err) }(time.Now())
+	}
+	return c.getListByFilter(correlationId, filterFunc, nil, sortFunc, selectFunc)
+}
+
+// Gets a list of data items, conditions of the filter allow to select items by an index
+func (c *MemoryPersistence) getListByFilter(correlationId string, filterFunc func(interface{}) bool,
+	conditions [][]*filterCondition, sortFunc func(a, b interface{}) bool,
+	selectFunc func(in interface{}) (out interface{})) (results []interface{}, err error) {
+	c.rlock()
+	defer c.runlock()
 
 	// Apply filter
 	if filterFunc != nil {
-		for _, v := range c.Items {
-			if filterFunc(v) {
-				results = append(results, v)
-			}
+		candidates, _ := c.candidateItems(conditions)
+		if results, err = c.filterItemsOf(correlationId, candidates, filterFunc); err != nil {
+			return nil, err
 		}
 	} else {
 		results = make([]interface{}, len(c.Items))
@@ -300,14 +918,15 @@ func (c *MemoryPersistence) GetListByFilter(correlationId string, filterFunc fun
 
 	// Apply sorting
 	if sortFunc != nil {
-		localSort := sorter{items: results, compFunc: sortFunc}
-		sort.Sort(localSort)
+		if err = c.sortItems(correlationId, results, sortFunc); err != nil {
+			return nil, err
+		}
 	}
 
 	// Get projection
 	if selectFunc != nil {
-		for i, v := range results {
-			results[i] = selectFunc(v)
+		if err = c.selectItems(correlationId, results, selectFunc); err != nil {
+			return nil, err
 		}
 	}
 
@@ -315,11 +934,144 @@ func (c *MemoryPersistence) GetListByFilter(correlationId string, filterFunc fun
 	//W!
 	for i := 0; i < len(results); i++ {
 		//results[i] = CloneObject(results[i])
-		results[i] = CloneObjectForResult(results[i], c.Prototype)
+		results[i] = c.cloneResult(results[i])
 	}
 	return results, nil
 }
 
+// Gets a page of data items retrieved by FilterParams that are automatically
+// converted into a filter function using ComposeFilter.
+// When query cache is enabled, unsorted results are cached until the next change of items.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) sorting compare function
+// Returns *cdata.DataPage, error
+// data page or error.
+func (c *MemoryPersistence) GetPageByFilterParams(correlationId string, filter *cdata.FilterParams,
+	paging *cdata.PagingParams, sortFunc func(a, b interface{}) bool) (page *cdata.DataPage, err error) {
+	if err = c.checkPaging(correlationId, paging); err != nil {
+		return nil, err
+	}
+	builder := composeFilterBuilder(c.Prototype, filter, c.Collation)
+	filterFunc, err := builder.Build()
+	if err != nil {
+		return nil, wrapError(err, correlationId, "INVALID_FILTER", "Invalid filter")
+	}
+	getPage := func(sortFunc func(a, b interface{}) bool) (page *cdata.DataPage, err error) {
+		if c.LogOperations {
+			defer func(start time.Time) { c.logOperation(correlationId, "get_page_by_filter", start, nil, err) }(time.Now())
+		}
+		return c.getPageByFilter(correlationId, filterFunc, builder.conditionGroups(), paging, sortFunc, nil, nil)
+	}
+
+	cache := c.queryCache
+	if cache == nil || sortFunc != nil {
+		return getPage(sortFunc)
+	}
+
+	key := queryCacheKey("page", filter, paging)
+	if cached, ok := cache.get(key); ok {
+		c.Logger.Trace(correlationId, "Retrieved page from query cache")
+		return c.clonePage(cached.(*cdata.DataPage)), nil
+	}
+	version := cache.currentVersion()
+	page, err = getPage(nil)
+	if err == nil {
+		cache.put(key, version, c.clonePage(page))
+	}
+	return page, err
+}
+
+// Gets a list of data items retrieved by FilterParams that are automatically
+// converted into a filter function using ComposeFilter.
+// When query cache is enabled, unsorted results are cached until the next change of items.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) sorting compare function
+// Returns []interface{}, error
+// array of items and error
+func (c *MemoryPersistence) GetListByFilterParams(correlationId string, filter *cdata.FilterParams,
+	sortFunc func(a, b interface{}) bool) (results []interface{}, err error) {
+	builder := composeFilterBuilder(c.Prototype, filter, c.Collation)
+	filterFunc, err := builder.Build()
+	if err != nil {
+		return nil, wrapError(err, correlationId, "INVALID_FILTER", "Invalid filter")
+	}
+	getList := func(sortFunc func(a, b interface{}) bool) (results []interface{}, err error) {
+		if c.LogOperations {
+			defer func(start time.Time) { c.logOperation(correlationId, "get_list_by_filter", start, nil, err) }(time.Now())
+		}
+		return c.getListByFilter(correlationId, filterFunc, builder.conditionGroups(), sortFunc, nil)
+	}
+
+	cache := c.queryCache
+	if cache == nil || sortFunc != nil {
+		return getList(sortFunc)
+	}
+
+	key := queryCacheKey("list", filter, nil)
+	if cached, ok := cache.get(key); ok {
+		c.Logger.Trace(correlationId, "Retrieved list from query cache")
+		return c.cloneResults(cached.([]interface{})), nil
+	}
+	version := cache.currentVersion()
+	results, err = getList(nil)
+	if err == nil {
+		cache.put(key, version, c.cloneResults(results))
+	}
+	return results, err
+}
+
+// Gets a count of data items retrieved by FilterParams that are automatically
+// converted into a filter function using ComposeFilter.
+// When query cache is enabled, results are cached until the next change of items.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters
+// Returns int64, error
+// data count or error.
+func (c *MemoryPersistence) GetCountByFilterParams(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
+	builder := composeFilterBuilder(c.Prototype, filter, c.Collation)
+	filterFunc, err := builder.Build()
+	if err != nil {
+		return 0, wrapError(err, correlationId, "INVALID_FILTER", "Invalid filter")
+	}
+	getCount := func() (count int64, err error) {
+		if c.LogOperations {
+			defer func(start time.Time) { c.logOperation(correlationId, "get_count_by_filter", start, nil, err) }(time.Now())
+		}
+		return c.getCountByFilter(correlationId, filterFunc, builder.conditionGroups())
+	}
+
+	cache := c.queryCache
+	if cache == nil {
+		return getCount()
+	}
+
+	key := queryCacheKey("count", filter, nil)
+	if cached, ok := cache.get(key); ok {
+		return cached.(int64), nil
+	}
+	version := cache.currentVersion()
+	count, err = getCount()
+	if err == nil {
+		cache.put(key, version, count)
+	}
+	return count, err
+}
+
 // Gets a random item from items that match to a given filter.
 // This method shall be called by a func (c* IdentifiableMemoryPersistence) GetOneRandom method from child type that
 // receives FilterParams and converts them into a filter function.
@@ -331,17 +1083,15 @@ func (c *MemoryPersistence) GetListByFilter(correlationId string, filterFunc fun
 // Returns: interface{}, error
 // random item or error.
 func (c *MemoryPersistence) GetOneRandom(correlationId string, filterFunc func(interface{}) bool) (result interface{}, err error) {
-	c.Lock.RLock()
-	defer c.Lock.RUnlock()
+	c.rlock()
+	defer c.runlock()
 
 	var items []interface{}
 
 	// Apply filter
 	if filterFunc != nil {
-		for _, v := range c.Items {
-			if filterFunc(v) {
-				items = append(items, v)
-			}
+		if items, err = c.filterItems(correlationId, filterFunc); err != nil {
+			return nil, err
 		}
 	} else {
 		copy(items, c.Items)
@@ -359,10 +1109,47 @@ func (c *MemoryPersistence) GetOneRandom(correlationId string, filterFunc func(i
 		c.Logger.Trace(correlationId, "Nothing to return as random item")
 	}
 	//result = CloneObject(item)
-	result = CloneObjectForResult(item, c.Prototype)
+	result = c.cloneResult(item)
 	return result, nil
 }
 
+// Gets the first item that matches to a given filter, for unique lookups like finding a user by email.
+// The scan stops at the first match, unless ErrorOnMultiple is set to check that the match is the only one.
+// This method shall be called by a func (c* IdentifiableMemoryPersistence) GetOneByFilter method from child type that
+// receives FilterParams and converts them into a filter function.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter   func(interface{}) bool
+//   (optional) a filter function to filter items.
+// Returns: interface{}, error
+// the found item, nil if nothing was found or ConflictError if ErrorOnMultiple is set and more than one item matches.
+func (c *MemoryPersistence) GetOneByFilter(correlationId string, filterFunc func(interface{}) bool) (result interface{}, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	found, multiple := -1, false
+	if err = c.findItems(correlationId, filterFunc, func(index int) bool {
+		multiple = found >= 0
+		if !multiple {
+			found = index
+		}
+		return c.ErrorOnMultiple && !multiple
+	}); err != nil {
+		return nil, err
+	}
+	if multiple {
+		return nil, errors.NewConflictError(correlationId, "MULTIPLE_ITEMS", "More than one item matches the filter")
+	}
+
+	if found < 0 {
+		c.Logger.Trace(correlationId, "Nothing found by filter")
+		return nil, nil
+	}
+	c.Logger.Trace(correlationId, "Retrieved item by filter")
+	return c.cloneResult(c.Items[found]), nil
+}
+
 // Creates a data item.
 // Returns:
 //   - correlation_id string
@@ -372,16 +1159,38 @@ func (c *MemoryPersistence) GetOneRandom(correlationId string, filterFunc func(i
 // Returns:  interface{}, error
 // created item or error.
 func (c *MemoryPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "create", start, c.ids.get(item), err) }(time.Now())
+	}
+	c.lock()
 
-	newItem := CloneObject(item, c.Prototype)
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	newItem := c.cloneItem(item)
+	if newItem == nil {
+		c.unlock()
+		return nil, newInvalidItemError(correlationId)
+	}
+	c.applyComputedFields(&newItem)
+	if err = c.checkQuota(correlationId, nil, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+	if err = c.validateChange(correlationId, nil, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
 	c.Items = append(c.Items, newItem)
+	c.notifyChange(nil, newItem)
 
-	c.Lock.Unlock()
+	c.unlock()
 	c.Logger.Trace(correlationId, "Created item")
 
-	errsave := c.Save(correlationId)
-	result = CloneObjectForResult(newItem, c.Prototype)
+	errsave := c.save(correlationId)
+	result = c.cloneResult(newItem)
 
 	return result, errsave
 }
@@ -397,11 +1206,26 @@ func (c *MemoryPersistence) Create(correlationId string, item interface{}) (resu
 // Retruns: error
 // error or nil for success.
 func (c *MemoryPersistence) DeleteByFilter(correlationId string, filterFunc func(interface{}) bool) (err error) {
-	c.Lock.Lock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "delete_by_filter", start, nil, err) }(time.Now())
+	}
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return err
+	}
+
+	matches, err := c.matchItems(correlationId, filterFunc)
+	if err != nil {
+		c.unlock()
+		return err
+	}
 
 	deleted := 0
-	for i := 0; i < len(c.Items); {
-		if filterFunc(c.Items[i]) {
+	for i, j := 0, 0; i < len(c.Items); j++ {
+		if matches[j] {
+			c.notifyChange(c.Items[i], nil)
 			if i == len(c.Items)-1 {
 				c.Items = c.Items[:i]
 			} else {
@@ -412,7 +1236,7 @@ func (c *MemoryPersistence) DeleteByFilter(correlationId string, filterFunc func
 			i++
 		}
 	}
-	c.Lock.Unlock()
+	c.unlock()
 
 	if deleted == 0 {
 		return nil
@@ -420,7 +1244,7 @@ func (c *MemoryPersistence) DeleteByFilter(correlationId string, filterFunc func
 
 	c.Logger.Trace(correlationId, "Deleted %s items", deleted)
 
-	errsave := c.Save(correlationId)
+	errsave := c.save(correlationId)
 	return errsave
 }
 
@@ -435,16 +1259,26 @@ func (c *MemoryPersistence) DeleteByFilter(correlationId string, filterFunc func
 // Return int, error
 // data count or error.
 func (c *MemoryPersistence) GetCountByFilter(correlationId string, filterFunc func(interface{}) bool) (count int64, err error) {
-	c.Lock.RLock()
-	defer c.Lock.RUnlock()
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "get_count_by_filter", start, nil, err) }(time.Now())
+	}
+	return c.getCountByFilter(correlationId, filterFunc, nil)
+}
+
+// Gets a count of data items, conditions of the filter allow to select items by an index
+func (c *MemoryPersistence) getCountByFilter(correlationId string, filterFunc func(interface{}) bool,
+	conditions [][]*filterCondition) (count int64, err error) {
+	c.rlock()
+	defer c.runlock()
 
 	// Apply filtering
 	if filterFunc != nil {
-		for _, v := range c.Items {
-			if filterFunc(v) {
-				count++
-			}
+		candidates, _ := c.candidateItems(conditions)
+		items, err := c.filterItemsOf(correlationId, candidates, filterFunc)
+		if err != nil {
+			return 0, err
 		}
+		count = int64(len(items))
 	} else {
 		count = 0
 	}
@@ -0,0 +1,228 @@
+package persistence
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Named set of items shared by persistence components in one process,
+for instance a read model and an admin model of the same data.
+Items are kept once in a single IdentifiableMemoryPersistence, so all components
+see the same items under the same lock. The store is opened when the first component
+is opened and closed when the last one is closed, so items are loaded and saved once.
+
+Stores are created by GetMemoryStore and attached to by SharedMemoryPersistence.
+*/
+type MemoryStore struct {
+	name        string
+	persistence *IdentifiableMemoryPersistence
+	lock        sync.Mutex
+	openCount   int
+}
+
+var memoryStoresLock sync.Mutex
+var memoryStores = map[string]*MemoryStore{}
+
+// Gets a shared store by its name, creating it on the first call.
+// Parameters:
+//   - name string
+//   a unique name of the store in the process
+//   - prototype reflect.Type
+//   type of the stored items
+// Returns *MemoryStore, error
+// the store or ConfigError when the prototype is nil or the store exists with a different prototype.
+func GetMemoryStore(name string, prototype reflect.Type) (*MemoryStore, error) {
+	return getOrCreateMemoryStore(name, prototype, nil)
+}
+
+// Gets a store by its name or creates it and initializes it under the registry lock
+func getOrCreateMemoryStore(name string, prototype reflect.Type, init func(store *MemoryStore)) (*MemoryStore, error) {
+	if prototype == nil {
+		return nil, errors.NewConfigError("", "NO_PROTOTYPE", "Prototype of data items is not set").
+			WithDetails("store", name)
+	}
+
+	memoryStoresLock.Lock()
+	defer memoryStoresLock.Unlock()
+
+	if store, ok := memoryStores[name]; ok {
+		if store.persistence.Prototype != prototype {
+			return nil, errors.NewConfigError("", "STORE_TYPE_MISMATCH",
+				"Memory store "+name+" keeps items of type "+store.persistence.Prototype.String()).
+				WithDetails("store", name).WithDetails("type", prototype.String())
+		}
+		return store, nil
+	}
+	store := &MemoryStore{
+		name:        name,
+		persistence: NewIdentifiableMemoryPersistence(prototype),
+	}
+	if init != nil {
+		init(store)
+	}
+	memoryStores[name] = store
+	return store, nil
+}
+
+// Removes a closed store from the registry, so the next GetMemoryStore creates an empty one.
+// Parameters:
+//   - name string
+//   a name of the store
+// Returns bool
+// true if the store was removed, false if it doesn't exist or is still open.
+func RemoveMemoryStore(name string) bool {
+	memoryStoresLock.Lock()
+	defer memoryStoresLock.Unlock()
+
+	store, ok := memoryStores[name]
+	if !ok || store.OpenCount() > 0 {
+		return false
+	}
+	delete(memoryStores, name)
+	return true
+}
+
+// Gets the name of the store.
+// Returns string
+func (c *MemoryStore) Name() string {
+	return c.name
+}
+
+// Gets the persistence that keeps items of the store. Its loader, saver and options
+// are set before the first component is opened.
+// Returns *IdentifiableMemoryPersistence
+func (c *MemoryStore) Persistence() *IdentifiableMemoryPersistence {
+	return c.persistence
+}
+
+// Gets the number of open components attached to the store.
+// Returns int
+func (c *MemoryStore) OpenCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.openCount
+}
+
+// Opens the persistence for the first attached component
+func (c *MemoryStore) open(correlationId string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.openCount == 0 {
+		if err := c.persistence.Open(correlationId); err != nil {
+			return err
+		}
+	}
+	c.openCount++
+	return nil
+}
+
+// Closes the persistence after the last attached component
+func (c *MemoryStore) close(correlationId string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.openCount == 0 {
+		return nil
+	}
+	c.openCount--
+	if c.openCount == 0 {
+		return c.persistence.Close(correlationId)
+	}
+	return nil
+}
+
+/*
+Persistence component attached to a named MemoryStore. All methods of IdentifiableMemoryPersistence
+work with the shared items, while Open and Close only attach and detach the component,
+so the items are loaded when the first component is opened and saved when the last one is closed.
+
+Options set by Configure are applied to the shared persistence, so they shall be the same
+in all components of the store.
+
+ Example
+
+  readModel, _ := NewSharedMemoryPersistence("orders", reflect.TypeOf(Order{}))
+  adminModel, _ := NewSharedMemoryPersistence("orders", reflect.TypeOf(Order{}))
+  readModel.Store().Persistence().Loader = persister
+  readModel.Store().Persistence().Saver = persister
+
+  readModel.Open("123")
+  adminModel.Open("123")
+  adminModel.Create("123", Order{Id: "1"})
+  order, _ := readModel.GetOneById("123", "1") // The created order
+*/
+type SharedMemoryPersistence struct {
+	*IdentifiableMemoryPersistence
+	store  *MemoryStore
+	opened bool
+}
+
+// Creates a new component attached to the named store.
+// Parameters:
+//   - name string
+//   a name of the shared store
+//   - prototype reflect.Type
+//   type of the stored items
+// Returns *SharedMemoryPersistence, error
+// the component or ConfigError when the store keeps items of a different type.
+func NewSharedMemoryPersistence(name string, prototype reflect.Type) (*SharedMemoryPersistence, error) {
+	store, err := GetMemoryStore(name, prototype)
+	if err != nil {
+		return nil, err
+	}
+	return newSharedMemoryPersistence(store), nil
+}
+
+func newSharedMemoryPersistence(store *MemoryStore) *SharedMemoryPersistence {
+	return &SharedMemoryPersistence{
+		IdentifiableMemoryPersistence: store.persistence,
+		store:                         store,
+	}
+}
+
+// Gets the store the component is attached to.
+// Returns *MemoryStore
+func (c *SharedMemoryPersistence) Store() *MemoryStore {
+	return c.store
+}
+
+// Checks if the component is opened.
+// Returns bool
+func (c *SharedMemoryPersistence) IsOpen() bool {
+	return c.opened
+}
+
+// Opens the component. The shared store is opened and its items are loaded
+// when it is the first open component of the store.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+func (c *SharedMemoryPersistence) Open(correlationId string) error {
+	if c.opened {
+		return nil
+	}
+	if err := c.store.open(correlationId); err != nil {
+		return err
+	}
+	c.opened = true
+	return nil
+}
+
+// Closes the component. The shared store is closed and its items are saved
+// when it is the last open component of the store.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+func (c *SharedMemoryPersistence) Close(correlationId string) error {
+	if !c.opened {
+		return nil
+	}
+	c.opened = false
+	return c.store.close(correlationId)
+}
@@ -0,0 +1,186 @@
+package persistence
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default buckets of duration histograms in seconds
+var DefaultMetricsBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+/*
+Metrics of a persistence exposed as Prometheus collectors:
+number of items, histograms of load and save durations and counters of errors.
+Metrics are labeled by the persistence name, so metrics of several persistences
+are registered in the same registry.
+
+Example
+
+    metrics := persistence.EnableMetrics("beacons")
+    prometheus.MustRegister(metrics)
+    http.Handle("/metrics", promhttp.Handler())
+*/
+// implements prometheus.Collector
+type PersistenceMetrics struct {
+	name   string
+	items  prometheus.GaugeFunc
+	load   prometheus.Histogram
+	save   prometheus.Histogram
+	errors *prometheus.CounterVec
+}
+
+// Creates metrics of the persistence labeled by its name
+func newPersistenceMetrics(name string, persistence *MemoryPersistence) *PersistenceMetrics {
+	labels := prometheus.Labels{"persistence": name}
+	return &PersistenceMetrics{
+		name: name,
+		items: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "pip_persistence_items",
+			Help:        "Number of items stored in the persistence.",
+			ConstLabels: labels,
+		}, func() float64 {
+			persistence.rlock()
+			defer persistence.runlock()
+			return float64(len(persistence.Items))
+		}),
+		load: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "pip_persistence_load_duration_seconds",
+			Help:        "Duration of loading items.",
+			ConstLabels: labels,
+			Buckets:     DefaultMetricsBuckets,
+		}),
+		save: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "pip_persistence_save_duration_seconds",
+			Help:        "Duration of saving items.",
+			ConstLabels: labels,
+			Buckets:     DefaultMetricsBuckets,
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "pip_persistence_errors_total",
+			Help:        "Number of failed operations.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+	}
+}
+
+// Records duration and result of an operation
+func (c *PersistenceMetrics) observe(operation string, duration time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	switch operation {
+	case PersisterOperationLoad:
+		c.load.Observe(duration.Seconds())
+	case PersisterOperationSave:
+		c.save.Observe(duration.Seconds())
+	}
+	if err != nil {
+		c.errors.WithLabelValues(operation).Inc()
+	}
+}
+
+// Sends descriptors of the metrics to the channel.
+// Parameters:
+//   - ch chan<- *prometheus.Desc
+//   a channel of descriptors
+func (c *PersistenceMetrics) Describe(ch chan<- *prometheus.Desc) {
+	c.items.Describe(ch)
+	c.load.Describe(ch)
+	c.save.Describe(ch)
+	c.errors.Describe(ch)
+}
+
+// Sends current values of the metrics to the channel.
+// Parameters:
+//   - ch chan<- prometheus.Metric
+//   a channel of metrics
+func (c *PersistenceMetrics) Collect(ch chan<- prometheus.Metric) {
+	c.items.Collect(ch)
+	c.load.Collect(ch)
+	c.save.Collect(ch)
+	c.errors.Collect(ch)
+}
+
+/*
+Collector of statistics of the pool of buffers that convert loaded items.
+The pool is shared by all persistences, so the collector is registered once.
+*/
+type loadPoolCollector struct {
+	buffers *prometheus.Desc
+}
+
+var loadPoolCollectorOnce sync.Once
+var loadPoolCollectorInstance *loadPoolCollector
+
+// Gets the collector of statistics of the pool of buffers that convert loaded items:
+// number of buffers reused from the pool and allocated.
+// Returns prometheus.Collector
+func LoadPoolCollector() prometheus.Collector {
+	loadPoolCollectorOnce.Do(func() {
+		loadPoolCollectorInstance = &loadPoolCollector{
+			buffers: prometheus.NewDesc("pip_persistence_load_buffers_total",
+				"Number of buffers used to convert loaded items, reused from the pool or allocated.",
+				[]string{"source"}, nil),
+		}
+	})
+	return loadPoolCollectorInstance
+}
+
+func (c *loadPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.buffers
+}
+
+func (c *loadPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	pool := GetLoadPoolStats()
+	ch <- prometheus.MustNewConstMetric(c.buffers, prometheus.CounterValue, float64(pool.Gets-pool.Allocations), "pool")
+	ch <- prometheus.MustNewConstMetric(c.buffers, prometheus.CounterValue, float64(pool.Allocations), "allocated")
+}
+
+// Creates HTTP handler for /metrics endpoint that serves metrics of several persistences
+// and the pool of load buffers from a dedicated registry, for applications
+// that don't register them in the default registry.
+// Parameters:
+//   - metrics ...*PersistenceMetrics
+//   metrics of persistences
+// Returns http.Handler, error
+// the handler or error when the metrics can't be registered, for instance because of duplicated names.
+func NewMetricsHandler(metrics ...*PersistenceMetrics) (http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(LoadPoolCollector()); err != nil {
+		return nil, err
+	}
+	for _, m := range metrics {
+		if err := registry.Register(m); err != nil {
+			return nil, err
+		}
+	}
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
+
+// Enables collection of metrics: number of items, durations of load and save and errors.
+// Parameters:
+//   - name string
+//   a name of the persistence used as label of the metrics
+// Returns *PersistenceMetrics
+// metrics to register in a Prometheus registry or to serve with NewMetricsHandler.
+func (c *MemoryPersistence) EnableMetrics(name string) *PersistenceMetrics {
+	c.lock()
+	defer c.unlock()
+
+	if c.metrics == nil || c.metrics.name != name {
+		c.metrics = newPersistenceMetrics(name, c)
+	}
+	return c.metrics
+}
+
+// Gets metrics of the persistence or nil if they are not enabled
+func (c *MemoryPersistence) Metrics() *PersistenceMetrics {
+	c.rlock()
+	defer c.runlock()
+
+	return c.metrics
+}
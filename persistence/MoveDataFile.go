@@ -0,0 +1,177 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Calculates SHA-256 checksum of the file
+func fileChecksum(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// Copies the file through a temporary file with a unique name and verifies checksum of the copy.
+// The copy keeps mode of the source file unless the mode is configured.
+func (c *JsonFilePersister) copyFile(correlationId string, srcPath string, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+srcPath).WithCause(err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+srcPath).WithCause(err)
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(dstPath), filepath.Base(dstPath)+".*.tmp")
+	if err != nil {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+dstPath).WithCause(err)
+	}
+	tempPath := dst.Name()
+	hash := sha256.New()
+	if c.FileMode == 0 {
+		err = dst.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = c.applyPermissions(correlationId, dst)
+	}
+	if err == nil {
+		_, err = io.Copy(io.MultiWriter(dst, hash), src)
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+
+	var checksum []byte
+	if err == nil {
+		checksum, err = fileChecksum(tempPath)
+	}
+	if err == nil && !bytes.Equal(checksum, hash.Sum(nil)) {
+		err = errors.NewFileError(correlationId, "CHECKSUM_MISMATCH", "Copy of data file is damaged: "+dstPath)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, dstPath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		if _, ok := err.(*errors.ApplicationError); ok {
+			return err
+		}
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+dstPath).WithCause(err)
+	}
+	return nil
+}
+
+// Moves the data file to a new location. The file is copied, the copy is verified
+// by checksum, and only then the persister switches to the new path and removes the old file.
+// Files next to the data file, like item versions and stored indexes, are moved as well.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - newPath string
+//   a new path of the data file
+// Returns error or nil for success.
+func (c *JsonFilePersister) MoveTo(correlationId string, newPath string) error {
+	if c.path == "" {
+		return errors.NewConfigError(correlationId, "NO_PATH", "Data file path is not set")
+	}
+	if newPath == "" {
+		return errors.NewBadRequestError(correlationId, "NO_PATH", "New data file path is not set")
+	}
+	if newPath == c.path {
+		return nil
+	}
+
+	oldPath := c.path
+	err := c.createDir(correlationId, newPath)
+	if err != nil {
+		return err
+	}
+
+	moved := [][2]string{}
+	for _, suffix := range []string{"", ".versions", ".indexes"} {
+		if _, err = os.Stat(oldPath + suffix); os.IsNotExist(err) {
+			continue
+		}
+		if err = c.copyFile(correlationId, oldPath+suffix, newPath+suffix); err != nil {
+			// Roll back already copied files
+			for _, paths := range moved {
+				os.Remove(paths[1])
+			}
+			return err
+		}
+		moved = append(moved, [2]string{oldPath + suffix, newPath + suffix})
+	}
+	if len(moved) > 0 {
+		syncDir(newPath)
+	}
+
+	c.path = newPath
+	for _, paths := range moved {
+		os.Remove(paths[0])
+	}
+	return nil
+}
+
+// Moves the data file while the persistence is open. Writes are blocked during the move,
+// current items are saved, then the file is copied, verified by checksum and the persister
+// switches to the new path.
+func (c *MemoryPersistence) moveDataFile(correlationId string, persister *JsonFilePersister, newPath string) error {
+	c.lock()
+	defer c.unlock()
+
+	if err := c.saveItems(correlationId); err != nil {
+		return err
+	}
+	oldPath := persister.Path()
+	if err := persister.MoveTo(correlationId, newPath); err != nil {
+		return err
+	}
+	c.rememberModified(correlationId)
+	c.Logger.Info(correlationId, "Moved data file from %s to %s", oldPath, newPath)
+	return nil
+}
+
+// Safely relocates the data file while the component is open.
+// Writes are paused during the move, the file is copied and verified by checksum
+// before the persistence switches to the new path.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - newPath string
+//   a new path of the data file
+// Returns error or nil for success.
+func (c *FilePersistence) MoveDataFile(correlationId string, newPath string) error {
+	return c.moveDataFile(correlationId, c.Persister, newPath)
+}
+
+// Safely relocates the data file while the component is open.
+// Writes are paused during the move, the file is copied and verified by checksum
+// before the persistence switches to the new path.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - newPath string
+//   a new path of the data file
+// Returns error or nil for success.
+func (c *IdentifiableFilePersistence) MoveDataFile(correlationId string, newPath string) error {
+	return c.moveDataFile(correlationId, c.Persister, newPath)
+}
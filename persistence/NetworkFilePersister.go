@@ -0,0 +1,401 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Methods to detect changes of the data file made by other writers
+const (
+	// Changes are not detected, the last save wins
+	ConflictDetectionNone = "none"
+	// Changes are detected by modification time and size of the data file
+	ConflictDetectionMtime = "mtime"
+	// Changes are detected by SHA-256 checksum of the data file content
+	ConflictDetectionEtag = "etag"
+)
+
+/*
+Version of the data file last loaded or saved by the persister.
+*/
+type networkFileVersion struct {
+	exists   bool
+	modified time.Time
+	size     int64
+	etag     string
+}
+
+/*
+Persistence component that keeps the data file on a network filesystem like NFS, SMB/CIFS
+or a WebDAV share mounted by davfs2, where renames are not always atomic, file handles
+go stale and other hosts can write the same file.
+
+Loads and saves are serialized between hosts by a lock file next to the data file,
+and lock files left by crashed processes are removed when they get older than lock_stale_timeout.
+Operations that fail with EBUSY, ESTALE or EAGAIN are retried. Saved content is read back
+and compared with written bytes, and saves are rejected with ConflictError when the data file
+was changed by another writer since it was last loaded or saved.
+
+The whole data file is read into memory before items are decoded, so a stale handle
+in the middle of the file retries the read instead of failing the load.
+
+ Configuration parameters
+
+  - path:                 path to the data file on the mounted share
+  - options:
+      - lock_timeout:       timeout to acquire the lock file in milliseconds (default: 30000)
+      - lock_stale_timeout: age of lock files left by crashed processes in milliseconds (default: 120000)
+      - retries:            number of retries of operations failed with transient errors (default: 5)
+      - retry_delay:        delay between retries in milliseconds, doubled with every retry (default: 200)
+      - verify_writes:      read the saved file back and compare its checksum (default: true)
+      - conflict_detection: none, mtime or etag (default: mtime)
+
+   Encoding options of JsonFilePersister like indent and file_mode are supported as well.
+
+ Example
+
+  persister := NewNetworkFilePersister(reflect.TypeOf(MyData{}), "/mnt/share/data.json")
+  persister.ConflictDetection = ConflictDetectionEtag
+
+  persistence := NewMemoryPersistence(reflect.TypeOf(MyData{}))
+  persistence.Loader = persister
+  persistence.Saver = persister
+*/
+// implements ILoader, ISaver, IConfigurable, IModificationTracker
+type NetworkFilePersister struct {
+	lock    sync.Mutex
+	version *networkFileVersion
+	// Persister that encodes and decodes items
+	Persister *JsonFilePersister
+	// Timeout to acquire the lock file (default: 30 seconds)
+	LockTimeout time.Duration
+	// Age of lock files left by crashed processes (default: 2 minutes)
+	LockStaleTimeout time.Duration
+	// Number of retries of operations failed with transient errors (default: 5)
+	Retries int
+	// Delay before the first retry, doubled with every retry (default: 200 milliseconds)
+	RetryDelay time.Duration
+	// Read the saved file back and compare its checksum (default: true)
+	VerifyWrites bool
+	// Method to detect changes made by other writers (default: mtime)
+	ConflictDetection string
+}
+
+// Creates a new instance of the persister.
+// Parameters:
+//   - prototype reflect.Type
+//   type of the data items
+//   - path string
+//   (optional) a path to the data file on the mounted share
+// Returns *NetworkFilePersister
+func NewNetworkFilePersister(prototype reflect.Type, path string) *NetworkFilePersister {
+	return &NetworkFilePersister{
+		Persister:         NewJsonFilePersister(prototype, path),
+		LockTimeout:       30 * time.Second,
+		LockStaleTimeout:  2 * time.Minute,
+		Retries:           5,
+		RetryDelay:        200 * time.Millisecond,
+		VerifyWrites:      true,
+		ConflictDetection: ConflictDetectionMtime,
+	}
+}
+
+// Gets the path to the data file.
+// Returns string
+func (c *NetworkFilePersister) Path() string {
+	return c.Persister.Path()
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//   - config  *config.ConfigParams
+//   parameters to be set
+func (c *NetworkFilePersister) Configure(config *config.ConfigParams) {
+	c.Persister.Configure(config)
+	c.LockTimeout = time.Duration(config.GetAsLongWithDefault("options.lock_timeout",
+		int64(c.LockTimeout/time.Millisecond))) * time.Millisecond
+	c.LockStaleTimeout = time.Duration(config.GetAsLongWithDefault("options.lock_stale_timeout",
+		int64(c.LockStaleTimeout/time.Millisecond))) * time.Millisecond
+	c.Retries = config.GetAsIntegerWithDefault("options.retries", c.Retries)
+	c.RetryDelay = time.Duration(config.GetAsLongWithDefault("options.retry_delay",
+		int64(c.RetryDelay/time.Millisecond))) * time.Millisecond
+	c.VerifyWrites = config.GetAsBooleanWithDefault("options.verify_writes", c.VerifyWrites)
+	c.ConflictDetection = config.GetAsStringWithDefault("options.conflict_detection", c.ConflictDetection)
+}
+
+// Checks if the error is caused by a busy resource or a stale handle and the operation can be retried
+func isTransientFileError(err error) bool {
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
+	} else if linkErr, ok := err.(*os.LinkError); ok {
+		err = linkErr.Err
+	}
+	return err == syscall.EBUSY || err == syscall.ESTALE || err == syscall.EAGAIN || err == syscall.EINTR
+}
+
+// Executes the file operation retrying it on transient errors
+func (c *NetworkFilePersister) retry(operation func() error) error {
+	delay := c.RetryDelay
+	err := operation()
+	for attempt := 0; attempt < c.Retries && isTransientFileError(err); attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = operation()
+	}
+	return err
+}
+
+// Creates the lock file waiting while it is held by other processes, and returns a function to remove it
+func (c *NetworkFilePersister) acquireLock(correlationId string) (func(), error) {
+	lockPath := c.Path() + ".lock"
+	if err := c.Persister.createDir(correlationId, lockPath); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s %d %s", host, os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+
+	deadline := time.Now().Add(c.LockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.WriteString(owner)
+			file.Close()
+			return func() { c.retry(func() error { return os.Remove(lockPath) }) }, nil
+		}
+		if !os.IsExist(err) && !isTransientFileError(err) {
+			return nil, errors.NewFileError(correlationId, "LOCK_FAILED", "Failed to create lock file: "+lockPath).WithCause(err)
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > c.LockStaleTimeout {
+			// The lock was left by a crashed process
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			owner, _ := ioutil.ReadFile(lockPath)
+			return nil, errors.NewConflictError(correlationId, "LOCK_TIMEOUT", "Data file is locked: "+c.Path()).
+				WithDetails("lock_path", lockPath).WithDetails("owner", string(owner))
+		}
+		if c.RetryDelay > 0 {
+			time.Sleep(c.RetryDelay)
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// Reads the data file and its version
+func (c *NetworkFilePersister) readFile() (content []byte, version *networkFileVersion, err error) {
+	err = c.retry(func() error {
+		version = &networkFileVersion{}
+		info, err := os.Stat(c.Path())
+		if os.IsNotExist(err) {
+			content = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if content, err = ioutil.ReadFile(c.Path()); err != nil {
+			return err
+		}
+		checksum := sha256.Sum256(content)
+		*version = networkFileVersion{exists: true, modified: info.ModTime(), size: info.Size(),
+			etag: hex.EncodeToString(checksum[:])}
+		return nil
+	})
+	return content, version, err
+}
+
+// Gets the current version of the data file for conflict detection
+func (c *NetworkFilePersister) currentVersion() (version *networkFileVersion, err error) {
+	if c.ConflictDetection == ConflictDetectionEtag {
+		_, version, err = c.readFile()
+		return version, err
+	}
+	err = c.retry(func() error {
+		info, err := os.Stat(c.Path())
+		if os.IsNotExist(err) {
+			version = &networkFileVersion{}
+			return nil
+		}
+		if err == nil {
+			version = &networkFileVersion{exists: true, modified: info.ModTime(), size: info.Size()}
+		}
+		return err
+	})
+	return version, err
+}
+
+// Checks if the data file was changed since it was last loaded or saved
+func (c *NetworkFilePersister) checkConflict(correlationId string) error {
+	if c.ConflictDetection == ConflictDetectionNone || c.version == nil {
+		return nil
+	}
+	current, err := c.currentVersion()
+	if err != nil {
+		return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+c.Path()).WithCause(err)
+	}
+	changed := current.exists != c.version.exists
+	if !changed && current.exists {
+		if c.ConflictDetection == ConflictDetectionEtag {
+			changed = current.etag != c.version.etag
+		} else {
+			changed = !current.modified.Equal(c.version.modified) || current.size != c.version.size
+		}
+	}
+	if changed {
+		return errors.NewConflictError(correlationId, "DATA_FILE_CONFLICT",
+			"Data file was changed by another writer: "+c.Path()).WithDetails("path", c.Path())
+	}
+	return nil
+}
+
+// Loads data items from the data file under the lock file.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items, nil when the data file doesn't exist, or error.
+func (c *NetworkFilePersister) Load(correlationId string) (data []interface{}, err error) {
+	if c.Path() == "" {
+		return nil, errors.NewConfigError(correlationId, "NO_PATH", "Data file path is not set")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	release, err := c.acquireLock(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	content, version, err := c.readFile()
+	if err != nil {
+		return nil, errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+c.Path()).WithCause(err)
+	}
+	if content != nil {
+		if data, err = c.Persister.LoadFrom(correlationId, bytes.NewReader(content)); err != nil {
+			return nil, err
+		}
+	}
+	c.version = version
+	return data, nil
+}
+
+// Writes the content into a temporary file that replaces the data file
+func (c *NetworkFilePersister) writeFile(correlationId string, content []byte) error {
+	tempPath := c.Path() + ".tmp"
+	err := c.retry(func() error {
+		file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, c.Persister.fileMode())
+		if err != nil {
+			return err
+		}
+		if err = c.Persister.applyPermissions(correlationId, file); err == nil {
+			if _, err = file.Write(content); err == nil {
+				err = file.Sync()
+			}
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+	if err == nil {
+		err = c.retry(func() error { return os.Rename(tempPath, c.Path()) })
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		if _, ok := err.(*errors.ApplicationError); ok {
+			return err
+		}
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+c.Path()).WithCause(err)
+	}
+	syncDir(c.Path())
+	return nil
+}
+
+// Saves given data items into the data file under the lock file.
+// The save is rejected when the data file was changed by another writer,
+// and the written file is read back and verified when VerifyWrites is set.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface{}
+//   list of data items to save
+// Returns error
+// ConflictError when the data file is locked or was changed by another writer,
+// FileError when it can't be written or verified.
+func (c *NetworkFilePersister) Save(correlationId string, items []interface{}) error {
+	if c.Path() == "" {
+		return errors.NewConfigError(correlationId, "NO_PATH", "Data file path is not set")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	buffer := &bytes.Buffer{}
+	if err := c.Persister.SaveTo(correlationId, buffer, items); err != nil {
+		return err
+	}
+	if err := c.Persister.createDir(correlationId, c.Path()); err != nil {
+		return err
+	}
+
+	release, err := c.acquireLock(correlationId)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err = c.checkConflict(correlationId); err != nil {
+		return err
+	}
+	if err = c.writeFile(correlationId, buffer.Bytes()); err != nil {
+		return err
+	}
+
+	var version *networkFileVersion
+	if c.VerifyWrites {
+		var content []byte
+		if content, version, err = c.readFile(); err != nil {
+			return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+c.Path()).WithCause(err)
+		}
+		if !bytes.Equal(content, buffer.Bytes()) {
+			c.version = nil
+			return errors.NewFileError(correlationId, "VERIFY_FAILED", "Saved data file differs from written data: "+c.Path()).
+				WithDetails("path", c.Path()).WithDetails("written", buffer.Len()).WithDetails("read", len(content))
+		}
+	} else if version, err = c.currentVersion(); err != nil {
+		return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+c.Path()).WithCause(err)
+	}
+	if version.exists && version.etag == "" {
+		checksum := sha256.Sum256(buffer.Bytes())
+		version.etag = hex.EncodeToString(checksum[:])
+	}
+	c.version = version
+	return nil
+}
+
+// Gets modification time of the data file, zero time when it doesn't exist.
+// Parameters:
+//   - correlationId string
+//   transaction id to trace execution through call chain.
+// Returns time.Time, error
+func (c *NetworkFilePersister) LastModified(correlationId string) (time.Time, error) {
+	return c.Persister.LastModified(correlationId)
+}
+
+// Gets information about the data file, nil if the file doesn't exist
+func (c *NetworkFilePersister) dataFileInfo(correlationId string) (os.FileInfo, error) {
+	return c.Persister.dataFileInfo(correlationId)
+}
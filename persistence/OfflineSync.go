@@ -0,0 +1,351 @@
+package persistence
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Strategies that define how MergeFrom resolves concurrent changes
+const (
+	// The whole item is taken from the replica that changed it last
+	MergeStrategyLastWriterWins = "lww"
+	// Each field is taken from the replica that changed it last
+	MergeStrategyFieldLevel = "field"
+)
+
+/*
+Version of an item tracked by a replica. Versions of deleted items
+are kept as tombstones, so deletions are not undone by merges.
+*/
+type ItemVersion struct {
+	Clock     VectorClock      `json:"clock"`
+	Timestamp int64            `json:"timestamp"`
+	Writer    string           `json:"writer"`
+	Deleted   bool             `json:"deleted"`
+	Fields    map[string]int64 `json:"fields,omitempty"`
+}
+
+// Creates a deep copy of the version
+func (c *ItemVersion) clone() *ItemVersion {
+	result := &ItemVersion{
+		Clock:     c.Clock.Merge(nil),
+		Timestamp: c.Timestamp,
+		Writer:    c.Writer,
+		Deleted:   c.Deleted,
+	}
+	if c.Fields != nil {
+		result.Fields = make(map[string]int64, len(c.Fields))
+		for name, timestamp := range c.Fields {
+			result.Fields[name] = timestamp
+		}
+	}
+	return result
+}
+
+// Checks if the version wins over another one in last-writer-wins resolution
+func (c *ItemVersion) winsOver(other *ItemVersion) bool {
+	if c.Timestamp != other.Timestamp {
+		return c.Timestamp > other.Timestamp
+	}
+	return c.Writer > other.Writer
+}
+
+func toIdKey(id interface{}) string {
+	return convert.StringConverter.ToString(id)
+}
+
+// Enables tracking of item versions for the replica
+func (c *IdentifiableMemoryPersistence) setReplicaId(replicaId string) {
+	c.ReplicaId = replicaId
+	if replicaId != "" && c.versions == nil {
+		c.versions = map[string]*ItemVersion{}
+		c.addChangeHandler(c.trackVersion)
+	}
+}
+
+// Gets a timestamp in milliseconds that is greater than all timestamps seen by the replica,
+// so later changes always win even when the system clock is behind or has low resolution
+func (c *IdentifiableMemoryPersistence) nextTimestamp() int64 {
+	now := c.Clock.Now().UnixNano() / int64(time.Millisecond)
+	if now <= c.lastTimestamp {
+		now = c.lastTimestamp + 1
+	}
+	c.lastTimestamp = now
+	return now
+}
+
+// Remembers the latest timestamp of the merged version
+func (c *IdentifiableMemoryPersistence) observeTimestamp(version *ItemVersion) {
+	if version.Timestamp > c.lastTimestamp {
+		c.lastTimestamp = version.Timestamp
+	}
+	for _, timestamp := range version.Fields {
+		if timestamp > c.lastTimestamp {
+			c.lastTimestamp = timestamp
+		}
+	}
+}
+
+// Updates version of a changed item
+func (c *IdentifiableMemoryPersistence) trackVersion(oldItem interface{}, newItem interface{}) {
+	if c.merging || (oldItem == nil && newItem == nil) {
+		return
+	}
+
+	item := newItem
+	if item == nil {
+		item = oldItem
+	}
+	key := toIdKey(c.ids.get(item))
+	now := c.nextTimestamp()
+
+	version, ok := c.versions[key]
+	if !ok {
+		version = &ItemVersion{}
+	}
+	version = version.clone()
+	version.Clock = version.Clock.Increment(c.ReplicaId)
+	version.Timestamp = now
+	version.Writer = c.ReplicaId
+	version.Deleted = newItem == nil
+	if c.MergeStrategy == MergeStrategyFieldLevel && newItem != nil {
+		if version.Fields == nil {
+			version.Fields = map[string]int64{}
+		}
+		for _, field := range DiffItemFields(oldItem, newItem) {
+			version.Fields[field.Field] = now
+		}
+	}
+	c.versions[key] = version
+}
+
+// Gets versions of items tracked by the replica including tombstones of deleted items.
+// Returns map[string]*ItemVersion
+// copies of item versions by item ids.
+func (c *IdentifiableMemoryPersistence) GetItemVersions() map[string]*ItemVersion {
+	c.rlock()
+	defer c.runlock()
+
+	result := make(map[string]*ItemVersion, len(c.versions))
+	for key, version := range c.versions {
+		result[key] = version.clone()
+	}
+	return result
+}
+
+// Sets a value of the item field found by its name or json tag
+func setItemField(item *interface{}, name string, value interface{}) {
+	if field, ok := findField(toFieldType(*item), name); ok {
+		name = field.Name
+	}
+	SetObjectProperty(item, name, value)
+}
+
+// Resolves concurrent changes of an item
+func (c *IdentifiableMemoryPersistence) resolveConflict(localItem interface{}, localVersion *ItemVersion,
+	otherItem interface{}, otherVersion *ItemVersion) (interface{}, *ItemVersion) {
+	winnerItem, winnerVersion := localItem, localVersion
+	loserItem, loserVersion := otherItem, otherVersion
+	if otherVersion.winsOver(localVersion) {
+		winnerItem, winnerVersion = otherItem, otherVersion
+		loserItem, loserVersion = localItem, localVersion
+	}
+
+	version := winnerVersion.clone()
+	version.Clock = localVersion.Clock.Merge(otherVersion.Clock)
+	if c.MergeStrategy != MergeStrategyFieldLevel || winnerItem == nil || loserItem == nil {
+		return winnerItem, version
+	}
+
+	// Take fields that were changed later by the loser
+	item := c.cloneItem(winnerItem)
+	loserFields := itemFields(loserItem)
+	names := make([]string, 0, len(loserVersion.Fields))
+	for name := range loserVersion.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if version.Fields == nil {
+		version.Fields = map[string]int64{}
+	}
+	for _, name := range names {
+		timestamp := loserVersion.Fields[name]
+		if timestamp > version.Fields[name] {
+			setItemField(&item, name, loserFields[name])
+			version.Fields[name] = timestamp
+		}
+	}
+	return item, version
+}
+
+// Merges items changed by another replica, for instance, a copy of the data edited offline.
+// Changes are ordered by vector clocks, concurrent changes are resolved
+// deterministically according to the merge strategy, so both replicas
+// get the same data after merging each other.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - other *IdentifiableMemoryPersistence
+//   a replica to merge changes from. It must have configured replica id
+// Returns error or nil for success.
+func (c *IdentifiableMemoryPersistence) MergeFrom(correlationId string, other *IdentifiableMemoryPersistence) error {
+	if c.versions == nil || other.versions == nil {
+		return errors.NewConfigError(correlationId, "NO_REPLICA_ID", "Replica id must be configured to merge changes")
+	}
+
+	// Take a snapshot of the other replica
+	other.rlock()
+	otherItems := map[string]interface{}{}
+	for _, item := range other.Items {
+		otherItems[toIdKey(c.ids.get(item))] = other.cloneItem(item)
+	}
+	otherVersions := make(map[string]*ItemVersion, len(other.versions))
+	for key, version := range other.versions {
+		otherVersions[key] = version.clone()
+	}
+	other.runlock()
+
+	keys := []string{}
+	for key := range otherVersions {
+		keys = append(keys, key)
+	}
+	for key := range otherItems {
+		if _, ok := otherVersions[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return err
+	}
+
+	localItems := map[string]interface{}{}
+	for _, item := range c.Items {
+		localItems[toIdKey(c.ids.get(item))] = item
+	}
+
+	c.merging = true
+	changed := 0
+	for _, key := range keys {
+		localItem, localExists := localItems[key]
+		otherItem := otherItems[key]
+		localVersion, ok := c.versions[key]
+		if !ok {
+			localVersion = &ItemVersion{Clock: VectorClock{}}
+		}
+		otherVersion, ok := otherVersions[key]
+		if !ok {
+			otherVersion = &ItemVersion{Clock: VectorClock{}}
+		}
+
+		var item interface{}
+		var version *ItemVersion
+		switch localVersion.Clock.Compare(otherVersion.Clock) {
+		case ClockBefore:
+			item, version = otherItem, otherVersion
+		case ClockConcurrent:
+			if !localExists {
+				localItem = nil
+			}
+			item, version = c.resolveConflict(localItem, localVersion, otherItem, otherVersion)
+		default:
+			// Items without versions are copied from the other replica
+			if localExists || otherItem == nil || len(otherVersion.Clock) > 0 {
+				continue
+			}
+			item, version = otherItem, otherVersion
+		}
+		c.versions[key] = version
+		c.observeTimestamp(version)
+		changed++
+
+		index := -1
+		if localExists {
+			index = c.GetIndexById(c.ids.get(localItem))
+		}
+		switch {
+		case item == nil && index >= 0:
+			c.notifyChange(c.Items[index], nil)
+			c.Items = append(c.Items[:index], c.Items[index+1:]...)
+		case item != nil && index >= 0:
+			c.notifyChange(c.Items[index], item)
+			c.Items[index] = item
+		case item != nil:
+			c.notifyChange(nil, item)
+			c.Items = append(c.Items, item)
+		}
+	}
+	c.merging = false
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Merged %d changed items from replica %s", changed, other.ReplicaId)
+
+	return c.save(correlationId)
+}
+
+/*
+Persister that stores item versions in a file next to the data file,
+so replicas edited offline can be merged after restart.
+*/
+type versionsFilePersister struct {
+	persister   *JsonFilePersister
+	persistence *IdentifiableMemoryPersistence
+}
+
+func (c *versionsFilePersister) path() string {
+	return c.persister.Path() + ".versions"
+}
+
+func (c *versionsFilePersister) Load(correlationId string) (data []interface{}, err error) {
+	data, err = c.persister.Load(correlationId)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, err := ioutil.ReadFile(c.path())
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, errors.NewFileError(correlationId, "READ_FAILED", "Failed to read versions file: "+c.path()).WithCause(err)
+	}
+	versions := map[string]*ItemVersion{}
+	if err = json.Unmarshal(buffer, &versions); err != nil {
+		return nil, errors.NewFileError(correlationId, "PARSE_FAILED", "Failed to parse versions file: "+c.path()).WithCause(err)
+	}
+	for _, version := range versions {
+		if version.Clock == nil {
+			version.Clock = VectorClock{}
+		}
+	}
+	c.persistence.versions = versions
+	for _, version := range versions {
+		c.persistence.observeTimestamp(version)
+	}
+	return data, nil
+}
+
+func (c *versionsFilePersister) Save(correlationId string, items []interface{}) error {
+	if err := c.persister.Save(correlationId, items); err != nil {
+		return err
+	}
+
+	buffer, err := json.Marshal(c.persistence.versions)
+	if err != nil {
+		return errors.NewInternalError(correlationId, "CAN'T_CONVERT", "Failed convert to JSON").WithCause(err)
+	}
+	if err = c.persister.writeSideFile(correlationId, c.path(), buffer); err != nil {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write versions file: "+c.path()).WithCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package persistence
+
+import (
+	"time"
+)
+
+// Writes a structured debug log entry about a completed operation
+// when logging of operations is turned on by options.log_operations.
+func (c *MemoryPersistence) logOperation(correlationId string, operation string, start time.Time, id interface{}, err error) {
+	duration := time.Since(start).Seconds() * 1000
+	if err != nil {
+		c.Logger.Debug(correlationId, "operation=%s id=%v duration=%.3fms result=error error=%q",
+			operation, id, duration, err.Error())
+		return
+	}
+	c.Logger.Debug(correlationId, "operation=%s id=%v duration=%.3fms result=ok", operation, id, duration)
+}
+
+// Gets the id of the resulting item, or the id of the requested item
+// when the operation failed.
+func (c *MemoryPersistence) operationItemId(result interface{}, item interface{}) interface{} {
+	if result != nil {
+		return c.ids.get(result)
+	}
+	return c.ids.get(item)
+}
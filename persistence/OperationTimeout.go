@@ -0,0 +1,160 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Number of items evaluated by filters between checks of the operation deadline
+const deadlineCheckInterval = 1024
+
+// Creates an error for an operation that exceeded OperationTimeout
+func newOperationTimeoutError(correlationId string, operation string, timeout int64) *errors.ApplicationError {
+	return errors.NewInvalidStateError(correlationId, "OPERATION_TIMEOUT",
+		"Operation "+operation+" timed out after "+strconv.FormatInt(timeout, 10)+" ms").
+		WithDetails("operation", operation).
+		WithDetails("timeout", timeout)
+}
+
+// Gets a deadline for an operation started now or zero time when operations have no timeout
+func (c *MemoryPersistence) operationDeadline() time.Time {
+	if c.OperationTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(c.OperationTimeout) * time.Millisecond)
+}
+
+// Checks that the operation deadline has not passed
+func (c *MemoryPersistence) checkDeadline(correlationId string, operation string, deadline time.Time) error {
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return newOperationTimeoutError(correlationId, operation, c.OperationTimeout)
+	}
+	return nil
+}
+
+/*
+Guard of calls to the loader and saver. It is held until a call returns, even when
+the call was abandoned after OperationTimeout, so calls never run concurrently
+and an abandoned save can't overwrite items written by a later one.
+*/
+type persisterGuard struct {
+	once sync.Once
+	slot chan struct{}
+}
+
+// Acquires the guard, waits until the context is done, returns false when it was not acquired
+func (c *persisterGuard) acquire(ctx context.Context) bool {
+	c.once.Do(func() { c.slot = make(chan struct{}, 1) })
+	select {
+	case c.slot <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *persisterGuard) release() {
+	<-c.slot
+}
+
+// Calls a load or save operation with OperationTimeout.
+// The call is cancelled through the context, and if it doesn't return in time
+// it is abandoned, so a hung file system doesn't block the persistence lock.
+// Calls are serialized, a new call waits for an abandoned one within its own timeout.
+func (c *MemoryPersistence) withOperationTimeout(correlationId string, operation string,
+	call func(ctx context.Context) error) error {
+	timeout := c.OperationTimeout
+	if timeout <= 0 {
+		c.persisterCalls.acquire(context.Background())
+		defer c.persisterCalls.release()
+		return call(context.Background())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+	defer cancel()
+
+	if !c.persisterCalls.acquire(ctx) {
+		c.Logger.Warn(correlationId, "Skipped %s because an abandoned call is still running", operation)
+		return newOperationTimeoutError(correlationId, operation, timeout).
+			WithDetails("reason", "previous call is still running")
+	}
+
+	// The result is reported to the caller or logged when the call was abandoned
+	var lock sync.Mutex
+	finished, abandoned := false, false
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		err := call(ctx)
+		c.persisterCalls.release()
+
+		lock.Lock()
+		finished = true
+		wasAbandoned := abandoned
+		lock.Unlock()
+		if wasAbandoned && err != nil {
+			c.Logger.Warn(correlationId, "Abandoned %s failed after %v: %v", operation, time.Since(start), err)
+		} else if wasAbandoned {
+			c.Logger.Info(correlationId, "Abandoned %s finished after %v", operation, time.Since(start))
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return newOperationTimeoutError(correlationId, operation, timeout).WithCause(err)
+		}
+		return err
+	case <-ctx.Done():
+		lock.Lock()
+		abandoned = !finished
+		lock.Unlock()
+		if !abandoned {
+			if err := <-done; err != nil {
+				return newOperationTimeoutError(correlationId, operation, timeout).WithCause(err)
+			}
+			return nil
+		}
+		c.Logger.Warn(correlationId, "Abandoned %s after %d ms", operation, timeout)
+		return newOperationTimeoutError(correlationId, operation, timeout)
+	}
+}
+
+// Loads items with the configured loader within OperationTimeout
+func (c *MemoryPersistence) loadWithTimeout(correlationId string) ([]interface{}, error) {
+	loader := c.Loader
+	// Loaded items are read only after the call returns in time
+	var items []interface{}
+	err := c.withOperationTimeout(correlationId, PersisterOperationLoad, func(ctx context.Context) (err error) {
+		if contextLoader, ok := loader.(IContextLoader); ok {
+			items, err = contextLoader.LoadWithContext(ctx, correlationId)
+		} else {
+			items, err = loader.Load(correlationId)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Saves items with the configured saver within OperationTimeout
+func (c *MemoryPersistence) saveWithTimeout(correlationId string, items []interface{}) error {
+	saver := c.Saver
+	if c.OperationTimeout > 0 {
+		// Abandoned saves must not see later changes of items
+		items = append([]interface{}(nil), items...)
+	}
+	return c.withOperationTimeout(correlationId, PersisterOperationSave, func(ctx context.Context) error {
+		if contextSaver, ok := saver.(IContextSaver); ok {
+			return contextSaver.SaveWithContext(ctx, correlationId, items)
+		}
+		return saver.Save(correlationId, items)
+	})
+}
@@ -0,0 +1,181 @@
+package persistence
+
+import (
+	"reflect"
+)
+
+// MemoryPersistenceOption sets an optional parameter of a memory persistence
+// created by NewMemoryPersistenceWithOptions and similar constructors.
+type MemoryPersistenceOption func(c *MemoryPersistence)
+
+// IndexOption enables an index of items passed to WithIndexes option.
+type IndexOption func(c *MemoryPersistence)
+
+// Sets a loader to load items when the persistence is opened.
+// Parameters:
+//   - loader ILoader
+//   a loader of items
+// Returns MemoryPersistenceOption
+func WithLoader(loader ILoader) MemoryPersistenceOption {
+	return func(c *MemoryPersistence) {
+		c.Loader = loader
+	}
+}
+
+// Sets a saver to save items after changes.
+// Parameters:
+//   - saver ISaver
+//   a saver of items
+// Returns MemoryPersistenceOption
+func WithSaver(saver ISaver) MemoryPersistenceOption {
+	return func(c *MemoryPersistence) {
+		c.Saver = saver
+	}
+}
+
+// Sets the maximum number of items returned in a single page.
+// Parameters:
+//   - maxPageSize int
+//   the maximum page size
+// Returns MemoryPersistenceOption
+func WithMaxPageSize(maxPageSize int) MemoryPersistenceOption {
+	return func(c *MemoryPersistence) {
+		c.MaxPageSize = maxPageSize
+	}
+}
+
+// Sets the strategy to copy stored and returned items.
+// Parameters:
+//   - strategy string
+//   one of CloneStrategyShallow, CloneStrategyDeep or CloneStrategyNone
+// Returns MemoryPersistenceOption
+func WithCloneStrategy(strategy string) MemoryPersistenceOption {
+	return func(c *MemoryPersistence) {
+		c.CloneStrategy = strategy
+	}
+}
+
+// Sets the clock used for timestamps, TTLs, retention and autosave timers.
+// Parameters:
+//   - clock IClock
+//   a clock, for instance ManualClock in tests
+// Returns MemoryPersistenceOption
+func WithClock(clock IClock) MemoryPersistenceOption {
+	return func(c *MemoryPersistence) {
+		c.Clock = clock
+	}
+}
+
+// Enables indexes of items.
+// Parameters:
+//   - indexes ...IndexOption
+//   indexes created by GeoIndex and TimeIndex
+// Returns MemoryPersistenceOption
+func WithIndexes(indexes ...IndexOption) MemoryPersistenceOption {
+	return func(c *MemoryPersistence) {
+		for _, index := range indexes {
+			index(c)
+		}
+	}
+}
+
+// Creates a spatial index on latitude and longitude fields for geospatial queries.
+// Parameters:
+//   - precision int
+//   geohash precision of the index
+// Returns IndexOption
+func GeoIndex(precision int) IndexOption {
+	return func(c *MemoryPersistence) {
+		c.setGeoIndexPrecision(precision)
+	}
+}
+
+// Creates an index of time partitions for date range queries.
+// Parameters:
+//   - timestampField string
+//   name of the item field with timestamp
+//   - period int64
+//   period of time partitions in milliseconds, 0 for one day
+// Returns IndexOption
+func TimeIndex(timestampField string, period int64) IndexOption {
+	return func(c *MemoryPersistence) {
+		if period <= 0 {
+			period = 24 * 60 * 60 * 1000
+		}
+		c.TimestampField = timestampField
+		c.setPartitionPeriod(period)
+	}
+}
+
+// Applies options to the persistence under lock
+func (c *MemoryPersistence) applyOptions(opts []MemoryPersistenceOption) {
+	c.lock()
+	defer c.unlock()
+
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+// Creates a new instance of the MemoryPersistence with optional parameters.
+// Parameters:
+//  - prototype reflect.Type
+//   type of contained data
+//  - opts ...MemoryPersistenceOption
+//   optional parameters, for instance WithLoader or WithMaxPageSize
+// Return *MemoryPersistence
+// a MemoryPersistence
+func NewMemoryPersistenceWithOptions(prototype reflect.Type, opts ...MemoryPersistenceOption) *MemoryPersistence {
+	c := NewMemoryPersistence(prototype)
+	c.applyOptions(opts)
+	return c
+}
+
+// Creates a new instance of the IdentifiableMemoryPersistence with optional parameters.
+// Parameters:
+//  - prototype reflect.Type
+//   type of contained data
+//  - opts ...MemoryPersistenceOption
+//   optional parameters, for instance WithLoader or WithMaxPageSize
+// Return *IdentifiableMemoryPersistence
+// created IdentifiableMemoryPersistence
+func NewIdentifiableMemoryPersistenceWithOptions(prototype reflect.Type,
+	opts ...MemoryPersistenceOption) *IdentifiableMemoryPersistence {
+	c := NewIdentifiableMemoryPersistence(prototype)
+	c.applyOptions(opts)
+	return c
+}
+
+// Creates a new instance of the FilePersistence with optional parameters.
+// Parameters:
+//  - prototype reflect.Type
+//   type of contained data
+//  - persister *JsonFilePersister
+//   (optional) a persister component that loads and saves data from/to flat file.
+//  - opts ...MemoryPersistenceOption
+//   optional parameters, for instance WithMaxPageSize or WithIndexes
+// Return *FilePersistence
+// created FilePersistence
+func NewFilePersistenceWithOptions(prototype reflect.Type, persister *JsonFilePersister,
+	opts ...MemoryPersistenceOption) *FilePersistence {
+	c := NewFilePersistence(prototype, persister)
+	c.applyOptions(opts)
+	return c
+}
+
+// Creates a new instance of the IdentifiableFilePersistence with optional parameters.
+// Parameters:
+//  - prototype reflect.Type
+//   type of contained data
+//  - persister *JsonFilePersister
+//   (optional) a persister component that loads and saves data from/to flat file.
+//  - opts ...MemoryPersistenceOption
+//   optional parameters, for instance WithMaxPageSize or WithIndexes
+// Return *IdentifiableFilePersistence
+// created IdentifiableFilePersistence
+func NewIdentifiableFilePersistenceWithOptions(prototype reflect.Type, persister *JsonFilePersister,
+	opts ...MemoryPersistenceOption) *IdentifiableFilePersistence {
+	c := NewIdentifiableFilePersistence(prototype, persister)
+	c.applyOptions(opts)
+	return c
+}
@@ -0,0 +1,35 @@
+package persistence
+
+import (
+	"strconv"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Checks that paging parameters don't exceed MaxSkip and MaxTake limits.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+// Returns error
+// BadRequestError with SKIP_TOO_LARGE or TAKE_TOO_LARGE code, or nil if paging is within limits.
+func (c *MemoryPersistence) checkPaging(correlationId string, paging *cdata.PagingParams) error {
+	if paging == nil {
+		return nil
+	}
+	if c.MaxSkip > 0 && paging.Skip != nil && *paging.Skip > c.MaxSkip {
+		return errors.NewBadRequestError(correlationId, "SKIP_TOO_LARGE",
+			"Skip "+strconv.FormatInt(*paging.Skip, 10)+" exceeds maximum of "+strconv.FormatInt(c.MaxSkip, 10)).
+			WithDetails("skip", *paging.Skip).
+			WithDetails("max_skip", c.MaxSkip)
+	}
+	if c.MaxTake > 0 && paging.Take != nil && *paging.Take > c.MaxTake {
+		return errors.NewBadRequestError(correlationId, "TAKE_TOO_LARGE",
+			"Take "+strconv.FormatInt(*paging.Take, 10)+" exceeds maximum of "+strconv.FormatInt(c.MaxTake, 10)).
+			WithDetails("take", *paging.Take).
+			WithDetails("max_take", c.MaxTake)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Number of items unmarshaled by a worker at once
+const parallelLoadChunkSize = 1000
+
+/*
+Chunk of raw items unmarshaled by a load worker.
+*/
+type loadChunk struct {
+	raw   []json.RawMessage
+	items []interface{}
+	err   error
+}
+
+// Unmarshals raw items of the chunk
+func (c *loadChunk) unmarshal() {
+	c.items = make([]interface{}, len(c.raw))
+	for i, raw := range c.raw {
+		if c.err = json.Unmarshal(raw, &c.items[i]); c.err != nil {
+			break
+		}
+	}
+	c.raw = nil
+}
+
+// Splits items of the array into chunks of raw JSON while reading the file,
+// and unmarshals the chunks by LoadParallelism goroutines.
+// Only boundaries of items are found on the reading goroutine, and chunks are joined
+// in the original order when all workers are done.
+func (c *JsonFilePersister) decodeItemsParallel(ctx context.Context, correlationId string, decoder *json.Decoder,
+	reader *countingReader, progress *PersisterProgress, parseError func(cause error) error) ([]interface{}, error) {
+	jobs := make(chan *loadChunk, c.LoadParallelism)
+	var wg sync.WaitGroup
+	for w := 0; w < c.LoadParallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				chunk.unmarshal()
+			}
+		}()
+	}
+
+	chunks := []*loadChunk{}
+	current := &loadChunk{raw: make([]json.RawMessage, 0, parallelLoadChunkSize)}
+	var err error
+	for decoder.More() {
+		if err = checkCancelled(ctx, correlationId); err != nil {
+			break
+		}
+		var raw json.RawMessage
+		if jsonerr := decoder.Decode(&raw); jsonerr != nil {
+			err = parseError(jsonerr)
+			break
+		}
+		current.raw = append(current.raw, raw)
+		if len(current.raw) == parallelLoadChunkSize {
+			chunks = append(chunks, current)
+			jobs <- current
+			current = &loadChunk{raw: make([]json.RawMessage, 0, parallelLoadChunkSize)}
+		}
+		progress.Items++
+		progress.Bytes = reader.count
+		c.reportProgress(correlationId, progress, false)
+	}
+	if err == nil && len(current.raw) > 0 {
+		chunks = append(chunks, current)
+		jobs <- current
+	}
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]interface{}, 0, progress.Items)
+	for _, chunk := range chunks {
+		if chunk.err != nil {
+			return nil, parseError(chunk.err)
+		}
+		data = append(data, chunk.items...)
+	}
+	return data, nil
+}
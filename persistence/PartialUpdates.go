@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Removes nil and zero values from partial data
+func omitEmptyValues(values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if value == nil || reflect.ValueOf(value).IsZero() {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// Updates fields listed in the field mask with values taken from the data.
+// Unlike UpdatePartially, masked fields are always set, so zero values explicitly clear the fields,
+// while fields that are not in the mask are never changed regardless of their values in the data.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be updated.
+//   - data interface{}
+//   a struct, a pointer to struct or a map with new values of the fields
+//   - mask []string
+//   names, json tags or paths like "address.city" of the fields to update
+// Returns: interface{}, error
+// updated item, BadRequestError if the mask contains fields that are not defined in the data struct or error.
+func (c *IdentifiableMemoryPersistence) UpdatePartiallyWithMask(correlationId string, id interface{},
+	data interface{}, mask []string) (result interface{}, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "update_partially", start, id, err) }(time.Now())
+	}
+
+	values := make(map[string]interface{}, len(mask))
+	typ := reflect.TypeOf(data)
+	for _, path := range mask {
+		if typ != nil && isStructType(typ) {
+			if _, ok := findPathType(typ, path); !ok {
+				return nil, errors.NewBadRequestError(correlationId, "UNKNOWN_FIELD",
+					"Field "+path+" is not defined in "+typ.String()).WithDetails("field", path)
+			}
+		}
+		values[path] = GetPathValue(data, path)
+	}
+	return c.updatePartially(correlationId, id, values)
+}
@@ -0,0 +1,190 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+/*
+Time partition of items. It keeps items which timestamps fall
+into the same period together with min and max timestamp.
+*/
+type timePartition struct {
+	start   int64
+	minTime time.Time
+	maxTime time.Time
+	items   []interface{}
+	// Positions of the items in the persistence
+	positions []int
+}
+
+/*
+Index that groups items into time partitions by a timestamp field.
+The index is rebuilt lazily on the first query after items change.
+*/
+type timePartitionIndex struct {
+	lock       sync.Mutex
+	period     int64
+	dirty      bool
+	partitions []*timePartition
+}
+
+func (c *timePartitionIndex) invalidate(oldItem interface{}, newItem interface{}) {
+	c.lock.Lock()
+	c.dirty = true
+	c.lock.Unlock()
+}
+
+// Groups all items into partitions. Must be called under index lock.
+func (c *timePartitionIndex) rebuild(persistence *MemoryPersistence) {
+	byStart := map[int64]*timePartition{}
+	c.partitions = []*timePartition{}
+	for position, item := range persistence.Items {
+		timestamp, ok := persistence.GetItemTimestamp(item)
+		if !ok {
+			continue
+		}
+		start := timestamp.UnixNano() / int64(time.Millisecond)
+		start = start - ((start%c.period)+c.period)%c.period
+		partition, ok := byStart[start]
+		if !ok {
+			partition = &timePartition{start: start, minTime: timestamp, maxTime: timestamp}
+			byStart[start] = partition
+			c.partitions = append(c.partitions, partition)
+		}
+		if timestamp.Before(partition.minTime) {
+			partition.minTime = timestamp
+		}
+		if timestamp.After(partition.maxTime) {
+			partition.maxTime = timestamp
+		}
+		partition.items = append(partition.items, item)
+		partition.positions = append(partition.positions, position)
+	}
+	sort.Slice(c.partitions, func(i, j int) bool {
+		return c.partitions[i].start < c.partitions[j].start
+	})
+	c.dirty = false
+}
+
+// Gets partitions that overlap the given time range
+func (c *timePartitionIndex) find(persistence *MemoryPersistence, from time.Time, to time.Time) (partitions []*timePartition, total int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.dirty || c.partitions == nil {
+		c.rebuild(persistence)
+	}
+
+	for _, partition := range c.partitions {
+		if !from.IsZero() && partition.maxTime.Before(from) {
+			continue
+		}
+		if !to.IsZero() && partition.minTime.After(to) {
+			continue
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions, len(c.partitions)
+}
+
+// Gets timestamp of the item from its timestamp field.
+// Parameters:
+//   - item interface{}
+//   an item to get timestamp from
+// Returns time.Time, bool
+// the item timestamp and true if the field is present and can be converted to time
+func (c *MemoryPersistence) GetItemTimestamp(item interface{}) (time.Time, bool) {
+	if c.TimestampField == "" {
+		return time.Time{}, false
+	}
+	var index []int
+	if field, ok := findField(reflect.TypeOf(item), c.TimestampField); ok {
+		index = field.Index
+	}
+	timestamp := convert.DateTimeConverter.ToNullableDateTime(getFieldValue(item, index, c.TimestampField))
+	if timestamp == nil || timestamp.IsZero() {
+		return time.Time{}, false
+	}
+	return *timestamp, true
+}
+
+// Sets period of time partitions for items
+func (c *MemoryPersistence) setPartitionPeriod(period int64) {
+	if c.partitionIndex == nil {
+		c.partitionIndex = &timePartitionIndex{}
+		c.addChangeHandler(c.partitionIndex.invalidate)
+	}
+	c.partitionIndex.lock.Lock()
+	if c.partitionIndex.period != period {
+		c.partitionIndex.period = period
+		c.partitionIndex.dirty = true
+	}
+	c.partitionIndex.lock.Unlock()
+}
+
+// Gets a page of items which timestamps fall into the given range.
+// Time partitions outside the range are skipped without scanning their items.
+// Items are returned in the order of their timestamp partitions unless sort function is set.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - from time.Time
+//   (optional) a start of the range inclusive, zero time for no limit
+//   - to time.Time
+//   (optional) an end of the range inclusive, zero time for no limit
+//   - filterFunc func(interface{}) bool
+//   (optional) an additional filter function
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) sorting compare function func Less (a, b interface{}) bool  see sort.Interface Less function
+// Returns *cdata.DataPage, error
+// data page or error.
+func (c *MemoryPersistence) GetPageByDateRange(correlationId string, from time.Time, to time.Time,
+	filterFunc func(interface{}) bool, paging *cdata.PagingParams, sortFunc func(a, b interface{}) bool) (page *cdata.DataPage, err error) {
+	if err = c.checkPaging(correlationId, paging); err != nil {
+		return nil, err
+	}
+	c.rlock()
+	defer c.runlock()
+
+	if c.partitionIndex == nil {
+		return cdata.NewDataPage(nil, []interface{}{}), nil
+	}
+
+	partitions, total := c.partitionIndex.find(c, from, to)
+	items := []interface{}{}
+	for _, partition := range partitions {
+		for _, item := range partition.items {
+			timestamp, _ := c.GetItemTimestamp(item)
+			if !from.IsZero() && timestamp.Before(from) {
+				continue
+			}
+			if !to.IsZero() && timestamp.After(to) {
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+	if filterFunc != nil {
+		if items, err = c.filterItemsOf(correlationId, items, filterFunc); err != nil {
+			return nil, err
+		}
+	}
+
+	if sortFunc != nil {
+		if err = c.sortItems(correlationId, items, sortFunc); err != nil {
+			return nil, err
+		}
+	}
+
+	page = c.extractPage(items, paging)
+	c.Logger.Trace(correlationId, "Retrieved %d items from %d of %d time partitions", len(page.Data), len(partitions), total)
+	return page, nil
+}
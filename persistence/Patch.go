@@ -0,0 +1,378 @@
+package persistence
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Operations of JSON Patch (RFC 6902)
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+/*
+Operation of JSON Patch (RFC 6902). Paths are JSON Pointers like "/address/city" or "/tags/0".
+*/
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func newInvalidPatchError(message string, path string) error {
+	return errors.NewBadRequestError("", "INVALID_PATCH", message).WithDetails("path", path)
+}
+
+// Converts a value into generic JSON document of maps, arrays and primitive values
+func toJsonDocument(value interface{}) (interface{}, error) {
+	buffer, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(buffer, &result)
+	return result, err
+}
+
+// Splits JSON Pointer into reference tokens
+func parseJsonPointer(path string) ([]string, error) {
+	if path == "" {
+		return []string{}, nil
+	}
+	if path[0] != '/' {
+		return nil, newInvalidPatchError("Path "+path+" is not a valid JSON Pointer", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Parses index of array element, "-" is accepted when appending
+func parseArrayIndex(token string, length int, appending bool, path string) (int, error) {
+	if appending && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && token[0] == '0') {
+		return 0, newInvalidPatchError("Path "+path+" has invalid array index", path)
+	}
+	if index > length || (!appending && index == length) {
+		return 0, newInvalidPatchError("Path "+path+" is out of array bounds", path)
+	}
+	return index, nil
+}
+
+// Gets a value from the document by reference tokens
+func getPatchValue(doc interface{}, tokens []string, path string) (interface{}, error) {
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+			}
+			doc = value
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), false, path)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+		}
+	}
+	return doc, nil
+}
+
+// Applies a change to the parent of the last token and returns the updated document
+func patchNode(doc interface{}, tokens []string, path string,
+	apply func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return apply(doc, tokens[0])
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+		}
+		child, err := patchNode(child, tokens[1:], path, apply)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = child
+		return node, nil
+	case []interface{}:
+		index, err := parseArrayIndex(tokens[0], len(node), false, path)
+		if err != nil {
+			return nil, err
+		}
+		child, err := patchNode(node[index], tokens[1:], path, apply)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+	}
+	return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+}
+
+func addPatchValue(doc interface{}, tokens []string, value interface{}, path string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return patchNode(doc, tokens, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), true, path)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+	})
+}
+
+func removePatchValue(doc interface{}, tokens []string, path string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return patchNode(doc, tokens, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), false, path)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:index], node[index+1:]...), nil
+		}
+		return nil, newInvalidPatchError("Path "+path+" doesn't exist", path)
+	})
+}
+
+// Applies JSON Patch (RFC 6902) operations to a generic JSON document.
+// The document is changed in place, so callers shall pass a copy if the original must be preserved.
+// Parameters:
+//   - doc interface{}
+//   a JSON document of maps, arrays and primitive values
+//   - operations []PatchOperation
+//   patch operations to apply
+// Returns interface{}, error
+// patched document, BadRequestError if the patch is not valid or ConflictError if a test operation failed.
+func ApplyJsonPatch(doc interface{}, operations []PatchOperation) (interface{}, error) {
+	for _, operation := range operations {
+		tokens, err := parseJsonPointer(operation.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch operation.Op {
+		case PatchAdd:
+			doc, err = addPatchValue(doc, tokens, operation.Value, operation.Path)
+		case PatchRemove:
+			doc, err = removePatchValue(doc, tokens, operation.Path)
+		case PatchReplace:
+			if _, err = getPatchValue(doc, tokens, operation.Path); err == nil {
+				if doc, err = removePatchValue(doc, tokens, operation.Path); err == nil {
+					doc, err = addPatchValue(doc, tokens, operation.Value, operation.Path)
+				}
+			}
+		case PatchMove, PatchCopy:
+			var from []string
+			var value interface{}
+			if from, err = parseJsonPointer(operation.From); err != nil {
+				return nil, err
+			}
+			if operation.Op == PatchMove && strings.HasPrefix(operation.Path+"/", operation.From+"/") &&
+				operation.Path != operation.From {
+				return nil, newInvalidPatchError("Value can't be moved into its own child", operation.Path)
+			}
+			if value, err = getPatchValue(doc, from, operation.From); err != nil {
+				return nil, err
+			}
+			if operation.Op == PatchMove {
+				doc, err = removePatchValue(doc, from, operation.From)
+			} else {
+				value, err = toJsonDocument(value)
+			}
+			if err == nil {
+				doc, err = addPatchValue(doc, tokens, value, operation.Path)
+			}
+		case PatchTest:
+			var value interface{}
+			if value, err = getPatchValue(doc, tokens, operation.Path); err == nil &&
+				!reflect.DeepEqual(value, operation.Value) {
+				err = errors.NewConflictError("", "PATCH_TEST_FAILED", "Value at "+operation.Path+" doesn't match").
+					WithDetails("path", operation.Path)
+			}
+		default:
+			err = newInvalidPatchError("Patch operation "+operation.Op+" is not supported", operation.Path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// Applies merge patch (RFC 7386) to a generic JSON document.
+// Null values in the patch remove fields, objects are merged recursively and other values replace fields.
+// Parameters:
+//   - doc interface{}
+//   a JSON document of maps, arrays and primitive values
+//   - patch interface{}
+//   a merge patch
+// Returns interface{}
+// patched document.
+func ApplyMergePatch(doc interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	docMap, ok := doc.(map[string]interface{})
+	if !ok {
+		docMap = map[string]interface{}{}
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(docMap, key)
+		} else {
+			docMap[key] = ApplyMergePatch(docMap[key], value)
+		}
+	}
+	return docMap
+}
+
+// Applies JSON Patch or merge patch to an item and converts the result into the item type
+func patchItem(item interface{}, patch interface{}) (interface{}, error) {
+	var patchDoc interface{}
+	var err error
+	switch v := patch.(type) {
+	case []byte:
+		err = json.Unmarshal(v, &patchDoc)
+	case string:
+		err = json.Unmarshal([]byte(v), &patchDoc)
+	default:
+		patchDoc, err = toJsonDocument(patch)
+	}
+	if err != nil {
+		return nil, newInvalidPatchError("Patch is not a valid JSON", "")
+	}
+	doc, err := toJsonDocument(item)
+	if err != nil {
+		return nil, newInvalidItemError("")
+	}
+
+	switch patchDoc.(type) {
+	case []interface{}:
+		operations := []PatchOperation{}
+		buffer, _ := json.Marshal(patchDoc)
+		if err = json.Unmarshal(buffer, &operations); err != nil {
+			return nil, newInvalidPatchError("Patch operations are not valid", "")
+		}
+		if doc, err = ApplyJsonPatch(doc, operations); err != nil {
+			return nil, err
+		}
+	case map[string]interface{}:
+		doc = ApplyMergePatch(doc, patchDoc)
+	default:
+		return nil, newInvalidPatchError("Patch must be an array of operations or an object", "")
+	}
+
+	buffer, err := json.Marshal(doc)
+	if err != nil {
+		return nil, newInvalidItemError("")
+	}
+	value := reflect.New(reflect.TypeOf(item))
+	if err = json.Unmarshal(buffer, value.Interface()); err != nil {
+		return nil, errors.NewBadRequestError("", "INVALID_PATCH", "Patched item doesn't match data type").
+			WithCause(err)
+	}
+	return value.Elem().Interface(), nil
+}
+
+// Updates a data item by JSON Patch (RFC 6902) operations or merge patch (RFC 7386).
+// The patch is applied atomically: when any operation fails the item is not changed.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be updated.
+//   - patch interface{}
+//   an array of PatchOperation or generic operations for JSON Patch, an object for merge patch,
+//   or the patch serialized into JSON string or bytes.
+// Returns interface{}, error
+// updated item or error.
+func (c *IdentifiableMemoryPersistence) UpdateByPatch(correlationId string, id interface{},
+	patch interface{}) (result interface{}, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "update_by_patch", start, id, err) }(time.Now())
+	}
+	c.lock()
+
+	if err = c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	index := c.GetIndexById(id)
+	if index < 0 {
+		c.Logger.Trace(correlationId, "Item %s was not found", id)
+		c.unlock()
+		return nil, c.notFound(correlationId, id)
+	}
+
+	oldItem := c.Items[index]
+	newItem, err := patchItem(oldItem, patch)
+	if err == nil && !c.ids.isSameItem(oldItem, newItem) {
+		err = errors.NewBadRequestError("", "ID_CHANGED", "Patch can't change id of the item").
+			WithDetails("id", id)
+	}
+	if err != nil {
+		c.unlock()
+		return nil, wrapError(err, correlationId, "PATCH_FAILED", "Failed to patch item")
+	}
+
+	c.applyComputedFields(&newItem)
+	if err = c.validateChange(correlationId, oldItem, newItem); err != nil {
+		c.unlock()
+		return nil, err
+	}
+	c.notifyChange(oldItem, newItem)
+	c.Items[index] = newItem
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Patched item %s", id)
+
+	errsave := c.save(correlationId)
+
+	result = c.cloneResult(newItem)
+	return result, errsave
+}
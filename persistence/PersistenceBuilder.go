@@ -0,0 +1,301 @@
+package persistence
+
+import (
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+	"github.com/pip-services3-go/pip-services3-commons-go/run"
+)
+
+// PersistenceDecorator wraps a persistence into a decorator, for instance NewRateLimitedPersistence.
+// Decorators keep the decorated persistence and implement the same operations on top of it.
+type PersistenceDecorator func(persistence interface{}) interface{}
+
+/*
+Builder that chains decorators like caching, rate limiting, audit or
+change publication around a base persistence in the declared order.
+The first declared decorator wraps the base persistence and the last one
+receives calls first.
+
+The result is a single component for the container that passes configuration,
+references and lifecycle calls to all layers.
+
+Example
+
+    persistence := NewPersistenceBuilder(NewMyFilePersistence()).
+        With(func(p interface{}) interface{} { return NewMyAuditPersistence(p) }).
+        WithRateLimit().
+        Build()
+    persistence.Configure(NewConfigParamsFromTuples("path", "./data/items.json", "options.rate", 10))
+    err := persistence.Open("123")
+*/
+type PersistenceBuilder struct {
+	base       interface{}
+	decorators []PersistenceDecorator
+}
+
+// Creates a new builder for the base persistence.
+// Parameters:
+//   - base interface{}
+//   the base persistence that stores items
+// Returns *PersistenceBuilder
+func NewPersistenceBuilder(base interface{}) *PersistenceBuilder {
+	return &PersistenceBuilder{base: base}
+}
+
+// Adds a decorator on top of the previously declared ones.
+// Parameters:
+//   - decorator PersistenceDecorator
+//   a function that wraps the persistence into the decorator
+// Returns *PersistenceBuilder
+// the builder to chain calls
+func (c *PersistenceBuilder) With(decorator PersistenceDecorator) *PersistenceBuilder {
+	c.decorators = append(c.decorators, decorator)
+	return c
+}
+
+// Adds RateLimitedPersistence decorator on top of the previously declared ones.
+// Returns *PersistenceBuilder
+// the builder to chain calls
+func (c *PersistenceBuilder) WithRateLimit() *PersistenceBuilder {
+	return c.With(func(persistence interface{}) interface{} {
+		return NewRateLimitedPersistence(persistence)
+	})
+}
+
+// Builds the chain of decorators around the base persistence.
+// Returns *DecoratedPersistence
+// the component that exposes the outer decorator
+func (c *PersistenceBuilder) Build() *DecoratedPersistence {
+	layers := []interface{}{c.base}
+	persistence := c.base
+	for _, decorator := range c.decorators {
+		persistence = decorator(persistence)
+		layers = append(layers, persistence)
+	}
+	return &DecoratedPersistence{Persistence: persistence, layers: layers}
+}
+
+/*
+Persistence built by PersistenceBuilder. Data operations are passed to the outer decorator,
+configuration and references are passed to all layers. Layers are opened from
+the base persistence to the outer decorator and closed in the reverse order.
+*/
+// implements IConfigurable, IReferenceable, IUnreferenceable, IOpenable, ICleanable,
+// IGetter, IWriter, ISetter, IPartialUpdater
+type DecoratedPersistence struct {
+	// The outer decorator
+	Persistence interface{}
+	layers      []interface{}
+}
+
+// Gets all layers starting from the base persistence.
+// Returns []interface{}
+func (c *DecoratedPersistence) Layers() []interface{} {
+	return append([]interface{}{}, c.layers...)
+}
+
+// Configures all layers by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *DecoratedPersistence) Configure(conf *config.ConfigParams) {
+	for _, layer := range c.layers {
+		if configurable, ok := layer.(config.IConfigurable); ok {
+			configurable.Configure(conf)
+		}
+	}
+}
+
+// Sets references to dependent components of all layers.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the component dependencies.
+func (c *DecoratedPersistence) SetReferences(references refer.IReferences) {
+	for _, layer := range c.layers {
+		if referenceable, ok := layer.(refer.IReferenceable); ok {
+			referenceable.SetReferences(references)
+		}
+	}
+}
+
+// Unsets references to dependent components of all layers.
+func (c *DecoratedPersistence) UnsetReferences() {
+	for _, layer := range c.layers {
+		if unreferenceable, ok := layer.(refer.IUnreferenceable); ok {
+			unreferenceable.UnsetReferences()
+		}
+	}
+}
+
+// Checks if the base persistence is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *DecoratedPersistence) IsOpen() bool {
+	if openable, ok := c.layers[0].(run.IOpenable); ok {
+		return openable.IsOpen()
+	}
+	return true
+}
+
+// Opens all layers from the base persistence to the outer decorator.
+// Layers that were opened are closed again when one of them fails.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil when no errors occured.
+func (c *DecoratedPersistence) Open(correlationId string) error {
+	for index, layer := range c.layers {
+		openable, ok := layer.(run.IOpenable)
+		if !ok || openable.IsOpen() {
+			continue
+		}
+		if err := openable.Open(correlationId); err != nil {
+			c.close(correlationId, index-1)
+			return err
+		}
+	}
+	return nil
+}
+
+// Closes all layers from the outer decorator to the base persistence.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil when no errors occured.
+func (c *DecoratedPersistence) Close(correlationId string) error {
+	return c.close(correlationId, len(c.layers)-1)
+}
+
+// Closes layers starting from the given index down to the base persistence
+// and returns the first error
+func (c *DecoratedPersistence) close(correlationId string, from int) error {
+	var result error
+	for index := from; index >= 0; index-- {
+		if closable, ok := c.layers[index].(run.IClosable); ok {
+			if err := closable.Close(correlationId); err != nil && result == nil {
+				result = err
+			}
+		}
+	}
+	return result
+}
+
+// Clears all layers.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil when no errors occured.
+func (c *DecoratedPersistence) Clear(correlationId string) error {
+	for _, layer := range c.layers {
+		if cleanable, ok := layer.(run.ICleanable); ok {
+			if err := cleanable.Clear(correlationId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *DecoratedPersistence) unsupported(correlationId string, operation string) error {
+	return errors.NewUnsupportedError(correlationId, "NOT_SUPPORTED",
+		"Operation "+operation+" is not supported by decorated persistence")
+}
+
+// Gets a data item by its unique id.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be retrieved.
+// Returns interface{}, error
+// data item or error.
+func (c *DecoratedPersistence) GetOneById(correlationId string, id interface{}) (item interface{}, err error) {
+	getter, ok := c.Persistence.(IGetter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "GetOneById")
+	}
+	return getter.GetOneById(correlationId, id)
+}
+
+// Creates a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be created.
+// Returns interface{}, error
+// created item or error.
+func (c *DecoratedPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Create")
+	}
+	return writer.Create(correlationId, item)
+}
+
+// Updates a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be updated.
+// Returns interface{}, error
+// updated item or error.
+func (c *DecoratedPersistence) Update(correlationId string, item interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Update")
+	}
+	return writer.Update(correlationId, item)
+}
+
+// Deleted a data item by it's unique id.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the item to be deleted
+// Returns interface{}, error
+// deleted item or error.
+func (c *DecoratedPersistence) DeleteById(correlationId string, id interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "DeleteById")
+	}
+	return writer.DeleteById(correlationId, id)
+}
+
+// Sets a data item. If the data item exists it updates it, otherwise it create a new data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   a item to be set.
+// Returns interface{}, error
+// updated item or error.
+func (c *DecoratedPersistence) Set(correlationId string, item interface{}) (result interface{}, err error) {
+	setter, ok := c.Persistence.(ISetter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Set")
+	}
+	return setter.Set(correlationId, item)
+}
+
+// Updates only few selected fields in a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be updated.
+//   - data *cdata.AnyValueMap
+//   a map with fields to be updated.
+// Returns interface{}, error
+// updated item or error.
+func (c *DecoratedPersistence) UpdatePartially(correlationId string, id interface{}, data *cdata.AnyValueMap) (result interface{}, err error) {
+	updater, ok := c.Persistence.(IPartialUpdater)
+	if !ok {
+		return nil, c.unsupported(correlationId, "UpdatePartially")
+	}
+	return updater.UpdatePartially(correlationId, id, data)
+}
@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"path/filepath"
+	"reflect"
+)
+
+// Prefix of names of memory stores created for data files
+const fileStorePrefix = "file:"
+
+// Gets a persistence of the data file shared by all components in the process.
+// Each call returns a new component attached to one MemoryStore per data file,
+// so the file is loaded when the first component is opened and saved when the last one
+// is closed, and components never load and overwrite the same file independently.
+// Paths are compared in their absolute form, and a data file can be shared
+// only by components with the same item type.
+// Parameters:
+//   - prototype reflect.Type
+//   type of the stored items
+//   - path string
+//   a path to the data file
+// Returns *SharedMemoryPersistence, error
+// the component, or ConfigError when the prototype is nil, the path is empty or the data file
+// is already used with items of another type.
+//
+// Example
+//
+//   orders, _ := ProvideFilePersistence(reflect.TypeOf(Order{}), "./data/orders.json")
+//   reports, _ := ProvideFilePersistence(reflect.TypeOf(Order{}), "data/orders.json")
+//   orders.Open("123")  // Loads the data file
+//   reports.Open("123") // Uses loaded items
+//   orders.Close("123")
+//   reports.Close("123") // Saves the data file
+func ProvideFilePersistence(prototype reflect.Type, path string) (*SharedMemoryPersistence, error) {
+	if err := validatePrototypeAndPath(prototype, path); err != nil {
+		return nil, err
+	}
+	if path != StdioPath {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+	}
+
+	store, err := getOrCreateMemoryStore(fileStorePrefix+path, prototype, func(store *MemoryStore) {
+		persister := NewJsonFilePersister(prototype, path)
+		store.persistence.Loader = persister
+		store.persistence.Saver = persister
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newSharedMemoryPersistence(store), nil
+}
+
+// Removes a closed persistence of the data file from the process,
+// so the next ProvideFilePersistence loads the file again with new options.
+// Parameters:
+//   - path string
+//   a path to the data file
+// Returns bool
+// true if the persistence was removed, false if it doesn't exist or is still open.
+func ReleaseFilePersistence(path string) bool {
+	if path != StdioPath {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+	}
+	return RemoveMemoryStore(fileStorePrefix + path)
+}
@@ -0,0 +1,80 @@
+package persistence
+
+import (
+	"context"
+	"io"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Operations reported by progress callbacks
+const (
+	PersisterOperationLoad = "load"
+	PersisterOperationSave = "save"
+)
+
+/*
+Progress of a long load or save operation reported to ProgressCallback.
+*/
+type PersisterProgress struct {
+	// Operation: load or save
+	Operation string
+	// Number of processed items
+	Items int64
+	// Number of read or written bytes
+	Bytes int64
+	// Size of the data file for load or 0 when it is unknown
+	TotalBytes int64
+	// True when the operation is completed
+	Done bool
+}
+
+/*
+Reader that counts read bytes.
+*/
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+/*
+Writer that counts written bytes.
+*/
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// Reports progress when the callback is set and the interval of items has passed
+func (c *JsonFilePersister) reportProgress(correlationId string, progress *PersisterProgress, force bool) {
+	if c.ProgressCallback == nil {
+		return
+	}
+	interval := int64(c.ProgressInterval)
+	if interval <= 0 {
+		interval = 1000
+	}
+	if force || progress.Items%interval == 0 {
+		c.ProgressCallback(correlationId, *progress)
+	}
+}
+
+// Checks if the operation was cancelled through the context
+func checkCancelled(ctx context.Context, correlationId string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.NewInvalidStateError(correlationId, "CANCELLED", "Operation was cancelled").WithCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+)
+
+// Resolves loader and saver components from references by descriptors set in
+// dependencies.persister, dependencies.loader and dependencies.saver configuration
+// parameters, so the storage backend can be changed in container configuration.
+// The persister is used as both loader and saver, while the loader and saver
+// override each of them separately.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the loader and saver.
+func (c *MemoryPersistence) resolvePersister(references refer.IReferences) {
+	c.lock()
+	defer c.unlock()
+
+	c.dependencyResolver.SetReferences(references)
+	if persister := c.dependencyResolver.GetOneOptional("persister"); persister != nil {
+		c.setLoaderAndSaver(persister, persister)
+	}
+	if loader := c.dependencyResolver.GetOneOptional("loader"); loader != nil {
+		c.setLoaderAndSaver(loader, nil)
+	}
+	if saver := c.dependencyResolver.GetOneOptional("saver"); saver != nil {
+		c.setLoaderAndSaver(nil, saver)
+	}
+}
+
+// Sets the loader and saver if they implement ILoader and ISaver interfaces.
+// Must be called under lock.
+func (c *MemoryPersistence) setLoaderAndSaver(loader interface{}, saver interface{}) {
+	if l, ok := loader.(ILoader); ok {
+		c.Loader = l
+		if persister, ok := loader.(*JsonFilePersister); ok {
+			persister.Prototype = c.Prototype
+		}
+	}
+	if s, ok := saver.(ISaver); ok {
+		c.Saver = s
+	}
+}
+
+// Sets references to dependent components. A JSON file persister
+// resolved by dependencies.persister descriptor replaces the default one.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the component dependencies.
+func (c *FilePersistence) SetReferences(references refer.IReferences) {
+	c.MemoryPersistence.SetReferences(references)
+	if persister, ok := c.Loader.(*JsonFilePersister); ok {
+		c.Persister = persister
+	}
+}
+
+// Sets references to dependent components. A JSON file persister
+// resolved by dependencies.persister descriptor replaces the default one.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the component dependencies.
+func (c *IdentifiableFilePersistence) SetReferences(references refer.IReferences) {
+	c.IdentifiableMemoryPersistence.SetReferences(references)
+
+	c.lock()
+	defer c.unlock()
+	if persister, ok := c.Loader.(*JsonFilePersister); ok {
+		c.Persister = persister
+		// Keep item versions next to the data file
+		if c.versions != nil {
+			versions := &versionsFilePersister{persister: persister, persistence: &c.IdentifiableMemoryPersistence}
+			c.Loader = versions
+			c.Saver = versions
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+/*
+Registry of concrete types stored in a persistence of interface items.
+Each type has a name that is saved in the discriminator field,
+so the right struct can be reconstructed on load.
+*/
+type itemSubtypes struct {
+	field  string
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+func valueType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem()
+	}
+	return typ
+}
+
+// Gets type of the item by value of its discriminator field in serialized form
+func (c *itemSubtypes) resolve(item map[string]interface{}) (reflect.Type, bool) {
+	name, ok := item[c.field].(string)
+	if !ok {
+		return nil, false
+	}
+	prototype, ok := c.byName[name]
+	return prototype, ok
+}
+
+// Converts items into maps with discriminator field
+func (c *itemSubtypes) encode(items []interface{}) []interface{} {
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		name, ok := c.byType[valueType(reflect.TypeOf(item))]
+		if !ok {
+			result[i] = item
+			continue
+		}
+		buffer, err := json.Marshal(item)
+		value := map[string]interface{}{}
+		if err != nil || json.Unmarshal(buffer, &value) != nil {
+			result[i] = item
+			continue
+		}
+		value[c.field] = name
+		result[i] = value
+	}
+	return result
+}
+
+// Registers a concrete type of items. It allows to store items of different types
+// in a persistence which prototype is an interface they all implement.
+// The type name is saved in the discriminator field configured by options.type_field.
+// Parameters:
+//   - name string
+//   a name of the type stored in the discriminator field
+//   - prototype reflect.Type
+//   a concrete type of items
+//
+// Example
+//
+//   persistence := NewIdentifiableMemoryPersistence(reflect.TypeOf((*Notification)(nil)).Elem())
+//   persistence.RegisterSubtype("email", reflect.TypeOf(EmailNotification{}))
+//   persistence.RegisterSubtype("sms", reflect.TypeOf(SmsNotification{}))
+func (c *MemoryPersistence) RegisterSubtype(name string, prototype reflect.Type) {
+	c.lock()
+	defer c.unlock()
+
+	if c.subtypes == nil {
+		c.subtypes = &itemSubtypes{
+			field:  "type",
+			byName: map[string]reflect.Type{},
+			byType: map[reflect.Type]string{},
+		}
+	}
+	c.subtypes.byName[name] = valueType(prototype)
+	c.subtypes.byType[valueType(prototype)] = name
+}
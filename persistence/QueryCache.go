@@ -0,0 +1,131 @@
+package persistence
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+/*
+Cache of query results used by MemoryPersistence for queries by FilterParams.
+Entries are keyed by query kind, filter and paging parameters
+and the whole cache is invalidated on every change of items.
+When the cache is full the oldest entries are evicted first.
+*/
+type queryCache struct {
+	lock    sync.Mutex
+	maxSize int
+	version int64
+	entries map[string]interface{}
+	keys    []string
+}
+
+func newQueryCache(maxSize int) *queryCache {
+	return &queryCache{
+		maxSize: maxSize,
+		entries: map[string]interface{}{},
+	}
+}
+
+// Gets a cached result by its key
+func (c *queryCache) get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	value, ok := c.entries[key]
+	return value, ok
+}
+
+// Gets the current cache version. It shall be taken before executing
+// a query and passed to put, so results calculated before invalidation are not cached.
+func (c *queryCache) currentVersion() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.version
+}
+
+// Puts a query result into the cache if it wasn't invalidated since the given version
+func (c *queryCache) put(key string, version int64, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if version != c.version {
+		return
+	}
+	if _, ok := c.entries[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.entries[key] = value
+
+	for len(c.keys) > c.maxSize {
+		delete(c.entries, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+}
+
+// Removes all cached results
+func (c *queryCache) invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.version++
+	c.entries = map[string]interface{}{}
+	c.keys = nil
+}
+
+// Composes a cache key from query kind, filter and paging parameters
+func queryCacheKey(kind string, filter *cdata.FilterParams, paging *cdata.PagingParams) string {
+	var builder strings.Builder
+	builder.WriteString(kind)
+
+	if filter != nil {
+		keys := filter.Keys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			builder.WriteString(";")
+			builder.WriteString(strconv.Quote(key))
+			builder.WriteString("=")
+			builder.WriteString(strconv.Quote(filter.GetAsString(key)))
+		}
+	}
+
+	if paging != nil {
+		builder.WriteString("|")
+		if paging.Skip != nil {
+			builder.WriteString(strconv.FormatInt(*paging.Skip, 10))
+		}
+		builder.WriteString(",")
+		if paging.Take != nil {
+			builder.WriteString(strconv.FormatInt(*paging.Take, 10))
+		}
+		builder.WriteString("," + strconv.FormatBool(paging.Total))
+	}
+
+	return builder.String()
+}
+
+// Makes a copy of cached items, so callers can't modify the cache
+func (c *MemoryPersistence) cloneResults(items []interface{}) []interface{} {
+	if items == nil {
+		return nil
+	}
+	results := make([]interface{}, len(items))
+	for i, item := range items {
+		results[i] = c.cloneResult(item)
+	}
+	return results
+}
+
+// Makes a copy of a cached page, so callers can't modify the cache
+func (c *MemoryPersistence) clonePage(page *cdata.DataPage) *cdata.DataPage {
+	var total *int64
+	if page.Total != nil {
+		value := *page.Total
+		total = &value
+	}
+	return cdata.NewDataPage(total, c.cloneResults(page.Data))
+}
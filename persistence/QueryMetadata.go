@@ -0,0 +1,71 @@
+package persistence
+
+import (
+	"time"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+/*
+QueryMetadata describes how a page query was executed.
+API layers can return it to clients or write it to logs to diagnose slow queries.
+*/
+type QueryMetadata struct {
+	// Number of items scanned to execute the query
+	ScannedItems int64 `json:"scanned_items"`
+	// Number of items that match the filter
+	MatchedItems int64 `json:"matched_items"`
+	// Time spent to execute the query
+	Duration time.Duration `json:"duration"`
+	// True if an index is used to select items instead of full scan
+	IndexUsed bool `json:"index_used"`
+	// Name of the used index
+	Index string `json:"index"`
+	// True if the requested take was reduced to the maximum page size
+	TakeTruncated bool `json:"take_truncated"`
+	// True if more matched items follow the returned page
+	HasMore bool `json:"has_more"`
+}
+
+/*
+DataPageEx is a data page extended with execution metadata of the query.
+*/
+type DataPageEx struct {
+	*cdata.DataPage
+	Metadata *QueryMetadata `json:"metadata"`
+}
+
+// Gets a page of data items retrieved by a given filter together with execution metadata:
+// numbers of scanned and matched items, duration, used index and truncation flags.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function to filter items
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) sorting compare function
+//   - selectFunc func(in interface{}) (out interface{})
+//   (optional) projection parameters
+// Returns *DataPageEx, error
+// data page with metadata or error.
+func (c *MemoryPersistence) GetPageByFilterEx(correlationId string, filterFunc func(interface{}) bool,
+	paging *cdata.PagingParams, sortFunc func(a, b interface{}) bool,
+	selectFunc func(in interface{}) (out interface{})) (page *DataPageEx, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "get_page_by_filter_ex", start, nil, err) }(time.Now())
+	}
+
+	start := time.Now()
+	metadata := &QueryMetadata{}
+	dataPage, err := c.getPageByFilter(correlationId, filterFunc, nil, paging, sortFunc, selectFunc, metadata)
+	if err != nil {
+		return nil, err
+	}
+	metadata.Duration = time.Since(start)
+
+	c.Logger.Trace(correlationId, "Scanned %d items, matched %d items in %v", metadata.ScannedItems,
+		metadata.MatchedItems, metadata.Duration)
+	return &DataPageEx{DataPage: dataPage, Metadata: metadata}, nil
+}
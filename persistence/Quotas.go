@@ -0,0 +1,161 @@
+package persistence
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Quota of items per value of a key field, for instance, per tenant or owner.
+Counts of items are maintained on every change, so checks don't scan items.
+*/
+type itemQuota struct {
+	field    string
+	maxItems int64
+	limits   map[string]int64
+	counts   map[string]int64
+}
+
+// Gets value of the quota key field of the item
+func (c *itemQuota) key(item interface{}) string {
+	if item == nil {
+		return ""
+	}
+	var index []int
+	if field, ok := findField(reflect.TypeOf(item), c.field); ok {
+		index = field.Index
+	}
+	value := getFieldValue(item, index, c.field)
+	if value == nil {
+		return ""
+	}
+	return convert.StringConverter.ToString(value)
+}
+
+// Gets maximum number of items for the key, 0 means unlimited
+func (c *itemQuota) limit(key string) int64 {
+	if limit, ok := c.limits[key]; ok {
+		return limit
+	}
+	return c.maxItems
+}
+
+// Recalculates counts of items per key
+func (c *itemQuota) recount(items []interface{}) {
+	c.counts = map[string]int64{}
+	for _, item := range items {
+		if key := c.key(item); key != "" {
+			c.counts[key]++
+		}
+	}
+}
+
+// Sets the field which values are used as quota keys
+func (c *MemoryPersistence) setQuotaField(field string) {
+	if field == "" {
+		c.quota = nil
+		return
+	}
+	if c.quota == nil {
+		c.quota = &itemQuota{limits: map[string]int64{}}
+		c.addChangeHandler(c.updateQuota)
+	}
+	if c.quota.field != field {
+		c.quota.field = field
+		c.quota.recount(c.Items)
+	}
+}
+
+// Updates counts of items per key on change
+func (c *MemoryPersistence) updateQuota(oldItem interface{}, newItem interface{}) {
+	quota := c.quota
+	if quota == nil {
+		return
+	}
+	if oldItem == nil && newItem == nil {
+		quota.recount(c.Items)
+		return
+	}
+	if key := quota.key(oldItem); key != "" {
+		quota.counts[key]--
+	}
+	if key := quota.key(newItem); key != "" {
+		quota.counts[key]++
+	}
+}
+
+// Checks if adding of the item or moving it from another key exceeds the quota, must be called under lock
+func (c *MemoryPersistence) checkQuota(correlationId string, oldItem interface{}, newItem interface{}) error {
+	quota := c.quota
+	if quota == nil {
+		return nil
+	}
+	key := quota.key(newItem)
+	if key == "" || key == quota.key(oldItem) {
+		return nil
+	}
+	limit := quota.limit(key)
+	if limit > 0 && quota.counts[key] >= limit {
+		return errors.NewConflictError(correlationId, "QUOTA_EXCEEDED",
+			"Quota of "+strconv.FormatInt(limit, 10)+" items for "+quota.field+" "+key+" is exceeded").
+			WithDetails("field", quota.field).
+			WithDetails("key", key).
+			WithDetails("limit", limit)
+	}
+	return nil
+}
+
+// Sets maximum number of items for a specific value of the quota field.
+// It overrides the default limit set by options.quota_max_items.
+// Parameters:
+//   - key string
+//   a value of the quota field, for instance, a tenant id
+//   - maxItems int64
+//   a maximum number of items, 0 for unlimited
+func (c *MemoryPersistence) SetQuotaLimit(key string, maxItems int64) {
+	c.lock()
+	defer c.unlock()
+
+	if c.quota != nil {
+		c.quota.limits[key] = maxItems
+	}
+}
+
+// Gets number of items for a value of the quota field.
+// Parameters:
+//   - key string
+//   a value of the quota field, for instance, a tenant id
+// Returns int64
+// number of items or 0 if quotas are not configured
+func (c *MemoryPersistence) GetQuotaUsage(key string) int64 {
+	c.rlock()
+	defer c.runlock()
+
+	if c.quota == nil {
+		return 0
+	}
+	return c.quota.counts[key]
+}
+
+// Checks if a whole set of items fits into quotas, must be called under lock
+func (c *MemoryPersistence) checkQuotas(correlationId string, items []interface{}) error {
+	quota := c.quota
+	if quota == nil {
+		return nil
+	}
+	check := &itemQuota{field: quota.field, maxItems: quota.maxItems, limits: quota.limits}
+	check.recount(items)
+	for key, count := range check.counts {
+		if limit := check.limit(key); limit > 0 && count > limit {
+			return errors.NewConflictError(correlationId, "QUOTA_EXCEEDED",
+				"Quota of "+strconv.FormatInt(limit, 10)+" items for "+quota.field+" "+key+" is exceeded").
+				WithDetails("field", quota.field).
+				WithDetails("key", key).
+				WithDetails("limit", limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package persistence
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Token bucket that refills with constant rate up to its capacity.
+*/
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	accessed time.Time
+}
+
+/*
+Decorator that throttles operations of a persistence per caller
+with token buckets. It protects the persistence lock and file I/O
+from callers that send too many requests.
+
+By default callers are identified by correlation id. Set KeyFunc
+to throttle by tenant or another attribute encoded in correlation id.
+
+Configuration parameters
+
+- options:
+    - rate:                Number of operations per second allowed for a caller (default: 100)
+    - burst:               Maximum number of operations a caller can execute at once (default: 100)
+    - wait_timeout:        Time in milliseconds to wait for a free token before failing, 0 to fail immediately (default: 0)
+
+Example
+
+    persistence := NewRateLimitedPersistence(NewMyFilePersistence())
+    persistence.Configure(NewConfigParamsFromTuples("options.rate", 10, "options.burst", 20))
+    item, err := persistence.GetOneById("tenant1", "1")
+*/
+// implements IGetter, IWriter, ISetter, IPartialUpdater, IConfigurable
+type RateLimitedPersistence struct {
+	Persistence interface{}
+	// Function that gets a key of the caller from correlation id
+	KeyFunc func(correlationId string) string
+	// Clock to refill buckets and wait for tokens
+	Clock       IClock
+	Rate        float64
+	Burst       int
+	WaitTimeout int64
+	lock        sync.Mutex
+	buckets     map[string]*tokenBucket
+}
+
+// Creates a new rate limiting decorator.
+// Parameters:
+//   - persistence interface{}
+//   a decorated persistence that implements IGetter, IWriter, ISetter or IPartialUpdater
+// Returns *RateLimitedPersistence
+func NewRateLimitedPersistence(persistence interface{}) *RateLimitedPersistence {
+	return &RateLimitedPersistence{
+		Persistence: persistence,
+		KeyFunc:     func(correlationId string) string { return correlationId },
+		Clock:       SystemClock,
+		Rate:        100,
+		Burst:       100,
+		buckets:     map[string]*tokenBucket{},
+	}
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *RateLimitedPersistence) Configure(config *config.ConfigParams) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.Rate = config.GetAsDoubleWithDefault("options.rate", c.Rate)
+	c.Burst = config.GetAsIntegerWithDefault("options.burst", c.Burst)
+	c.WaitTimeout = config.GetAsLongWithDefault("options.wait_timeout", c.WaitTimeout)
+}
+
+// Takes a token from the caller bucket and returns time to wait for the next token if it's empty
+func (c *RateLimitedPersistence) take(key string, now time.Time) time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	bucket, ok := c.buckets[key]
+	if !ok {
+		// Drop buckets of inactive callers, they are full anyway
+		if len(c.buckets) >= 10000 {
+			for k, b := range c.buckets {
+				if now.Sub(b.accessed).Seconds()*c.Rate >= float64(c.Burst) {
+					delete(c.buckets, k)
+				}
+			}
+		}
+		bucket = &tokenBucket{tokens: float64(c.Burst), updated: now}
+		c.buckets[key] = bucket
+	}
+	bucket.accessed = now
+
+	bucket.tokens += now.Sub(bucket.updated).Seconds() * c.Rate
+	if bucket.tokens > float64(c.Burst) {
+		bucket.tokens = float64(c.Burst)
+	}
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	if c.Rate <= 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Duration((1 - bucket.tokens) / c.Rate * float64(time.Second))
+}
+
+// Waits for a free token of the caller or returns error when rate limit is exceeded
+func (c *RateLimitedPersistence) acquire(correlationId string) error {
+	key := c.KeyFunc(correlationId)
+	deadline := c.Clock.Now().Add(time.Duration(c.WaitTimeout) * time.Millisecond)
+	for {
+		now := c.Clock.Now()
+		wait := c.take(key, now)
+		if wait == 0 {
+			return nil
+		}
+		if now.Add(wait).After(deadline) {
+			return errors.NewConflictError(correlationId, "RATE_LIMIT_EXCEEDED",
+				"Rate limit for "+key+" is exceeded").WithDetails("key", key).WithStatus(429)
+		}
+		c.Clock.Sleep(wait)
+	}
+}
+
+func (c *RateLimitedPersistence) unsupported(correlationId string, operation string) error {
+	return errors.NewUnsupportedError(correlationId, "NOT_SUPPORTED",
+		"Operation "+operation+" is not supported by decorated persistence")
+}
+
+// Gets a data item by its unique id.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be retrieved.
+// Returns interface{}, error
+// data item or error.
+func (c *RateLimitedPersistence) GetOneById(correlationId string, id interface{}) (item interface{}, err error) {
+	getter, ok := c.Persistence.(IGetter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "GetOneById")
+	}
+	if err = c.acquire(correlationId); err != nil {
+		return nil, err
+	}
+	return getter.GetOneById(correlationId, id)
+}
+
+// Creates a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be created.
+// Returns interface{}, error
+// created item or error.
+func (c *RateLimitedPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Create")
+	}
+	if err = c.acquire(correlationId); err != nil {
+		return nil, err
+	}
+	return writer.Create(correlationId, item)
+}
+
+// Updates a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   an item to be updated.
+// Returns interface{}, error
+// updated item or error.
+func (c *RateLimitedPersistence) Update(correlationId string, item interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Update")
+	}
+	if err = c.acquire(correlationId); err != nil {
+		return nil, err
+	}
+	return writer.Update(correlationId, item)
+}
+
+// Deleted a data item by it's unique id.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the item to be deleted
+// Returns interface{}, error
+// deleted item or error.
+func (c *RateLimitedPersistence) DeleteById(correlationId string, id interface{}) (result interface{}, err error) {
+	writer, ok := c.Persistence.(IWriter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "DeleteById")
+	}
+	if err = c.acquire(correlationId); err != nil {
+		return nil, err
+	}
+	return writer.DeleteById(correlationId, id)
+}
+
+// Sets a data item. If the data item exists it updates it, otherwise it create a new data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - item interface{}
+//   a item to be set.
+// Returns interface{}, error
+// updated item or error.
+func (c *RateLimitedPersistence) Set(correlationId string, item interface{}) (result interface{}, err error) {
+	setter, ok := c.Persistence.(ISetter)
+	if !ok {
+		return nil, c.unsupported(correlationId, "Set")
+	}
+	if err = c.acquire(correlationId); err != nil {
+		return nil, err
+	}
+	return setter.Set(correlationId, item)
+}
+
+// Updates only few selected fields in a data item.
+// Parameters:
+//   - correlation_id string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of data item to be updated.
+//   - data *cdata.AnyValueMap
+//   a map with fields to be updated.
+// Returns interface{}, error
+// updated item or error.
+func (c *RateLimitedPersistence) UpdatePartially(correlationId string, id interface{}, data *cdata.AnyValueMap) (result interface{}, err error) {
+	updater, ok := c.Persistence.(IPartialUpdater)
+	if !ok {
+		return nil, c.unsupported(correlationId, "UpdatePartially")
+	}
+	if err = c.acquire(correlationId); err != nil {
+		return nil, err
+	}
+	return updater.UpdatePartially(correlationId, id, data)
+}
@@ -0,0 +1,318 @@
+package persistence
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+/*
+ReadSnapshot is an immutable copy of items in MemoryPersistence indexed by ids
+and by configured fields. Snapshots are published by the persistence when items change
+and are read without the persistence lock, so read-heavy workloads never wait for writers.
+A snapshot never changes: to see the latest changes get a new one with GetReadSnapshot.
+
+Example
+
+    persistence.Configure(config.NewConfigParamsFromTuples(
+        "options.read_snapshot", true,
+        "options.read_snapshot_indexes", "status",
+    ))
+    ...
+    snapshot := persistence.GetReadSnapshot()
+    item := snapshot.GetOneById("1")
+    items := snapshot.GetListByField("status", "active")
+*/
+type ReadSnapshot struct {
+	itemIds     idAccessor
+	items       []interface{}
+	ids         map[string]int
+	indexes     map[string]map[string][]int
+	clone       func(interface{}) interface{}
+	maxPageSize int64
+	// Runs filter and sort functions with settings of the persistence taken with the snapshot,
+	// so panics and OperationTimeout are handled like in queries under lock
+	callbacks *MemoryPersistence
+	// Time when the snapshot was taken
+	Time time.Time
+}
+
+// Creates a snapshot of the given items
+func (c *MemoryPersistence) newReadSnapshot(items []interface{}, fields []string) *ReadSnapshot {
+	snapshot := &ReadSnapshot{
+		itemIds:     c.ids,
+		items:       items,
+		ids:         make(map[string]int, len(items)),
+		indexes:     make(map[string]map[string][]int, len(fields)),
+		clone:       c.cloneResult,
+		maxPageSize: int64(c.MaxPageSize),
+		callbacks: &MemoryPersistence{
+			Logger:                  c.Logger,
+			OperationTimeout:        c.OperationTimeout,
+			FilterParallelism:       c.FilterParallelism,
+			ParallelFilterThreshold: c.ParallelFilterThreshold,
+		},
+		Time: c.Clock.Now(),
+	}
+	for _, field := range fields {
+		snapshot.indexes[field] = map[string][]int{}
+	}
+	for i, item := range items {
+		if id := c.ids.get(item); id != nil {
+			snapshot.ids[convert.StringConverter.ToString(id)] = i
+		}
+		for field, index := range snapshot.indexes {
+			key := convert.StringConverter.ToString(GetPathValue(item, field))
+			index[key] = append(index[key], i)
+		}
+	}
+	return snapshot
+}
+
+// Gets a number of items in the snapshot.
+// Returns int
+func (c *ReadSnapshot) Len() int {
+	return len(c.items)
+}
+
+// Gets an item by its id.
+// Parameters:
+//   - id interface{}
+//   an id of the item
+// Returns interface{}
+// the found item or nil if it doesn't exist.
+func (c *ReadSnapshot) GetOneById(id interface{}) interface{} {
+	index, ok := c.ids[convert.StringConverter.ToString(id)]
+	if !ok {
+		return nil
+	}
+	return c.clone(c.items[index])
+}
+
+// Gets items with the given value of a field. Indexed fields are looked up without scanning.
+// Parameters:
+//   - field string
+//   a name or path of the field
+//   - value interface{}
+//   a value of the field
+// Returns []interface{}
+// found items in their original order.
+func (c *ReadSnapshot) GetListByField(field string, value interface{}) []interface{} {
+	key := convert.StringConverter.ToString(value)
+	index, ok := c.indexes[field]
+	if !ok {
+		results := []interface{}{}
+		for _, item := range c.items {
+			if convert.StringConverter.ToString(GetPathValue(item, field)) == key {
+				results = append(results, c.clone(item))
+			}
+		}
+		return results
+	}
+
+	results := make([]interface{}, len(index[key]))
+	for i, position := range index[key] {
+		results[i] = c.clone(c.items[position])
+	}
+	return results
+}
+
+// Gets items of the snapshot with a changed item, like updateViews does for views.
+// Items are replaced rather than changed in place, so they are shared between snapshots.
+func (c *ReadSnapshot) applyChange(oldItem interface{}, newItem interface{}) []interface{} {
+	items := make([]interface{}, 0, len(c.items)+1)
+	replaced := oldItem == nil
+	for _, item := range c.items {
+		if !replaced && c.itemIds.isSameItem(item, oldItem) {
+			replaced = true
+			if newItem != nil {
+				items = append(items, newItem)
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	if oldItem == nil && newItem != nil {
+		items = append(items, newItem)
+	}
+	return items
+}
+
+// Selects items that match a filter without cloning them.
+// Panics in the filter and sort functions are returned as errors.
+func (c *ReadSnapshot) filter(filterFunc func(interface{}) bool, sortFunc func(a, b interface{}) bool) (items []interface{}, err error) {
+	if filterFunc == nil {
+		// Items of the snapshot are copied, so they are not sorted in place
+		items = append([]interface{}{}, c.items...)
+	} else if items, err = c.callbacks.filterItemsOf("", c.items, filterFunc); err != nil {
+		return nil, err
+	}
+	if sortFunc != nil {
+		if err = c.callbacks.sortItems("", items, sortFunc); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// Gets items that match a filter.
+// Parameters:
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) a sort function
+// Returns []interface{}, error
+// found items or error when the filter or sort function panics or exceeds OperationTimeout.
+func (c *ReadSnapshot) GetListByFilter(filterFunc func(interface{}) bool, sortFunc func(a, b interface{}) bool) ([]interface{}, error) {
+	items, err := c.filter(filterFunc, sortFunc)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(items))
+	for i, item := range items {
+		results[i] = c.clone(item)
+	}
+	return results, nil
+}
+
+// Gets a page of items that match a filter.
+// Parameters:
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function
+//   - paging *cdata.PagingParams
+//   (optional) paging parameters
+//   - sortFunc func(a, b interface{}) bool
+//   (optional) a sort function
+// Returns *cdata.DataPage, error
+// a page of found items or error when the filter or sort function panics or exceeds OperationTimeout.
+func (c *ReadSnapshot) GetPageByFilter(filterFunc func(interface{}) bool, paging *cdata.PagingParams,
+	sortFunc func(a, b interface{}) bool) (*cdata.DataPage, error) {
+	items, err := c.filter(filterFunc, sortFunc)
+	if err != nil {
+		return nil, err
+	}
+	if paging == nil {
+		paging = cdata.NewEmptyPagingParams()
+	}
+	skip := paging.GetSkip(-1)
+	take := paging.GetTake(c.maxPageSize)
+	var total *int64
+	if paging.Total {
+		count := int64(len(items))
+		total = &count
+	}
+	if skip > 0 {
+		if skip >= int64(len(items)) {
+			skip = int64(len(items))
+		}
+		items = items[skip:]
+	}
+	if int64(len(items)) >= take {
+		items = items[:take]
+	}
+
+	results := make([]interface{}, len(items))
+	for i, item := range items {
+		results[i] = c.clone(item)
+	}
+	return cdata.NewDataPage(total, results), nil
+}
+
+/*
+State of read snapshots in MemoryPersistence.
+*/
+type readSnapshots struct {
+	current    atomic.Value
+	fields     []string
+	interval   int64
+	dirty      int32
+	refreshing int32
+}
+
+// Parses comma-separated names of indexed fields
+func parseSnapshotIndexes(value string) []string {
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Enables or disables read snapshots. Must be called under lock.
+func (c *MemoryPersistence) setReadSnapshot(enabled bool, interval int64, fields []string) {
+	if !enabled {
+		c.readSnapshots.Store((*readSnapshots)(nil))
+		return
+	}
+	if !c.readSnapshotHandler {
+		c.addChangeHandler(c.updateReadSnapshot)
+		c.readSnapshotHandler = true
+	}
+	snapshots := &readSnapshots{fields: fields, interval: interval}
+	snapshots.current.Store(c.newReadSnapshot(c.copyItems(), fields))
+	c.readSnapshots.Store(snapshots)
+}
+
+// Gets the state of read snapshots or nil when they are disabled
+func (c *MemoryPersistence) getReadSnapshots() *readSnapshots {
+	snapshots, _ := c.readSnapshots.Load().(*readSnapshots)
+	return snapshots
+}
+
+// Publishes a new snapshot on every change or marks the snapshot as outdated
+// to refresh it after the interval. Reloaded items are published immediately.
+// Called under write lock.
+func (c *MemoryPersistence) updateReadSnapshot(oldItem interface{}, newItem interface{}) {
+	snapshots := c.getReadSnapshots()
+	if snapshots == nil {
+		return
+	}
+	if oldItem == nil && newItem == nil {
+		snapshots.current.Store(c.newReadSnapshot(c.copyItems(), snapshots.fields))
+		return
+	}
+	if snapshots.interval > 0 {
+		atomic.StoreInt32(&snapshots.dirty, 1)
+		return
+	}
+	// Change handlers are called before items are changed, so the change is applied to the previous snapshot
+	previous := snapshots.current.Load().(*ReadSnapshot)
+	snapshots.current.Store(c.newReadSnapshot(previous.applyChange(oldItem, newItem), snapshots.fields))
+}
+
+// Copies the list of items. Must be called under lock.
+func (c *MemoryPersistence) copyItems() []interface{} {
+	return append(make([]interface{}, 0, len(c.Items)), c.Items...)
+}
+
+// Gets the latest read snapshot of items. It doesn't wait for the persistence lock,
+// so it can be called by readers while writers change items.
+// When options.read_snapshot_interval is set, an outdated snapshot older than the interval
+// is refreshed in background and the previous one is returned until the refresh is done.
+// Returns *ReadSnapshot
+// the latest snapshot or nil if read snapshots are not enabled in options.read_snapshot.
+func (c *MemoryPersistence) GetReadSnapshot() *ReadSnapshot {
+	snapshots := c.getReadSnapshots()
+	if snapshots == nil {
+		return nil
+	}
+
+	snapshot := snapshots.current.Load().(*ReadSnapshot)
+	if snapshots.interval > 0 && atomic.LoadInt32(&snapshots.dirty) == 1 &&
+		c.Clock.Now().Sub(snapshot.Time) >= time.Duration(snapshots.interval)*time.Millisecond &&
+		atomic.CompareAndSwapInt32(&snapshots.refreshing, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&snapshots.refreshing, 0)
+			c.rlock()
+			defer c.runlock()
+			atomic.StoreInt32(&snapshots.dirty, 0)
+			snapshots.current.Store(c.newReadSnapshot(c.copyItems(), snapshots.fields))
+		}()
+	}
+	return snapshot
+}
@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+)
+
+/*
+Background timer that periodically saves unsaved changes.
+It is useful together with durability mode "none" to bound the amount of lost changes.
+*/
+type autosaver struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (c *autosaver) run(persistence *MemoryPersistence, ticker IClockTicker) {
+	defer close(c.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			if err := persistence.saveUnsaved(""); err != nil {
+				if handler := persistence.SaveErrorHandler; handler != nil {
+					handler("", err)
+				} else {
+					persistence.Logger.Error("", err, "Failed to autosave items")
+				}
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Stops the timer and waits until the current save is completed.
+// Must be called without persistence lock.
+func (c *autosaver) close() {
+	close(c.stop)
+	<-c.done
+}
+
+// Starts the autosave timer if the interval is set. Must be called under lock.
+func (c *MemoryPersistence) startAutosave() {
+	if c.autosave != nil || c.AutosaveInterval <= 0 {
+		return
+	}
+	c.autosave = &autosaver{stop: make(chan struct{}), done: make(chan struct{})}
+	go c.autosave.run(c, c.Clock.NewTicker(time.Duration(c.AutosaveInterval)*time.Millisecond))
+}
+
+// Detaches the running autosave timer. Must be called under lock,
+// and the returned timer must be closed after the lock is released.
+func (c *MemoryPersistence) detachAutosave() *autosaver {
+	autosave := c.autosave
+	c.autosave = nil
+	return autosave
+}
+
+// Changes the autosave interval and restarts the timer of the opened persistence.
+// Must be called under lock. Returns the detached timer to close after the lock is released.
+func (c *MemoryPersistence) setAutosaveInterval(interval int64) *autosaver {
+	if interval == c.AutosaveInterval {
+		return nil
+	}
+	c.AutosaveInterval = interval
+	detached := c.detachAutosave()
+	if c.opened {
+		c.startAutosave()
+	}
+	return detached
+}
+
+// Changes configuration of the file persister and reloads items
+// when the opened persistence is switched to another file.
+// Changes are applied under lock, so they are safe while the component is running.
+func (c *MemoryPersistence) reconfigurePersister(persister *JsonFilePersister, config *config.ConfigParams) {
+	c.lock()
+	defer c.unlock()
+
+	path := persister.Path()
+	persister.Configure(config)
+	persister.Prototype = c.Prototype
+	if !c.opened || persister.Path() == path {
+		return
+	}
+
+	c.Logger.Info("", "Data file changed from %s to %s, reloading items", path, persister.Path())
+	if err := c.load(""); err != nil {
+		c.Logger.Error("", err, "Failed to reload items from %s", persister.Path())
+	}
+}
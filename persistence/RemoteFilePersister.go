@@ -0,0 +1,308 @@
+package persistence
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+	"github.com/pip-services3-go/pip-services3-components-go/auth"
+	"github.com/pip-services3-go/pip-services3-components-go/connect"
+)
+
+// Protocols of remote file servers
+const (
+	RemoteProtocolSftp = "sftp"
+	RemoteProtocolFtps = "ftps"
+	RemoteProtocolFtp  = "ftp"
+)
+
+/*
+Persistence component that loads and saves the data file on a remote file server,
+for environments where data exchange happens over managed file transfer.
+The server is resolved by connection parameters and logged into with credential parameters,
+so they can be taken from discovery services and credential stores.
+
+The transport is pluggable: FTPS with explicit TLS and plain FTP are supported by the built-in client,
+while other protocols, like SFTP that requires an SSH implementation this module doesn't depend on,
+are served by IRemoteFileDialer components referenced as *:file-dialer:<protocol>:*:1.0,
+for instance an adapter over github.com/pkg/sftp client. A referenced dialer of ftps or ftp
+replaces the built-in client, and the Dialer field replaces dialers of all protocols.
+Items are saved into a temporary file that replaces the data file when the upload is complete,
+so readers on the server never see a partially written file.
+
+ Configuration parameters
+
+  - path:             path to the data file on the server
+  - connection(s):
+      - discovery_key:  (optional) a key to retrieve the connection from IDiscovery
+      - protocol:       ftps, ftp or a protocol of referenced dialer like sftp (default: ftps)
+      - host:           host name or IP address
+      - port:           port number (default: 22 for sftp, 21 for ftps and ftp)
+      - uri:            resource URI like ftps://host:21/data/items.json, its path is used when path is not set
+  - credential(s):
+      - store_key:      (optional) a key to retrieve the credentials from ICredentialStore
+      - username:       user name
+      - password:       user password
+  - options:
+      - timeout:        timeout to connect in milliseconds (default: 30000)
+
+ References
+
+  - *:discovery:*:*:1.0         (optional) IDiscovery services to resolve connections
+  - *:credential-store:*:*:1.0  (optional) Credential stores to resolve credentials
+  - *:file-dialer:<protocol>:*:1.0  (optional) IRemoteFileDialer components that connect to servers of the protocol
+
+ Example
+
+  persister := NewRemoteFilePersister(reflect.TypeOf(MyData{}), "/exchange/data.json")
+  persister.Configure(cconf.NewConfigParamsFromTuples(
+      "connection.protocol", "ftps",
+      "connection.host", "files.example.com",
+      "credential.username", "exchange",
+      "credential.password", "secret",
+  ))
+
+  persistence := NewMemoryPersistence(reflect.TypeOf(MyData{}))
+  persistence.Loader = persister
+  persistence.Saver = persister
+
+  // SFTP servers are reached through a referenced dialer
+  persister.SetReferences(cref.NewReferencesFromTuples(
+      cref.NewDescriptor("myapp", "file-dialer", "sftp", "default", "1.0"), mySftpDialer,
+  ))
+*/
+// implements ILoader, ISaver, IConfigurable, IReferenceable
+type RemoteFilePersister struct {
+	path               string
+	persister          *JsonFilePersister
+	connectionResolver *connect.ConnectionResolver
+	credentialResolver *auth.CredentialResolver
+	// Timeout to connect to the server (default: 30 seconds)
+	Timeout time.Duration
+	// TLS configuration of FTPS connections, for instance with root CAs of the server
+	TlsConfig *tls.Config
+	// Dialer that connects to the server instead of referenced or built-in ones
+	Dialer IRemoteFileDialer
+
+	references refer.IReferences
+}
+
+// Creates a new instance of the persister.
+// Parameters:
+//   - prototype reflect.Type
+//   type of the data items
+//   - path string
+//   (optional) a path to the data file on the server
+// Returns *RemoteFilePersister
+func NewRemoteFilePersister(prototype reflect.Type, path string) *RemoteFilePersister {
+	return &RemoteFilePersister{
+		path:               path,
+		persister:          NewJsonFilePersister(prototype, ""),
+		connectionResolver: connect.NewEmptyConnectionResolver(),
+		credentialResolver: auth.NewEmptyCredentialResolver(),
+		Timeout:            30 * time.Second,
+	}
+}
+
+// Gets the path to the data file on the server.
+// Returns string
+func (c *RemoteFilePersister) Path() string {
+	return c.path
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//   - config  *config.ConfigParams
+//   parameters to be set
+func (c *RemoteFilePersister) Configure(config *config.ConfigParams) {
+	c.path = config.GetAsStringWithDefault("path", c.path)
+	c.connectionResolver.Configure(config)
+	c.credentialResolver.Configure(config)
+	c.Timeout = time.Duration(config.GetAsLongWithDefault("options.timeout", int64(c.Timeout/time.Millisecond))) * time.Millisecond
+	c.persister.Configure(config)
+}
+
+// Sets references to discovery services, credential stores and dialers of remote file servers.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the component dependencies.
+func (c *RemoteFilePersister) SetReferences(references refer.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.credentialResolver.SetReferences(references)
+	c.references = references
+}
+
+// Gets the dialer of the protocol set in Dialer field or referenced as *:file-dialer:<protocol>:*:1.0
+func (c *RemoteFilePersister) getDialer(correlationId string, protocol string) (IRemoteFileDialer, error) {
+	if c.Dialer != nil {
+		return c.Dialer, nil
+	}
+	if c.references == nil {
+		return nil, nil
+	}
+	reference := c.references.GetOneOptional(refer.NewDescriptor("*", "file-dialer", protocol, "*", "1.0"))
+	if reference == nil {
+		return nil, nil
+	}
+	dialer, ok := reference.(IRemoteFileDialer)
+	if !ok {
+		return nil, errors.NewConfigError(correlationId, "INVALID_DIALER",
+			fmt.Sprintf("Referenced dialer of type %T doesn't implement IRemoteFileDialer", reference)).
+			WithDetails("protocol", protocol).WithDetails("type", fmt.Sprintf("%T", reference))
+	}
+	return dialer, nil
+}
+
+// Resolves connection and credential parameters and connects to the server
+func (c *RemoteFilePersister) connect(correlationId string) (IRemoteFileClient, string, error) {
+	connection, err := c.connectionResolver.Resolve(correlationId)
+	if err != nil {
+		return nil, "", err
+	}
+	if connection == nil {
+		return nil, "", errors.NewConfigError(correlationId, "NO_CONNECTION", "Connection to the file server is not set")
+	}
+
+	protocol, host, port, path := connection.Protocol(), connection.Host(), connection.Port(), c.path
+	if uri := connection.Uri(); uri != "" {
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return nil, "", errors.NewConfigError(correlationId, "INVALID_URI", "Invalid URI of the file server").
+				WithDetails("uri", uri).WithCause(err)
+		}
+		if parsed.Scheme != "" {
+			protocol = parsed.Scheme
+		}
+		host = parsed.Hostname()
+		if parsed.Port() != "" {
+			port, _ = strconv.Atoi(parsed.Port())
+		}
+		if path == "" {
+			path = parsed.Path
+		}
+	}
+	if protocol == "" {
+		protocol = RemoteProtocolFtps
+	}
+	if port == 0 {
+		port = 21
+		if protocol == RemoteProtocolSftp {
+			port = 22
+		}
+	}
+	if host == "" || path == "" {
+		return nil, "", errors.NewConfigError(correlationId, "NO_CONNECTION", "Host and path of the data file are not set").
+			WithDetails("host", host).WithDetails("path", path)
+	}
+
+	var username, password string
+	credential, err := c.credentialResolver.Lookup(correlationId)
+	if err != nil {
+		return nil, "", err
+	}
+	if credential != nil {
+		username, password = credential.Username(), credential.Password()
+	}
+
+	dialer, err := c.getDialer(correlationId, protocol)
+	if err != nil {
+		return nil, "", err
+	}
+	if dialer == nil && (protocol == RemoteProtocolFtps || protocol == RemoteProtocolFtp) {
+		tlsConfig := c.TlsConfig
+		if protocol == RemoteProtocolFtps && tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		} else if protocol == RemoteProtocolFtp {
+			tlsConfig = nil
+		}
+		dialer = RemoteFileDialer(func(host string, port int, username string, password string,
+			timeout time.Duration) (IRemoteFileClient, error) {
+			return dialFtp(host, port, username, password, timeout, tlsConfig)
+		})
+	}
+	if dialer == nil {
+		return nil, "", errors.NewConfigError(correlationId, "UNSUPPORTED_PROTOCOL",
+			"No dialer is referenced for protocol "+protocol).WithDetails("protocol", protocol)
+	}
+
+	client, err := dialer.Dial(host, port, username, password, c.Timeout)
+	if err != nil {
+		return nil, "", errors.NewConnectionError(correlationId, "CONNECT_FAILED",
+			"Failed to connect to "+protocol+"://"+host+":"+strconv.Itoa(port)).
+			WithDetails("host", host).WithDetails("port", port).WithCause(err)
+	}
+	return client, path, nil
+}
+
+// Loads data items from the data file on the server.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items, nil when the data file doesn't exist, or error.
+func (c *RemoteFilePersister) Load(correlationId string) (data []interface{}, err error) {
+	client, path, err := c.connect(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	reader, err := client.Retrieve(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+path).WithCause(err)
+	}
+	data, err = c.persister.LoadFrom(correlationId, reader)
+	if closeErr := reader.Close(); err == nil && closeErr != nil {
+		err = errors.NewFileError(correlationId, "READ_FAILED", "Failed to read data file: "+path).WithCause(closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Saves given data items into the data file on the server.
+// Items are streamed into a temporary file next to the data file that replaces it after the upload.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface{}
+//   list of data items to save
+// Returns error
+func (c *RemoteFilePersister) Save(correlationId string, items []interface{}) error {
+	client, path, err := c.connect(correlationId)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(c.persister.SaveTo(correlationId, writer, items))
+	}()
+
+	tempPath := path + ".tmp"
+	err = client.Store(tempPath, reader)
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		if appErr, ok := err.(*errors.ApplicationError); ok {
+			return appErr
+		}
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write data file: "+tempPath).WithCause(err)
+	}
+	if err = client.Rename(tempPath, path); err != nil {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to replace data file: "+path).WithCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,525 @@
+package persistence
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Types of replication events
+const (
+	// Item was created or updated
+	ReplicationSet = "set"
+	// Item was deleted
+	ReplicationDelete = "delete"
+	// All items were replaced, replicas must resync
+	ReplicationReset = "reset"
+)
+
+/*
+Change of an item streamed from primary to replicas.
+Events are numbered by sequence, so replicas can detect missed events.
+*/
+type ReplicationEvent struct {
+	Sequence int64       `json:"sequence"`
+	Type     string      `json:"type"`
+	Item     interface{} `json:"item"`
+}
+
+/*
+Interface for components that deliver replication events to replicas,
+for instance, by sending them to a message queue.
+Publish is called synchronously on every change, so it shall not block for long.
+*/
+type IReplicationPublisher interface {
+	Publish(correlationId string, event *ReplicationEvent) error
+}
+
+/*
+Interface for sources of replication events that replicas pull from.
+It is implemented by ReplicationPrimary for in-process replication
+and by HttpReplicationSource for replication between service instances.
+*/
+type IReplicationSource interface {
+	// Gets events after the given sequence number.
+	// Returns ConflictError with REPLICATION_GAP code when events were already discarded.
+	GetChanges(correlationId string, after int64) (events []*ReplicationEvent, err error)
+
+	// Gets all items and the sequence number of the last event included into them.
+	GetSnapshot(correlationId string) (sequence int64, items []interface{}, err error)
+}
+
+func newReplicationGapError(correlationId string, after int64) error {
+	return errors.NewConflictError(correlationId, "REPLICATION_GAP",
+		"Replication events after "+strconv.FormatInt(after, 10)+" are not available").
+		WithDetails("after", after)
+}
+
+func isReplicationGap(err error) bool {
+	appErr, ok := err.(*errors.ApplicationError)
+	return ok && appErr.Code == "REPLICATION_GAP"
+}
+
+/*
+Primary side of replication. It records changes of a persistence
+into a bounded log of events and streams them to publishers.
+Replicas pull events by GetChanges or over HTTP when the primary
+is registered as http.Handler.
+
+Example
+
+    primary := NewReplicationPrimary(&persistence.MemoryPersistence, 10000)
+    http.Handle("/replication", primary)
+*/
+type ReplicationPrimary struct {
+	persistence *MemoryPersistence
+	lock        sync.Mutex
+	sequence    int64
+	events      []*ReplicationEvent
+	maxEvents   int
+	publishers  []IReplicationPublisher
+}
+
+// Creates a new primary and starts recording changes of the persistence.
+// Parameters:
+//   - persistence *MemoryPersistence
+//   a persistence to replicate
+//   - maxEvents int
+//   a maximum number of events kept for replicas, older events require full resync
+// Returns *ReplicationPrimary
+func NewReplicationPrimary(persistence *MemoryPersistence, maxEvents int) *ReplicationPrimary {
+	c := &ReplicationPrimary{
+		persistence: persistence,
+		maxEvents:   maxEvents,
+		events:      []*ReplicationEvent{},
+	}
+
+	persistence.lock()
+	persistence.addChangeHandler(c.record)
+	persistence.unlock()
+
+	return c
+}
+
+// Adds a publisher that receives every replication event.
+// Parameters:
+//   - publisher IReplicationPublisher
+//   a publisher to add
+func (c *ReplicationPrimary) AddPublisher(publisher IReplicationPublisher) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.publishers = append(c.publishers, publisher)
+}
+
+// Gets sequence number of the last recorded event
+func (c *ReplicationPrimary) Sequence() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.sequence
+}
+
+// Records a change of the persistence
+func (c *ReplicationPrimary) record(oldItem interface{}, newItem interface{}) {
+	c.lock.Lock()
+
+	c.sequence++
+	event := &ReplicationEvent{Sequence: c.sequence}
+	switch {
+	case newItem != nil:
+		event.Type = ReplicationSet
+		event.Item = c.persistence.cloneResult(newItem)
+	case oldItem != nil:
+		event.Type = ReplicationDelete
+		event.Item = c.persistence.cloneResult(oldItem)
+	default:
+		event.Type = ReplicationReset
+	}
+
+	c.events = append(c.events, event)
+	if c.maxEvents > 0 && len(c.events) > c.maxEvents {
+		c.events = c.events[len(c.events)-c.maxEvents:]
+	}
+	publishers := c.publishers
+
+	c.lock.Unlock()
+
+	for _, publisher := range publishers {
+		if err := publisher.Publish("", event); err != nil {
+			c.persistence.Logger.Error("", err, "Failed to publish replication event %d", event.Sequence)
+		}
+	}
+}
+
+// Gets events after the given sequence number.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - after int64
+//   a sequence number of the last received event
+// Returns []*ReplicationEvent, error
+// events or ConflictError with REPLICATION_GAP code when events were discarded.
+func (c *ReplicationPrimary) GetChanges(correlationId string, after int64) (events []*ReplicationEvent, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if after > c.sequence {
+		return nil, newReplicationGapError(correlationId, after)
+	}
+	if after == c.sequence {
+		return []*ReplicationEvent{}, nil
+	}
+	if len(c.events) == 0 || c.events[0].Sequence > after+1 {
+		return nil, newReplicationGapError(correlationId, after)
+	}
+	start := int(after + 1 - c.events[0].Sequence)
+	events = make([]*ReplicationEvent, len(c.events)-start)
+	copy(events, c.events[start:])
+	return events, nil
+}
+
+// Gets all items and the sequence number of the last event included into them.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns int64, []interface{}, error
+// the sequence number, items or error.
+func (c *ReplicationPrimary) GetSnapshot(correlationId string) (sequence int64, items []interface{}, err error) {
+	// Changes are recorded under write lock, so the sequence can't change while items are read
+	c.persistence.rlock()
+	defer c.persistence.runlock()
+
+	sequence = c.Sequence()
+	items = make([]interface{}, len(c.persistence.Items))
+	for i, item := range c.persistence.Items {
+		items[i] = c.persistence.cloneResult(item)
+	}
+	return sequence, items, nil
+}
+
+// Handles HTTP requests from replicas.
+// GET with snapshot=true returns all items, GET with after=N returns events after N.
+func (c *ReplicationPrimary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	correlationId := query.Get("correlation_id")
+	w.Header().Set("Content-Type", "application/json")
+
+	if query.Get("snapshot") == "true" {
+		sequence, items, _ := c.GetSnapshot(correlationId)
+		json.NewEncoder(w).Encode(map[string]interface{}{"sequence": sequence, "items": items})
+		return
+	}
+
+	after, err := strconv.ParseInt(query.Get("after"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errors.NewBadRequestError(correlationId, "INVALID_SEQUENCE", "Sequence number is not valid"))
+		return
+	}
+	events, err := c.GetChanges(correlationId, after)
+	if err != nil {
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
+
+/*
+Source of replication events that pulls them from ReplicationPrimary over HTTP.
+*/
+type HttpReplicationSource struct {
+	Url    string
+	Client *http.Client
+}
+
+// Creates a new HTTP replication source.
+// Parameters:
+//   - url string
+//   an url where ReplicationPrimary is served
+// Returns *HttpReplicationSource
+func NewHttpReplicationSource(url string) *HttpReplicationSource {
+	return &HttpReplicationSource{
+		Url:    url,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *HttpReplicationSource) get(correlationId string, params url.Values, result interface{}) (status int, err error) {
+	params.Set("correlation_id", correlationId)
+	response, err := c.Client.Get(c.Url + "?" + params.Encode())
+	if err != nil {
+		return 0, errors.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to connect to "+c.Url).WithCause(err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, errors.NewConnectionError(correlationId, "READ_FAILED", "Failed to read response from "+c.Url).WithCause(err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return response.StatusCode, nil
+	}
+	if err = json.Unmarshal(body, result); err != nil {
+		return response.StatusCode, errors.NewInvocationError(correlationId, "PARSE_FAILED", "Failed to parse response from "+c.Url).WithCause(err)
+	}
+	return response.StatusCode, nil
+}
+
+// Gets events after the given sequence number from the primary.
+func (c *HttpReplicationSource) GetChanges(correlationId string, after int64) (events []*ReplicationEvent, err error) {
+	result := struct {
+		Events []*ReplicationEvent `json:"events"`
+	}{}
+	status, err := c.get(correlationId, url.Values{"after": {strconv.FormatInt(after, 10)}}, &result)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+		return result.Events, nil
+	case http.StatusGone:
+		return nil, newReplicationGapError(correlationId, after)
+	}
+	return nil, errors.NewInvocationError(correlationId, "REQUEST_FAILED",
+		"Replication request to "+c.Url+" failed with status "+strconv.Itoa(status))
+}
+
+// Gets all items from the primary.
+func (c *HttpReplicationSource) GetSnapshot(correlationId string) (sequence int64, items []interface{}, err error) {
+	result := struct {
+		Sequence int64         `json:"sequence"`
+		Items    []interface{} `json:"items"`
+	}{}
+	status, err := c.get(correlationId, url.Values{"snapshot": {"true"}}, &result)
+	if err != nil {
+		return 0, nil, err
+	}
+	if status != http.StatusOK {
+		return 0, nil, errors.NewInvocationError(correlationId, "REQUEST_FAILED",
+			"Replication request to "+c.Url+" failed with status "+strconv.Itoa(status))
+	}
+	return result.Sequence, result.Items, nil
+}
+
+/*
+Replica side of replication. It applies events from the primary
+to a read-replica persistence and performs full resync when
+it detects a gap in event sequence.
+*/
+type ReplicationReplica struct {
+	persistence *MemoryPersistence
+	source      IReplicationSource
+	lock        sync.Mutex
+	sequence    int64
+	stop        chan bool
+}
+
+// Creates a new replica.
+// Parameters:
+//   - persistence *MemoryPersistence
+//   a persistence to apply changes to
+//   - source IReplicationSource
+//   a source to pull events and snapshots from
+// Returns *ReplicationReplica
+func NewReplicationReplica(persistence *MemoryPersistence, source IReplicationSource) *ReplicationReplica {
+	return &ReplicationReplica{
+		persistence: persistence,
+		source:      source,
+		sequence:    -1,
+	}
+}
+
+// Gets sequence number of the last applied event
+func (c *ReplicationReplica) Sequence() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.sequence
+}
+
+// Converts an item received from the primary into the persistence prototype
+func (c *ReplicationReplica) toItem(item interface{}) interface{} {
+	prototype := c.persistence.Prototype
+	if item == nil || reflect.TypeOf(item) == prototype {
+		return item
+	}
+	buffer, err := json.Marshal(item)
+	if err != nil {
+		return item
+	}
+	value := reflect.New(prototype)
+	if err = json.Unmarshal(buffer, value.Interface()); err != nil {
+		return item
+	}
+	return c.persistence.toStoredItem(value.Elem().Interface())
+}
+
+func (c *ReplicationReplica) applyEvent(event *ReplicationEvent) {
+	p := c.persistence
+	item := c.toItem(event.Item)
+	index := -1
+	if item != nil {
+		for i, v := range p.Items {
+			if c.persistence.ids.isSameItem(v, item) {
+				index = i
+				break
+			}
+		}
+	}
+
+	switch {
+	case event.Type == ReplicationSet && index >= 0:
+		p.notifyChange(p.Items[index], item)
+		p.Items[index] = item
+	case event.Type == ReplicationSet:
+		p.notifyChange(nil, item)
+		p.Items = append(p.Items, item)
+	case event.Type == ReplicationDelete && index >= 0:
+		p.notifyChange(p.Items[index], nil)
+		p.Items = append(p.Items[:index], p.Items[index+1:]...)
+	}
+}
+
+// Applies events pushed by the primary, for instance, received from a message queue.
+// Already applied events are skipped, a gap or reset event triggers full resync.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - events []*ReplicationEvent
+//   events to apply ordered by sequence
+// Returns error or nil for success.
+func (c *ReplicationReplica) Apply(correlationId string, events []*ReplicationEvent) error {
+	c.lock.Lock()
+
+	if c.sequence < 0 {
+		c.lock.Unlock()
+		return c.Resync(correlationId)
+	}
+
+	c.persistence.lock()
+	applied := 0
+	gap := false
+	for _, event := range events {
+		if event.Sequence <= c.sequence {
+			continue
+		}
+		if event.Sequence != c.sequence+1 || event.Type == ReplicationReset {
+			gap = true
+			break
+		}
+		c.applyEvent(event)
+		c.sequence = event.Sequence
+		applied++
+	}
+	c.persistence.unlock()
+	c.lock.Unlock()
+
+	if gap {
+		c.persistence.Logger.Debug(correlationId, "Detected gap in replication events, resyncing")
+		return c.Resync(correlationId)
+	}
+	if applied > 0 {
+		c.persistence.Logger.Trace(correlationId, "Applied %d replication events", applied)
+		return c.persistence.save(correlationId)
+	}
+	return nil
+}
+
+// Pulls new events from the source and applies them.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *ReplicationReplica) Sync(correlationId string) error {
+	sequence := c.Sequence()
+	if sequence < 0 {
+		return c.Resync(correlationId)
+	}
+
+	events, err := c.source.GetChanges(correlationId, sequence)
+	if isReplicationGap(err) {
+		return c.Resync(correlationId)
+	}
+	if err != nil {
+		return err
+	}
+	return c.Apply(correlationId, events)
+}
+
+// Replaces all items with a snapshot from the source.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *ReplicationReplica) Resync(correlationId string) error {
+	sequence, items, err := c.source.GetSnapshot(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.persistence.lock()
+	c.persistence.Items = make([]interface{}, len(items))
+	for i, item := range items {
+		c.persistence.Items[i] = c.toItem(item)
+	}
+	c.persistence.notifyChange(nil, nil)
+	c.sequence = sequence
+	c.persistence.unlock()
+	c.lock.Unlock()
+
+	c.persistence.Logger.Debug(correlationId, "Resynced %d items at replication sequence %d", len(items), sequence)
+	return c.persistence.save(correlationId)
+}
+
+// Starts pulling events from the source periodically.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - interval time.Duration
+//   an interval between pulls
+func (c *ReplicationReplica) Start(correlationId string, interval time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stop != nil {
+		return
+	}
+	stop := make(chan bool)
+	c.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := c.Sync(correlationId); err != nil {
+				c.persistence.Logger.Error(correlationId, err, "Failed to replicate changes")
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stops pulling events from the source.
+func (c *ReplicationReplica) Stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
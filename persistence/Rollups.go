@@ -0,0 +1,166 @@
+package persistence
+
+import (
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// RollupGroup contains aggregates of items in a group of a rollup.
+type RollupGroup struct {
+	// Number of items in the group
+	Count int64 `json:"count"`
+	// Sums of numeric fields by field names
+	Sums map[string]float64 `json:"sums"`
+}
+
+/*
+Rollup of items in MemoryPersistence. It keeps counts and sums of fields
+per group and is updated incrementally on every change,
+so dashboard counters don't require scanning all items.
+*/
+type rollup struct {
+	groupBy   string
+	sumFields []string
+	groups    map[string]*RollupGroup
+}
+
+// Gets a group key of the item
+func (c *rollup) key(item interface{}) string {
+	if c.groupBy == "" {
+		return ""
+	}
+	return convert.StringConverter.ToString(GetPathValue(item, c.groupBy))
+}
+
+// Adds an item to its group or removes it when sign is -1
+func (c *rollup) add(item interface{}, sign float64) {
+	key := c.key(item)
+	group, ok := c.groups[key]
+	if !ok {
+		group = &RollupGroup{Sums: map[string]float64{}}
+		c.groups[key] = group
+	}
+	group.Count += int64(sign)
+	for _, field := range c.sumFields {
+		group.Sums[field] += sign * convert.DoubleConverter.ToDouble(GetPathValue(item, field))
+	}
+	if group.Count <= 0 {
+		delete(c.groups, key)
+	}
+}
+
+// Recalculates the rollup from all items
+func (c *rollup) rebuild(items []interface{}) {
+	c.groups = map[string]*RollupGroup{}
+	for _, item := range items {
+		c.add(item, 1)
+	}
+}
+
+// Updates all registered rollups. Called under write lock.
+func (c *MemoryPersistence) updateRollups(oldItem interface{}, newItem interface{}) {
+	for _, rollup := range c.rollups {
+		if oldItem == nil && newItem == nil {
+			rollup.rebuild(c.Items)
+			continue
+		}
+		if oldItem != nil {
+			rollup.add(oldItem, -1)
+		}
+		if newItem != nil {
+			rollup.add(newItem, 1)
+		}
+	}
+}
+
+// Registers a rollup. Must be called under write lock.
+func (c *MemoryPersistence) registerRollup(name string, groupBy string, sumFields []string) {
+	if c.rollups == nil {
+		c.rollups = map[string]*rollup{}
+		c.addChangeHandler(c.updateRollups)
+	}
+	rollup := &rollup{groupBy: groupBy, sumFields: sumFields}
+	rollup.rebuild(c.Items)
+	c.rollups[name] = rollup
+}
+
+// Registers rollups from the configuration section. Must be called under write lock.
+func (c *MemoryPersistence) configureRollups(rollups *config.ConfigParams) {
+	for _, name := range rollups.GetSectionNames() {
+		section := rollups.GetSection(name)
+		sumFields := []string{}
+		for _, field := range strings.Split(section.GetAsString("sum"), ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				sumFields = append(sumFields, field)
+			}
+		}
+		c.registerRollup(name, section.GetAsString("group_by"), sumFields)
+	}
+}
+
+// Registers a named rollup that counts items and sums their fields per group.
+// The rollup is maintained on every change and can be read by GetRollup without scanning all items.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a unique name of the rollup
+//   - groupBy string
+//   (optional) a field or path to group items by, empty to aggregate all items in a single group
+//   - sumFields []string
+//   (optional) numeric fields or paths to sum
+// Returns error or nil for success.
+func (c *MemoryPersistence) RegisterRollup(correlationId string, name string, groupBy string, sumFields []string) error {
+	c.lock()
+	defer c.unlock()
+
+	c.registerRollup(name, groupBy, sumFields)
+	c.Logger.Trace(correlationId, "Registered rollup %s with %d groups", name, len(c.rollups[name].groups))
+	return nil
+}
+
+// Removes a previously registered rollup.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the rollup
+func (c *MemoryPersistence) UnregisterRollup(correlationId string, name string) {
+	c.lock()
+	defer c.unlock()
+
+	delete(c.rollups, name)
+	c.Logger.Trace(correlationId, "Unregistered rollup %s", name)
+}
+
+// Gets aggregates of a registered rollup.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - name string
+//   a name of the rollup
+// Returns map[string]RollupGroup, error
+// aggregates by group keys or NotFoundError if the rollup is not registered.
+func (c *MemoryPersistence) GetRollup(correlationId string, name string) (map[string]RollupGroup, error) {
+	c.rlock()
+	defer c.runlock()
+
+	rollup, ok := c.rollups[name]
+	if !ok {
+		return nil, errors.NewNotFoundError(correlationId, "ROLLUP_NOT_FOUND", "Rollup "+name+" is not registered").
+			WithDetails("rollup", name)
+	}
+
+	groups := make(map[string]RollupGroup, len(rollup.groups))
+	for key, group := range rollup.groups {
+		sums := make(map[string]float64, len(group.Sums))
+		for field, sum := range group.Sums {
+			sums[field] = sum
+		}
+		groups[key] = RollupGroup{Count: group.Count, Sums: sums}
+	}
+	return groups, nil
+}
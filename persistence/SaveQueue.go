@@ -0,0 +1,182 @@
+package persistence
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/*
+Background writer that saves items off the caller's goroutine.
+At most one save is queued at a time: requests that come while a save is queued
+are coalesced into it, so high write rates cause few saves.
+*/
+type saveQueue struct {
+	persistence *MemoryPersistence
+	// Maximum number of unsaved change requests before Save blocks, 0 for unlimited
+	maxPending int64
+	signal     chan string
+	stop       chan struct{}
+	done       chan struct{}
+	lock       sync.Mutex
+	cond       *sync.Cond
+	requested  int64
+	completed  int64
+	lastErr    error
+	active     bool
+}
+
+func newSaveQueue(persistence *MemoryPersistence) *saveQueue {
+	c := &saveQueue{persistence: persistence}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+// Starts the background writer
+func (c *saveQueue) start() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.active {
+		return
+	}
+	c.signal = make(chan string, 1)
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	c.active = true
+	go c.run(c.signal, c.stop, c.done)
+}
+
+// Checks if the background writer is running
+func (c *saveQueue) running() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.active
+}
+
+// Queues a save. Blocks while the number of unsaved requests exceeds the limit.
+// Returns error of the last completed save.
+func (c *saveQueue) request(correlationId string) error {
+	c.lock.Lock()
+	for c.active && c.maxPending > 0 && c.requested-c.completed >= c.maxPending {
+		c.cond.Wait()
+	}
+	c.requested++
+	signal := c.signal
+	err := c.lastErr
+	c.lock.Unlock()
+
+	select {
+	case signal <- correlationId:
+	default:
+		// A save is already queued and will include this change
+	}
+	return err
+}
+
+func (c *saveQueue) run(signal chan string, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case correlationId := <-signal:
+			c.save(correlationId)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *saveQueue) save(correlationId string) {
+	c.lock.Lock()
+	target := c.requested
+	c.lock.Unlock()
+
+	err := c.persistence.saveNow(correlationId)
+
+	c.lock.Lock()
+	c.completed = target
+	c.lastErr = err
+	c.cond.Broadcast()
+	c.lock.Unlock()
+
+	if err != nil {
+		if handler := c.persistence.SaveErrorHandler; handler != nil {
+			handler(correlationId, err)
+		} else {
+			c.persistence.Logger.Error(correlationId, err, "Failed to save items in background")
+		}
+	}
+}
+
+// Waits until all queued saves are completed.
+// Returns error of the last save.
+func (c *saveQueue) flush() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	target := c.requested
+	for c.active && c.completed < target {
+		c.cond.Wait()
+	}
+	return c.lastErr
+}
+
+// Stops the background writer after queued saves are completed
+func (c *saveQueue) close() error {
+	err := c.flush()
+
+	c.lock.Lock()
+	if !c.active {
+		c.lock.Unlock()
+		return err
+	}
+	c.active = false
+	stop, done := c.stop, c.done
+	c.cond.Broadcast()
+	c.lock.Unlock()
+
+	close(stop)
+	<-done
+	return err
+}
+
+// Turns on or off saving in background. Must be called under write lock.
+// Returns the detached queue that must be closed after the lock is released.
+func (c *MemoryPersistence) setBackgroundSave(enabled bool, maxPending int64) *saveQueue {
+	if !enabled {
+		queue := c.saveQueue
+		c.saveQueue = nil
+		return queue
+	}
+	if c.saveQueue == nil {
+		c.saveQueue = newSaveQueue(c)
+		if c.opened {
+			c.saveQueue.start()
+		}
+	}
+	c.saveQueue.lock.Lock()
+	c.saveQueue.maxPending = maxPending
+	c.saveQueue.lock.Unlock()
+	return nil
+}
+
+// Waits until all changes are saved. When saving in background is off it saves items immediately.
+// Items are written even in durability mode "none", and synced to the disk in mode "fsync".
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *MemoryPersistence) Flush(correlationId string) error {
+	c.rlock()
+	queue := c.saveQueue
+	c.runlock()
+
+	if queue != nil && queue.running() {
+		if err := queue.flush(); err != nil {
+			return err
+		}
+		return c.saveUnsaved(correlationId)
+	}
+	atomic.StoreInt32(&c.unsaved, 0)
+	return c.saveNow(correlationId)
+}
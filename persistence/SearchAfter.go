@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"strconv"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// SearchAfterPage is a page of items retrieved with search_after pagination.
+type SearchAfterPage struct {
+	Data []interface{} `json:"data"`
+	// Sort key of the last item to pass as after parameter for the next page,
+	// nil when there are no more items
+	After []interface{} `json:"after"`
+}
+
+// Gets a sort key of the item: values of sort fields followed by the id
+func (c *MemoryPersistence) searchAfterKey(item interface{}, sort cdata.SortParams) []interface{} {
+	key := make([]interface{}, len(sort)+1)
+	for i, field := range sort {
+		key[i] = GetPathValue(item, field.Name)
+	}
+	key[len(sort)] = c.ids.get(item)
+	return key
+}
+
+// Compares sort keys by sort fields and then by ids in ascending order
+func (c *MemoryPersistence) compareSearchAfterKeys(key1 []interface{}, key2 []interface{}, sort cdata.SortParams) int {
+	for i, value := range key1 {
+		result, ok := compareCollated(value, key2[i], c.Collation)
+		if !ok || result == 0 {
+			continue
+		}
+		if i < len(sort) && !sort[i].Ascending {
+			return -result
+		}
+		return result
+	}
+	return 0
+}
+
+// Gets a page of items that go after the given sort key, for deep pagination.
+// Unlike skip based paging the cost is proportional to the page size rather than the offset,
+// and pages stay stable when items are added or removed between requests.
+// Ids are used as tiebreakers, so items with equal sort values are never skipped or repeated.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filter *cdata.FilterParams
+//   (optional) filter parameters
+//   - sort *cdata.SortParams
+//   (optional) sort fields with directions
+//   - after []interface{}
+//   (optional) After key of the previous page, nil for the first page
+//   - take int64
+//   a maximum number of items on the page, 0 for the maximum page size
+// Returns *SearchAfterPage, error
+// the page of items or BadRequestError if the after key doesn't match sort fields.
+func (c *MemoryPersistence) GetPageAfter(correlationId string, filter *cdata.FilterParams,
+	sort *cdata.SortParams, after []interface{}, take int64) (*SearchAfterPage, error) {
+	fields := cdata.SortParams{}
+	if sort != nil {
+		fields = *sort
+	}
+	if after != nil && len(after) != len(fields)+1 {
+		return nil, errors.NewBadRequestError(correlationId, "INVALID_SEARCH_AFTER",
+			"Search after key must have "+strconv.Itoa(len(fields)+1)+" values for sort fields and id").
+			WithDetails("after", after)
+	}
+	if _, err := c.ComposeSort(sort); err != nil {
+		return nil, wrapError(err, correlationId, "INVALID_SORT", "Invalid sort")
+	}
+	filterFunc, err := c.ComposeFilter(filter)
+	if err != nil {
+		return nil, wrapError(err, correlationId, "INVALID_FILTER", "Invalid filter")
+	}
+	if take <= 0 || take > int64(c.MaxPageSize) {
+		take = int64(c.MaxPageSize)
+	}
+
+	items, err := c.GetTopByFilter(correlationId, func(item interface{}) bool {
+		if filterFunc != nil && !filterFunc(item) {
+			return false
+		}
+		return after == nil || c.compareSearchAfterKeys(c.searchAfterKey(item, fields), after, fields) > 0
+	}, func(a, b interface{}) bool {
+		return c.compareSearchAfterKeys(c.searchAfterKey(a, fields), c.searchAfterKey(b, fields), fields) < 0
+	}, int(take))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SearchAfterPage{Data: items}
+	if int64(len(items)) == take {
+		page.After = c.searchAfterKey(items[len(items)-1], fields)
+	}
+	return page, nil
+}
@@ -0,0 +1,241 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// Session is a user session stored by SessionMemoryPersistence.
+type Session struct {
+	// Session token
+	Id string `json:"id"`
+	// Id of the user who opened the session
+	UserId string `json:"user_id"`
+	// Custom session data
+	Data map[string]interface{} `json:"data"`
+	// Time when the session was opened
+	OpenTime time.Time `json:"open_time"`
+	// Time of the last request in the session
+	RequestTime time.Time `json:"request_time"`
+	// Time when the session expires unless it is used again
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+/*
+SessionMemoryPersistence stores user sessions keyed by tokens in memory.
+Sessions have sliding expiration: every successful GetSessionById extends the session
+for the session timeout. Expired sessions are not returned and are removed by CloseExpired.
+
+Configuration parameters
+
+- options:
+    - session_timeout:     Time in milliseconds after the last request when a session expires (default: 1800000)
+
+Example
+
+    sessions := NewSessionMemoryPersistence()
+    sessions.Open("123")
+
+    session, err := sessions.OpenSession("123", "user1", map[string]interface{}{"role": "admin"})
+    session, err = sessions.GetSessionById("123", session.Id)
+    if session == nil {
+        // The session is expired or closed
+    }
+    err = sessions.CloseExpired("123")
+*/
+type SessionMemoryPersistence struct {
+	IdentifiableMemoryPersistence
+	// Time in milliseconds after the last request when a session expires
+	SessionTimeout int64
+}
+
+// Creates a new persistence of sessions stored in memory.
+// Returns *SessionMemoryPersistence
+func NewSessionMemoryPersistence() *SessionMemoryPersistence {
+	return &SessionMemoryPersistence{
+		IdentifiableMemoryPersistence: *NewIdentifiableMemoryPersistence(reflect.TypeOf(Session{})),
+		SessionTimeout:                30 * 60 * 1000,
+	}
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *SessionMemoryPersistence) Configure(config *config.ConfigParams) {
+	c.IdentifiableMemoryPersistence.Configure(config)
+
+	c.lock()
+	c.SessionTimeout = config.GetAsLongWithDefault("options.session_timeout", c.SessionTimeout)
+	c.unlock()
+}
+
+// Gets the time when a session used now expires. Must be called under lock.
+func (c *SessionMemoryPersistence) expireTime(now time.Time) time.Time {
+	return now.Add(time.Duration(c.SessionTimeout) * time.Millisecond)
+}
+
+// Opens a new session with a random token.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - userId string
+//   an id of the user who opens the session
+//   - data map[string]interface{}
+//   (optional) custom session data
+// Returns Session, error
+// opened session or error.
+func (c *SessionMemoryPersistence) OpenSession(correlationId string, userId string, data map[string]interface{}) (Session, error) {
+	c.rlock()
+	now := c.Clock.Now().UTC()
+	session := Session{
+		Id:          cdata.IdGenerator.NextLong(),
+		UserId:      userId,
+		Data:        data,
+		OpenTime:    now,
+		RequestTime: now,
+		ExpireTime:  c.expireTime(now),
+	}
+	c.runlock()
+
+	result, err := c.IdentifiableMemoryPersistence.Create(correlationId, session)
+	if err != nil {
+		return Session{}, err
+	}
+	session, _ = result.(Session)
+	return session, nil
+}
+
+// Gets an active session by its token and extends its expiration.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - token string
+//   a session token
+// Returns *Session, error
+// the session or nil if it doesn't exist or expired.
+func (c *SessionMemoryPersistence) GetSessionById(correlationId string, token string) (*Session, error) {
+	c.lock()
+
+	now := c.Clock.Now().UTC()
+	index := c.GetIndexById(token)
+	if index < 0 {
+		c.unlock()
+		return nil, nil
+	}
+	oldItem := c.Items[index]
+	session, _ := oldItem.(Session)
+	if !session.ExpireTime.After(now) {
+		c.unlock()
+		return nil, nil
+	}
+
+	if err := c.checkWritable(correlationId); err == nil {
+		session.RequestTime = now
+		session.ExpireTime = c.expireTime(now)
+		var newItem interface{} = session
+		c.notifyChange(oldItem, newItem)
+		c.Items[index] = newItem
+		c.unlock()
+
+		if err = c.save(correlationId); err != nil {
+			return nil, err
+		}
+	} else {
+		// Read-only instances return sessions without extending them
+		c.unlock()
+	}
+	return &session, nil
+}
+
+// Gets active sessions of a user ordered by open time.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - userId string
+//   an id of the user
+// Returns []Session, error
+// active sessions of the user or error.
+func (c *SessionMemoryPersistence) GetByUser(correlationId string, userId string) ([]Session, error) {
+	now := c.Clock.Now().UTC()
+	items, err := c.GetListByFilter(correlationId, func(item interface{}) bool {
+		session, ok := item.(Session)
+		return ok && session.UserId == userId && session.ExpireTime.After(now)
+	}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(items))
+	for i, item := range items {
+		sessions[i], _ = item.(Session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].OpenTime.Before(sessions[j].OpenTime) })
+	return sessions, nil
+}
+
+// Closes a session by its token.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - token string
+//   a session token
+// Returns error or nil for success.
+func (c *SessionMemoryPersistence) CloseSession(correlationId string, token string) error {
+	_, err := c.IdentifiableMemoryPersistence.DeleteById(correlationId, token)
+	return err
+}
+
+// Removes all expired sessions.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *SessionMemoryPersistence) CloseExpired(correlationId string) error {
+	now := c.Clock.Now().UTC()
+	return c.DeleteByFilter(correlationId, func(item interface{}) bool {
+		session, ok := item.(Session)
+		return ok && !session.ExpireTime.After(now)
+	})
+}
+
+/*
+SessionFilePersistence stores user sessions keyed by tokens in a JSON file,
+so sessions survive restarts of the service. See SessionMemoryPersistence.
+
+Configuration parameters
+
+- path:                    path to the file where sessions are stored
+- options:
+    - session_timeout:     Time in milliseconds after the last request when a session expires (default: 1800000)
+*/
+type SessionFilePersistence struct {
+	SessionMemoryPersistence
+	Persister *JsonFilePersister
+}
+
+// Creates a new persistence of sessions stored in a file.
+// Parameters:
+//   - path string
+//   a path to the file where sessions are stored
+// Returns *SessionFilePersistence
+func NewSessionFilePersistence(path string) *SessionFilePersistence {
+	c := &SessionFilePersistence{SessionMemoryPersistence: *NewSessionMemoryPersistence()}
+	c.Persister = NewJsonFilePersister(c.Prototype, path)
+	c.Loader = c.Persister
+	c.Saver = c.Persister
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *SessionFilePersistence) Configure(config *config.ConfigParams) {
+	c.SessionMemoryPersistence.Configure(config)
+	c.reconfigurePersister(c.Persister, config)
+}
@@ -0,0 +1,212 @@
+package persistence
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// SettingsSection is a named section of settings stored by SettingsFilePersistence.
+type SettingsSection struct {
+	// Name of the section
+	Id string `json:"id"`
+	// Settings of the section by their keys. Values keep their JSON types: strings, numbers, booleans or objects
+	Parameters map[string]interface{} `json:"parameters"`
+	// Time of the last change
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// SettingsListener is notified when a section of settings is changed.
+// It receives a copy of new settings of the section.
+type SettingsListener func(correlationId string, id string, parameters *cdata.AnyValueMap)
+
+/*
+SettingsFilePersistence is a durable configuration store for application settings and feature flags.
+Settings are grouped into sections, every section maps keys to typed values,
+and listeners are notified after every saved change.
+
+Configuration parameters
+
+- path:                    path to the file where settings are stored
+- options:
+    - durability:          Durability of saved changes: none, flush or fsync (default: flush)
+
+Example
+
+    settings := NewSettingsFilePersistence("./data/settings.json")
+    settings.AddListener(func(correlationId string, id string, parameters *cdata.AnyValueMap) {
+        fmt.Println("Settings changed:", id)
+    })
+    settings.Open("123")
+
+    settings.SetSection("123", "features", cdata.NewAnyValueMapFromTuples("dark_mode", true))
+    settings.ModifySection("123", "stats", nil, cdata.NewAnyValueMapFromTuples("logins", 1))
+    features, err := settings.GetSection("123", "features")
+    enabled := features.GetAsBoolean("dark_mode")
+*/
+type SettingsFilePersistence struct {
+	IdentifiableFilePersistence
+	listeners []SettingsListener
+}
+
+// Creates a new persistence of settings.
+// Parameters:
+//   - path string
+//   a path to the file where settings are stored
+// Returns *SettingsFilePersistence
+func NewSettingsFilePersistence(path string) *SettingsFilePersistence {
+	prototype := reflect.TypeOf(SettingsSection{})
+	return &SettingsFilePersistence{
+		IdentifiableFilePersistence: *NewIdentifiableFilePersistence(prototype, NewJsonFilePersister(prototype, path)),
+	}
+}
+
+// Adds a listener that is notified about changed sections.
+// Parameters:
+//   - listener SettingsListener
+//   a listener to notify
+func (c *SettingsFilePersistence) AddListener(listener SettingsListener) {
+	c.lock()
+	defer c.unlock()
+
+	c.listeners = append(c.listeners, listener)
+}
+
+// Gets settings of a section. Must be called under lock.
+func (c *SettingsFilePersistence) getSection(id string) (int, map[string]interface{}) {
+	index := c.GetIndexById(id)
+	if index < 0 {
+		return index, nil
+	}
+	section, _ := c.Items[index].(SettingsSection)
+	return index, section.Parameters
+}
+
+// Atomically changes settings of a section, saves them and notifies listeners.
+// The update function gets a copy of current settings that it can change.
+func (c *SettingsFilePersistence) change(correlationId string, id string,
+	update func(parameters map[string]interface{})) (*cdata.AnyValueMap, error) {
+	c.lock()
+
+	if err := c.checkWritable(correlationId); err != nil {
+		c.unlock()
+		return nil, err
+	}
+
+	index, current := c.getSection(id)
+	parameters := cdata.NewAnyValueMap(current).Value()
+	update(parameters)
+
+	var oldItem interface{}
+	if index >= 0 {
+		oldItem = c.Items[index]
+	}
+	var newItem interface{} = SettingsSection{Id: id, Parameters: parameters, UpdateTime: c.Clock.Now().UTC()}
+	c.notifyChange(oldItem, newItem)
+	if index >= 0 {
+		c.Items[index] = newItem
+	} else {
+		c.Items = append(c.Items, newItem)
+	}
+	listeners := c.listeners
+
+	c.unlock()
+	c.Logger.Trace(correlationId, "Changed settings section %s", id)
+
+	if err := c.save(correlationId); err != nil {
+		return nil, err
+	}
+	for _, listener := range listeners {
+		listener(correlationId, id, cdata.NewAnyValueMap(parameters))
+	}
+	return cdata.NewAnyValueMap(parameters), nil
+}
+
+// Gets names of all sections.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns []string, error
+// section names or error.
+func (c *SettingsFilePersistence) GetSectionIds(correlationId string) ([]string, error) {
+	c.rlock()
+	defer c.runlock()
+
+	ids := make([]string, 0, len(c.Items))
+	for _, item := range c.Items {
+		if section, ok := item.(SettingsSection); ok {
+			ids = append(ids, section.Id)
+		}
+	}
+	return ids, nil
+}
+
+// Gets settings of a section.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   a name of the section
+// Returns *cdata.AnyValueMap, error
+// a copy of section settings, empty if the section doesn't exist.
+func (c *SettingsFilePersistence) GetSection(correlationId string, id string) (*cdata.AnyValueMap, error) {
+	c.rlock()
+	defer c.runlock()
+
+	_, parameters := c.getSection(id)
+	return cdata.NewAnyValueMap(parameters), nil
+}
+
+// Replaces all settings of a section.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   a name of the section
+//   - parameters *cdata.AnyValueMap
+//   new settings of the section
+// Returns *cdata.AnyValueMap, error
+// saved settings or error.
+func (c *SettingsFilePersistence) SetSection(correlationId string, id string,
+	parameters *cdata.AnyValueMap) (*cdata.AnyValueMap, error) {
+	return c.change(correlationId, id, func(current map[string]interface{}) {
+		for key := range current {
+			delete(current, key)
+		}
+		if parameters != nil {
+			for key, value := range parameters.Value() {
+				current[key] = value
+			}
+		}
+	})
+}
+
+// Atomically modifies selected settings of a section.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id string
+//   a name of the section
+//   - updateParams *cdata.AnyValueMap
+//   (optional) settings to set
+//   - incrementParams *cdata.AnyValueMap
+//   (optional) numeric settings to increment by given values
+// Returns *cdata.AnyValueMap, error
+// saved settings or error.
+func (c *SettingsFilePersistence) ModifySection(correlationId string, id string,
+	updateParams *cdata.AnyValueMap, incrementParams *cdata.AnyValueMap) (*cdata.AnyValueMap, error) {
+	return c.change(correlationId, id, func(current map[string]interface{}) {
+		if updateParams != nil {
+			for key, value := range updateParams.Value() {
+				current[key] = value
+			}
+		}
+		if incrementParams != nil {
+			for key, delta := range incrementParams.Value() {
+				current[key] = convert.DoubleConverter.ToDouble(current[key]) + convert.DoubleConverter.ToDouble(delta)
+			}
+		}
+	})
+}
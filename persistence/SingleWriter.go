@@ -0,0 +1,249 @@
+package persistence
+
+import (
+	"os"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Optional interface for loaders that can report when their data was last changed.
+It allows read-only instances to reload data only when it was changed by the writer.
+*/
+type IModificationTracker interface {
+	LastModified(correlationId string) (time.Time, error)
+}
+
+// Loaders of data files that can report the file itself, not only its modification time
+type dataFileTracker interface {
+	dataFileInfo(correlationId string) (os.FileInfo, error)
+}
+
+/*
+State of loaded or saved data used to detect changes made by other instances.
+Data files are replaced on every save, so besides the modification time, which may not change
+within its resolution, they are compared by identity of the file and its size.
+*/
+type dataState struct {
+	modified time.Time
+	file     os.FileInfo
+}
+
+func (c dataState) equal(other dataState) bool {
+	if c.file != nil || other.file != nil {
+		return c.file != nil && other.file != nil && os.SameFile(c.file, other.file) &&
+			c.file.Size() == other.file.Size() && c.file.ModTime().Equal(other.file.ModTime())
+	}
+	return c.modified.Equal(other.modified)
+}
+
+// Gets the current state of data, returns false when the loader doesn't track modifications
+func (c *MemoryPersistence) currentDataState(correlationId string) (state dataState, ok bool, err error) {
+	if tracker, ok := c.Loader.(dataFileTracker); ok {
+		state.file, err = tracker.dataFileInfo(correlationId)
+		return state, true, err
+	}
+	if tracker, ok := c.Loader.(IModificationTracker); ok {
+		state.modified, err = tracker.LastModified(correlationId)
+		return state, true, err
+	}
+	return state, false, nil
+}
+
+/*
+Optional interface for locks that can extend the lock held by the caller in one atomic operation,
+for instance distributed locks that keep an owner token in the lock value.
+The writer lock is renewed through it when the referenced lock implements it.
+*/
+type IRenewableLock interface {
+	// Extends the lock held by the caller, returns false when the lock is held by another owner
+	RenewLock(correlationId string, key string, ttl int64) (bool, error)
+}
+
+/*
+Coordination of instances that share the same data source.
+Only the instance that holds the writer lock can change and save items,
+other instances are read-only and reload items when they change.
+*/
+type singleWriter struct {
+	key      string
+	ttl      int64
+	interval int64
+	isWriter bool
+	expires  time.Time
+	state    dataState
+	stop     chan bool
+}
+
+// Tries to become the writer, must be called under persistence lock
+func (c *MemoryPersistence) acquireWriter(correlationId string) {
+	w := c.writer
+	acquired, err := c.locker.TryAcquireLock(correlationId, w.key, w.ttl)
+	if err != nil {
+		c.Logger.Error(correlationId, err, "Failed to acquire writer lock %s", w.key)
+		return
+	}
+	if acquired {
+		w.isWriter = true
+		w.expires = c.Clock.Now().Add(time.Duration(w.ttl) * time.Millisecond)
+		c.Logger.Info(correlationId, "Became the writer for %s", w.key)
+	}
+}
+
+// Renews the writer lock without releasing it, must be called under persistence lock.
+// Locks without atomic renewal are acquired again only after they expire,
+// until then TryAcquireLock fails while this instance still holds them.
+func (c *MemoryPersistence) renewWriter(correlationId string) {
+	w := c.writer
+	now := c.Clock.Now()
+	var acquired bool
+	var err error
+	if renewable, ok := c.locker.(IRenewableLock); ok {
+		acquired, err = renewable.RenewLock(correlationId, w.key, w.ttl)
+	} else {
+		acquired, err = c.locker.TryAcquireLock(correlationId, w.key, w.ttl)
+		if err == nil && !acquired && now.Before(w.expires) {
+			return
+		}
+	}
+	if err != nil || !acquired {
+		w.isWriter = false
+		c.Logger.Warn(correlationId, "Lost writer lock %s, switched to read-only mode", w.key)
+		return
+	}
+	w.expires = now.Add(time.Duration(w.ttl) * time.Millisecond)
+}
+
+// Checks if this instance holds the writer lock that has not expired, must be called under persistence lock
+func (c *MemoryPersistence) holdsWriter() bool {
+	w := c.writer
+	return w.isWriter && c.Clock.Now().Before(w.expires)
+}
+
+// Renews the writer lock or reloads items changed by the writer, must be called under persistence lock
+func (c *MemoryPersistence) checkWriter(correlationId string) {
+	w := c.writer
+	if w.isWriter {
+		c.renewWriter(correlationId)
+		return
+	}
+
+	// Reload items before a new writer changes them
+	changed := true
+	if state, ok, err := c.currentDataState(correlationId); ok {
+		changed = err != nil || !state.equal(w.state)
+		w.state = state
+	}
+	if changed {
+		if err := c.load(correlationId); err != nil {
+			c.Logger.Error(correlationId, err, "Failed to reload items")
+		}
+	}
+	c.acquireWriter(correlationId)
+}
+
+// Starts coordination with other instances, must be called under persistence lock
+func (c *MemoryPersistence) startWriter(correlationId string) error {
+	w := c.writer
+	if c.locker == nil {
+		return errors.NewConfigError(correlationId, "NO_LOCK", "Lock reference is required for single writer mode")
+	}
+	if w.stop != nil {
+		return nil
+	}
+	if w.key == "" {
+		if persister, ok := c.Loader.(*JsonFilePersister); ok {
+			w.key = persister.Path()
+		} else {
+			w.key = c.Prototype.String()
+		}
+	}
+
+	w.state, _, _ = c.currentDataState(correlationId)
+	c.acquireWriter(correlationId)
+
+	stop := make(chan bool)
+	w.stop = stop
+	go func() {
+		ticker := c.Clock.NewTicker(time.Duration(w.interval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C():
+				c.lock()
+				// Stop when single writer mode was switched off or replaced by reconfiguration
+				if c.writer != w {
+					c.unlock()
+					return
+				}
+				// Skip ticks received before the coordination was stopped
+				if w.stop == stop {
+					c.checkWriter(correlationId)
+				}
+				c.unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+// Applies single writer options, must be called under persistence lock.
+// The coordination of the opened persistence is stopped and the writer lock is released
+// before the writer is detached or replaced, and a replaced writer is started with new options.
+func (c *MemoryPersistence) configureWriter(config *config.ConfigParams) {
+	current := c.writer
+	if !config.GetAsBooleanWithDefault("options.single_writer", current != nil) {
+		if current != nil {
+			c.stopWriter("")
+			c.writer = nil
+		}
+		return
+	}
+
+	w := &singleWriter{ttl: 30000, interval: 10000}
+	if current != nil {
+		w.key, w.ttl, w.interval = current.key, current.ttl, current.interval
+	}
+	w.key = config.GetAsStringWithDefault("options.writer_lock_key", w.key)
+	w.ttl = config.GetAsLongWithDefault("options.writer_lock_ttl", w.ttl)
+	w.interval = config.GetAsLongWithDefault("options.writer_check_interval", w.interval)
+	if current != nil && current.key == w.key && current.ttl == w.ttl && current.interval == w.interval {
+		return
+	}
+
+	if current != nil {
+		c.stopWriter("")
+	}
+	c.writer = w
+	if c.opened {
+		if err := c.startWriter(""); err != nil {
+			c.Logger.Error("", err, "Failed to start single writer mode")
+		}
+	}
+}
+
+// Stops coordination and releases the writer lock, must be called under persistence lock
+func (c *MemoryPersistence) stopWriter(correlationId string) {
+	w := c.writer
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+	if w.isWriter {
+		w.isWriter = false
+		c.locker.ReleaseLock(correlationId, w.key)
+	}
+}
+
+// Checks if this instance can change items.
+// Returns true if single writer mode is disabled or this instance holds the writer lock.
+func (c *MemoryPersistence) IsWriter() bool {
+	c.rlock()
+	defer c.runlock()
+
+	return c.writer == nil || c.holdsWriter()
+}
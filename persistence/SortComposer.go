@@ -0,0 +1,230 @@
+package persistence
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"golang.org/x/text/cases"
+)
+
+/*
+Collation defines how strings are compared when items are sorted or filtered.
+By default strings are compared by their bytes.
+*/
+type Collation struct {
+	// Compare strings ignoring letter case
+	IgnoreCase bool
+	// Compare strings ignoring diacritics, so "Jose" matches "José"
+	IgnoreDiacritics bool
+	// Compare sequences of digits by their numeric values, so "item2" goes before "item10"
+	Numeric bool
+}
+
+// Parses collation from comma-separated flags: ignore_case, ignore_diacritics, numeric.
+// Parameters:
+//   - value string
+//   comma-separated collation flags
+// Returns *Collation
+// parsed collation or nil if no flags are set.
+func ParseCollation(value string) *Collation {
+	var collation *Collation
+	for _, flag := range strings.Split(value, ",") {
+		flag = strings.ToLower(strings.TrimSpace(flag))
+		if flag == "" {
+			continue
+		}
+		if collation == nil {
+			collation = &Collation{}
+		}
+		switch flag {
+		case "ignore_case":
+			collation.IgnoreCase = true
+		case "ignore_diacritics":
+			collation.IgnoreDiacritics = true
+		case "numeric":
+			collation.Numeric = true
+		}
+	}
+	return collation
+}
+
+// Compares two strings according to the collation.
+// Parameters:
+//   - value1 string
+//   the first string
+//   - value2 string
+//   the second string
+// Returns int
+// -1 if value1 goes before value2, 1 if it goes after, and 0 if they are equal.
+func (c *Collation) Compare(value1 string, value2 string) int {
+	if c == nil {
+		return strings.Compare(value1, value2)
+	}
+	value1 = c.Normalize(value1)
+	value2 = c.Normalize(value2)
+	if !c.Numeric {
+		return strings.Compare(value1, value2)
+	}
+	return compareNatural([]rune(value1), []rune(value2))
+}
+
+// Converts a string into the form used in comparisons:
+// folds letter case and removes diacritics when they are ignored.
+// Parameters:
+//   - value string
+//   a string to convert
+// Returns string
+// the normalized string.
+func (c *Collation) Normalize(value string) string {
+	if c == nil {
+		return value
+	}
+	if c.IgnoreDiacritics {
+		value = foldDiacritics(value)
+	}
+	if c.IgnoreCase {
+		value = cases.Fold().String(value)
+	}
+	return value
+}
+
+// Compares strings where sequences of digits are compared by numeric values
+func compareNatural(value1 []rune, value2 []rune) int {
+	i, j := 0, 0
+	for i < len(value1) && j < len(value2) {
+		if unicode.IsDigit(value1[i]) && unicode.IsDigit(value2[j]) {
+			start1, start2 := i, j
+			for i < len(value1) && unicode.IsDigit(value1[i]) {
+				i++
+			}
+			for j < len(value2) && unicode.IsDigit(value2[j]) {
+				j++
+			}
+			number1 := strings.TrimLeft(string(value1[start1:i]), "0")
+			number2 := strings.TrimLeft(string(value2[start2:j]), "0")
+			if len(number1) != len(number2) {
+				if len(number1) < len(number2) {
+					return -1
+				}
+				return 1
+			}
+			if result := strings.Compare(number1, number2); result != 0 {
+				return result
+			}
+			continue
+		}
+		if value1[i] != value2[j] {
+			if value1[i] < value2[j] {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+	switch {
+	case len(value1)-i < len(value2)-j:
+		return -1
+	case len(value1)-i > len(value2)-j:
+		return 1
+	}
+	return 0
+}
+
+// compareCollated compares two values like compareOrdered
+// but uses the collation for strings
+func compareCollated(value1 interface{}, value2 interface{}, collation *Collation) (int, bool) {
+	if collation != nil {
+		v1, ok1 := getValue(value1).(string)
+		v2, ok2 := getValue(value2).(string)
+		if ok1 && ok2 {
+			return collation.Compare(v1, v2), true
+		}
+	}
+	return compareOrdered(value1, value2)
+}
+
+/*
+ComposeSort converts SortParams into a sort function that compares items
+by several fields in the order they are listed. Fields are matched with names,
+json tags or paths of prototype fields. Use the sort function with GetPageByFilter
+or GetListByFilter, they keep the original order of equal items.
+
+Parameters:
+  - prototype reflect.Type
+  type of items to be sorted
+  - sort *cdata.SortParams
+  (optional) sort fields with directions
+  - collation *Collation
+  (optional) collation to compare strings
+Returns func(a, b interface{}) bool, error
+sort function, nil if no sort fields are set, or BadRequestError if some fields don't match prototype fields.
+
+Example
+
+  sortFunc, err := ComposeSort(reflect.TypeOf(MyData{}), cdata.NewSortParams([]cdata.SortField{
+      cdata.NewSortField("name", true),
+      cdata.NewSortField("age", false),
+  }), &Collation{IgnoreCase: true})
+*/
+func ComposeSort(prototype reflect.Type, sort *cdata.SortParams,
+	collation *Collation) (func(a, b interface{}) bool, error) {
+	if sort == nil || len(*sort) == 0 {
+		return nil, nil
+	}
+
+	typ := prototype
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	fields := *sort
+	indexes := make([][]int, len(fields))
+	for i, field := range fields {
+		if typ == nil || typ.Kind() != reflect.Struct {
+			continue
+		}
+		if isFieldPath(field.Name) {
+			if _, ok := findPathType(typ, field.Name); !ok {
+				return nil, errors.NewBadRequestError("", "UNKNOWN_FIELD",
+					"Field "+field.Name+" is not defined in "+typ.Name()).WithDetails("field", field.Name)
+			}
+			continue
+		}
+		structField, ok := findField(typ, field.Name)
+		if !ok && !hasExtraField(typ) {
+			return nil, errors.NewBadRequestError("", "UNKNOWN_FIELD",
+				"Field "+field.Name+" is not defined in "+typ.Name()).WithDetails("field", field.Name)
+		}
+		indexes[i] = structField.Index
+	}
+
+	return func(a, b interface{}) bool {
+		for i, field := range fields {
+			result, ok := compareCollated(getFieldValue(a, indexes[i], field.Name),
+				getFieldValue(b, indexes[i], field.Name), collation)
+			if !ok || result == 0 {
+				continue
+			}
+			if field.Ascending {
+				return result < 0
+			}
+			return result > 0
+		}
+		return false
+	}, nil
+}
+
+// Converts SortParams into a sort function for items of the persistence
+// using the collation set in options.collation.
+// Parameters:
+//   - sort *cdata.SortParams
+//   (optional) sort fields with directions
+// Returns func(a, b interface{}) bool, error
+// sort function, nil if no sort fields are set, or BadRequestError if some fields are unknown.
+func (c *MemoryPersistence) ComposeSort(sort *cdata.SortParams) (func(a, b interface{}) bool, error) {
+	return ComposeSort(c.Prototype, sort, c.Collation)
+}
@@ -0,0 +1,217 @@
+package persistence
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Names of tables that can be used without quoting
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+/*
+Persistence component that loads and saves data items in a table of SQL database
+through database/sql, so memory persistence can keep items in embedded databases like SQLite.
+Items are stored as JSON documents in a table with id and data columns
+that is created on the first load.
+
+The package doesn't import database drivers, so applications import the driver they use,
+for instance github.com/mattn/go-sqlite3 for sqlite3 driver.
+
+Example
+
+  import _ "github.com/mattn/go-sqlite3"
+
+  persister := NewSqlPersister("sqlite3", "./data/orders.db", "orders")
+  persistence := NewIdentifiableMemoryPersistence(reflect.TypeOf(Order{}))
+  persistence.Loader = persister
+  persistence.Saver = persister
+  persistence.Open("123")
+  ...
+  persistence.Close("123")
+  persister.Close("123")
+*/
+// implements ILoader, ISaver
+type SqlPersister struct {
+	// Name of the registered database/sql driver
+	Driver string
+	// Data source name passed to the driver, for instance a path to SQLite database
+	Connection string
+	// Name of the table with items
+	Table string
+	// Name or json name of the struct field with item ids, empty for Id or the field tagged with pip:"id"
+	IdField string
+
+	lock sync.Mutex
+	db   *sql.DB
+}
+
+// Creates a new instance of the persister.
+// Parameters:
+//   - driver string
+//   a name of the registered database/sql driver
+//   - connection string
+//   a data source name passed to the driver
+//   - table string
+//   a name of the table with items
+// Returns *SqlPersister
+func NewSqlPersister(driver string, connection string, table string) *SqlPersister {
+	return &SqlPersister{
+		Driver:     driver,
+		Connection: connection,
+		Table:      table,
+	}
+}
+
+// Opens the database and creates the table on the first call
+func (c *SqlPersister) open(correlationId string) (*sql.DB, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.db != nil {
+		return c.db, nil
+	}
+	if c.Connection == "" {
+		return nil, errors.NewConfigError(correlationId, "NO_CONNECTION", "Database connection is not set").
+			WithDetails("driver", c.Driver)
+	}
+	if !sqlTableName.MatchString(c.Table) {
+		return nil, errors.NewConfigError(correlationId, "INVALID_TABLE", "Invalid table name "+c.Table).
+			WithDetails("table", c.Table)
+	}
+
+	connectFailed := func(cause error) error {
+		return errors.NewConnectionError(correlationId, "CONNECT_FAILED",
+			"Failed to open database with driver "+c.Driver+", check that the driver is imported").
+			WithDetails("driver", c.Driver).WithCause(cause)
+	}
+	db, err := sql.Open(c.Driver, c.Connection)
+	if err != nil {
+		return nil, connectFailed(err)
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(255) PRIMARY KEY, data TEXT NOT NULL)", c.Table)
+	if _, err = db.Exec(query); err != nil {
+		db.Close()
+		return nil, connectFailed(err)
+	}
+	c.db = db
+	return db, nil
+}
+
+// Loads data items from the table.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns []interface{}, error
+// loaded items as maps, ConfigError or ConnectionError when the database can't be opened,
+// or FileError when items can't be read.
+func (c *SqlPersister) Load(correlationId string) ([]interface{}, error) {
+	db, err := c.open(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	readFailed := func(cause error) error {
+		return errors.NewFileError(correlationId, "READ_FAILED", "Failed to read items from table "+c.Table).
+			WithDetails("table", c.Table).WithCause(cause)
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT data FROM %s ORDER BY id", c.Table))
+	if err != nil {
+		return nil, readFailed(err)
+	}
+	defer rows.Close()
+
+	items := []interface{}{}
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return nil, readFailed(err)
+		}
+		var item map[string]interface{}
+		if err = json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, readFailed(err)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, readFailed(err)
+	}
+	return items, nil
+}
+
+// Replaces all items in the table with given data items in one transaction.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+//   - items []interface{}
+//   list of data items to save
+// Returns error
+// ConfigError or ConnectionError when the database can't be opened,
+// or FileError when items can't be written.
+func (c *SqlPersister) Save(correlationId string, items []interface{}) error {
+	db, err := c.open(correlationId)
+	if err != nil {
+		return err
+	}
+	writeFailed := func(cause error) error {
+		return errors.NewFileError(correlationId, "WRITE_FAILED", "Failed to write items to table "+c.Table).
+			WithDetails("table", c.Table).WithCause(cause)
+	}
+
+	ids := idAccessor{}
+	if c.IdField != "" && len(items) > 0 {
+		typ := reflect.TypeOf(items[0])
+		if itemStructType(typ) == nil {
+			ids = idAccessor{field: c.IdField, key: c.IdField}
+		} else if ids, err = newIdAccessor(typ, c.IdField); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return writeFailed(err)
+	}
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s", c.Table)); err != nil {
+		tx.Rollback()
+		return writeFailed(err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, data) VALUES (?, ?)", c.Table)
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			tx.Rollback()
+			return writeFailed(err)
+		}
+		if _, err = tx.Exec(query, fmt.Sprint(ids.get(item)), string(data)); err != nil {
+			tx.Rollback()
+			return writeFailed(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return writeFailed(err)
+	}
+	return nil
+}
+
+// Closes the database, it is opened again by the next load or save.
+// Parameters:
+//   - correlation_id string
+//   transaction id to trace execution through call chain.
+// Returns error
+func (c *SqlPersister) Close(correlationId string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.db == nil {
+		return nil
+	}
+	err := c.db.Close()
+	c.db = nil
+	return err
+}
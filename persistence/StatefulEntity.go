@@ -0,0 +1,209 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// StateTransition is a recorded change of an entity state.
+type StateTransition struct {
+	Id string `json:"id"`
+	// Id of the entity
+	EntityId string `json:"entity_id"`
+	// Previous state, empty for created entities
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Time time.Time `json:"time"`
+}
+
+/*
+StatefulEntityPersistence stores entities that move through a state machine,
+for instance, orders or saga instances. Changes of the state field are checked against
+the transition table, and invalid transitions are rejected with ConflictError.
+Every state change is recorded in the History persistence.
+
+History is kept in memory by default. Set loader and saver of the History persistence
+to keep it in a file or another data source.
+
+Configuration parameters
+
+- options:
+    - state_field:         Name of the field with entity states (default: state)
+- transitions:
+    - <state>:             Comma-separated states that entities in the state can move to
+
+Example
+
+    persistence := NewStatefulEntityPersistence(reflect.TypeOf(Order{}))
+    persistence.Configure(NewConfigParamsFromTuples(
+        "transitions.new", "paid,cancelled",
+        "transitions.paid", "shipped",
+    ))
+    persistence.Open("123")
+
+    persistence.Create("123", Order{Id: "1", State: "new"})
+    persistence.UpdatePartially("123", "1", cdata.NewAnyValueMapFromTuples("state", "paid"))
+    _, err := persistence.UpdatePartially("123", "1", cdata.NewAnyValueMapFromTuples("state", "new"))  // ConflictError
+    history, err := persistence.GetTransitionHistory("123", "1")
+*/
+type StatefulEntityPersistence struct {
+	IdentifiableMemoryPersistence
+	// Name of the field with entity states
+	StateField string
+	// Allowed transitions from states to lists of next states.
+	// When the table is empty, all transitions are allowed.
+	Transitions map[string][]string
+	// Persistence of recorded StateTransition items
+	History *IdentifiableMemoryPersistence
+}
+
+// Creates a new persistence of entities with states.
+// Parameters:
+//   - prototype reflect.Type
+//   type of stored entities
+// Returns *StatefulEntityPersistence
+func NewStatefulEntityPersistence(prototype reflect.Type) *StatefulEntityPersistence {
+	c := &StatefulEntityPersistence{
+		IdentifiableMemoryPersistence: *NewIdentifiableMemoryPersistence(prototype),
+		StateField:                    "state",
+		Transitions:                   map[string][]string{},
+		History:                       NewIdentifiableMemoryPersistence(reflect.TypeOf(StateTransition{})),
+	}
+	c.addChangeValidator(c.validateTransition)
+	c.addChangeHandler(c.recordTransition)
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// Parameters:
+//  - config  *config.ConfigParams
+//  configuration parameters to be set.
+func (c *StatefulEntityPersistence) Configure(config *config.ConfigParams) {
+	c.IdentifiableMemoryPersistence.Configure(config)
+
+	c.lock()
+	defer c.unlock()
+
+	c.StateField = config.GetAsStringWithDefault("options.state_field", c.StateField)
+	transitions := config.GetSection("transitions")
+	for _, state := range transitions.Keys() {
+		next := []string{}
+		for _, to := range strings.Split(transitions.GetAsString(state), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				next = append(next, to)
+			}
+		}
+		c.Transitions[state] = next
+	}
+}
+
+// Opens the component with its history.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *StatefulEntityPersistence) Open(correlationId string) error {
+	if err := c.History.Open(correlationId); err != nil {
+		return err
+	}
+	if err := c.IdentifiableMemoryPersistence.Open(correlationId); err != nil {
+		c.History.Close(correlationId)
+		return err
+	}
+	return nil
+}
+
+// Closes the component with its history.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *StatefulEntityPersistence) Close(correlationId string) error {
+	err := c.IdentifiableMemoryPersistence.Close(correlationId)
+	if historyErr := c.History.Close(correlationId); err == nil {
+		err = historyErr
+	}
+	return err
+}
+
+// Gets a state of the item
+func (c *StatefulEntityPersistence) itemState(item interface{}) string {
+	return convert.StringConverter.ToString(GetPathValue(item, c.StateField))
+}
+
+// Checks if the state change is allowed by the transition table. Called under write lock.
+func (c *StatefulEntityPersistence) validateTransition(correlationId string, oldItem interface{}, newItem interface{}) error {
+	if oldItem == nil || newItem == nil || len(c.Transitions) == 0 {
+		return nil
+	}
+	from, to := c.itemState(oldItem), c.itemState(newItem)
+	if from == to {
+		return nil
+	}
+	for _, state := range c.Transitions[from] {
+		if state == to {
+			return nil
+		}
+	}
+	id := c.ids.get(newItem)
+	return errors.NewConflictError(correlationId, "INVALID_TRANSITION",
+		"Transition of item "+convert.StringConverter.ToString(id)+" from state "+from+" to "+to+" is not allowed").
+		WithDetails("id", id).
+		WithDetails("from", from).
+		WithDetails("to", to)
+}
+
+// Records a change of the item state into history. Called under write lock.
+func (c *StatefulEntityPersistence) recordTransition(oldItem interface{}, newItem interface{}) {
+	if newItem == nil {
+		return
+	}
+	from, to := "", c.itemState(newItem)
+	if oldItem != nil {
+		from = c.itemState(oldItem)
+	}
+	if from == to {
+		return
+	}
+	transition := StateTransition{
+		EntityId: convert.StringConverter.ToString(c.ids.get(newItem)),
+		From:     from,
+		To:       to,
+		Time:     c.Clock.Now().UTC(),
+	}
+	if _, err := c.History.Create("", transition); err != nil {
+		c.Logger.Warn("", "Failed to record transition of item %s: %v", transition.EntityId, err)
+	}
+}
+
+// Gets recorded state transitions of an entity ordered by time.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the entity
+// Returns []StateTransition, error
+// transitions of the entity or error.
+func (c *StatefulEntityPersistence) GetTransitionHistory(correlationId string, id interface{}) ([]StateTransition, error) {
+	entityId := convert.StringConverter.ToString(id)
+	items, err := c.History.GetListByFilter(correlationId, func(item interface{}) bool {
+		transition, ok := item.(StateTransition)
+		return ok && transition.EntityId == entityId
+	}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := make([]StateTransition, len(items))
+	for i, item := range items {
+		transitions[i], _ = item.(StateTransition)
+	}
+	sort.SliceStable(transitions, func(i, j int) bool { return transitions[i].Time.Before(transitions[j].Time) })
+	return transitions, nil
+}
@@ -0,0 +1,65 @@
+package persistence
+
+import (
+	"reflect"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Modes to keep items in memory
+const (
+	// Items are kept as struct values and copied on every change and read (default).
+	// Works with any prototype, items of pointer prototypes are returned as pointers to copies
+	StorageModeValue = "value"
+	// Items are kept as pointers to structs, so changes and reads copy only pointers
+	// under CloneStrategyNone and stored items can be changed through returned pointers.
+	// Requires a pointer to struct prototype
+	StorageModePointer = "pointer"
+)
+
+// Checks that the storage mode is known and matches kind of the prototype.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+// ConfigError when the mode is unknown or the prototype is not a pointer to struct in pointer mode.
+func (c *MemoryPersistence) checkStorageMode(correlationId string) error {
+	switch c.StorageMode {
+	case "", StorageModeValue:
+		return nil
+	case StorageModePointer:
+		if c.Prototype.Kind() != reflect.Ptr || c.Prototype.Elem().Kind() != reflect.Struct {
+			return errors.NewConfigError(correlationId, "STORAGE_MODE_MISMATCH",
+				"Pointer storage mode requires a pointer to struct prototype, but got "+c.Prototype.String()).
+				WithDetails("storage_mode", c.StorageMode).WithDetails("type", c.Prototype.String())
+		}
+		return nil
+	default:
+		return errors.NewConfigError(correlationId, "INVALID_STORAGE_MODE", "Invalid storage mode "+c.StorageMode).
+			WithDetails("storage_mode", c.StorageMode)
+	}
+}
+
+// Converts a struct value or a pointer to it into the form kept by the storage mode
+func (c *MemoryPersistence) toStoredItem(item interface{}) interface{} {
+	value := reflect.ValueOf(item)
+	if c.StorageMode == StorageModePointer {
+		if value.Kind() == reflect.Struct {
+			pointer := reflect.New(value.Type())
+			pointer.Elem().Set(value)
+			return pointer.Interface()
+		}
+	} else if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+		return value.Elem().Interface()
+	}
+	return item
+}
+
+// Copies a stored item to change it. Unlike cloneItem it always copies pointers,
+// so items shared under CloneStrategyNone are not changed before the change is validated
+func (c *MemoryPersistence) copyStoredItem(item interface{}) interface{} {
+	if c.StorageMode == StorageModePointer {
+		return cloneObjectForResult(item, c.Prototype, c.CloneStrategy == CloneStrategyDeep)
+	}
+	return c.cloneItem(item)
+}
@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"time"
+)
+
+/*
+Lightweight record about a deleted data item.
+*/
+type Tombstone struct {
+	Id          interface{} `json:"id"`
+	DeletedTime time.Time   `json:"deleted_time"`
+}
+
+/*
+Tombstones of deleted items kept for a time window.
+Tombstones are ordered by deletion time.
+*/
+type tombstoneStore struct {
+	ids        *idAccessor
+	window     time.Duration
+	tombstones []*Tombstone
+}
+
+// Removes tombstones older than the window
+func (c *tombstoneStore) expire(now time.Time) {
+	expired := 0
+	for expired < len(c.tombstones) && now.Sub(c.tombstones[expired].DeletedTime) > c.window {
+		expired++
+	}
+	if expired > 0 {
+		c.tombstones = append([]*Tombstone{}, c.tombstones[expired:]...)
+	}
+}
+
+// Removes a tombstone of an item that was created again
+func (c *tombstoneStore) remove(id interface{}) {
+	key := toIdKey(id)
+	for i, tombstone := range c.tombstones {
+		if toIdKey(tombstone.Id) == key {
+			c.tombstones = append(c.tombstones[:i], c.tombstones[i+1:]...)
+			return
+		}
+	}
+}
+
+// Records deletions of items, called on every change of items
+func (c *tombstoneStore) update(oldItem interface{}, newItem interface{}, now time.Time) {
+	switch {
+	case oldItem != nil && newItem == nil:
+		c.tombstones = append(c.tombstones, &Tombstone{Id: c.ids.get(oldItem), DeletedTime: now})
+	case oldItem == nil && newItem != nil:
+		c.remove(c.ids.get(newItem))
+	}
+	c.expire(now)
+}
+
+// Enables tracking of deleted items for a window in milliseconds, 0 disables it
+func (c *IdentifiableMemoryPersistence) setTombstoneWindow(window int64) {
+	c.TombstoneWindow = window
+	if window <= 0 {
+		return
+	}
+	if c.tombstones == nil {
+		c.tombstones = &tombstoneStore{ids: &c.ids}
+		c.addChangeHandler(func(oldItem interface{}, newItem interface{}) {
+			if c.tombstones.window > 0 {
+				c.tombstones.update(oldItem, newItem, c.Clock.Now())
+			}
+		})
+	}
+	c.tombstones.window = time.Duration(window) * time.Millisecond
+}
+
+// Gets tombstones of data items deleted since a given time.
+// Tombstones are kept only when options.tombstone_window is set.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - since time.Time
+//   a time to get deletions after.
+// Returns []*Tombstone, error
+// tombstones ordered by deletion time or error.
+func (c *IdentifiableMemoryPersistence) GetDeletedSince(correlationId string, since time.Time) (tombstones []*Tombstone, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	tombstones = []*Tombstone{}
+	if c.tombstones == nil || c.TombstoneWindow <= 0 {
+		return tombstones, nil
+	}
+	now := c.Clock.Now()
+	for _, tombstone := range c.tombstones.tombstones {
+		if tombstone.DeletedTime.After(since) && now.Sub(tombstone.DeletedTime) <= c.tombstones.window {
+			tombstones = append(tombstones, &Tombstone{Id: tombstone.Id, DeletedTime: tombstone.DeletedTime})
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d tombstones", len(tombstones))
+	return tombstones, nil
+}
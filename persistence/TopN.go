@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Bounded heap that keeps the best N items. The worst of kept
+items is at the top, so it can be replaced by a better one in O(log N).
+*/
+type topHeap struct {
+	items    []interface{}
+	order    []int
+	lessFunc func(a, b interface{}) bool
+}
+
+func (c *topHeap) Len() int { return len(c.items) }
+
+func (c *topHeap) Less(i, j int) bool {
+	// Worse items and later items with equal rank go to the top
+	if c.lessFunc(c.items[j], c.items[i]) {
+		return true
+	}
+	if c.lessFunc(c.items[i], c.items[j]) {
+		return false
+	}
+	return c.order[i] > c.order[j]
+}
+
+func (c *topHeap) Swap(i, j int) {
+	c.items[i], c.items[j] = c.items[j], c.items[i]
+	c.order[i], c.order[j] = c.order[j], c.order[i]
+}
+
+func (c *topHeap) Push(x interface{}) {}
+
+func (c *topHeap) Pop() interface{} { return nil }
+
+// Gets the best N data items retrieved by a given filter in a single scan.
+// Unlike sorting the whole filtered list it keeps only N items in a bounded heap.
+// Items with equal rank are returned in their original order.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function to filter items
+//   - sortFunc func(a, b interface{}) bool
+//   sorting compare function func Less (a, b interface{}) bool, the first items in the sort order are the best,
+//   BadRequestError is returned when it is nil
+//   - n int
+//   a maximum number of items to return, no items are returned when it is 0 or negative
+// Returns []interface{}, error
+// sorted list of the best items or error.
+func (c *MemoryPersistence) GetTopByFilter(correlationId string, filterFunc func(interface{}) bool,
+	sortFunc func(a, b interface{}) bool, n int) (items []interface{}, err error) {
+	if sortFunc == nil {
+		return nil, errors.NewBadRequestError(correlationId, "NO_SORT", "Sort function is not set")
+	}
+	if n <= 0 {
+		return []interface{}{}, nil
+	}
+
+	c.rlock()
+	defer c.runlock()
+
+	candidates := c.Items
+	if filterFunc != nil {
+		if candidates, err = c.filterItems(correlationId, filterFunc); err != nil {
+			return nil, err
+		}
+	}
+	top, err := c.selectTop(correlationId, candidates, sortFunc, n)
+	if err != nil {
+		return nil, err
+	}
+
+	items = make([]interface{}, len(top))
+	for i, item := range top {
+		items[i] = c.cloneResult(item)
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d top items", len(items))
+	return items, nil
+}
+
+// Selects the best N items in sort order keeping the original order of items with equal rank.
+// Panics in the sort function are returned as errors, and scans that exceed OperationTimeout are stopped.
+func (c *MemoryPersistence) selectTop(correlationId string, candidates []interface{},
+	sortFunc func(a, b interface{}) bool, n int) (items []interface{}, err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "sort", &index, &err)
+
+	deadline := c.operationDeadline()
+	top := &topHeap{
+		items:    make([]interface{}, 0, n),
+		order:    make([]int, 0, n),
+		lessFunc: sortFunc,
+	}
+	for i, item := range candidates {
+		index = i
+		if i > 0 && i%deadlineCheckInterval == 0 {
+			if err = c.checkDeadline(correlationId, "sort", deadline); err != nil {
+				return nil, err
+			}
+		}
+		if len(top.items) < n {
+			top.items = append(top.items, item)
+			top.order = append(top.order, i)
+			if len(top.items) == n {
+				heap.Init(top)
+			}
+		} else if sortFunc(item, top.items[0]) {
+			top.items[0] = item
+			top.order[0] = i
+			heap.Fix(top, 0)
+		}
+	}
+
+	sort.Sort(sort.Reverse(top))
+	return top.items, nil
+}
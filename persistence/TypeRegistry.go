@@ -0,0 +1,103 @@
+package persistence
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+/*
+Registry of item prototypes by their names. It allows to create
+persistence components from configuration where item type is set
+by name, for instance, "item_type: mypackage.Order".
+
+Example
+
+    persistence.DefaultTypeRegistry.RegisterType(reflect.TypeOf(Order{}))
+
+    config := NewConfigParamsFromTuples("item_type", "mypackage.Order")
+    persistence := NewIdentifiableMemoryPersistence(reflect.TypeOf(map[string]interface{}{}))
+    persistence.Configure(config)
+*/
+type TypeRegistry struct {
+	lock  sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// Default registry used to resolve item types set in configuration
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// Creates a new empty registry.
+// Returns *TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{types: map[string]reflect.Type{}}
+}
+
+// Registers a prototype under the given name.
+// Parameters:
+//   - name string
+//   a name of the type
+//   - prototype reflect.Type
+//   a prototype of items
+func (c *TypeRegistry) Register(name string, prototype reflect.Type) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.types[name] = prototype
+}
+
+// Registers a prototype under its qualified name, for instance, "mypackage.Order".
+// Pointer types are registered under names of types they point to.
+// Parameters:
+//   - prototype reflect.Type
+//   a prototype of items
+func (c *TypeRegistry) RegisterType(prototype reflect.Type) {
+	typ := prototype
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	c.Register(typ.String(), prototype)
+}
+
+// Finds a prototype by its name.
+// Parameters:
+//   - name string
+//   a name of the type
+// Returns reflect.Type, bool
+// the prototype and true if it was registered
+func (c *TypeRegistry) Resolve(name string) (reflect.Type, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	prototype, ok := c.types[name]
+	return prototype, ok
+}
+
+// Gets names of all registered types.
+// Returns []string
+// sorted type names
+func (c *TypeRegistry) Names() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	names := make([]string, 0, len(c.types))
+	for name := range c.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sets prototype of items by its name registered in DefaultTypeRegistry
+func (c *MemoryPersistence) setItemType(name string) {
+	prototype, ok := DefaultTypeRegistry.Resolve(name)
+	if !ok {
+		c.Logger.Error("", nil, "Item type %s is not registered", name)
+		return
+	}
+	if prototype != c.Prototype && len(c.Items) > 0 {
+		c.Logger.Warn("", "Item type can't be changed to %s because persistence contains items", name)
+		return
+	}
+	c.Prototype = prototype
+}
@@ -0,0 +1,219 @@
+package persistence
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-commons-go/refer"
+	"github.com/pip-services3-go/pip-services3-components-go/cache"
+)
+
+// Backends of UniversalIdentifiablePersistence
+const (
+	// Items are kept only in memory (default)
+	BackendMemory = "memory"
+	// Items are kept in JSON file set by path parameter
+	BackendFile = "file"
+	// Items are kept in a table of SQLite database through database/sql driver imported by the application
+	BackendSqlite = "sqlite"
+	// Items are kept in a key-value store referenced as ICache
+	BackendKeyValue = "keyvalue"
+)
+
+/*
+Identifiable persistence component that selects the storage backend from configuration
+when it is opened, so storage is switched between environments, for instance memory
+in development and SQLite or key-value store in production, without code changes.
+All backends keep items in memory with all features of IdentifiableMemoryPersistence,
+and differ only in where items are loaded from and saved to.
+
+The sqlite backend uses database/sql, so applications import a driver registered as sqlite3,
+for instance github.com/mattn/go-sqlite3, or set another driver in options.driver.
+
+Configuration parameters
+
+- path:                    path to the data file of file backend or the database of sqlite backend
+- connection:
+    - uri:                 (optional) data source name of sqlite backend used instead of the path
+- options:
+    - backend:             Storage backend: memory, file, sqlite or keyvalue (default: memory)
+    - driver:              Name of database/sql driver of sqlite backend (default: sqlite3)
+    - table:               Name of the table with items of sqlite backend (default: lowercase name of the item type)
+    - key:                 Key of the value with items of keyvalue backend (default: lowercase name of the item type)
+    - other options of IdentifiableMemoryPersistence and JsonFilePersister
+
+References
+
+- *:logger:*:*:1.0    ILogger components to pass log messages
+- *:cache:*:*:1.0     ICache component with key-value store of keyvalue backend
+
+Example
+
+    persistence := NewUniversalIdentifiablePersistence(reflect.TypeOf(MyData{}))
+    persistence.Configure(config.NewConfigParamsFromTuples(
+        "options.backend", "file",
+        "path", "./data/data.json",
+    ))
+    persistence.Open("123") // Loads items from the data file
+*/
+// implements IConfigurable, IReferenceable, IOpenable, ICleanable
+type UniversalIdentifiablePersistence struct {
+	IdentifiableMemoryPersistence
+	// Backend opened by the next Open: memory, file, sqlite or keyvalue
+	Backend string
+
+	config        *config.ConfigParams
+	references    refer.IReferences
+	sqlPersister  *SqlPersister
+	openedBackend string
+}
+
+// Creates a new instance of the persistence.
+// Parameters:
+//   - prototype reflect.Type
+//   type of contained data, panics when it is nil
+// Return *UniversalIdentifiablePersistence
+// created UniversalIdentifiablePersistence with memory backend
+func NewUniversalIdentifiablePersistence(prototype reflect.Type) *UniversalIdentifiablePersistence {
+	c := &UniversalIdentifiablePersistence{}
+	c.IdentifiableMemoryPersistence = *NewIdentifiableMemoryPersistence(prototype)
+	c.Backend = BackendMemory
+	c.config = config.NewEmptyConfigParams()
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// Backend changes are applied by the next Open.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *UniversalIdentifiablePersistence) Configure(config *config.ConfigParams) {
+	c.IdentifiableMemoryPersistence.Configure(config)
+	c.config = c.config.Override(config)
+	c.Backend = config.GetAsStringWithDefault("options.backend", c.Backend)
+}
+
+// Sets references to dependent components.
+// Parameters:
+//   - references refer.IReferences
+//   references to locate the component dependencies.
+func (c *UniversalIdentifiablePersistence) SetReferences(references refer.IReferences) {
+	c.IdentifiableMemoryPersistence.SetReferences(references)
+	c.references = references
+}
+
+// Gets the backend the component was opened with.
+// Returns string
+// the opened backend or empty string when the component is closed.
+func (c *UniversalIdentifiablePersistence) OpenedBackend() string {
+	return c.openedBackend
+}
+
+// Opens the component with the configured backend and loads items from it.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+// ConfigError when the backend is unknown or its parameters are not set,
+// ReferenceError when the key-value store is not referenced, or an error of loading.
+func (c *UniversalIdentifiablePersistence) Open(correlationId string) error {
+	if c.IsOpen() {
+		return nil
+	}
+	loader, saver, err := c.createBackend(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.lock()
+	c.Loader = loader
+	c.Saver = saver
+	c.unlock()
+
+	if err = c.IdentifiableMemoryPersistence.Open(correlationId); err != nil {
+		c.closeBackend(correlationId)
+		return err
+	}
+	c.openedBackend = c.Backend
+	c.Logger.Debug(correlationId, "Opened persistence with %s backend", c.Backend)
+	return nil
+}
+
+// Closes the component, saves items to the backend and closes connections to it.
+// Parameters:
+//   - correlationId  string
+//   (optional) transaction id to trace execution through call chain.
+// Returns error
+func (c *UniversalIdentifiablePersistence) Close(correlationId string) error {
+	err := c.IdentifiableMemoryPersistence.Close(correlationId)
+	if closeErr := c.closeBackend(correlationId); err == nil {
+		err = closeErr
+	}
+	c.openedBackend = ""
+	return err
+}
+
+// Creates loader and saver of the configured backend
+func (c *UniversalIdentifiablePersistence) createBackend(correlationId string) (ILoader, ISaver, error) {
+	switch c.Backend {
+	case BackendMemory:
+		return nil, nil, nil
+	case BackendFile:
+		persister := NewJsonFilePersister(c.Prototype, "")
+		persister.Configure(c.config)
+		if persister.Path() == "" {
+			return nil, nil, errors.NewConfigError(correlationId, "NO_PATH", "Data file path is not set").
+				WithDetails("backend", c.Backend)
+		}
+		return persister, persister, nil
+	case BackendSqlite:
+		connection := c.config.GetAsStringWithDefault("connection.uri", c.config.GetAsString("path"))
+		persister := NewSqlPersister(
+			c.config.GetAsStringWithDefault("options.driver", "sqlite3"),
+			connection,
+			c.config.GetAsStringWithDefault("options.table", c.defaultStoreName()),
+		)
+		persister.IdField = c.config.GetAsString("options.id_field")
+		c.sqlPersister = persister
+		return persister, persister, nil
+	case BackendKeyValue:
+		if c.references == nil {
+			return nil, nil, refer.NewReferenceError(correlationId, refer.NewDescriptor("*", "cache", "*", "*", "1.0"))
+		}
+		reference, err := c.references.GetOneRequired(refer.NewDescriptor("*", "cache", "*", "*", "1.0"))
+		if err != nil {
+			return nil, nil, err
+		}
+		store, ok := reference.(cache.ICache)
+		if !ok {
+			return nil, nil, errors.NewConfigError(correlationId, "INVALID_STORE",
+				fmt.Sprintf("Referenced cache of type %T doesn't implement ICache", reference)).
+				WithDetails("backend", c.Backend).WithDetails("type", fmt.Sprintf("%T", reference))
+		}
+		persister := NewKeyValuePersister(store, c.config.GetAsStringWithDefault("options.key", c.defaultStoreName()))
+		return persister, persister, nil
+	default:
+		return nil, nil, errors.NewConfigError(correlationId, "INVALID_BACKEND", "Invalid persistence backend "+c.Backend).
+			WithDetails("backend", c.Backend)
+	}
+}
+
+// Closes the database of sqlite backend
+func (c *UniversalIdentifiablePersistence) closeBackend(correlationId string) error {
+	if c.sqlPersister == nil {
+		return nil
+	}
+	err := c.sqlPersister.Close(correlationId)
+	c.sqlPersister = nil
+	return err
+}
+
+// Gets the default name of the table or the key with items
+func (c *UniversalIdentifiablePersistence) defaultStoreName() string {
+	if typ := itemStructType(c.Prototype); typ != nil && typ.Name() != "" {
+		return strings.ToLower(typ.Name())
+	}
+	return "items"
+}
@@ -0,0 +1,167 @@
+package persistence
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/convert"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Policies for fields in loaded data that are not defined in the prototype
+const (
+	// Unknown fields are silently dropped
+	UnknownFieldsDrop = "drop"
+	// Unknown fields are dropped with a warning in the log
+	UnknownFieldsWarn = "warn"
+	// Loading fails with BadRequestError
+	UnknownFieldsFail = "fail"
+)
+
+/*
+Fields of loaded items that are not defined in the prototype.
+They are kept by item ids and written back on save, so foreign data survives round-trips.
+*/
+type unknownFieldStore struct {
+	ids    *idAccessor
+	fields map[string]map[string]interface{}
+}
+
+// Removes fields of deleted items and all fields on reset
+func (c *unknownFieldStore) update(oldItem interface{}, newItem interface{}) {
+	if newItem != nil {
+		return
+	}
+	if oldItem == nil {
+		c.fields = map[string]map[string]interface{}{}
+		return
+	}
+	delete(c.fields, convert.StringConverter.ToString(c.ids.get(oldItem)))
+}
+
+// Adds preserved fields to serialized items
+func (c *unknownFieldStore) encode(items []interface{}) []interface{} {
+	if len(c.fields) == 0 {
+		return items
+	}
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		result[i] = item
+		fields, ok := c.fields[convert.StringConverter.ToString(c.ids.get(item))]
+		if !ok {
+			continue
+		}
+		value, ok := item.(map[string]interface{})
+		if !ok {
+			buffer, err := json.Marshal(item)
+			if err != nil || json.Unmarshal(buffer, &value) != nil {
+				continue
+			}
+		}
+		for key, field := range fields {
+			if _, ok := value[key]; !ok {
+				value[key] = field
+			}
+		}
+		result[i] = value
+	}
+	return result
+}
+
+// Collects names of struct fields recognized by JSON decoding in lower case
+func collectJsonFields(typ reflect.Type, names map[string]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			collectJsonFields(fieldType, names)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+}
+
+// Gets top level fields of a serialized item that are not defined in the prototype
+func findUnknownFields(prototype reflect.Type, item map[string]interface{}) map[string]interface{} {
+	prototype = valueType(prototype)
+	if prototype.Kind() != reflect.Struct {
+		return nil
+	}
+	names := map[string]bool{}
+	collectJsonFields(prototype, names)
+
+	var result map[string]interface{}
+	for key, value := range item {
+		if !names[strings.ToLower(key)] {
+			if result == nil {
+				result = map[string]interface{}{}
+			}
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// Sets a policy for unknown fields and turns on or off preserving them.
+// Must be called under write lock.
+func (c *MemoryPersistence) setUnknownFields(policy string, preserve bool) {
+	c.UnknownFields = policy
+	if preserve && c.unknownFields == nil {
+		c.unknownFields = &unknownFieldStore{ids: &c.ids, fields: map[string]map[string]interface{}{}}
+		c.addChangeHandler(c.unknownFields.update)
+	} else if !preserve && c.unknownFields != nil {
+		c.unknownFields.fields = map[string]map[string]interface{}{}
+		c.unknownFields = nil
+	}
+}
+
+// Checks fields of a loaded item according to the configured policy.
+// Returns error when loading must fail.
+func (c *MemoryPersistence) checkUnknownFields(correlationId string, prototype reflect.Type,
+	item map[string]interface{}, preserved map[string]map[string]interface{}) error {
+	policy := c.UnknownFields
+	if (policy == "" || policy == UnknownFieldsDrop) && c.unknownFields == nil {
+		return nil
+	}
+	fields := findUnknownFields(prototype, item)
+	if len(fields) == 0 {
+		return nil
+	}
+	if c.subtypes != nil {
+		delete(fields, c.subtypes.field)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	switch policy {
+	case UnknownFieldsFail:
+		return errors.NewBadRequestError(correlationId, "UNKNOWN_FIELDS",
+			"Loaded item has unknown fields "+strings.Join(names, ", ")).
+			WithDetails("fields", names)
+	case UnknownFieldsWarn:
+		c.Logger.Warn(correlationId, "Loaded item has unknown fields %s", strings.Join(names, ", "))
+	}
+	if preserved != nil && len(fields) > 0 {
+		if id := c.ids.get(item); id != nil {
+			preserved[convert.StringConverter.ToString(id)] = fields
+		}
+	}
+	return nil
+}
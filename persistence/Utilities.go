@@ -3,15 +3,35 @@ package persistence
 import (
 	"reflect"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/jinzhu/copier"
 	"github.com/pip-services3-go/pip-services3-commons-go/convert"
-	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
 	refl "github.com/pip-services3-go/pip-services3-commons-go/reflect"
 )
 
+// Panics with a clear message when a constructor receives nil prototype,
+// instead of failing later on the first load or change
+func checkPrototype(prototype reflect.Type) {
+	if prototype == nil {
+		panic("Prototype cannot be nil")
+	}
+}
+
+// Checks arguments of constructors that return errors instead of panics
+func validatePrototypeAndPath(prototype reflect.Type, path string) error {
+	if prototype == nil {
+		return errors.NewConfigError("", "NO_PROTOTYPE", "Prototype of data items is not set")
+	}
+	if path == "" {
+		return errors.NewConfigError("", "NO_PATH", "Data file path is not set")
+	}
+	return nil
+}
+
 func toFieldType(obj interface{}) reflect.Type {
 	// Unwrap value
 	wrap, ok := obj.(refl.IValueWrapper)
@@ -35,6 +55,59 @@ func matchField(field reflect.StructField, name string) bool {
 		strings.ToLower(field.Name) == strings.ToLower(name)
 }
 
+func matchFieldOrTag(field reflect.StructField, name string) bool {
+	if matchField(field, name) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field.Name)
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	return unicode.IsUpper(r) && tag != "" && tag != "-" &&
+		strings.ToLower(tag) == strings.ToLower(name)
+}
+
+// findField searches for a public struct field by its name or json tag (case insensitive)
+func findField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		if matchFieldOrTag(field, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// getFieldValue reads a value of a struct field by its index or a map value by its name.
+// Returns nil if the value cannot be read
+func getFieldValue(obj interface{}, index []int, name string) interface{} {
+	if obj == nil {
+		return nil
+	}
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() == reflect.Struct && index != nil {
+		return val.FieldByIndex(index).Interface()
+	}
+	if isFieldPath(name) {
+		return GetPathValue(obj, name)
+	}
+	value := GetProperty(obj, name)
+	if value == nil && val.Kind() == reflect.Struct {
+		value, _ = getExtraValue(obj, name)
+	}
+	return value
+}
+
 func getValue(obj interface{}) interface{} {
 	wrap, ok := obj.(refl.IValueWrapper)
 	if ok {
@@ -146,14 +219,15 @@ func SetProperty(obj interface{}, name string, value interface{}) {
 	}
 }
 
-// Get object Id value
+// Get object Id value. Ids of structs are kept in a field tagged with `pip:"id"`,
+// a field registered by RegisterIdField or Id field.
 // Parameters:
 //   - item interface{}
 //   an object to read property from.
 // Returns interface{}
 // the property value or nil if property doesn't exist or introspection failed.
 func GetObjectId(item interface{}) interface{} {
-	return GetProperty(item, "Id")
+	return idAccessor{}.get(item)
 }
 
 // SetObjectId is set object Id value
@@ -164,16 +238,28 @@ func GetObjectId(item interface{}) interface{} {
 //   id value for set
 // Results saved in input object
 func SetObjectId(item *interface{}, id interface{}) {
-	value := *item
-	if reflect.ValueOf(value).Kind() == reflect.Map {
-		//refl.ObjectWriter.SetProperty(value, "Id", id)
-		SetProperty(value, "Id", id)
+	idAccessor{}.set(item, id)
+}
+
+// SetObjectProperty is set value of object property specified by its name.
+// Unlike SetProperty it also works with objects passed by value.
+// Parameters:
+//   - item *interface{}
+//   an pointer on object to set property
+//   - name string
+//   a name of the property to set.
+//   - value interface{}
+//   a new value for the property to set.
+// Results saved in input object
+func SetObjectProperty(item *interface{}, name string, value interface{}) {
+	obj := *item
+	if kind := reflect.ValueOf(obj).Kind(); kind == reflect.Map || kind == reflect.Ptr {
+		SetProperty(obj, name, value)
 	} else {
-		typePointer := reflect.New(reflect.TypeOf(value))
-		typePointer.Elem().Set(reflect.ValueOf(value))
+		typePointer := reflect.New(reflect.TypeOf(obj))
+		typePointer.Elem().Set(reflect.ValueOf(obj))
 		typeInterface := typePointer.Interface()
-		//refl.ObjectWriter.SetProperty(typeInterface, "Id", id)
-		SetProperty(typeInterface, "Id", id)
+		SetProperty(typeInterface, name, value)
 		*item = reflect.ValueOf(typeInterface).Elem().Interface()
 	}
 }
@@ -184,15 +270,7 @@ func SetObjectId(item *interface{}, id interface{}) {
 //   an pointer on object to set id property
 // Results saved in input object
 func GenerateObjectId(item *interface{}) {
-	value := *item
-	idField := GetProperty(value, "Id")
-	if idField != nil {
-		if reflect.ValueOf(idField).IsZero() {
-			SetObjectId(item, cdata.IdGenerator.NextLong())
-		}
-	} else {
-		panic("Id field doesn't exist")
-	}
+	idAccessor{}.generate(item)
 }
 
 // CloneObject is clones object function
@@ -202,15 +280,25 @@ func GenerateObjectId(item *interface{}) {
 // Return interface{}
 // copy of input item
 func CloneObject(item interface{}, proto reflect.Type) interface{} {
+	return cloneObject(item, proto, false)
+}
+
+// Clones object into the prototype type, nested values are copied when deep is set
+func cloneObject(item interface{}, proto reflect.Type, deep bool) interface{} {
 	var dest interface{}
 	var src = item
 
+	// Items of interface prototype are cloned into their concrete types
+	if proto.Kind() == reflect.Interface && src != nil {
+		proto = reflect.TypeOf(src)
+	}
+
 	if reflect.ValueOf(src).Kind() == reflect.Map {
 		itemType := reflect.TypeOf(src)
 		mapType := reflect.MapOf(itemType.Key(), itemType.Elem())
 		newMap := reflect.MakeMap(mapType)
 		dest = newMap.Interface()
-		err := copier.CopyWithOption(&dest, src, copier.Option{DeepCopy: false, IgnoreEmpty: false})
+		err := copier.CopyWithOption(&dest, src, copier.Option{DeepCopy: deep, IgnoreEmpty: false})
 		if err != nil {
 			return nil
 		}
@@ -225,7 +313,7 @@ func CloneObject(item interface{}, proto reflect.Type) interface{} {
 		if reflect.TypeOf(src).Kind() == reflect.Ptr {
 			src = reflect.ValueOf(src).Elem().Interface()
 		}
-		err := copier.CopyWithOption(destPtr.Interface(), src, copier.Option{DeepCopy: false, IgnoreEmpty: false})
+		err := copier.CopyWithOption(destPtr.Interface(), src, copier.Option{DeepCopy: deep, IgnoreEmpty: false})
 		if err != nil {
 			return nil
 		}
@@ -244,14 +332,23 @@ func CloneObject(item interface{}, proto reflect.Type) interface{} {
 // Return interface{}
 // copy of input item
 func CloneObjectForResult(src interface{}, proto reflect.Type) interface{} {
+	return cloneObjectForResult(src, proto, false)
+}
+
+// Clones object for result, nested values are copied when deep is set
+func cloneObjectForResult(src interface{}, proto reflect.Type, deep bool) interface{} {
 	var dest interface{}
 
+	if proto.Kind() == reflect.Interface && src != nil {
+		proto = reflect.TypeOf(src)
+	}
+
 	if reflect.ValueOf(src).Kind() == reflect.Map {
 		itemType := reflect.TypeOf(src)
 		mapType := reflect.MapOf(itemType.Key(), itemType.Elem())
 		newMap := reflect.MakeMap(mapType)
 		dest = newMap.Interface()
-		err := copier.CopyWithOption(&dest, src, copier.Option{DeepCopy: false, IgnoreEmpty: false})
+		err := copier.CopyWithOption(&dest, src, copier.Option{DeepCopy: deep, IgnoreEmpty: false})
 		if err != nil {
 			return nil
 		}
@@ -262,7 +359,7 @@ func CloneObjectForResult(src interface{}, proto reflect.Type) interface{} {
 		} else {
 			destPtr = reflect.New(proto)
 		}
-		err := copier.CopyWithOption(destPtr.Interface(), src, copier.Option{DeepCopy: false, IgnoreEmpty: false})
+		err := copier.CopyWithOption(destPtr.Interface(), src, copier.Option{DeepCopy: deep, IgnoreEmpty: false})
 		if err != nil {
 			return nil
 		}
@@ -289,6 +386,90 @@ func CompareValues(value1 interface{}, value2 interface{}) bool {
 	return value1 == value2
 }
 
+// Error helpers
+
+// wrapError converts a non-application error into InternalError with the given code
+// and keeps the original error as a cause. Application errors are returned as is
+// with correlation id set when it is missing.
+func wrapError(err error, correlationId string, code string, message string) error {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := err.(*errors.ApplicationError); ok {
+		if appErr.CorrelationId == "" {
+			appErr.CorrelationId = correlationId
+		}
+		return appErr
+	}
+	return errors.NewInternalError(correlationId, code, message).WithCause(err)
+}
+
+// newInvalidItemError creates BadRequestError for items that cannot be converted to the prototype
+func newInvalidItemError(correlationId string) error {
+	return errors.NewBadRequestError(correlationId, "INVALID_ITEM", "Item cannot be converted to persistence data type")
+}
+
+// compareOrdered compares two values of numeric, string, boolean or time types.
+// Returns -1, 0 or 1 and true, or false if the values cannot be compared.
+// Nil values are considered less than any other value
+func compareOrdered(value1 interface{}, value2 interface{}) (int, bool) {
+	value1 = getValue(value1)
+	value2 = getValue(value2)
+	if value1 == nil || value2 == nil {
+		switch {
+		case value1 == nil && value2 == nil:
+			return 0, true
+		case value1 == nil:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+
+	if t1, ok := value1.(time.Time); ok {
+		t2 := convert.DateTimeConverter.ToNullableDateTime(value2)
+		if t2 == nil {
+			return 0, false
+		}
+		switch {
+		case t1.Before(*t2):
+			return -1, true
+		case t1.After(*t2):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	v1 := reflect.ValueOf(value1)
+	v2 := reflect.ValueOf(value2)
+	if v1.Kind() == reflect.String && v2.Kind() == reflect.String {
+		return strings.Compare(v1.String(), v2.String()), true
+	}
+	if v1.Kind() == reflect.Bool && v2.Kind() == reflect.Bool {
+		b1, b2 := v1.Bool(), v2.Bool()
+		switch {
+		case b1 == b2:
+			return 0, true
+		case !b1:
+			return -1, true
+		}
+		return 1, true
+	}
+
+	f1 := convert.DoubleConverter.ToNullableDouble(value1)
+	f2 := convert.DoubleConverter.ToNullableDouble(value2)
+	if f1 == nil || f2 == nil {
+		return 0, false
+	}
+	switch {
+	case *f1 < *f2:
+		return -1, true
+	case *f1 > *f2:
+		return 1, true
+	}
+	return 0, true
+}
+
 // Convert methods
 
 // FromIds method convert ids string array to array of interface{} object
@@ -0,0 +1,81 @@
+package persistence
+
+// Results of comparison between two vector clocks
+const (
+	// The first clock happened before the second one
+	ClockBefore = -1
+	// Both clocks are equal
+	ClockEqual = 0
+	// The first clock happened after the second one
+	ClockAfter = 1
+	// Clocks were changed concurrently
+	ClockConcurrent = 2
+)
+
+/*
+Vector clock that counts changes made by each replica.
+It allows to detect if one change happened before another or concurrently.
+*/
+type VectorClock map[string]int64
+
+// Creates a copy of the clock with incremented counter of the replica.
+// Parameters:
+//   - replicaId string
+//   an id of the replica that made a change
+// Returns VectorClock
+// a new clock
+func (c VectorClock) Increment(replicaId string) VectorClock {
+	result := c.Merge(nil)
+	result[replicaId]++
+	return result
+}
+
+// Creates a clock with maximum counters of both clocks.
+// Parameters:
+//   - other VectorClock
+//   a clock to merge with
+// Returns VectorClock
+// a new merged clock
+func (c VectorClock) Merge(other VectorClock) VectorClock {
+	result := VectorClock{}
+	for replicaId, counter := range c {
+		result[replicaId] = counter
+	}
+	for replicaId, counter := range other {
+		if counter > result[replicaId] {
+			result[replicaId] = counter
+		}
+	}
+	return result
+}
+
+// Compares the clock with another one.
+// Parameters:
+//   - other VectorClock
+//   a clock to compare with
+// Returns int
+// ClockBefore, ClockEqual, ClockAfter or ClockConcurrent
+func (c VectorClock) Compare(other VectorClock) int {
+	before, after := false, false
+	for replicaId, counter := range c {
+		if counter > other[replicaId] {
+			after = true
+		} else if counter < other[replicaId] {
+			before = true
+		}
+	}
+	for replicaId, counter := range other {
+		if _, ok := c[replicaId]; !ok && counter > 0 {
+			before = true
+		}
+	}
+	switch {
+	case before && after:
+		return ClockConcurrent
+	case before:
+		return ClockBefore
+	case after:
+		return ClockAfter
+	}
+	return ClockEqual
+}
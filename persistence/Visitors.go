@@ -0,0 +1,94 @@
+package persistence
+
+import (
+	"time"
+)
+
+// Calls the visit function with a stored item found by its id, without cloning it.
+// It is a read path for hot lookups that can't afford a copy of the item per call.
+// The item is passed under read lock, so the function must be fast, must not call other
+// methods of the persistence and must not change the item or keep references to its
+// maps, slices or pointers after it returns.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - id interface{}
+//   an id of the item to visit.
+//   - visitFunc func(item interface{})
+//   a function called with the stored item when it is found
+// Returns bool, error
+// true if the item was found and visited, NotFoundError according to options.not_found_error
+// or InternalError when the visit function panics.
+func (c *IdentifiableMemoryPersistence) VisitById(correlationId string, id interface{},
+	visitFunc func(item interface{})) (found bool, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "visit_by_id", start, id, err) }(time.Now())
+	}
+	id = c.NormalizeId(id)
+
+	c.rlock()
+	defer c.runlock()
+
+	index := c.GetIndexById(id)
+	if index < 0 {
+		return false, c.notFound(correlationId, id)
+	}
+
+	defer c.recoverCallback(correlationId, "visit", &index, &err)
+	visitFunc(c.Items[index])
+	return true, nil
+}
+
+// Calls the visit function for stored items that match the filter, without cloning them
+// and without collecting them into intermediate slices, for aggregations over large sets of items.
+// Items are visited in a consistent snapshot: the latest read snapshot when options.read_snapshot
+// is enabled, otherwise the items under read lock. The same restrictions as in VisitById apply
+// to the visit function.
+// Parameters:
+//   - correlationId string
+//   (optional) transaction id to trace execution through call chain.
+//   - filterFunc func(interface{}) bool
+//   (optional) a filter function to select visited items
+//   - visitFunc func(item interface{}) bool
+//   a function called for every matching item, it returns false to stop visiting
+// Returns int, error
+// a number of visited items, InternalError when the filter or visit function panics
+// or InvalidStateError when the scan exceeds the operation timeout.
+func (c *MemoryPersistence) VisitByFilter(correlationId string, filterFunc func(interface{}) bool,
+	visitFunc func(item interface{}) bool) (count int, err error) {
+	if c.LogOperations {
+		defer func(start time.Time) { c.logOperation(correlationId, "visit_by_filter", start, nil, err) }(time.Now())
+	}
+	if snapshot := c.GetReadSnapshot(); snapshot != nil {
+		return c.visitItems(correlationId, snapshot.items, filterFunc, visitFunc)
+	}
+
+	c.rlock()
+	defer c.runlock()
+	return c.visitItems(correlationId, c.Items, filterFunc, visitFunc)
+}
+
+// Calls the visit function for items that match the filter until it returns false
+func (c *MemoryPersistence) visitItems(correlationId string, items []interface{}, filterFunc func(interface{}) bool,
+	visitFunc func(item interface{}) bool) (count int, err error) {
+	index := 0
+	defer c.recoverCallback(correlationId, "visit", &index, &err)
+
+	deadline := c.operationDeadline()
+	for i, item := range items {
+		index = i
+		if i > 0 && i%deadlineCheckInterval == 0 {
+			if err = c.checkDeadline(correlationId, "visit", deadline); err != nil {
+				return count, err
+			}
+		}
+		if filterFunc != nil && !filterFunc(item) {
+			continue
+		}
+		count++
+		if !visitFunc(item) {
+			break
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,215 @@
+// Service to fetch and push datasets of persistence components.
+// It is used by GrpcPersister to seed memory persistence from data services
+// and to checkpoint it back to them.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: dataset.proto
+
+package protos
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	DatasetService_Fetch_FullMethodName = "/pip_services.data.v1.DatasetService/Fetch"
+	DatasetService_Push_FullMethodName  = "/pip_services.data.v1.DatasetService/Push"
+)
+
+// DatasetServiceClient is the client API for DatasetService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DatasetServiceClient interface {
+	// Streams all items of a dataset
+	Fetch(ctx context.Context, in *FetchRequest, opts ...grpc.CallOption) (DatasetService_FetchClient, error)
+	// Replaces all items of a dataset with items from streamed batches
+	Push(ctx context.Context, opts ...grpc.CallOption) (DatasetService_PushClient, error)
+}
+
+type datasetServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDatasetServiceClient(cc grpc.ClientConnInterface) DatasetServiceClient {
+	return &datasetServiceClient{cc}
+}
+
+func (c *datasetServiceClient) Fetch(ctx context.Context, in *FetchRequest, opts ...grpc.CallOption) (DatasetService_FetchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DatasetService_ServiceDesc.Streams[0], DatasetService_Fetch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &datasetServiceFetchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DatasetService_FetchClient interface {
+	Recv() (*DatasetItem, error)
+	grpc.ClientStream
+}
+
+type datasetServiceFetchClient struct {
+	grpc.ClientStream
+}
+
+func (x *datasetServiceFetchClient) Recv() (*DatasetItem, error) {
+	m := new(DatasetItem)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *datasetServiceClient) Push(ctx context.Context, opts ...grpc.CallOption) (DatasetService_PushClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DatasetService_ServiceDesc.Streams[1], DatasetService_Push_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &datasetServicePushClient{stream}
+	return x, nil
+}
+
+type DatasetService_PushClient interface {
+	Send(*PushRequest) error
+	CloseAndRecv() (*PushReply, error)
+	grpc.ClientStream
+}
+
+type datasetServicePushClient struct {
+	grpc.ClientStream
+}
+
+func (x *datasetServicePushClient) Send(m *PushRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *datasetServicePushClient) CloseAndRecv() (*PushReply, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PushReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DatasetServiceServer is the server API for DatasetService service.
+// All implementations must embed UnimplementedDatasetServiceServer
+// for forward compatibility
+type DatasetServiceServer interface {
+	// Streams all items of a dataset
+	Fetch(*FetchRequest, DatasetService_FetchServer) error
+	// Replaces all items of a dataset with items from streamed batches
+	Push(DatasetService_PushServer) error
+	mustEmbedUnimplementedDatasetServiceServer()
+}
+
+// UnimplementedDatasetServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDatasetServiceServer struct {
+}
+
+func (UnimplementedDatasetServiceServer) Fetch(*FetchRequest, DatasetService_FetchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Fetch not implemented")
+}
+func (UnimplementedDatasetServiceServer) Push(DatasetService_PushServer) error {
+	return status.Errorf(codes.Unimplemented, "method Push not implemented")
+}
+func (UnimplementedDatasetServiceServer) mustEmbedUnimplementedDatasetServiceServer() {}
+
+// UnsafeDatasetServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DatasetServiceServer will
+// result in compilation errors.
+type UnsafeDatasetServiceServer interface {
+	mustEmbedUnimplementedDatasetServiceServer()
+}
+
+func RegisterDatasetServiceServer(s grpc.ServiceRegistrar, srv DatasetServiceServer) {
+	s.RegisterService(&DatasetService_ServiceDesc, srv)
+}
+
+func _DatasetService_Fetch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FetchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatasetServiceServer).Fetch(m, &datasetServiceFetchServer{stream})
+}
+
+type DatasetService_FetchServer interface {
+	Send(*DatasetItem) error
+	grpc.ServerStream
+}
+
+type datasetServiceFetchServer struct {
+	grpc.ServerStream
+}
+
+func (x *datasetServiceFetchServer) Send(m *DatasetItem) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DatasetService_Push_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DatasetServiceServer).Push(&datasetServicePushServer{stream})
+}
+
+type DatasetService_PushServer interface {
+	SendAndClose(*PushReply) error
+	Recv() (*PushRequest, error)
+	grpc.ServerStream
+}
+
+type datasetServicePushServer struct {
+	grpc.ServerStream
+}
+
+func (x *datasetServicePushServer) SendAndClose(m *PushReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *datasetServicePushServer) Recv() (*PushRequest, error) {
+	m := new(PushRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DatasetService_ServiceDesc is the grpc.ServiceDesc for DatasetService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DatasetService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pip_services.data.v1.DatasetService",
+	HandlerType: (*DatasetServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Fetch",
+			Handler:       _DatasetService_Fetch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Push",
+			Handler:       _DatasetService_Push_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "dataset.proto",
+}
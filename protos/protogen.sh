@@ -0,0 +1,7 @@
+#!/bin/sh
+# Generates Go messages and gRPC client and server of dataset.proto.
+# Requires protoc with protoc-gen-go and protoc-gen-go-grpc plugins in PATH.
+cd "$(dirname "$0")"
+protoc --go_out=. --go_opt=paths=source_relative \
+    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+    dataset.proto
@@ -0,0 +1,68 @@
+package test_build
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	"github.com/pip-services3-go/pip-services3-data-go/build"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Order struct {
+	Id     string  `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+func TestDefaultPersistenceFactory(t *testing.T) {
+	cpersist.DefaultTypeRegistry.RegisterType(reflect.TypeOf(Order{}))
+	assert.Contains(t, cpersist.DefaultTypeRegistry.Names(), "test_build.Order")
+
+	factory := build.NewDefaultPersistenceFactory()
+	locator := cref.NewDescriptor("pip-services", "persistence", "memory", "orders", "1.0")
+	assert.NotNil(t, factory.CanCreate(locator))
+
+	component, err := factory.Create(locator)
+	assert.Nil(t, err)
+	persistence, ok := component.(*cpersist.IdentifiableMemoryPersistence)
+	assert.True(t, ok)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("item_type", "test_build.Order"))
+	assert.Equal(t, reflect.TypeOf(Order{}), persistence.Prototype)
+
+	item, err := persistence.Create("", Order{Id: "1", Amount: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, item.(Order).Amount)
+}
+
+func TestPersisterDependency(t *testing.T) {
+	cpersist.DefaultTypeRegistry.RegisterType(reflect.TypeOf(Order{}))
+	path := filepath.Join(t.TempDir(), "orders.json")
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(map[string]interface{}{}), path)
+	references := cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "persister", "json", "orders", "1.0"), persister,
+	)
+
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Order{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"dependencies.persister", "pip-services:persister:json:orders:1.0",
+	))
+	persistence.SetReferences(references)
+	assert.Equal(t, persistence.Prototype, persister.Prototype)
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	_, err = persistence.Create("", Order{Id: "1", Amount: 10})
+	assert.Nil(t, err)
+	err = persistence.Close("")
+	assert.Nil(t, err)
+
+	items, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "1", items[0].(map[string]interface{})["id"])
+}
@@ -0,0 +1,63 @@
+package test_codegen
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-data-go/codegen"
+	"github.com/stretchr/testify/assert"
+)
+
+const model = `package orders
+
+import "time"
+
+type Order struct {
+	Id      string    ` + "`json:\"id\"`" + `
+	Amount  float64   ` + "`json:\"amount\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+
+type Note struct {
+	Text string
+}
+`
+
+func TestGenerator(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "model.go"), []byte(model), 0644)
+	assert.Nil(t, err)
+
+	generator := codegen.NewGenerator("Order", dir)
+	generator.Tests = true
+	err = generator.Write()
+	assert.Nil(t, err)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "order_persistence.go"), nil, 0)
+	assert.Nil(t, err)
+	for _, name := range []string{"OrderPage", "OrderFilter", "OrderFilterBuilder", "OrderFilterFields",
+		"OrderStringField", "OrderFloat64Field", "OrderTimeField", "OrderMemoryPersistence", "OrderFilePersistence"} {
+		assert.NotNil(t, file.Scope.Lookup(name), name)
+	}
+	_, err = parser.ParseFile(fset, filepath.Join(dir, "order_persistence_test.go"), nil, 0)
+	assert.Nil(t, err)
+
+	source, _, err := generator.Generate()
+	assert.Nil(t, err)
+	assert.Contains(t, string(source), "func (c *OrderFilter) AmountGreaterThan(value float64) *OrderFilter")
+	assert.Contains(t, string(source), `c.Put("created_lte", value)`)
+	assert.Contains(t, string(source), "func (c *OrderFilterFields) Amount() *OrderFloat64Field")
+	assert.Contains(t, string(source), "func (c *OrderFloat64Field) GreaterThan(value float64) *OrderFilterBuilder")
+	assert.Contains(t, string(source), "func (c *OrderTimeField) In(values ...time.Time) *OrderFilterBuilder")
+	assert.Contains(t, string(source), "func (c *OrderStringField) StartsWith(value string) *OrderFilterBuilder")
+	assert.NotContains(t, string(source), "func (c *OrderFloat64Field) StartsWith")
+
+	_, _, err = codegen.NewGenerator("Note", dir).Generate()
+	assert.NotNil(t, err)
+	_, _, err = codegen.NewGenerator("Missing", dir).Generate()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,138 @@
+package test_datatool
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-data-go/datatool"
+	"github.com/stretchr/testify/assert"
+)
+
+const testItems = `[
+  {"id": "1", "key": "Key 1", "content": "alice@example.com", "rank": 3},
+  {"id": "2", "key": "Key 2", "content": "bob@example.com", "rank": 1},
+  {"id": "3", "key": "Key 2", "content": "carol@example.com", "rank": 2}
+]`
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	assert.Nil(t, err)
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	return string(content)
+}
+
+func TestValidate(t *testing.T) {
+	out := &bytes.Buffer{}
+	tool := datatool.NewTool(nil, out)
+
+	report, err := tool.Validate(writeFile(t, "data.json", testItems))
+	assert.Nil(t, err)
+	assert.True(t, report.Valid())
+	assert.Equal(t, 3, report.Items)
+
+	path := writeFile(t, "invalid.json", `[{"id":"1"},{"id":"1"},{"key":"2"},5]`)
+	report, err = tool.Validate(path)
+	assert.Nil(t, err)
+	assert.False(t, report.Valid())
+	assert.Len(t, report.Problems, 3)
+
+	err = tool.Run([]string{"validate", path})
+	assert.NotNil(t, err)
+	assert.Contains(t, out.String(), "item 1 has the same id 1 as item 0")
+
+	_, err = tool.Validate(writeFile(t, "broken.json", `[{"id":"1"},`))
+	assert.NotNil(t, err)
+}
+
+func TestCompactAndConvert(t *testing.T) {
+	tool := datatool.NewTool(nil, &bytes.Buffer{})
+	path := writeFile(t, "data.json", testItems)
+
+	err := tool.Run([]string{"compact", path})
+	assert.Nil(t, err)
+	compacted := readFile(t, path)
+	assert.NotContains(t, compacted, "\n")
+	assert.True(t, len(compacted) < len(testItems))
+
+	ndjson := filepath.Join(t.TempDir(), "data.ndjson")
+	err = tool.Run([]string{"convert", "-format", "ndjson", "-o", ndjson, path})
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(readFile(t, ndjson)), "\n")
+	assert.Len(t, lines, 3)
+
+	jsonPath := filepath.Join(t.TempDir(), "data.json")
+	err = tool.Run([]string{"convert", "-format", "json", "-indent", "  ", "-o", jsonPath, ndjson})
+	assert.Nil(t, err)
+	var items []map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(readFile(t, jsonPath)), &items))
+	assert.Len(t, items, 3)
+	assert.Equal(t, "3", items[2]["id"])
+
+	err = tool.Run([]string{"convert", "-format", "xml", path})
+	assert.NotNil(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	out := &bytes.Buffer{}
+	tool := datatool.NewTool(nil, out)
+	oldPath := writeFile(t, "old.json", testItems)
+	newPath := writeFile(t, "new.json", `[{"id":"1","key":"Key 1","content":"alice@example.com","rank":3},`+
+		`{"id":"2","key":"Key 3","content":"bob@example.com","rank":1},{"id":"4"}]`)
+
+	differences, err := tool.Diff(oldPath, newPath)
+	assert.Nil(t, err)
+	assert.Len(t, differences, 3)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, out.String(), `"change":"updated"`)
+	assert.Contains(t, out.String(), `"change":"deleted"`)
+	assert.Contains(t, out.String(), `"change":"created"`)
+}
+
+func TestAnonymize(t *testing.T) {
+	tool := datatool.NewTool(nil, &bytes.Buffer{})
+	path := writeFile(t, "data.json", testItems)
+	output := filepath.Join(t.TempDir(), "anon.json")
+
+	err := tool.Run([]string{"anonymize", "-rule", "content=email", "-o", output, path})
+	assert.Nil(t, err)
+	anonymized := readFile(t, output)
+	assert.NotContains(t, anonymized, "alice@example.com")
+	assert.Contains(t, anonymized, `"key":"Key 1"`)
+
+	err = tool.Run([]string{"anonymize", "-rule", "content", path})
+	assert.NotNil(t, err)
+}
+
+func TestQuery(t *testing.T) {
+	out := &bytes.Buffer{}
+	tool := datatool.NewTool(strings.NewReader(testItems), out)
+
+	err := tool.Run([]string{"query", "-filter", "key=Key 2", "-sort", "-rank", "-take", "1", "-"})
+	assert.Nil(t, err)
+
+	var items []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &items))
+	assert.Len(t, items, 1)
+	assert.Equal(t, "3", items[0]["id"])
+}
+
+func TestRunErrors(t *testing.T) {
+	tool := datatool.NewTool(nil, &bytes.Buffer{})
+
+	assert.NotNil(t, tool.Run([]string{}))
+	assert.NotNil(t, tool.Run([]string{"unknown"}))
+	assert.NotNil(t, tool.Run([]string{"diff", "one.json"}))
+	assert.NotNil(t, tool.Run([]string{"validate", filepath.Join(t.TempDir(), "missing.json")}))
+}
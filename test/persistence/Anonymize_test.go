@@ -0,0 +1,64 @@
+package test_persistence
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Contact struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone"`
+	Card    string `json:"card"`
+	Comment string `json:"comment"`
+}
+
+type capturingSaver struct {
+	items []interface{}
+}
+
+func (c *capturingSaver) Save(correlationId string, items []interface{}) error {
+	c.items = items
+	return nil
+}
+
+func TestExportAnonymized(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Contact{}))
+	contact := Contact{Id: "1", Name: "Jane Doe", Email: "jane@corp.com",
+		Phone: "+1 (555) 123-4567", Card: "Ab-12", Comment: "VIP"}
+	persistence.Create("", contact)
+	persistence.Create("", Contact{Id: "2", Email: "jane@corp.com"})
+
+	saver := &capturingSaver{}
+	err := persistence.ExportAnonymized("", saver, cpersist.AnonymizeRules{
+		"name":    cpersist.AnonymizeName,
+		"email":   cpersist.AnonymizeEmail,
+		"phone":   cpersist.AnonymizeDigits,
+		"card":    cpersist.AnonymizeMask,
+		"comment": cpersist.AnonymizeClear,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, saver.items, 2)
+
+	exported := saver.items[0].(Contact)
+	assert.Equal(t, "1", exported.Id)
+	assert.NotEqual(t, contact.Name, exported.Name)
+	assert.Regexp(t, regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@example\.com$`), exported.Email)
+	assert.Regexp(t, regexp.MustCompile(`^\+\d \(\d{3}\) \d{3}-\d{4}$`), exported.Phone)
+	assert.Regexp(t, regexp.MustCompile(`^[A-Z][a-z]-\d{2}$`), exported.Card)
+	assert.Equal(t, "", exported.Comment)
+	// Equal values are replaced consistently
+	assert.Equal(t, exported.Email, saver.items[1].(Contact).Email)
+
+	// Stored items are not changed
+	item, _ := persistence.GetOneById("", "1")
+	assert.Equal(t, contact, item)
+
+	err = persistence.ExportAnonymized("", saver, cpersist.AnonymizeRules{"name": "unknown"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,57 @@
+package test_persistence
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachmentFilePersistence(t *testing.T) {
+	dir := t.TempDir()
+	attachments := persistence.NewAttachmentFilePersistence(filepath.Join(dir, "attachments.json"), filepath.Join(dir, "content"))
+	attachments.Configure(config.NewConfigParamsFromTuples(
+		"options.chunk_size", 10,
+		"options.file_mode", "0640",
+	))
+	assert.Nil(t, attachments.Open(""))
+	defer attachments.Close("")
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4)
+	attachment, err := attachments.CreateAttachment("",
+		persistence.Attachment{Name: "data.bin", ContentType: "application/octet-stream"}, bytes.NewReader(content))
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", attachment.Id)
+	assert.Equal(t, int64(64), attachment.Size)
+	assert.Equal(t, 7, attachment.Chunks)
+
+	reader, err := attachments.ReadContent("", attachment.Id)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, content, data)
+
+	// Chunk files get the configured mode of the data file
+	chunks, _ := filepath.Glob(filepath.Join(dir, "content", attachment.Id, "*.chunk"))
+	assert.Len(t, chunks, 7)
+	info, err := os.Stat(chunks[0])
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// Content is deleted with metadata
+	_, err = attachments.DeleteById("", attachment.Id)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "content", attachment.Id))
+	assert.True(t, os.IsNotExist(err))
+	_, err = attachments.ReadContent("", attachment.Id)
+	assert.NotNil(t, err)
+
+	_, err = attachments.CreateAttachment("", persistence.Attachment{Id: "../escape"}, bytes.NewReader(content))
+	assert.NotNil(t, err)
+}
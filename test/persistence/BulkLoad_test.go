@@ -0,0 +1,35 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkLoad(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+
+	err := persistence.LoadAll("", []interface{}{
+		Dummy{Id: "1", Key: "Key 1", Content: "Changed"},
+		Dummy{Id: "2", Key: "Key 2"},
+		Dummy{Key: "Key 3"},
+	})
+	assert.Nil(t, err)
+	items, _ := persistence.GetListByFilter("", nil, nil, nil)
+	assert.Len(t, items, 3)
+	item, _ := persistence.GetOneById("", "1")
+	assert.Equal(t, "Changed", item.(Dummy).Content)
+	assert.NotEqual(t, "", items[2].(Dummy).Id)
+
+	err = persistence.ReplaceAll("", []interface{}{
+		Dummy{Id: "4", Key: "Key 4"},
+		Dummy{Id: "4", Key: "Key 4", Content: "Last"},
+	})
+	assert.Nil(t, err)
+	items, _ = persistence.GetListByFilter("", nil, nil, nil)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "Last", items[0].(Dummy).Content)
+}
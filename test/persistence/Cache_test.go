@@ -0,0 +1,83 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	clock := persistence.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := persistence.NewCacheFilePersistence(path)
+	cache.Clock = clock
+	cache.Configure(config.NewConfigParamsFromTuples("options.max_size", 2))
+	assert.Nil(t, cache.Open(""))
+
+	_, err := cache.Store("", "key1", "value1", 1000)
+	assert.Nil(t, err)
+	_, err = cache.Store("", "key2", "value2", 3000)
+	assert.Nil(t, err)
+	_, err = cache.Store("", "key3", "value3", 2000)
+	assert.Nil(t, err)
+
+	// The value that expires first is evicted
+	value, err := cache.Retrieve("", "key1")
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+	value, err = cache.Retrieve("", "key3")
+	assert.Nil(t, err)
+	assert.Equal(t, "value3", value)
+	assert.Nil(t, cache.Close(""))
+
+	// Values survive restarts
+	cache = persistence.NewCacheFilePersistence(path)
+	cache.Clock = clock
+	assert.Nil(t, cache.Open(""))
+	defer cache.Close("")
+	value, err = cache.Retrieve("", "key2")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", value)
+
+	clock.Advance(2500 * time.Millisecond)
+	value, err = cache.Retrieve("", "key3")
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+
+	assert.Nil(t, cache.Remove("", "key2"))
+	value, err = cache.Retrieve("", "key2")
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+}
+
+func TestCacheFilePersistenceRetrieveAs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := persistence.NewCacheFilePersistence(path)
+	assert.Nil(t, cache.Open(""))
+	_, err := cache.Store("", "dummy", Dummy{Id: "1", Key: "Key 1"}, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, cache.Close(""))
+
+	// Structs are restored from maps loaded after a restart
+	cache = persistence.NewCacheFilePersistence(path)
+	assert.Nil(t, cache.Open(""))
+	defer cache.Close("")
+	var dummy Dummy
+	result, err := cache.RetrieveAs("", "dummy", &dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, &dummy, result)
+	assert.Equal(t, "Key 1", dummy.Key)
+
+	result, err = cache.RetrieveAs("", "missing", &dummy)
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	_, err = cache.Store("", "text", "value", 0)
+	assert.Nil(t, err)
+	_, err = cache.RetrieveAs("", "text", &dummy)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,154 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Asserts that the error was raised by a panicking callback
+func assertCallbackPanic(t *testing.T, err error, callback string) {
+	assert.NotNil(t, err)
+	if appErr, ok := err.(*errors.ApplicationError); assert.True(t, ok) {
+		assert.Equal(t, "CALLBACK_PANIC", appErr.Code)
+		assert.Equal(t, callback, appErr.Details["callback"])
+	}
+}
+
+func TestCallbackPanics(t *testing.T) {
+	dummies := NewDummyMemoryPersistence()
+	persistence := &dummies.IdentifiableMemoryPersistence
+	dummies.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	dummies.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	dummies.Create("", Dummy{Id: "3", Key: "Key 3", Content: "Content 3"})
+
+	badFilter := func(item interface{}) bool {
+		if item.(Dummy).Id == "2" {
+			panic("bad filter")
+		}
+		return true
+	}
+
+	_, err := persistence.GetPageByFilter("123", badFilter, nil, nil, nil)
+	assert.NotNil(t, err)
+	appErr, ok := err.(*errors.ApplicationError)
+	assert.True(t, ok)
+	assert.Equal(t, "CALLBACK_PANIC", appErr.Code)
+	assert.Equal(t, "123", appErr.CorrelationId)
+	assert.Equal(t, 1, appErr.Details["index"])
+	assert.Contains(t, appErr.Message, "bad filter")
+
+	persistence.FilterParallelism = 2
+	persistence.ParallelFilterThreshold = 1
+	_, err = persistence.GetCountByFilter("123", badFilter)
+	assert.NotNil(t, err)
+	persistence.FilterParallelism = 1
+
+	badSort := func(a, b interface{}) bool {
+		panic("bad sort")
+	}
+	_, err = persistence.GetListByFilter("123", nil, badSort, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "sort", err.(*errors.ApplicationError).Details["callback"])
+
+	// Lock must be released after the panic
+	err = persistence.DeleteByFilter("123", badFilter)
+	assert.NotNil(t, err)
+	count, err := persistence.GetCountByFilter("", func(item interface{}) bool { return true })
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+
+	_, err = dummies.Create("", Dummy{Id: "4", Key: "Key 4", Content: "Content 4"})
+	assert.Nil(t, err)
+}
+
+func TestCallbackPanicsInQueries(t *testing.T) {
+	dummies := NewDummyMemoryPersistence()
+	persistence := &dummies.IdentifiableMemoryPersistence
+	dummies.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	dummies.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+
+	badFilter := func(item interface{}) bool { panic("bad filter") }
+	badSort := func(a, b interface{}) bool { panic("bad sort") }
+	byKey := func(a, b interface{}) bool { return a.(Dummy).Key < b.(Dummy).Key }
+
+	_, err := persistence.GetTopByFilter("", badFilter, byKey, 1)
+	assertCallbackPanic(t, err, "filter")
+	_, err = persistence.GetTopByFilter("", nil, badSort, 1)
+	assertCallbackPanic(t, err, "sort")
+	_, err = persistence.GetTopByFilter("", nil, nil, 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, "NO_SORT", err.(*errors.ApplicationError).Code)
+
+	_, err = persistence.FindDuplicates("", func(item interface{}) string { panic("bad key") })
+	assertCallbackPanic(t, err, "key")
+	_, err = persistence.MergeItems("", []interface{}{"1", "2"}, func(items []interface{}) interface{} { panic("bad merge") })
+	assertCallbackPanic(t, err, "merge")
+	item, err := dummies.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.Key)
+
+	filter := cpersist.NewFilterBuilder(reflect.TypeOf(Dummy{}))
+	filter.Where("key").Matches(func(value interface{}) bool { panic("bad match") })
+	filterFunc, err := filter.Build()
+	assert.Nil(t, err)
+	_, err = persistence.GetTopByFilter("", filterFunc, byKey, 1)
+	assertCallbackPanic(t, err, "filter")
+
+	// Views with panicking callbacks are not registered
+	err = persistence.RegisterView("", "bad", badFilter, nil, nil)
+	assertCallbackPanic(t, err, "filter")
+	_, err = persistence.GetView("", "bad", nil)
+	assert.NotNil(t, err)
+
+	// Views that panic on changes fail until they are rebuilt
+	calls := 0
+	err = persistence.RegisterView("", "failing", func(item interface{}) bool {
+		calls++
+		if calls > 2 {
+			panic("bad view filter")
+		}
+		return true
+	}, nil, nil)
+	assert.Nil(t, err)
+	_, err = dummies.Create("", Dummy{Id: "3", Key: "Key 3", Content: "Content 3"})
+	assert.Nil(t, err)
+	_, err = persistence.GetView("", "failing", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "VIEW_FAILED", err.(*errors.ApplicationError).Code)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.read_snapshot", true))
+	snapshot := persistence.GetReadSnapshot()
+	_, err = snapshot.GetListByFilter(badFilter, nil)
+	assertCallbackPanic(t, err, "filter")
+	_, err = snapshot.GetPageByFilter(nil, nil, badSort)
+	assertCallbackPanic(t, err, "sort")
+}
+
+func TestCallbackPanicsInIndexedQueries(t *testing.T) {
+	badFilter := func(item interface{}) bool { panic("bad filter") }
+
+	places := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Place{}))
+	places.Configure(cconf.NewConfigParamsFromTuples(
+		"options.latitude_field", "lat",
+		"options.longitude_field", "lon",
+	))
+	places.Create("", Place{Id: "1", Name: "Big Ben", Lat: 51.5007, Lon: -0.1246})
+	center := cpersist.NewGeoPoint(51.5074, -0.1278)
+	_, err := places.GetPageByNear("", center, 5000, badFilter, nil)
+	assertCallbackPanic(t, err, "filter")
+	_, err = places.GetPageByBoundingBox("", cpersist.NewGeoBoundingBoxAround(center, 5000), badFilter, nil)
+	assertCallbackPanic(t, err, "filter")
+
+	events := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Event{}))
+	events.Configure(cconf.NewConfigParamsFromTuples("options.timestamp_field", "time"))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	events.Create("", Event{Name: "Event", Time: start})
+	_, err = events.GetPageByDateRange("", start, time.Time{}, badFilter, nil, nil)
+	assertCallbackPanic(t, err, "filter")
+}
@@ -0,0 +1,65 @@
+package test_persistence
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyProfiles(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	profile, err := cpersist.ParseLatencyProfile("fixed:50")
+	assert.Nil(t, err)
+	assert.Equal(t, 50*time.Millisecond, profile.Next(random))
+
+	profile, err = cpersist.ParseLatencyProfile("uniform:10:20")
+	assert.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		delay := profile.Next(random)
+		assert.True(t, delay >= 10*time.Millisecond && delay <= 20*time.Millisecond)
+	}
+
+	profile, err = cpersist.ParseLatencyProfile("spikes:1:500:0.1")
+	assert.Nil(t, err)
+	spikes := 0
+	for i := 0; i < 1000; i++ {
+		if profile.Next(random) == 500*time.Millisecond {
+			spikes++
+		}
+	}
+	assert.True(t, spikes > 50 && spikes < 150)
+
+	for _, value := range []string{"fixed", "uniform:20:10", "spikes:1:2:3", "normal:1"} {
+		_, err = cpersist.ParseLatencyProfile(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestChaosPersistence(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := cpersist.NewManualClock(start)
+	persistence := cpersist.NewChaosPersistence(
+		cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{})))
+	persistence.Clock = clock
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.latency", "fixed:5",
+		"latency.create", "fixed:100",
+	))
+
+	_, err := persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	item, err := persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", item.(Dummy).Key)
+	assert.Equal(t, 105*time.Millisecond, clock.Now().Sub(start))
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("latency.update", "slow"))
+	_, err = persistence.GetOneById("", "1")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,79 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := cpersist.NewManualClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("Ticker fired too early")
+	default:
+	}
+
+	clock.Sleep(3 * time.Second)
+	assert.Equal(t, start.Add(3500*time.Millisecond), <-ticker.C())
+	// Missed ticks are dropped
+	select {
+	case <-ticker.C():
+		t.Fatal("Missed ticks are delivered")
+	default:
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("Stopped ticker fired")
+	default:
+	}
+}
+
+func TestIdempotencyTtlWithClock(t *testing.T) {
+	clock := cpersist.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	persistence := cpersist.NewIdentifiableMemoryPersistenceWithOptions(reflect.TypeOf(Dummy{}),
+		cpersist.WithClock(clock))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.idempotency_ttl", 60000))
+
+	first, err := persistence.CreateIdempotent("", "key1", Dummy{Key: "Key 1"})
+	assert.Nil(t, err)
+	second, err := persistence.CreateIdempotent("", "key1", Dummy{Key: "Key 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, first.(Dummy).Id, second.(Dummy).Id)
+
+	// The key expires after the TTL
+	clock.Advance(2 * time.Minute)
+	third, err := persistence.CreateIdempotent("", "key1", Dummy{Key: "Key 1"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.(Dummy).Id, third.(Dummy).Id)
+}
+
+func TestRateLimitWithClock(t *testing.T) {
+	clock := cpersist.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	persistence := cpersist.NewRateLimitedPersistence(
+		cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{})))
+	persistence.Clock = clock
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.rate", 1,
+		"options.burst", 1,
+		"options.wait_timeout", 5000,
+	))
+
+	_, err := persistence.Create("client1", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	// Waiting for the next token advances the clock instead of sleeping
+	_, err = persistence.GetOneById("client1", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, clock.Now().Sub(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,39 @@
+package test_persistence
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type TaggedItem struct {
+	Id       string   `json:"id"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+}
+
+func TestCompact(t *testing.T) {
+	items := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(TaggedItem{}))
+	items.CloneStrategy = cpersist.CloneStrategyNone
+
+	tags := make([]string, 1, 10)
+	tags[0] = strings.Repeat("x", 3)
+	items.Create("", TaggedItem{Id: "1", Category: strings.Repeat("books", 2), Tags: tags})
+	items.Create("", TaggedItem{Id: "2", Category: strings.Repeat("books", 2), Tags: []string{strings.Repeat("x", 3)}})
+
+	report, err := items.Compact("")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Items)
+	assert.Equal(t, 2, report.InternedStrings)
+	assert.Equal(t, 1, report.TrimmedSlices)
+	assert.True(t, report.SavedBytes >= 13)
+	assert.Equal(t, 10, cap(tags))
+
+	item, err := items.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, TaggedItem{Id: "1", Category: "booksbooks", Tags: []string{"xxx"}}, item)
+	assert.Equal(t, 1, cap(item.(TaggedItem).Tags))
+}
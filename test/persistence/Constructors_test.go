@@ -0,0 +1,44 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructorsWithNilPrototype(t *testing.T) {
+	assert.PanicsWithValue(t, "Prototype cannot be nil", func() { cpersist.NewMemoryPersistence(nil) })
+	assert.PanicsWithValue(t, "Prototype cannot be nil", func() { cpersist.NewIdentifiableFilePersistence(nil, nil) })
+	assert.PanicsWithValue(t, "Prototype cannot be nil", func() { cpersist.NewDataGenerator(nil, 1) })
+
+	_, err := cpersist.GetMemoryStore("nil-prototype", nil)
+	assert.NotNil(t, err)
+	_, err = cpersist.ProvideFilePersistence(nil, "data.json")
+	assert.NotNil(t, err)
+}
+
+func TestNewFilePersistenceFromPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dummies.json")
+
+	persistence, err := cpersist.NewIdentifiableFilePersistenceFromPath(reflect.TypeOf(Dummy{}), path)
+	assert.Nil(t, err)
+	assert.Equal(t, path, persistence.Persister.Path())
+	assert.Nil(t, persistence.Open(""))
+	_, err = persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	assert.Nil(t, persistence.Close(""))
+
+	plain, err := cpersist.NewFilePersistenceFromPath(reflect.TypeOf(Dummy{}), path)
+	assert.Nil(t, err)
+	assert.Nil(t, plain.Open(""))
+	assert.Len(t, plain.Items, 1)
+	assert.Nil(t, plain.Close(""))
+
+	_, err = cpersist.NewFilePersistenceFromPath(reflect.TypeOf(Dummy{}), "")
+	assert.NotNil(t, err)
+	_, err = cpersist.NewIdentifiableFilePersistenceFromPath(nil, path)
+	assert.NotNil(t, err)
+}
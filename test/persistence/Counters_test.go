@@ -0,0 +1,52 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountersFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counters.json")
+	counters := persistence.NewCountersFilePersistence(path)
+	assert.Nil(t, counters.Open(""))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := counters.Increment("", "requests", 1)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	counter, err := counters.Increment("", "requests", -5)
+	assert.Nil(t, err)
+	assert.Equal(t, "requests", counter.Id)
+	assert.Equal(t, float64(45), counter.Value)
+
+	_, err = counters.Set("", "queue", 7.5)
+	assert.Nil(t, err)
+	assert.Nil(t, counters.Close(""))
+
+	counters = persistence.NewCountersFilePersistence(path)
+	assert.Nil(t, counters.Open(""))
+	defer counters.Close("")
+
+	value, err := counters.GetValue("", "requests")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(45), value)
+
+	value, err = counters.GetValue("", "missing")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), value)
+
+	values, err := counters.GetValues("")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"requests": 45, "queue": 7.5}, values)
+}
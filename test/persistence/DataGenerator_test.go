@@ -0,0 +1,56 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Shipment struct {
+	Id       string    `json:"id" fake:"format=uuid"`
+	Email    string    `json:"email" fake:"format=email"`
+	Code     string    `json:"code" fake:"len=6"`
+	Weight   float64   `json:"weight" fake:"min=1,max=5"`
+	Boxes    int       `json:"boxes" fake:"min=1,max=3"`
+	Status   string    `json:"status" fake:"oneof=new|sent"`
+	Shipped  time.Time `json:"shipped" fake:"min=0,max=86400"`
+	Tags     []string  `json:"tags" fake:"len=2"`
+	Internal string    `json:"-" fake:"-"`
+}
+
+func TestDataGenerator(t *testing.T) {
+	generator := cpersist.NewDataGenerator(reflect.TypeOf(Shipment{}), 1)
+	items, err := generator.GenerateItems(50)
+	assert.Nil(t, err)
+	for _, item := range items {
+		shipment := item.(Shipment)
+		assert.Len(t, shipment.Id, 32)
+		assert.Contains(t, shipment.Email, "@example.com")
+		assert.Len(t, shipment.Code, 6)
+		assert.True(t, shipment.Weight >= 1 && shipment.Weight <= 5)
+		assert.True(t, shipment.Boxes >= 1 && shipment.Boxes <= 3)
+		assert.Contains(t, []string{"new", "sent"}, shipment.Status)
+		assert.True(t, shipment.Shipped.Unix() <= 86400)
+		assert.Len(t, shipment.Tags, 2)
+		assert.Equal(t, "", shipment.Internal)
+	}
+
+	// Equal seeds produce equal items
+	other, _ := cpersist.NewDataGenerator(reflect.TypeOf(Shipment{}), 1).GenerateItems(50)
+	assert.Equal(t, items, other)
+
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Shipment{}))
+	err = generator.Seed("", persistence, 100)
+	assert.Nil(t, err)
+	count, _ := persistence.GetCountByFilterParams("", nil)
+	assert.Equal(t, int64(100), count)
+
+	type Invalid struct {
+		Value int `fake:"min=abc"`
+	}
+	_, err = cpersist.NewDataGenerator(reflect.TypeOf(Invalid{}), 1).Generate()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,33 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteByIdsEx(t *testing.T) {
+	saver := &countingSaver{}
+	sales := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Sale{}))
+	sales.Saver = saver
+	for _, id := range []string{"1", "2", "3", "4"} {
+		sales.Create("", Sale{Id: id})
+	}
+	saver.saves = 0
+
+	missing, err := sales.DeleteByIdsEx("", []interface{}{"1", "5", "3", "6"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"5", "6"}, missing)
+	assert.Equal(t, 1, saver.saves)
+
+	count, err := sales.GetCountByFilterParams("", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	missing, err = sales.DeleteByIdsEx("", []interface{}{"1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"1"}, missing)
+	assert.Equal(t, 1, saver.saves)
+}
@@ -0,0 +1,107 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	persistence.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+
+	changes, err := persistence.DryRun("", func(p *cpersist.IdentifiableMemoryPersistence) error {
+		if _, err := p.Create("", Dummy{Id: "3", Key: "Key 3"}); err != nil {
+			return err
+		}
+		if _, err := p.UpdatePartially("", "1", cdata.NewAnyValueMapFromTuples("content", "Changed")); err != nil {
+			return err
+		}
+		_, err := p.DeleteById("", "2")
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, cpersist.DiffUpdated, changes[0].Change)
+	assert.Equal(t, cpersist.DiffDeleted, changes[1].Change)
+	assert.Equal(t, cpersist.DiffCreated, changes[2].Change)
+
+	// Nothing was committed
+	items, _ := persistence.GetListByFilter("", nil, nil, nil)
+	assert.Len(t, items, 2)
+	item, _ := persistence.GetOneById("", "1")
+	assert.Equal(t, "Content 1", item.(Dummy).Content)
+
+	// Validation errors are returned as is
+	_, err = persistence.DryRun("", func(p *cpersist.IdentifiableMemoryPersistence) error {
+		_, err := p.Create("", Dummy{Id: "1", Key: "Key 1"})
+		return err
+	})
+	assert.NotNil(t, err)
+}
+
+func TestDryRunWithValidators(t *testing.T) {
+	tickets := cpersist.NewStatefulEntityPersistence(reflect.TypeOf(Ticket{}))
+	tickets.Configure(config.NewConfigParamsFromTuples(
+		"transitions.open", "closed",
+	))
+	assert.Nil(t, tickets.Open(""))
+	defer tickets.Close("")
+	_, err := tickets.Create("", Ticket{Id: "1", State: "closed"})
+	assert.Nil(t, err)
+
+	// The transition validator rejects the change in the copy
+	_, err = tickets.DryRun("", func(p *cpersist.IdentifiableMemoryPersistence) error {
+		_, err := p.Update("", Ticket{Id: "1", State: "open"})
+		return err
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_TRANSITION", err.(*cerr.ApplicationError).Code)
+
+	// Allowed changes are previewed without recording transitions
+	_, err = tickets.Create("", Ticket{Id: "2", State: "open"})
+	assert.Nil(t, err)
+	changes, err := tickets.DryRun("", func(p *cpersist.IdentifiableMemoryPersistence) error {
+		_, err := p.Update("", Ticket{Id: "2", State: "closed"})
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+	history, err := tickets.GetTransitionHistory("", "2")
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestDryRunWithConfiguredOptions(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Configure(config.NewConfigParamsFromTuples(
+		"options.quota_field", "Key",
+		"options.quota_max_items", 1,
+	))
+	persistence.Configure(config.NewConfigParamsFromTuples("options.omit_empty_updates", true))
+	_, err := persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+
+	// Quota is counted from copied items
+	_, err = persistence.DryRun("", func(p *cpersist.IdentifiableMemoryPersistence) error {
+		_, err := p.Create("", Dummy{Id: "2", Key: "Key 1"})
+		return err
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "QUOTA_EXCEEDED", err.(*cerr.ApplicationError).Code)
+
+	// Options of later Configure calls are applied too
+	changes, err := persistence.DryRun("", func(p *cpersist.IdentifiableMemoryPersistence) error {
+		assert.True(t, p.OmitEmptyUpdates)
+		_, err := p.UpdatePartially("", "1", cdata.NewAnyValueMapFromTuples("content", ""))
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Len(t, changes, 0)
+}
@@ -1,10 +1,22 @@
 package test_persistence
 
 import (
+	"errors"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clock "github.com/pip-services3-go/pip-services3-components-go/lock"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDummyFilePersistence(t *testing.T) {
@@ -29,3 +41,550 @@ func TestDummyFilePersistence(t *testing.T) {
 	t.Run("DummyFilePersistence:Batch", fixture.TestBatchOperations)
 
 }
+
+func TestDummyFilePersistenceSingleWriter(t *testing.T) {
+	filename := "../../data/dummies_writer.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	config := cconf.NewConfigParamsFromTuples(
+		"options.single_writer", true,
+		"options.writer_lock_ttl", 1000,
+		"options.writer_check_interval", 50,
+	)
+	references := cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "lock", "memory", "default", "1.0"), clock.NewMemoryLock(),
+	)
+
+	persistence1 := NewDummyFilePersistence(filename)
+	persistence1.Configure(config)
+	persistence1.SetReferences(references)
+	persistence2 := NewDummyFilePersistence(filename)
+	persistence2.Configure(config)
+	persistence2.SetReferences(references)
+
+	err := persistence1.Open("")
+	assert.Nil(t, err)
+	err = persistence2.Open("")
+	assert.Nil(t, err)
+	defer persistence2.Close("")
+
+	assert.True(t, persistence1.IsWriter())
+	assert.False(t, persistence2.IsWriter())
+
+	_, err = persistence2.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.NotNil(t, err)
+
+	_, err = persistence1.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+	dummy, _ := persistence2.GetOneById("", "1")
+	assert.Equal(t, "Content 1", dummy.Content)
+
+	persistence1.Close("")
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, persistence2.IsWriter())
+
+	_, err = persistence2.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	assert.Nil(t, err)
+}
+
+// Memory lock that counts released locks
+type releaseCountingLock struct {
+	*clock.MemoryLock
+	releases int32
+}
+
+func (c *releaseCountingLock) ReleaseLock(correlationId string, key string) error {
+	atomic.AddInt32(&c.releases, 1)
+	return c.MemoryLock.ReleaseLock(correlationId, key)
+}
+
+func TestDummyFilePersistenceSingleWriterKeepsData(t *testing.T) {
+	filename := "../../data/dummies_writer_keep.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	locker := &releaseCountingLock{MemoryLock: clock.NewMemoryLock()}
+	references := cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "lock", "memory", "default", "1.0"), locker,
+	)
+	newPersistence := func(interval int) *DummyFilePersistence {
+		persistence := NewDummyFilePersistence(filename)
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"options.single_writer", true,
+			"options.writer_lock_ttl", 1000,
+			"options.writer_check_interval", interval,
+		))
+		persistence.SetReferences(references)
+		return persistence
+	}
+
+	writer := newPersistence(10)
+	assert.Nil(t, writer.Open(""))
+	defer writer.Close("")
+	// The reader doesn't reload items before it is closed
+	reader := newPersistence(60000)
+	assert.Nil(t, reader.Open(""))
+	assert.True(t, writer.IsWriter())
+	assert.False(t, reader.IsWriter())
+
+	_, err := writer.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+
+	// The writer renews the lock without releasing it
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&locker.releases))
+	assert.True(t, writer.IsWriter())
+
+	// Closing the reader with outdated items doesn't overwrite the data file
+	assert.Nil(t, reader.Close(""))
+	check := NewDummyFilePersistence(filename)
+	assert.Nil(t, check.Open(""))
+	dummy, err := check.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Content 1", dummy.Content)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&locker.releases))
+}
+
+func TestDummyFilePersistenceSingleWriterReconfigure(t *testing.T) {
+	filename := "../../data/dummies_writer_reconfigure.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	locker := &releaseCountingLock{MemoryLock: clock.NewMemoryLock()}
+	persistence := NewDummyFilePersistence(filename)
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.single_writer", true,
+		"options.writer_lock_ttl", 1000,
+		"options.writer_check_interval", 10,
+	))
+	persistence.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "lock", "memory", "default", "1.0"), locker,
+	))
+	assert.Nil(t, persistence.Open(""))
+	defer persistence.Close("")
+	assert.True(t, persistence.IsWriter())
+
+	// Switching the mode off releases the lock and stops the coordination
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.single_writer", false))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&locker.releases))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, persistence.IsWriter())
+
+	// Another instance can become the writer
+	acquired, err := locker.TryAcquireLock("", filename, 1000)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+
+	_, err = persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+}
+
+func TestDummyFilePersistenceWithLock(t *testing.T) {
+	filename := "../../data/dummies_lock.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	references := cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "lock", "memory", "default", "1.0"), clock.NewMemoryLock(),
+	)
+	persistence1 := NewDummyFilePersistence(filename)
+	persistence1.SetReferences(references)
+	persistence1.Open("")
+	defer persistence1.Close("")
+	persistence2 := NewDummyFilePersistence(filename)
+	persistence2.SetReferences(references)
+	persistence2.Open("")
+	defer persistence2.Close("")
+
+	persistence1.Create("", Dummy{Id: "1", Key: "Key 1", Content: "1"})
+
+	increment := func(persistence *DummyFilePersistence) error {
+		return persistence.WithLock("", "dummy-1", func() error {
+			dummy, err := persistence.GetOneById("", "1")
+			if err != nil {
+				return err
+			}
+			dummy.Content += "1"
+			_, err = persistence.Update("", dummy)
+			return err
+		})
+	}
+
+	assert.Nil(t, increment(persistence2))
+	assert.Nil(t, increment(persistence1))
+	assert.Nil(t, increment(persistence2))
+
+	dummy, _ := persistence2.GetOneById("", "1")
+	assert.Equal(t, "1111", dummy.Content)
+
+	err := NewDummyMemoryPersistence().WithLock("", "dummy-1", func() error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestDummyFilePersistenceWithLockSameModTime(t *testing.T) {
+	filename := "../../data/dummies_lock_mtime.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	references := cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "lock", "memory", "default", "1.0"), clock.NewMemoryLock(),
+	)
+	persistence1 := NewDummyFilePersistence(filename)
+	persistence1.SetReferences(references)
+	persistence1.Open("")
+	defer persistence1.Close("")
+	persistence2 := NewDummyFilePersistence(filename)
+	persistence2.SetReferences(references)
+	persistence2.Open("")
+	defer persistence2.Close("")
+
+	persistence1.Create("", Dummy{Id: "1", Key: "Key 1", Content: "1"})
+	read := func() string {
+		var content string
+		persistence2.WithLock("", "dummy-1", func() error {
+			dummy, err := persistence2.GetOneById("", "1")
+			content = dummy.Content
+			return err
+		})
+		return content
+	}
+	assert.Equal(t, "1", read())
+
+	// Changes within resolution of modification time are detected
+	info, err := os.Stat(filename)
+	assert.Nil(t, err)
+	persistence1.Update("", Dummy{Id: "1", Key: "Key 1", Content: "2"})
+	assert.Nil(t, os.Chtimes(filename, info.ModTime(), info.ModTime()))
+	assert.Equal(t, "2", read())
+}
+
+func TestDummyFilePersistenceUnknownFields(t *testing.T) {
+	filename := "../../data/dummies_unknown.json"
+	defer os.Remove(filename)
+	err := os.WriteFile(filename, []byte(`[{"id": "1", "key": "Key 1", "content": "1", "color": "red"}]`), 0644)
+	assert.Nil(t, err)
+
+	persistence := NewDummyFilePersistence(filename)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.unknown_fields", "fail"))
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+
+	persistence = NewDummyFilePersistence(filename)
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.unknown_fields", "warn",
+		"options.preserve_unknown_fields", true,
+	))
+	err = persistence.Open("")
+	assert.Nil(t, err)
+
+	dummy, _ := persistence.GetOneById("", "1")
+	dummy.Content = "2"
+	_, err = persistence.Update("", dummy)
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"color":"red"`)
+	assert.Contains(t, string(data), `"content":"2"`)
+}
+
+func TestDummyFilePersistenceLoadErrors(t *testing.T) {
+	filename := "../../data/dummies_invalid.json"
+	defer os.Remove(filename)
+	err := os.WriteFile(filename, []byte(`[{"id": "1", "key": "Key 1"}, {"id": 2, "key": "Key 2"}]`), 0644)
+	assert.Nil(t, err)
+
+	persistence := NewDummyFilePersistence(filename)
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_DATA_ITEM", err.(*cerr.ApplicationError).Code)
+	assert.False(t, persistence.IsOpen())
+
+	persistence = NewDummyFilePersistence(filename)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.load_errors", "skip"))
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	page, _ := persistence.GetPageByFilter("", cdata.NewEmptyFilterParams(), nil)
+	assert.Len(t, page.Data, 1)
+	persistence.Close("")
+
+	// Skipped item was removed on save
+	data, _ := os.ReadFile(filename)
+	assert.NotContains(t, string(data), "Key 2")
+	err = os.WriteFile(filename, []byte(`[{"id": "1", "key": "Key 1"}, {"id": 2, "key": "Key 2"}]`), 0644)
+	assert.Nil(t, err)
+
+	persistence = NewDummyFilePersistence(filename)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.load_errors", "fail_open"))
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_DATA_ITEMS", err.(*cerr.ApplicationError).Code)
+	assert.True(t, persistence.IsOpen())
+	dummy, _ := persistence.GetOneById("", "1")
+	assert.Equal(t, "Key 1", dummy.Key)
+	persistence.Close("")
+}
+
+type FlexibleDummy struct {
+	Id    string                 `json:"id"`
+	Key   string                 `json:"key"`
+	Extra map[string]interface{} `json:"-"`
+}
+
+func TestFilePersistenceExtraFields(t *testing.T) {
+	filename := "../../data/dummies_extra.json"
+	defer os.Remove(filename)
+	err := os.WriteFile(filename, []byte(`[{"id": "1", "key": "Key 1", "color": "red"}, {"id": "2", "key": "Key 2"}]`), 0644)
+	assert.Nil(t, err)
+
+	persistence := cpersist.NewIdentifiableFilePersistence(reflect.TypeOf(FlexibleDummy{}), nil)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("path", filename))
+	err = persistence.Open("")
+	assert.Nil(t, err)
+
+	item, _ := persistence.GetOneById("", "1")
+	assert.Equal(t, "red", item.(FlexibleDummy).Extra["color"])
+
+	filter, err := cpersist.ComposeFilter(reflect.TypeOf(FlexibleDummy{}),
+		cdata.NewFilterParamsFromTuples("color", "red"))
+	assert.Nil(t, err)
+	items, _ := persistence.GetListByFilter("", filter, nil, nil)
+	assert.Len(t, items, 1)
+
+	_, err = persistence.Create("", FlexibleDummy{Id: "3", Key: "Key 3", Extra: map[string]interface{}{"size": "L"}})
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"color":"red"`)
+	assert.Contains(t, string(data), `"size":"L"`)
+}
+
+type countingSaver struct {
+	lock  sync.Mutex
+	saves int
+	items int
+	err   error
+}
+
+func (c *countingSaver) Save(correlationId string, items []interface{}) error {
+	time.Sleep(5 * time.Millisecond)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.saves++
+	c.items = len(items)
+	return c.err
+}
+
+func TestBackgroundSave(t *testing.T) {
+	saver := &countingSaver{}
+	persistence := NewDummyMemoryPersistence()
+	persistence.Saver = saver
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.background_save", true))
+	persistence.Open("")
+
+	for i := 0; i < 50; i++ {
+		_, err := persistence.Create("", Dummy{Key: "Key", Content: "Content"})
+		assert.Nil(t, err)
+	}
+	err := persistence.Flush("")
+	assert.Nil(t, err)
+
+	saver.lock.Lock()
+	assert.Equal(t, 50, saver.items)
+	assert.Less(t, saver.saves, 50)
+	saver.lock.Unlock()
+
+	errs := make(chan error, 10)
+	persistence.SaveErrorHandler = func(correlationId string, err error) {
+		errs <- err
+	}
+	saver.lock.Lock()
+	saver.err = errors.New("disk is full")
+	saver.lock.Unlock()
+	persistence.Create("", Dummy{Key: "Key", Content: "Content"})
+	assert.NotNil(t, persistence.Flush(""))
+	assert.NotNil(t, <-errs)
+
+	saver.lock.Lock()
+	saver.err = nil
+	saver.lock.Unlock()
+	assert.Nil(t, persistence.Close(""))
+}
+
+func TestDurability(t *testing.T) {
+	saver := &countingSaver{}
+	persistence := NewDummyMemoryPersistence()
+	persistence.Saver = saver
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.durability", "none"))
+	persistence.Open("")
+
+	persistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	persistence.Create("", Dummy{Key: "Key 2", Content: "Content 2"})
+	assert.Equal(t, 0, saver.saves)
+
+	assert.Nil(t, persistence.Flush(""))
+	assert.Equal(t, 1, saver.saves)
+	assert.Equal(t, 2, saver.items)
+
+	assert.Nil(t, persistence.Close(""))
+	assert.Equal(t, 2, saver.saves)
+
+	filename := "../../data/dummies_fsync.json"
+	defer os.Remove(filename)
+	filePersistence := NewDummyFilePersistence(filename)
+	filePersistence.Configure(cconf.NewConfigParamsFromTuples("options.durability", "fsync"))
+	filePersistence.Open("")
+	_, err := filePersistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	filePersistence.Close("")
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "Key 1")
+}
+
+func TestMoveDataFile(t *testing.T) {
+	filename := "../../data/dummies_move.json"
+	newFilename := "../../data/moved/dummies.json"
+	defer os.Remove(filename)
+	defer os.RemoveAll("../../data/moved")
+
+	persistence := cpersist.NewIdentifiableFilePersistence(reflect.TypeOf(Dummy{}), nil)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("path", filename))
+	persistence.Open("")
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, os.Chmod(filename, 0640))
+
+	err := persistence.MoveDataFile("", newFilename)
+	assert.Nil(t, err)
+	assert.Equal(t, newFilename, persistence.Persister.Path())
+	_, err = os.Stat(filename)
+	assert.True(t, os.IsNotExist(err))
+
+	// The copy keeps mode of the moved file and no temporary files are left
+	info, err := os.Stat(newFilename)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	entries, err := os.ReadDir("../../data/moved")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	persistence.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	persistence.Close("")
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), newFilename)
+	items, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+}
+
+func TestReconfigure(t *testing.T) {
+	filename1 := "../../data/dummies_reconfigure1.json"
+	filename2 := "../../data/dummies_reconfigure2.json"
+	defer os.Remove(filename1)
+	defer os.Remove(filename2)
+	err := os.WriteFile(filename2, []byte(`[{"id": "2", "key": "Key 2"}]`), 0644)
+	assert.Nil(t, err)
+
+	prototype := reflect.TypeOf(Dummy{})
+	persistence := cpersist.NewIdentifiableFilePersistence(prototype, cpersist.NewJsonFilePersister(prototype, filename1))
+	persistence.Open("")
+	defer persistence.Close("")
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"path", filename2,
+		"options.max_page_size", 5,
+		"options.read_only", true,
+	))
+	assert.Equal(t, 5, persistence.MaxPageSize)
+	item, _ := persistence.GetOneById("", "2")
+	assert.Equal(t, "Key 2", item.(Dummy).Key)
+	_, err = persistence.Create("", Dummy{Id: "3", Key: "Key 3"})
+	assert.NotNil(t, err)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.read_only", false,
+		"options.durability", "none",
+		"options.autosave_interval", 10,
+	))
+	_, err = persistence.Create("", Dummy{Id: "3", Key: "Key 3"})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filename2)
+		return strings.Contains(string(data), "Key 3")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReconfigureBackgroundComponents(t *testing.T) {
+	filename := "../../data/dummies_reconfigure_background.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+	contains := func(key string) bool {
+		data, _ := os.ReadFile(filename)
+		return strings.Contains(string(data), key)
+	}
+
+	locker := &releaseCountingLock{MemoryLock: clock.NewMemoryLock()}
+	persistence := NewDummyFilePersistence(filename)
+	persistence.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "lock", "memory", "default", "1.0"), locker,
+	))
+	assert.Nil(t, persistence.Open(""))
+	defer persistence.Close("")
+
+	// Single writer mode is started, restarted with new options and stopped
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.single_writer", true,
+		"options.writer_check_interval", 10,
+	))
+	assert.True(t, persistence.IsWriter())
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.writer_check_interval", 20))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&locker.releases))
+	assert.True(t, persistence.IsWriter())
+	acquired, _ := locker.TryAcquireLock("", filename, 1000)
+	assert.False(t, acquired)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.single_writer", false))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&locker.releases))
+	time.Sleep(50 * time.Millisecond)
+	_, err := persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+
+	// Background saves are started and stopped
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.background_save", true))
+	_, err = persistence.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.Nil(t, persistence.Flush(""))
+	assert.True(t, contains("Key 2"))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.background_save", false))
+	_, err = persistence.Create("", Dummy{Id: "3", Key: "Key 3"})
+	assert.Nil(t, err)
+	assert.True(t, contains("Key 3"))
+
+	// Autosave is started, restarted with a new interval and stopped
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.durability", "none",
+		"options.autosave_interval", 10,
+	))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.autosave_interval", 20))
+	_, err = persistence.Create("", Dummy{Id: "4", Key: "Key 4"})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return contains("Key 4") }, time.Second, 10*time.Millisecond)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.autosave_interval", 0))
+	_, err = persistence.Create("", Dummy{Id: "5", Key: "Key 5"})
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, contains("Key 5"))
+
+	// Read snapshots are published and dropped
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.read_snapshot", true))
+	assert.NotNil(t, persistence.GetReadSnapshot())
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.read_snapshot", false))
+	assert.Nil(t, persistence.GetReadSnapshot())
+}
@@ -77,7 +77,7 @@ func (c *DummyMapMemoryPersistence) DeleteByIds(correlationId string, ids []stri
 }
 
 func filterFunc(filter *cdata.FilterParams) func(interface{}) bool {
-	if &filter == nil {
+	if filter == nil {
 		filter = cdata.NewEmptyFilterParams()
 	}
 
@@ -106,7 +106,7 @@ func (c *DummyMapMemoryPersistence) GetPageByFilter(correlationId string, filter
 }
 
 func (c *DummyMapMemoryPersistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
-	if &filter == nil {
+	if filter == nil {
 		filter = cdata.NewEmptyFilterParams()
 	}
 	key := filter.GetAsNullableString("Key")
@@ -88,7 +88,7 @@ func (c *DummyMemoryPersistence) DeleteByIds(correlationId string, ids []string)
 
 func (c *DummyMemoryPersistence) GetPageByFilter(correlationId string, filter *cdata.FilterParams, paging *cdata.PagingParams) (page *DummyPage, err error) {
 
-	if &filter == nil {
+	if filter == nil {
 		filter = cdata.NewEmptyFilterParams()
 	}
 
@@ -118,7 +118,7 @@ func (c *DummyMemoryPersistence) GetPageByFilter(correlationId string, filter *c
 
 func (c *DummyMemoryPersistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
 
-	if &filter == nil {
+	if filter == nil {
 		filter = cdata.NewEmptyFilterParams()
 	}
 
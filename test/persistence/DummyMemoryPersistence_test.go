@@ -1,9 +1,17 @@
 package test_persistence
 
 import (
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDummyMemoryPersistence(t *testing.T) {
@@ -16,3 +24,526 @@ func TestDummyMemoryPersistence(t *testing.T) {
 	t.Run("DummyMemoryPersistence:Batch", fixture.TestBatchOperations)
 
 }
+
+func TestDummyMemoryPersistencePause(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	dummy, err := persistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+
+	err = persistence.Pause("")
+	assert.Nil(t, err)
+	assert.True(t, persistence.IsPaused())
+
+	// Writes are rejected
+	_, err = persistence.Create("", Dummy{Key: "Key 2", Content: "Content 2"})
+	assert.NotNil(t, err)
+	_, err = persistence.DeleteById("", dummy.Id)
+	assert.NotNil(t, err)
+
+	// Reads still work
+	result, err := persistence.GetOneById("", dummy.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, dummy.Id, result.Id)
+
+	err = persistence.Resume("")
+	assert.Nil(t, err)
+	assert.False(t, persistence.IsPaused())
+
+	_, err = persistence.Create("", Dummy{Key: "Key 2", Content: "Content 2"})
+	assert.Nil(t, err)
+}
+
+func TestDummyMemoryPersistenceNotFoundError(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.not_found_error", true,
+	))
+
+	_, err := persistence.GetOneById("", "missing")
+	assert.NotNil(t, err)
+
+	_, err = persistence.Update("", Dummy{Id: "missing", Key: "Key 1"})
+	assert.NotNil(t, err)
+
+	_, err = persistence.DeleteById("", "missing")
+	assert.NotNil(t, err)
+}
+
+func TestDummyMemoryPersistenceDuplicatePolicy(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	dummy, err := persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+
+	// Rejected by default
+	_, err = persistence.Create("", dummy)
+	assert.NotNil(t, err)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.duplicate_policy", "generate"))
+	result, err := persistence.Create("", dummy)
+	assert.Nil(t, err)
+	assert.NotEqual(t, dummy.Id, result.Id)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.duplicate_policy", "overwrite"))
+	dummy.Content = "Content 2"
+	result, err = persistence.Create("", dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, dummy.Id, result.Id)
+
+	count, err := persistence.GetCountByFilter("", cdata.NewEmptyFilterParams())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestDummyMemoryPersistenceParallelFilter(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.filter_parallelism", 4,
+		"options.parallel_filter_threshold", 10,
+	))
+
+	for i := 0; i < 100; i++ {
+		_, err := persistence.Create("", Dummy{Key: "Key " + strconv.Itoa(i%2), Content: strconv.Itoa(i)})
+		assert.Nil(t, err)
+	}
+
+	items, err := persistence.GetListByFilter("", func(item interface{}) bool {
+		return item.(Dummy).Key == "Key 1"
+	}, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, items, 50)
+	for i, item := range items {
+		assert.Equal(t, strconv.Itoa(i*2+1), item.(Dummy).Content)
+	}
+}
+
+func TestDummyMemoryPersistenceQueryCache(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.query_cache_size", 10,
+	))
+
+	_, err := persistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+
+	filter := cdata.NewFilterParamsFromTuples("key", "Key 1")
+	count, err := persistence.GetCountByFilterParams("", filter)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	page, err := persistence.GetPageByFilterParams("", filter, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+
+	// Writes invalidate cached results
+	_, err = persistence.Create("", Dummy{Key: "Key 1", Content: "Content 2"})
+	assert.Nil(t, err)
+
+	count, err = persistence.GetCountByFilterParams("", filter)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	page, err = persistence.GetPageByFilterParams("", filter, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+
+	// Items changed directly are seen after they are saved
+	persistence.Lock.Lock()
+	persistence.Items = append(persistence.Items, Dummy{Id: "3", Key: "Key 1", Content: "Content 3"})
+	persistence.Lock.Unlock()
+	assert.Nil(t, persistence.Save(""))
+
+	count, err = persistence.GetCountByFilterParams("", filter)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+	page, err = persistence.GetPageByFilterParams("", filter, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 3)
+
+	// Cached pages are not limited by previous paging options
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.max_page_size", 2))
+	page, err = persistence.GetPageByFilterParams("", filter, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+}
+
+func TestDummyMemoryPersistenceQueryCacheConcurrentWrites(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.query_cache_size", 10,
+	))
+	filter := cdata.NewFilterParamsFromTuples("key", "Key 1")
+
+	// Readers keep caching results while items are created
+	stop := make(chan bool)
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					persistence.GetCountByFilterParams("", filter)
+					persistence.GetListByFilterParams("", filter, nil)
+				}
+			}
+		}()
+	}
+
+	// Results read after a write never miss the written item
+	for i := 1; i <= 200; i++ {
+		_, err := persistence.Create("", Dummy{Key: "Key 1", Content: strconv.Itoa(i)})
+		assert.Nil(t, err)
+		count, err := persistence.GetCountByFilterParams("", filter)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(i), count)
+		items, err := persistence.GetListByFilterParams("", filter, nil)
+		assert.Nil(t, err)
+		assert.Len(t, items, i)
+	}
+	close(stop)
+	readers.Wait()
+}
+
+func TestDummyMemoryPersistenceViews(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	err := persistence.RegisterView("", "key1",
+		func(item interface{}) bool { return item.(Dummy).Key == "Key 1" },
+		func(a, b interface{}) bool { return a.(Dummy).Content < b.(Dummy).Content },
+		nil)
+	assert.Nil(t, err)
+
+	dummy1, _ := persistence.Create("", Dummy{Key: "Key 1", Content: "Content 2"})
+	persistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	persistence.Create("", Dummy{Key: "Key 2", Content: "Content 3"})
+
+	page, err := persistence.GetView("", "key1", cdata.NewPagingParams(0, 10, true))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), *page.Total)
+	assert.Equal(t, "Content 1", page.Data[0].(Dummy).Content)
+	assert.Equal(t, "Content 2", page.Data[1].(Dummy).Content)
+
+	dummy1.Content = "Content 0"
+	persistence.Update("", dummy1)
+	page, _ = persistence.GetView("", "key1", nil)
+	assert.Equal(t, "Content 0", page.Data[0].(Dummy).Content)
+
+	persistence.DeleteById("", dummy1.Id)
+	page, _ = persistence.GetView("", "key1", nil)
+	assert.Len(t, page.Data, 1)
+
+	// Items changed directly are seen after they are saved
+	persistence.Lock.Lock()
+	persistence.Items = append(persistence.Items, Dummy{Id: "4", Key: "Key 1", Content: "Content 0"})
+	persistence.Lock.Unlock()
+	assert.Nil(t, persistence.Save(""))
+	page, _ = persistence.GetView("", "key1", nil)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, "Content 0", page.Data[0].(Dummy).Content)
+
+	_, err = persistence.GetView("", "unknown", nil)
+	assert.NotNil(t, err)
+}
+
+func TestDummyMemoryPersistenceComputedFields(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	persistence.Create("", Dummy{Key: "Key 1", Content: "Old"})
+
+	persistence.AddComputedField("", "Content", func(item interface{}) interface{} {
+		return "Content of " + item.(Dummy).Key
+	})
+
+	page, err := persistence.GetPageByFilter("", cdata.NewEmptyFilterParams(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Content of Key 1", page.Data[0].Content)
+
+	dummy, err := persistence.Create("", Dummy{Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Content of Key 2", dummy.Content)
+
+	dummy.Key = "Key 3"
+	dummy, err = persistence.Update("", dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, "Content of Key 3", dummy.Content)
+
+	dummy, err = persistence.UpdatePartially("", dummy.Id, cdata.NewAnyValueMapFromTuples("key", "Key 4"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Content of Key 4", dummy.Content)
+}
+
+func TestDummyMemoryPersistenceHistogram(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	for i := 0; i < 10; i++ {
+		persistence.Create("", Dummy{Key: "Key " + strconv.Itoa(i), Content: strconv.Itoa(i)})
+	}
+
+	histogram, err := persistence.GetHistogramByFilter("",
+		func(item interface{}) bool { return item.(Dummy).Content != "9" },
+		func(item interface{}) interface{} { return item.(Dummy).Content },
+		cpersist.NewNumericBuckets(0, 5, 2))
+	assert.Nil(t, err)
+	assert.Len(t, histogram, 2)
+	assert.Equal(t, int64(5), histogram[0].Count)
+	assert.Equal(t, int64(4), histogram[1].Count)
+}
+
+func TestDummyMemoryPersistenceTop(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	for _, content := range []string{"5", "3", "8", "1", "9", "3", "7"} {
+		persistence.Create("", Dummy{Key: "Key " + content, Content: content})
+	}
+
+	items, err := persistence.GetTopByFilter("",
+		func(item interface{}) bool { return item.(Dummy).Content != "1" },
+		func(a, b interface{}) bool { return a.(Dummy).Content < b.(Dummy).Content },
+		3)
+	assert.Nil(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, "3", items[0].(Dummy).Content)
+	assert.Equal(t, "3", items[1].(Dummy).Content)
+	assert.Equal(t, "5", items[2].(Dummy).Content)
+
+	items, err = persistence.GetTopByFilter("", nil,
+		func(a, b interface{}) bool { return a.(Dummy).Content > b.(Dummy).Content },
+		10)
+	assert.Nil(t, err)
+	assert.Len(t, items, 7)
+	assert.Equal(t, "9", items[0].(Dummy).Content)
+
+	// Zero or negative number of items returns no items
+	for _, n := range []int{0, -1} {
+		items, err = persistence.GetTopByFilter("", nil,
+			func(a, b interface{}) bool { return a.(Dummy).Content > b.(Dummy).Content },
+			n)
+		assert.Nil(t, err)
+		assert.NotNil(t, items)
+		assert.Len(t, items, 0)
+	}
+}
+
+func TestDummyMemoryPersistenceDuplicates(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewEmptyConfigParams())
+
+	dummy1, _ := persistence.Create("", Dummy{Key: "Key 1", Content: "A"})
+	dummy2, _ := persistence.Create("", Dummy{Key: "key 1", Content: "B"})
+	persistence.Create("", Dummy{Key: "Key 2", Content: "C"})
+
+	groups, err := persistence.FindDuplicates("", func(item interface{}) string {
+		return strings.ToLower(item.(Dummy).Key)
+	})
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Len(t, groups[0], 2)
+
+	var mergedIds []interface{}
+	persistence.AddMergeHandler(func(correlationId string, targetId interface{}, ids []interface{}) error {
+		mergedIds = ids
+		return nil
+	})
+
+	result, err := persistence.MergeItems("", []interface{}{dummy1.Id, dummy2.Id}, func(items []interface{}) interface{} {
+		merged := items[0].(Dummy)
+		merged.Content = items[0].(Dummy).Content + items[1].(Dummy).Content
+		return merged
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, dummy1.Id, result.(Dummy).Id)
+	assert.Equal(t, "AB", result.(Dummy).Content)
+	assert.Equal(t, []interface{}{dummy2.Id}, mergedIds)
+
+	count, _ := persistence.GetCountByFilter("", cdata.NewEmptyFilterParams())
+	assert.Equal(t, int64(2), count)
+
+	_, err = persistence.MergeItems("", []interface{}{dummy1.Id, "unknown"}, func(items []interface{}) interface{} {
+		return items[0]
+	})
+	assert.NotNil(t, err)
+}
+
+func TestDummyMemoryPersistenceMergeChecks(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.quota_field", "key",
+		"options.quota_max_items", 1,
+	))
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "A"})
+	persistence.Create("", Dummy{Id: "2", Key: "Key 2", Content: "B"})
+	persistence.Create("", Dummy{Id: "3", Key: "Key 3", Content: "C"})
+	mergeAs := func(key string) func(items []interface{}) interface{} {
+		return func(items []interface{}) interface{} {
+			merged := items[0].(Dummy)
+			merged.Key = key
+			return merged
+		}
+	}
+
+	// Merges that exceed quotas don't change items
+	_, err := persistence.MergeItems("", []interface{}{"1", "2"}, mergeAs("Key 3"))
+	assert.NotNil(t, err)
+	assert.Equal(t, "QUOTA_EXCEEDED", err.(*errors.ApplicationError).Code)
+	item, err := persistence.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.Key)
+
+	// Merged item can take a quota of a removed item
+	persistence.AddMergeHandler(func(correlationId string, targetId interface{}, ids []interface{}) error {
+		return errors.NewInternalError(correlationId, "HANDLER_FAILED", "Failed to reassign references")
+	})
+	_, err = persistence.MergeItems("", []interface{}{"1", "2"}, mergeAs("Key 2"))
+	assert.NotNil(t, err)
+	assert.Equal(t, "HANDLER_FAILED", err.(*errors.ApplicationError).Code)
+	item, err = persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.Key)
+	assert.Equal(t, int64(1), persistence.GetQuotaUsage("Key 2"))
+}
+
+func TestDummyMemoryPersistenceDiff(t *testing.T) {
+	persistence1 := NewDummyMemoryPersistence()
+	persistence2 := NewDummyMemoryPersistence()
+
+	persistence1.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	persistence1.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	persistence2.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 22"})
+	persistence2.Create("", Dummy{Id: "3", Key: "Key 3", Content: "Content 3"})
+
+	diff, err := cpersist.Diff("", persistence1, persistence2)
+	assert.Nil(t, err)
+	assert.Len(t, diff, 3)
+	assert.Equal(t, cpersist.DiffDeleted, diff[0].Change)
+	assert.Equal(t, "1", diff[0].Id)
+	assert.Equal(t, cpersist.DiffUpdated, diff[1].Change)
+	assert.Len(t, diff[1].Fields, 1)
+	assert.Equal(t, "content", diff[1].Fields[0].Field)
+	assert.Equal(t, "Content 22", diff[1].Fields[0].NewValue)
+	assert.Equal(t, cpersist.DiffCreated, diff[2].Change)
+	assert.Equal(t, "3", diff[2].Id)
+}
+
+func TestDummyMemoryPersistenceMergeFrom(t *testing.T) {
+	for _, strategy := range []string{cpersist.MergeStrategyLastWriterWins, cpersist.MergeStrategyFieldLevel} {
+		replica1 := NewDummyMemoryPersistence()
+		replica1.Configure(cconf.NewConfigParamsFromTuples("options.replica_id", "A", "options.merge_strategy", strategy))
+		replica2 := NewDummyMemoryPersistence()
+		replica2.Configure(cconf.NewConfigParamsFromTuples("options.replica_id", "B", "options.merge_strategy", strategy))
+
+		replica1.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+		replica1.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+		err := replica2.MergeFrom("", &replica1.IdentifiableMemoryPersistence)
+		assert.Nil(t, err)
+		dummy, _ := replica2.GetOneById("", "1")
+		assert.Equal(t, "Content 1", dummy.Content)
+
+		// Concurrent offline changes
+		replica1.Update("", Dummy{Id: "1", Key: "Key 1", Content: "Content A"})
+		time.Sleep(5 * time.Millisecond)
+		replica2.Update("", Dummy{Id: "1", Key: "Key B", Content: "Content 1"})
+		replica2.DeleteById("", "2")
+
+		err = replica1.MergeFrom("", &replica2.IdentifiableMemoryPersistence)
+		assert.Nil(t, err)
+		err = replica2.MergeFrom("", &replica1.IdentifiableMemoryPersistence)
+		assert.Nil(t, err)
+
+		dummy1, _ := replica1.GetOneById("", "1")
+		dummy2, _ := replica2.GetOneById("", "1")
+		assert.Equal(t, dummy1, dummy2)
+		assert.Equal(t, "Key B", dummy1.Key)
+		if strategy == cpersist.MergeStrategyFieldLevel {
+			assert.Equal(t, "Content A", dummy1.Content)
+		} else {
+			assert.Equal(t, "Content 1", dummy1.Content)
+		}
+
+		dummy1, _ = replica1.GetOneById("", "2")
+		assert.Equal(t, "", dummy1.Id)
+	}
+}
+
+func TestDummyMemoryPersistenceQuotas(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.quota_field", "key",
+		"options.quota_max_items", 2,
+		"quotas.Key 2", 1,
+	))
+
+	_, err := persistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	dummy, err := persistence.Create("", Dummy{Key: "Key 1", Content: "Content 2"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("", Dummy{Key: "Key 1", Content: "Content 3"})
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(2), persistence.GetQuotaUsage("Key 1"))
+
+	_, err = persistence.Create("", Dummy{Key: "Key 2", Content: "Content 1"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("", Dummy{Key: "Key 2", Content: "Content 2"})
+	assert.NotNil(t, err)
+
+	persistence.DeleteById("", dummy.Id)
+	_, err = persistence.Create("", Dummy{Key: "Key 1", Content: "Content 3"})
+	assert.Nil(t, err)
+}
+
+func TestDummyMemoryPersistenceIdempotentCreate(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.idempotency_ttl", 50))
+
+	result1, err := persistence.CreateIdempotent("", "request1", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	result2, err := persistence.CreateIdempotent("", "request1", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, result1, result2)
+
+	count, _ := persistence.GetCountByFilter("", cdata.NewEmptyFilterParams())
+	assert.Equal(t, int64(1), count)
+
+	time.Sleep(60 * time.Millisecond)
+	result3, err := persistence.CreateIdempotent("", "request1", Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, result1.(Dummy).Id, result3.(Dummy).Id)
+}
+
+func TestDummyMemoryPersistenceIdNormalization(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.id_normalization", "trim, lowercase, uuid"))
+
+	dummy, err := persistence.Create("", Dummy{Id: " ABC ", Key: "Key 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", dummy.Id)
+
+	dummy, err = persistence.GetOneById("", "Abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", dummy.Key)
+
+	_, err = persistence.Create("", Dummy{Id: "abc", Key: "Key 2"})
+	assert.NotNil(t, err)
+
+	dummy, err = persistence.Create("", Dummy{Id: "{6BA7B8109DAD11D180B400C04FD430C8}", Key: "Key 3"})
+	assert.Nil(t, err)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", dummy.Id)
+
+	items, err := persistence.GetListByIds("", []string{"6BA7B810-9DAD-11D1-80B4-00C04FD430C8", "ABC"})
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+
+	dummy, err = persistence.DeleteById("", "ABC ")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", dummy.Id)
+}
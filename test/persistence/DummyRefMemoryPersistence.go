@@ -90,7 +90,7 @@ func (c *DummyRefMemoryPersistence) DeleteByIds(correlationId string, ids []stri
 
 func (c *DummyRefMemoryPersistence) GetPageByFilter(correlationId string, filter *cdata.FilterParams, paging *cdata.PagingParams) (page *DummyRefPage, err error) {
 
-	if &filter == nil {
+	if filter == nil {
 		filter = cdata.NewEmptyFilterParams()
 	}
 
@@ -121,7 +121,7 @@ func (c *DummyRefMemoryPersistence) GetPageByFilter(correlationId string, filter
 
 func (c *DummyRefMemoryPersistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
 
-	if &filter == nil {
+	if filter == nil {
 		filter = cdata.NewEmptyFilterParams()
 	}
 
@@ -0,0 +1,38 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Customer{}))
+	_, err := persistence.Create("", Customer{Id: "1", Name: "John"})
+	assert.Nil(t, err)
+
+	item, etag, err := persistence.GetOneByIdWithETag("", "1")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", etag)
+	assert.Equal(t, etag, cpersist.ComputeETag(item))
+
+	customer := item.(Customer)
+	customer.Name = "Bill"
+	_, err = persistence.UpdateIfMatch("", customer, etag)
+	assert.Nil(t, err)
+
+	// Old ETag doesn't match anymore
+	customer.Name = "Tom"
+	_, err = persistence.UpdateIfMatch("", customer, etag)
+	assert.NotNil(t, err)
+	_, err = persistence.DeleteByIdIfMatch("", "1", "W/"+etag)
+	assert.NotNil(t, err)
+
+	item, _ = persistence.GetOneById("", "1")
+	assert.Equal(t, "Bill", item.(Customer).Name)
+
+	_, err = persistence.DeleteByIdIfMatch("", "1", "*")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,101 @@
+package test_persistence
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Loader and saver that fail with the configured error
+type failingPersister struct {
+	err error
+}
+
+func (c *failingPersister) Load(correlationId string) ([]interface{}, error) {
+	return nil, c.err
+}
+
+func (c *failingPersister) Save(correlationId string, items []interface{}) error {
+	return c.err
+}
+
+func TestJsonFilePersisterErrors(t *testing.T) {
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), "")
+
+	_, err := persister.Load("123")
+	assert.NotNil(t, err)
+	appErr := err.(*cerr.ApplicationError)
+	assert.Equal(t, "NO_PATH", appErr.Code)
+	assert.Equal(t, cerr.Misconfiguration, appErr.Category)
+	assert.Equal(t, "123", appErr.CorrelationId)
+
+	err = persister.Save("123", []interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "NO_PATH", err.(*cerr.ApplicationError).Code)
+
+	filename := "../../data/dummies_invalid.json"
+	defer os.Remove(filename)
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("[{\"id\": \"1\","), 0644))
+
+	persister = cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename)
+	_, err = persister.Load("123")
+	assert.NotNil(t, err)
+	appErr = err.(*cerr.ApplicationError)
+	assert.Equal(t, "PARSE_FAILED", appErr.Code)
+	assert.Equal(t, cerr.FileError, appErr.Category)
+	assert.Equal(t, "123", appErr.CorrelationId)
+	assert.NotEmpty(t, appErr.Cause)
+}
+
+func TestMemoryPersistenceLoadSaveErrors(t *testing.T) {
+	persister := &failingPersister{err: errors.New("disk is full")}
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Loader = persister
+
+	// Plain errors are wrapped into InternalError
+	err := persistence.Open("123")
+	assert.NotNil(t, err)
+	appErr := err.(*cerr.ApplicationError)
+	assert.Equal(t, "LOAD_FAILED", appErr.Code)
+	assert.Equal(t, cerr.Internal, appErr.Category)
+	assert.Equal(t, "123", appErr.CorrelationId)
+	assert.Equal(t, "disk is full", appErr.Cause)
+
+	// Application errors are returned as they are with correlation id
+	persister.err = cerr.NewFileError("", "READ_FAILED", "Failed to read")
+	err = persistence.Open("123")
+	assert.NotNil(t, err)
+	appErr = err.(*cerr.ApplicationError)
+	assert.Equal(t, "READ_FAILED", appErr.Code)
+	assert.Equal(t, "123", appErr.CorrelationId)
+
+	persistence.Loader = nil
+	persistence.Saver = persister
+	persister.err = errors.New("disk is full")
+	assert.Nil(t, persistence.Open("123"))
+	err = persistence.Save("123")
+	assert.NotNil(t, err)
+	appErr = err.(*cerr.ApplicationError)
+	assert.Equal(t, "SAVE_FAILED", appErr.Code)
+	assert.Equal(t, "disk is full", appErr.Cause)
+}
+
+func TestMemoryPersistenceInvalidItem(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	assert.Nil(t, persistence.Open("123"))
+
+	// Pointer to nil pointer can't be copied into the prototype
+	var dummy *Dummy
+	_, err := persistence.Create("123", &dummy)
+	assert.NotNil(t, err)
+	appErr := err.(*cerr.ApplicationError)
+	assert.Equal(t, "INVALID_ITEM", appErr.Code)
+	assert.Equal(t, cerr.BadRequest, appErr.Category)
+	assert.Equal(t, "123", appErr.CorrelationId)
+}
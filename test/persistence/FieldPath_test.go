@@ -0,0 +1,92 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type Customer struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Address Address  `json:"address"`
+	Tags    []string `json:"tags"`
+}
+
+func TestFieldPath(t *testing.T) {
+	customer := Customer{Id: "1", Name: "John", Address: Address{City: "Boston"}, Tags: []string{"vip", "new"}}
+
+	assert.Equal(t, "Boston", cpersist.GetPathValue(customer, "address.city"))
+	assert.Equal(t, "new", cpersist.GetPathValue(customer, "Tags[1]"))
+	assert.Nil(t, cpersist.GetPathValue(customer, "tags[5]"))
+	assert.Nil(t, cpersist.GetPathValue(customer, "address.unknown"))
+
+	doc := map[string]interface{}{"address": map[string]interface{}{"city": "Denver"}}
+	assert.Equal(t, "Denver", cpersist.GetPathValue(doc, "address.City"))
+
+	var item interface{} = customer
+	err := cpersist.SetPathValue(&item, "address.city", "Austin")
+	assert.Nil(t, err)
+	err = cpersist.SetPathValue(&item, "tags[2]", "old")
+	assert.Nil(t, err)
+	assert.Equal(t, "Austin", item.(Customer).Address.City)
+	assert.Equal(t, []string{"vip", "new", "old"}, item.(Customer).Tags)
+	assert.Equal(t, "Boston", customer.Address.City)
+	assert.Len(t, customer.Tags, 2)
+
+	err = cpersist.SetPathValue(&item, "address.unknown", "x")
+	assert.NotNil(t, err)
+
+	projection := cpersist.SelectFields("name", "address.city")
+	assert.Equal(t, map[string]interface{}{"name": "John", "address.city": "Boston"}, projection(customer))
+
+	sortFunc := cpersist.SortByField("address.city", false)
+	assert.True(t, sortFunc(customer, item))
+	assert.False(t, sortFunc(item, customer))
+}
+
+func TestFieldPathFilters(t *testing.T) {
+	customer1 := Customer{Id: "1", Address: Address{City: "Boston"}, Tags: []string{"vip"}}
+	customer2 := Customer{Id: "2", Address: Address{City: "Denver"}}
+
+	filter, err := cpersist.NewFilterBuilder(reflect.TypeOf(Customer{})).
+		Where("address.city").Equals("Boston").
+		And().Where("tags[0]").Equals("vip").
+		Build()
+	assert.Nil(t, err)
+	assert.True(t, filter(customer1))
+	assert.False(t, filter(customer2))
+
+	_, err = cpersist.NewFilterBuilder(reflect.TypeOf(Customer{})).
+		Where("address.unknown").Equals("1").
+		Build()
+	assert.NotNil(t, err)
+
+	filter, err = cpersist.ComposeFilter(reflect.TypeOf(Customer{}),
+		cdata.NewFilterParamsFromTuples("address.city_in", "Denver,Austin"))
+	assert.Nil(t, err)
+	assert.False(t, filter(customer1))
+	assert.True(t, filter(customer2))
+}
+
+func TestUpdatePartiallyByPath(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Customer{}))
+	_, err := persistence.Create("", Customer{Id: "1", Address: Address{City: "Boston", Zip: "02101"}})
+	assert.Nil(t, err)
+
+	result, err := persistence.UpdatePartially("", "1",
+		cdata.NewAnyValueMapFromTuples("name", "John", "address.city", "Austin"))
+	assert.Nil(t, err)
+	customer := result.(Customer)
+	assert.Equal(t, "John", customer.Name)
+	assert.Equal(t, "Austin", customer.Address.City)
+	assert.Equal(t, "02101", customer.Address.Zip)
+}
@@ -0,0 +1,117 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	dummy1 := Dummy{Id: "1", Key: "Key 1", Content: "Content 1"}
+	dummy2 := Dummy{Id: "2", Key: "Key 2", Content: "Content 2"}
+
+	filter, err := cpersist.NewFilterBuilder(reflect.TypeOf(Dummy{})).
+		Where("Key").Equals("Key 1").
+		And().Where("content").StartsWith("Content").
+		Build()
+	assert.Nil(t, err)
+	assert.True(t, filter(dummy1))
+	assert.False(t, filter(dummy2))
+
+	filter, err = cpersist.NewFilterBuilder(reflect.TypeOf(Dummy{})).
+		Where("Id").Equals("1").
+		Or().Where("Id").GreaterThan("1").
+		Build()
+	assert.Nil(t, err)
+	assert.True(t, filter(dummy1))
+	assert.True(t, filter(dummy2))
+
+	_, err = cpersist.NewFilterBuilder(reflect.TypeOf(Dummy{})).
+		Where("Unknown").Equals("1").
+		Build()
+	assert.NotNil(t, err)
+}
+
+func TestTypedFilterBuilder(t *testing.T) {
+	created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	gadget1 := Gadget{Id: "1", Name: "ABC", Price: 10, Count: 40, Active: true, Created: created}
+	gadget2 := Gadget{Id: "2", Name: "XYZ", Price: 20, Count: 20, Created: created.Add(time.Hour)}
+
+	filter, err := NewGadgetFilterBuilder().
+		Where().Name().Equals("ABC").
+		And().Count().GreaterThan(30).
+		Build()
+	assert.Nil(t, err)
+	assert.True(t, filter(gadget1))
+	assert.False(t, filter(gadget2))
+
+	filter, err = NewGadgetFilterBuilder().
+		Where().Active().Equals(true).
+		Or().Created().GreaterThan(created).
+		And().Price().In(15, 20).
+		Build()
+	assert.Nil(t, err)
+	assert.True(t, filter(gadget1))
+	assert.True(t, filter(gadget2))
+
+	filter, err = NewGadgetFilterBuilder().
+		Where().Name().StartsWith("X").
+		And().Id().NotEquals("1").
+		Build()
+	assert.Nil(t, err)
+	assert.False(t, filter(gadget1))
+	assert.True(t, filter(gadget2))
+}
+
+func TestComposeFilter(t *testing.T) {
+	dummy1 := Dummy{Id: "1", Key: "Key 1", Content: "Content 1"}
+	dummy2 := Dummy{Id: "2", Key: "Key 2", Content: "Content 2"}
+
+	filter, err := cpersist.ComposeFilter(reflect.TypeOf(Dummy{}),
+		cdata.NewFilterParamsFromTuples("key_in", "Key 1,Key 3", "content_starts", "Content"))
+	assert.Nil(t, err)
+	assert.True(t, filter(dummy1))
+	assert.False(t, filter(dummy2))
+
+	filter, err = cpersist.ComposeFilter(reflect.TypeOf(Dummy{}),
+		cdata.NewFilterParamsFromTuples("id_gte", "2"))
+	assert.Nil(t, err)
+	assert.False(t, filter(dummy1))
+	assert.True(t, filter(dummy2))
+
+	_, err = cpersist.ComposeFilter(reflect.TypeOf(Dummy{}),
+		cdata.NewFilterParamsFromTuples("unknown", "2"))
+	assert.NotNil(t, err)
+}
+
+func TestCollatedFilter(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.collation", "ignore_case,ignore_diacritics"))
+	persistence.Create("", Dummy{Id: "1", Key: "José", Content: "Crème brûlée"})
+	persistence.Create("", Dummy{Id: "2", Key: "Zoë", Content: "Smørrebrød"})
+
+	items, err := persistence.IdentifiableMemoryPersistence.GetListByFilterParams("",
+		cdata.NewFilterParamsFromTuples("key", "jose"), nil)
+	assert.Nil(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "1", items[0].(Dummy).Id)
+
+	items, err = persistence.IdentifiableMemoryPersistence.GetListByFilterParams("",
+		cdata.NewFilterParamsFromTuples("content_contains", "BRULEE"), nil)
+	assert.Nil(t, err)
+	assert.Len(t, items, 1)
+
+	items, err = persistence.IdentifiableMemoryPersistence.GetListByFilterParams("",
+		cdata.NewFilterParamsFromTuples("key_in", "jose,zoe"), nil)
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+
+	filter, err := cpersist.ComposeFilter(reflect.TypeOf(Dummy{}), cdata.NewFilterParamsFromTuples("key", "jose"))
+	assert.Nil(t, err)
+	assert.False(t, filter(Dummy{Id: "1", Key: "José"}))
+}
@@ -0,0 +1,104 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPartitionedEvents(t *testing.T) (*cpersist.IdentifiableMemoryPersistence, time.Time) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Event{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.timestamp_field", "time",
+		"options.partition_period", 60*60*1000,
+	))
+
+	// Events are created out of time order to check that queries keep the order of items
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, hour := range []int{5, 0, 1, 4, 2, 3, 5, 0} {
+		name := "Even"
+		if hour%2 == 1 {
+			name = "Odd"
+		}
+		_, err := persistence.Create("", Event{Name: name, Time: start.Add(time.Duration(hour) * time.Hour)})
+		assert.Nil(t, err)
+	}
+	return persistence, start
+}
+
+func TestExplainIndexedFilter(t *testing.T) {
+	persistence, start := newPartitionedEvents(t)
+	filter := cdata.NewFilterParamsFromTuples(
+		"time_gte", start.Add(4*time.Hour).Format(time.RFC3339),
+		"name", "Odd",
+	)
+
+	plan, err := persistence.ExplainFilter("", filter)
+	assert.Nil(t, err)
+	assert.True(t, plan.IndexUsed)
+	assert.Equal(t, cpersist.IndexTimePartitions, plan.Index)
+	assert.Equal(t, int64(8), plan.TotalItems)
+	assert.Equal(t, int64(3), plan.ScannedItems)
+	assert.Equal(t, int64(2), plan.MatchedItems)
+	assert.Len(t, plan.Predicates, 2)
+
+	// Queries select the same items by the index in the order of items
+	page, err := persistence.GetPageByFilterParams("", filter, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+	for _, item := range page.Data {
+		assert.Equal(t, start.Add(5*time.Hour), item.(Event).Time)
+	}
+	items, err := persistence.GetListByFilterParams("", cdata.NewFilterParamsFromTuples(
+		"time_gt", start.Add(3*time.Hour).Format(time.RFC3339)), nil)
+	assert.Nil(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, start.Add(5*time.Hour), items[0].(Event).Time)
+	assert.Equal(t, start.Add(4*time.Hour), items[1].(Event).Time)
+	count, err := persistence.GetCountByFilterParams("", cdata.NewFilterParamsFromTuples(
+		"time", start.Format(time.RFC3339)))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Changed items are found after partitions are rebuilt
+	_, err = persistence.Create("", Event{Name: "Odd", Time: start.Add(6 * time.Hour)})
+	assert.Nil(t, err)
+	plan, _ = persistence.ExplainFilter("", filter)
+	assert.Equal(t, int64(4), plan.ScannedItems)
+	count, _ = persistence.GetCountByFilterParams("", filter)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestExplainUnindexedFilter(t *testing.T) {
+	persistence, start := newPartitionedEvents(t)
+
+	// Filters without lower limit of the timestamp scan all items
+	for _, filter := range []*cdata.FilterParams{
+		cdata.NewFilterParamsFromTuples("name", "Odd"),
+		cdata.NewFilterParamsFromTuples("time_lt", start.Add(2*time.Hour).Format(time.RFC3339)),
+	} {
+		plan, err := persistence.ExplainFilter("", filter)
+		assert.Nil(t, err)
+		assert.False(t, plan.IndexUsed)
+		assert.Equal(t, "", plan.Index)
+		assert.Equal(t, int64(8), plan.ScannedItems)
+	}
+
+	plan, err := persistence.ExplainFilter("", cdata.NewFilterParamsFromTuples("name", "Odd"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), plan.MatchedItems)
+
+	// Persistence without partitions can't use the index
+	plain := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Event{}))
+	plain.Create("", Event{Name: "Odd", Time: start})
+	plan, err = plain.ExplainFilter("", cdata.NewFilterParamsFromTuples("time_gte", start.Format(time.RFC3339)))
+	assert.Nil(t, err)
+	assert.False(t, plan.IndexUsed)
+	assert.Equal(t, int64(1), plan.ScannedItems)
+	assert.Equal(t, int64(1), plan.MatchedItems)
+}
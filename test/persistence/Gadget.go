@@ -0,0 +1,15 @@
+package test_persistence
+
+import "time"
+
+//go:generate go run github.com/pip-services3-go/pip-services3-data-go/cmd/persistencegen -type=Gadget
+
+// Data struct with typed persistence generated by persistencegen
+type Gadget struct {
+	Id      string    `json:"id"`
+	Name    string    `json:"name"`
+	Price   float64   `json:"price"`
+	Count   int       `json:"count"`
+	Active  bool      `json:"active"`
+	Created time.Time `json:"created"`
+}
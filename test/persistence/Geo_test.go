@@ -0,0 +1,75 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Place struct {
+	Id   string  `json:"id"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+func TestGeoDistance(t *testing.T) {
+	london := cpersist.NewGeoPoint(51.5074, -0.1278)
+	paris := cpersist.NewGeoPoint(48.8566, 2.3522)
+
+	distance := cpersist.GeoDistance(london, paris)
+	assert.InDelta(t, 343500, distance, 2000)
+
+	assert.Equal(t, "gcpvj", cpersist.GeoHash(london, 5))
+
+	box := cpersist.NewGeoBoundingBoxAround(london, 10000)
+	assert.True(t, box.Contains(london))
+	assert.False(t, box.Contains(paris))
+}
+
+func testGeoQueries(t *testing.T, indexPrecision int) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Place{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.latitude_field", "lat",
+		"options.longitude_field", "lon",
+		"options.geo_index_precision", indexPrecision,
+	))
+
+	persistence.Create("", Place{Id: "1", Name: "Big Ben", Lat: 51.5007, Lon: -0.1246})
+	persistence.Create("", Place{Id: "2", Name: "Tower Bridge", Lat: 51.5055, Lon: -0.0754})
+	persistence.Create("", Place{Id: "3", Name: "Eiffel Tower", Lat: 48.8584, Lon: 2.2945})
+
+	center := cpersist.NewGeoPoint(51.5074, -0.1278)
+	page, err := persistence.GetPageByNear("", center, 5000, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, "Big Ben", page.Data[0].(Place).Name)
+	assert.Equal(t, "Tower Bridge", page.Data[1].(Place).Name)
+
+	page, err = persistence.GetPageByNear("", center, 1000, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+
+	persistence.Create("", Place{Id: "4", Name: "Trafalgar Square", Lat: 51.5080, Lon: -0.1281})
+	page, err = persistence.GetPageByNear("", center, 1000, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, "Trafalgar Square", page.Data[0].(Place).Name)
+
+	box := cpersist.NewGeoBoundingBox(cpersist.NewGeoPoint(48, 2), cpersist.NewGeoPoint(49, 3))
+	page, err = persistence.GetPageByBoundingBox("", box, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "Eiffel Tower", page.Data[0].(Place).Name)
+}
+
+func TestGeoQueries(t *testing.T) {
+	testGeoQueries(t, 0)
+}
+
+func TestGeoQueriesWithIndex(t *testing.T) {
+	testGeoQueries(t, 3)
+}
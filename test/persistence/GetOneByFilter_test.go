@@ -0,0 +1,46 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOneByFilter(t *testing.T) {
+	contacts := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Contact{}))
+	contacts.Create("", Contact{Id: "1", Name: "Alice", Email: "alice@example.com"})
+	contacts.Create("", Contact{Id: "2", Name: "Bob", Email: "bob@example.com"})
+	contacts.Create("", Contact{Id: "3", Name: "Bob", Email: "bob2@example.com"})
+
+	byEmail := func(email string) func(interface{}) bool {
+		return func(item interface{}) bool { return item.(Contact).Email == email }
+	}
+	byName := func(item interface{}) bool { return item.(Contact).Name == "Bob" }
+
+	item, err := contacts.GetOneByFilter("", byEmail("bob@example.com"))
+	assert.Nil(t, err)
+	assert.Equal(t, "2", item.(Contact).Id)
+
+	item, err = contacts.GetOneByFilter("", byEmail("nobody@example.com"))
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	item, err = contacts.GetOneByFilter("", byName)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", item.(Contact).Id)
+
+	contacts.Configure(config.NewConfigParamsFromTuples("options.multiple_error", true))
+
+	item, err = contacts.GetOneByFilter("", byEmail("alice@example.com"))
+	assert.Nil(t, err)
+	assert.Equal(t, "1", item.(Contact).Id)
+
+	item, err = contacts.GetOneByFilter("", byName)
+	assert.Nil(t, item)
+	assert.NotNil(t, err)
+	assert.Equal(t, "MULTIPLE_ITEMS", err.(*errors.ApplicationError).Code)
+}
@@ -0,0 +1,155 @@
+package test_persistence
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/pip-services3-go/pip-services3-data-go/protos"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// In-process DatasetService with datasets kept in memory
+type datasetService struct {
+	protos.UnimplementedDatasetServiceServer
+	lock     sync.Mutex
+	datasets map[string][]*protos.DatasetItem
+	fail     bool
+}
+
+func (c *datasetService) Fetch(request *protos.FetchRequest, stream protos.DatasetService_FetchServer) error {
+	c.lock.Lock()
+	items, fail := c.datasets[request.Dataset], c.fail
+	c.lock.Unlock()
+	if fail {
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	for _, item := range items {
+		if err := stream.Send(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *datasetService) Push(stream protos.DatasetService_PushServer) error {
+	var dataset string
+	items := []*protos.DatasetItem{}
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		dataset = request.Dataset
+		items = append(items, request.Items...)
+	}
+	c.lock.Lock()
+	c.datasets[dataset] = items
+	c.lock.Unlock()
+	return stream.SendAndClose(&protos.PushReply{Count: int64(len(items))})
+}
+
+// Starts the service on an in-memory listener and connects a client to it
+func startDatasetService(t *testing.T) (*datasetService, protos.DatasetServiceClient) {
+	service := &datasetService{datasets: map[string][]*protos.DatasetItem{}}
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	protos.RegisterDatasetServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return service, protos.NewDatasetServiceClient(conn)
+}
+
+func TestGrpcPersister(t *testing.T) {
+	service, client := startDatasetService(t)
+	fields, err := structpb.NewStruct(map[string]interface{}{"id": "1", "key": "Key 1", "content": "Content 1"})
+	assert.Nil(t, err)
+	service.datasets["dummies"] = []*protos.DatasetItem{{Id: "1", Fields: fields}}
+
+	persister := cpersist.NewGrpcPersister(client, "dummies")
+	persister.BatchSize = 2
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Loader = persister
+	persistence.Saver = persister
+
+	// Memory persistence is seeded from the service
+	assert.Nil(t, persistence.Open(""))
+	item, err := persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", item.(Dummy).Key)
+
+	// And checkpointed back to it
+	for _, id := range []string{"2", "3", "4"} {
+		_, err = persistence.Create("", Dummy{Id: id, Key: "Key " + id})
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, persistence.Close(""))
+	assert.Len(t, service.datasets["dummies"], 4)
+	assert.Equal(t, "4", service.datasets["dummies"][3].Id)
+
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 4)
+	assert.Equal(t, "Key 4", loaded[3].(map[string]interface{})["key"])
+
+	// Pushing no items clears the dataset
+	assert.Nil(t, persister.Save("", []interface{}{}))
+	assert.Len(t, service.datasets["dummies"], 0)
+
+	service.lock.Lock()
+	service.fail = true
+	service.lock.Unlock()
+	_, err = persister.Load("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "FETCH_FAILED", err.(*cerr.ApplicationError).Code)
+}
+
+func TestGrpcPersisterItemEncoding(t *testing.T) {
+	service, client := startDatasetService(t)
+	persister := cpersist.NewGrpcPersister(client, "items")
+
+	item := map[string]interface{}{
+		"id":      "1",
+		"count":   float64(12.5),
+		"active":  true,
+		"removed": false,
+		"note":    nil,
+		"empty":   "",
+		"tags":    []interface{}{"a", float64(2), nil},
+		"address": map[string]interface{}{"city": "Kyiv", "lines": []interface{}{}},
+	}
+	assert.Nil(t, persister.Save("", []interface{}{item}))
+	assert.Equal(t, "1", service.datasets["items"][0].Id)
+
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{item}, loaded)
+
+	// Items must be objects
+	err = persister.Save("", []interface{}{"value"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_ITEM", err.(*cerr.ApplicationError).Code)
+}
@@ -0,0 +1,109 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Third-party struct with the key in a tagged field
+type Invoice struct {
+	Number string  `json:"number" pip:"id"`
+	Amount float64 `json:"amount"`
+}
+
+// Third-party struct with the key set by configuration
+type Sku struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func TestIdFieldTag(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Invoice{}))
+	persistence.Open("")
+	defer persistence.Close("")
+
+	created, err := persistence.Create("", Invoice{Number: "INV-1", Amount: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, "INV-1", created.(Invoice).Number)
+
+	item, err := persistence.GetOneById("", "INV-1")
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, item.(Invoice).Amount)
+
+	_, err = persistence.Update("", Invoice{Number: "INV-1", Amount: 20})
+	assert.Nil(t, err)
+	item, _ = persistence.GetOneById("", "INV-1")
+	assert.Equal(t, 20.0, item.(Invoice).Amount)
+
+	// Empty ids are generated into the tagged field
+	created, err = persistence.Create("", Invoice{Amount: 5})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.(Invoice).Number)
+
+	deleted, err := persistence.DeleteById("", "INV-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "INV-1", deleted.(Invoice).Number)
+	assert.Equal(t, "INV-1", cpersist.GetObjectId(&Invoice{Number: "INV-1"}))
+}
+
+func TestIdFieldOption(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Sku{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.id_field", "code"))
+	persistence.Open("")
+	defer persistence.Close("")
+
+	_, err := persistence.Create("", Sku{Code: "A-1", Name: "Apple"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("", Sku{Code: "A-1", Name: "Apricot"})
+	assert.NotNil(t, err)
+
+	item, err := persistence.GetOneById("", "A-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Apple", item.(Sku).Name)
+
+	assert.NotNil(t, cpersist.RegisterIdField(reflect.TypeOf(Sku{}), "missing"))
+	assert.NotNil(t, cpersist.RegisterIdField(reflect.TypeOf(map[string]interface{}{}), "code"))
+}
+
+// Struct with Id field that some persistence components key by login
+type Account struct {
+	Id    string `json:"id"`
+	Login string `json:"login"`
+}
+
+func TestIdFieldOptionPerInstance(t *testing.T) {
+	byLogin := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Account{}))
+	byLogin.Configure(cconf.NewConfigParamsFromTuples("options.id_field", "login"))
+	byLogin.Loader = &staticLoader{items: []interface{}{
+		map[string]interface{}{"id": "1", "login": "alice"},
+	}}
+	assert.Nil(t, byLogin.Open(""))
+	defer byLogin.Close("")
+
+	byId := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Account{}))
+	assert.Nil(t, byId.Open(""))
+	defer byId.Close("")
+
+	// Loaded maps are keyed by the json name of the field
+	item, err := byLogin.GetOneById("", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", item.(Account).Id)
+	_, err = byLogin.Create("", Account{Id: "2", Login: "bob"})
+	assert.Nil(t, err)
+	item, _ = byLogin.GetOneById("", "bob")
+	assert.Equal(t, "2", item.(Account).Id)
+
+	// Other instances of the same type keep using Id field
+	_, err = byId.Create("", Account{Id: "1", Login: "carol"})
+	assert.Nil(t, err)
+	item, err = byId.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "carol", item.(Account).Login)
+	item, _ = byId.GetOneById("", "carol")
+	assert.Nil(t, item)
+	assert.Equal(t, "1", cpersist.GetObjectId(Account{Id: "1", Login: "carol"}))
+}
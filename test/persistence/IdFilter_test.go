@@ -0,0 +1,76 @@
+package test_persistence
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdFilter(t *testing.T) {
+	dummies := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	dummies.Configure(cconf.NewConfigParamsFromTuples(
+		"options.id_filter", true,
+		"options.not_found_error", true,
+	))
+	dummies.Create("", Dummy{Id: "1", Key: "Key 1"})
+
+	exists, err := dummies.Exists("", "1")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	exists, err = dummies.Exists("", "2")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	_, err = dummies.GetOneById("", "2")
+	assert.NotNil(t, err)
+
+	for i := 2; i <= 300; i++ {
+		dummies.Create("", Dummy{Id: strconv.Itoa(i), Key: "Key"})
+	}
+	for i := 1; i <= 300; i++ {
+		exists, _ = dummies.Exists("", strconv.Itoa(i))
+		if !exists {
+			t.Fatalf("Item %d was not found", i)
+		}
+	}
+
+	misses := 0
+	for i := 301; i <= 1300; i++ {
+		if exists, _ = dummies.Exists("", strconv.Itoa(i)); exists {
+			misses++
+		}
+	}
+	// Bloom filter may rarely report missing ids as possible, then items are scanned
+	assert.True(t, misses < 50)
+
+	dummies.DeleteById("", "1")
+	_, err = dummies.GetOneById("", "1")
+	assert.NotNil(t, err)
+
+	item, err := dummies.Update("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.(Dummy).Key)
+}
+
+func TestIdFilterDirectChanges(t *testing.T) {
+	dummies := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	dummies.Configure(cconf.NewConfigParamsFromTuples("options.id_filter", true))
+	dummies.Create("", Dummy{Id: "1", Key: "Key 1"})
+
+	// Items changed directly are found after they are saved
+	dummies.Lock.Lock()
+	dummies.Items = append(dummies.Items, Dummy{Id: "2", Key: "Key 2"})
+	dummies.Lock.Unlock()
+	assert.Nil(t, dummies.Save(""))
+
+	exists, err := dummies.Exists("", "2")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	item, err := dummies.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.(Dummy).Key)
+}
@@ -0,0 +1,64 @@
+package test_persistence
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInboxPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	clock := persistence.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	inbox := persistence.NewInboxPersistence(path)
+	inbox.Clock = clock
+	assert.Nil(t, inbox.Open(""))
+	defer inbox.Close("")
+
+	for _, id := range []string{"1", "2", "1"} {
+		_, err := inbox.Receive("", persistence.InboxMessage{Id: id, Type: "event"})
+		assert.Nil(t, err)
+	}
+	received, err := inbox.Receive("", persistence.InboxMessage{Id: "3"})
+	assert.Nil(t, err)
+	assert.True(t, received)
+	received, err = inbox.Receive("", persistence.InboxMessage{Id: "3"})
+	assert.Nil(t, err)
+	assert.False(t, received)
+
+	messages, err := inbox.FetchPending("", 2, 60000)
+	assert.Nil(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "1", messages[0].Id)
+	assert.Equal(t, 1, messages[0].Attempts)
+
+	assert.Nil(t, inbox.Ack("", "1"))
+	assert.Nil(t, inbox.Fail("", "2", errors.New("boom")))
+
+	// Unacknowledged messages are delivered again after the lock expires
+	messages, err = inbox.FetchPending("", 10, 60000)
+	assert.Nil(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "3", messages[0].Id)
+	clock.Advance(2 * time.Minute)
+	messages, err = inbox.FetchPending("", 10, 60000)
+	assert.Nil(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.Nil(t, inbox.Ack("", "3"))
+
+	count, err := inbox.ReprocessFailed("")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	messages, err = inbox.FetchPending("", 10, 60000)
+	assert.Nil(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "2", messages[0].Id)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.Equal(t, "boom", messages[0].Error)
+
+	assert.NotNil(t, inbox.Ack("", "missing"))
+}
@@ -0,0 +1,105 @@
+package test_persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIndexedPlaces(path string, logger *captureLogger) *cpersist.IdentifiableFilePersistence {
+	prototype := reflect.TypeOf(Place{})
+	places := cpersist.NewIdentifiableFilePersistence(prototype, cpersist.NewJsonFilePersister(prototype, path))
+	places.Configure(cconf.NewConfigParamsFromTuples(
+		"options.latitude_field", "lat",
+		"options.longitude_field", "lon",
+		"options.geo_index_precision", 3,
+		"options.persist_indexes", true,
+	))
+	places.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "logger", "capture", "default", "1.0"), logger,
+	))
+	return places
+}
+
+func restoredIndexes(logger *captureLogger) bool {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	for _, message := range logger.messages {
+		if strings.Contains(message, "Restored indexes") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPersistIndexes(t *testing.T) {
+	path := "../../data/places_indexes.json"
+	defer os.Remove(path)
+	defer os.Remove(path + ".indexes")
+	box := cpersist.GeoBoundingBox{MinLatitude: 40, MinLongitude: -75, MaxLatitude: 41, MaxLongitude: -73}
+
+	places := newIndexedPlaces(path, &captureLogger{})
+	assert.Nil(t, places.Open(""))
+	places.Create("", Place{Id: "1", Name: "New York", Lat: 40.71, Lon: -74.0})
+	places.Create("", Place{Id: "2", Name: "London", Lat: 51.5, Lon: -0.12})
+	assert.Nil(t, places.Close(""))
+	info, err := os.Stat(path + ".indexes")
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	logger := &captureLogger{}
+	places = newIndexedPlaces(path, logger)
+	assert.Nil(t, places.Open(""))
+	assert.True(t, restoredIndexes(logger))
+	page, err := places.GetPageByBoundingBox("", box, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Nil(t, places.Close(""))
+
+	// Indexes of a changed data file are rebuilt
+	buffer, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, []byte(strings.Replace(string(buffer), "40.71", "50.71", 1)), 0777)
+	logger = &captureLogger{}
+	places = newIndexedPlaces(path, logger)
+	assert.Nil(t, places.Open(""))
+	assert.False(t, restoredIndexes(logger))
+	page, err = places.GetPageByBoundingBox("", box, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 0)
+	places.Close("")
+}
+
+func TestMoveDataFileWithIndexes(t *testing.T) {
+	path := "../../data/places_move.json"
+	newPath := "../../data/moved_places/places.json"
+	defer os.Remove(path)
+	defer os.Remove(path + ".indexes")
+	defer os.RemoveAll("../../data/moved_places")
+
+	places := newIndexedPlaces(path, &captureLogger{})
+	assert.Nil(t, places.Open(""))
+	places.Create("", Place{Id: "1", Name: "New York", Lat: 40.71, Lon: -74.0})
+	assert.Nil(t, places.Close(""))
+
+	places = newIndexedPlaces(path, &captureLogger{})
+	assert.Nil(t, places.Open(""))
+	assert.Nil(t, places.MoveDataFile("", newPath))
+	assert.Nil(t, places.Close(""))
+	_, err := os.Stat(path + ".indexes")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(newPath + ".indexes")
+	assert.Nil(t, err)
+
+	logger := &captureLogger{}
+	places = newIndexedPlaces(newPath, logger)
+	assert.Nil(t, places.Open(""))
+	assert.True(t, restoredIndexes(logger))
+	places.Close("")
+}
@@ -0,0 +1,62 @@
+package test_persistence
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebuildIndexes(t *testing.T) {
+	sales := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Sale{}))
+	sales.Configure(config.NewConfigParamsFromTuples(
+		"rollups.by_region.group_by", "region",
+		"rollups.by_region.sum", "amount",
+		"options.read_snapshot", true,
+	))
+	var lock sync.Mutex
+	reports := []persistence.IndexRebuildProgress{}
+	sales.IndexProgressCallback = func(correlationId string, progress persistence.IndexRebuildProgress) {
+		lock.Lock()
+		reports = append(reports, progress)
+		lock.Unlock()
+	}
+	assert.Nil(t, sales.Open(""))
+	defer sales.Close("")
+
+	// Bulk import that bypasses change handlers
+	sales.Lock.Lock()
+	sales.Items = append(sales.Items, Sale{Id: "1", Region: "east", Amount: 10}, Sale{Id: "2", Region: "west", Amount: 5})
+	sales.Lock.Unlock()
+
+	rebuild := sales.RebuildIndexes("")
+	rebuild.Wait()
+
+	progress := rebuild.Progress()
+	assert.True(t, progress.Done)
+	assert.Equal(t, 2, progress.Total)
+	assert.Equal(t, 2, progress.Rebuilt)
+
+	lock.Lock()
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "rollup:by_region", reports[0].Index)
+	assert.Equal(t, "read_snapshot", reports[1].Index)
+	lock.Unlock()
+
+	rollup, err := sales.GetRollup("", "by_region")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), rollup["east"].Count)
+	assert.Equal(t, 5.0, rollup["west"].Sums["amount"])
+	assert.Equal(t, 2, sales.GetReadSnapshot().Len())
+}
+
+func TestRebuildIndexesWithoutIndexes(t *testing.T) {
+	dummies := NewDummyMemoryPersistence()
+	rebuild := dummies.RebuildIndexes("")
+	<-rebuild.Done()
+	assert.True(t, rebuild.Progress().Done)
+	assert.Equal(t, 0, rebuild.Progress().Total)
+}
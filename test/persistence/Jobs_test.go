@@ -0,0 +1,56 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobsFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := persistence.NewManualClock(now)
+	jobs := persistence.NewJobsFilePersistence(path)
+	jobs.Clock = clock
+	assert.Nil(t, jobs.Open(""))
+	defer jobs.Close("")
+
+	_, err := jobs.Create("", persistence.Job{Id: "1", Type: "report", NextRun: now.Add(time.Minute)})
+	assert.Nil(t, err)
+	_, err = jobs.Create("", persistence.Job{Id: "2", Type: "cleanup", NextRun: now, Interval: 3600000})
+	assert.Nil(t, err)
+
+	due, err := jobs.FetchDue("", now, 60000)
+	assert.Nil(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, "2", due[0].Id)
+
+	// Claimed jobs are not fetched again until their locks expire
+	due, err = jobs.FetchDue("", now.Add(30*time.Second), 60000)
+	assert.Nil(t, err)
+	assert.Len(t, due, 0)
+	due, err = jobs.FetchDue("", now.Add(2*time.Minute), 60000)
+	assert.Nil(t, err)
+	assert.Len(t, due, 2)
+	assert.Equal(t, "2", due[0].Id)
+	assert.Equal(t, "1", due[1].Id)
+
+	clock.Advance(2 * time.Minute)
+	assert.Nil(t, jobs.CompleteJob("", "1"))
+	assert.Nil(t, jobs.CompleteJob("", "2"))
+
+	list, err := jobs.GetJobs("", cdata.NewFilterParamsFromTuples("type", "cleanup"))
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, now.Add(time.Hour), list[0].NextRun)
+	assert.True(t, list[0].LockedUntil.IsZero())
+
+	list, err = jobs.GetJobs("", nil)
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.NotNil(t, jobs.CompleteJob("", "1"))
+}
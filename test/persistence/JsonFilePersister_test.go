@@ -1,7 +1,12 @@
 package test_persistence
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 
 	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
@@ -17,3 +22,136 @@ func TestJsonFilePersister(t *testing.T) {
 	persistence.Configure(cconf.NewConfigParamsFromTuples("path", fileName))
 	assert.Equal(t, fileName, persistence.Path())
 }
+
+func TestJsonFilePersisterProgress(t *testing.T) {
+	filename := "../../data/dummies_progress.json"
+	defer os.Remove(filename)
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename)
+	persister.ProgressInterval = 2
+	reports := []cpersist.PersisterProgress{}
+	persister.ProgressCallback = func(correlationId string, progress cpersist.PersisterProgress) {
+		reports = append(reports, progress)
+	}
+
+	items := []interface{}{
+		Dummy{Id: "1", Key: "Key 1"}, Dummy{Id: "2", Key: "Key 2"}, Dummy{Id: "3", Key: "Key 3"},
+	}
+	err := persister.Save("", items)
+	assert.Nil(t, err)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, int64(2), reports[0].Items)
+	assert.True(t, reports[1].Done)
+	assert.Equal(t, int64(3), reports[1].Items)
+
+	reports = reports[:0]
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 3)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, cpersist.PersisterOperationLoad, reports[1].Operation)
+	assert.Equal(t, reports[1].TotalBytes, reports[1].Bytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = persister.LoadWithContext(ctx, "")
+	assert.NotNil(t, err)
+	err = persister.SaveWithContext(ctx, "", items[:1])
+	assert.NotNil(t, err)
+	loaded, _ = persister.Load("")
+	assert.Len(t, loaded, 3)
+}
+
+func TestJsonFilePersisterChunkedSave(t *testing.T) {
+	filename := "../../data/dummies_chunked.json"
+	defer os.Remove(filename)
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename)
+	persister.Configure(cconf.NewConfigParamsFromTuples("options.save_chunk_size", 16))
+	assert.Equal(t, 16, persister.ChunkSize)
+
+	items := []interface{}{}
+	for i := 0; i < 100; i++ {
+		items = append(items, Dummy{Id: strconv.Itoa(i), Key: "Key", Content: "Content"})
+	}
+	err := persister.Save("", items)
+	assert.Nil(t, err)
+
+	temps, err := filepath.Glob(filename + ".*.tmp")
+	assert.Nil(t, err)
+	assert.Len(t, temps, 0)
+
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 100)
+	assert.Equal(t, "99", loaded[99].(map[string]interface{})["id"])
+}
+
+func TestJsonFilePersisterPermissions(t *testing.T) {
+	dir := "../../data/secure"
+	filename := dir + "/dummies.json"
+	defer os.RemoveAll(dir)
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename)
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"options.file_mode", "0600",
+		"options.dir_mode", "0700",
+	))
+	assert.Equal(t, os.FileMode(0600), persister.FileMode)
+
+	err := persister.Save("", []interface{}{Dummy{Id: "1", Key: "Key 1"}})
+	assert.Nil(t, err)
+
+	info, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(dir)
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestJsonFilePersisterConcurrentWriters(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dummies.json")
+
+	// Writers don't share temporary files, so every save replaces the file with complete items
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename)
+			for i := 0; i < 10; i++ {
+				errs <- persister.Save("", []interface{}{Dummy{Id: strconv.Itoa(w), Key: "Key"}})
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	loaded, err := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename).Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 1)
+	temps, _ := filepath.Glob(filename + ".*.tmp")
+	assert.Len(t, temps, 0)
+}
+
+func TestJsonFilePersisterKeepsFileMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dummies.json")
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), filename)
+
+	assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "1", Key: "Key 1"}}))
+	info, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Replaced files keep their mode
+	assert.Nil(t, os.Chmod(filename, 0640))
+	assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "2", Key: "Key 2"}}))
+	info, err = os.Stat(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
@@ -0,0 +1,79 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	ccache "github.com/pip-services3-go/pip-services3-components-go/cache"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Key-value store that counts reads
+type countingCache struct {
+	*ccache.MemoryCache
+	reads int
+}
+
+func (c *countingCache) Retrieve(correlationId string, key string) (interface{}, error) {
+	c.reads++
+	return c.MemoryCache.Retrieve(correlationId, key)
+}
+
+func TestLazyKeyValuePersistence(t *testing.T) {
+	store := &countingCache{MemoryCache: ccache.NewMemoryCache()}
+	persistence := cpersist.NewLazyKeyValuePersistence(reflect.TypeOf(Dummy{}), nil)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.max_cached_items", 2))
+	persistence.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "cache", "memory", "default", "1.0"), store,
+	))
+	assert.Nil(t, persistence.Open(""))
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := persistence.Create("", Dummy{Id: id, Key: "Key " + id})
+		assert.Nil(t, err)
+	}
+	value, err := store.MemoryCache.Retrieve("", "dummy:1")
+	assert.Nil(t, err)
+	assert.Contains(t, value, "Key 1")
+	// The least recently used item is evicted
+	assert.Equal(t, 2, persistence.CachedCount())
+
+	// Nothing is loaded when the persistence is reopened
+	assert.Nil(t, persistence.Close(""))
+	assert.Nil(t, persistence.Open(""))
+	assert.Equal(t, 0, persistence.CachedCount())
+	assert.Equal(t, 0, store.reads)
+
+	// Items are loaded on the first access and then read from memory
+	item, err := persistence.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.(Dummy).Key)
+	_, err = persistence.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, store.reads)
+
+	item, err = persistence.GetOneById("", "4")
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	item, err = persistence.Update("", Dummy{Id: "1", Key: "Key 1", Content: "Updated"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", item.(Dummy).Content)
+	item, err = persistence.Update("", Dummy{Id: "5", Key: "Key 5"})
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	item, err = persistence.DeleteById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", item.(Dummy).Content)
+	item, err = persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	// The store is required
+	persistence = cpersist.NewLazyKeyValuePersistence(reflect.TypeOf(Dummy{}), nil)
+	assert.NotNil(t, persistence.Open(""))
+}
@@ -0,0 +1,53 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeasePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leases.json")
+	clock := persistence.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	leases1 := persistence.NewLeasePersistence(path)
+	leases1.Clock = clock
+	assert.Nil(t, leases1.Open(""))
+	defer leases1.Close("")
+	leases2 := persistence.NewLeasePersistence(path)
+	leases2.Clock = clock
+	assert.Nil(t, leases2.Open(""))
+	defer leases2.Close("")
+
+	lease, err := leases1.AcquireLease("", "scheduler", "instance1", 10000)
+	assert.Nil(t, err)
+	assert.Equal(t, "instance1", lease.Owner)
+
+	// Another instance sees the lease in the shared file
+	_, err = leases2.AcquireLease("", "scheduler", "instance2", 10000)
+	assert.NotNil(t, err)
+	lease, err = leases2.GetLease("", "scheduler")
+	assert.Nil(t, err)
+	assert.Equal(t, "instance1", lease.Owner)
+
+	clock.Advance(5 * time.Second)
+	lease, err = leases1.RenewLease("", "scheduler", "instance1", 10000)
+	assert.Nil(t, err)
+	assert.Equal(t, clock.Now().Add(10*time.Second), lease.ExpireTime)
+	assert.NotNil(t, leases2.ReleaseLease("", "scheduler", "instance2"))
+
+	// Expired lease can be taken by another owner
+	clock.Advance(11 * time.Second)
+	_, err = leases1.RenewLease("", "scheduler", "instance1", 10000)
+	assert.NotNil(t, err)
+	lease, err = leases2.AcquireLease("", "scheduler", "instance2", 10000)
+	assert.Nil(t, err)
+	assert.Equal(t, "instance2", lease.Owner)
+
+	assert.Nil(t, leases2.ReleaseLease("", "scheduler", "instance2"))
+	lease, err = leases1.GetLease("", "scheduler")
+	assert.Nil(t, err)
+	assert.Nil(t, lease)
+}
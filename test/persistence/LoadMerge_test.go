@@ -0,0 +1,55 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticLoader struct {
+	items []interface{}
+}
+
+func (c *staticLoader) Load(correlationId string) ([]interface{}, error) {
+	return c.items, nil
+}
+
+func TestMergeOnLoad(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	loader := &staticLoader{}
+
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Event{}))
+	persistence.Loader = loader
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.timestamp_field", "time",
+		"options.load_mode", "merge",
+	))
+	persistence.Open("")
+
+	persistence.Create("", Event{Id: "1", Name: "Memory 1", Time: start.Add(time.Hour)})
+	persistence.Create("", Event{Id: "2", Name: "Memory 2", Time: start})
+	persistence.Create("", Event{Id: "3", Name: "Memory 3", Time: start})
+
+	loader.items = []interface{}{
+		map[string]interface{}{"id": "1", "name": "File 1", "time": start.Format(time.RFC3339)},
+		map[string]interface{}{"id": "2", "name": "File 2", "time": start.Add(time.Hour).Format(time.RFC3339)},
+		map[string]interface{}{"id": "4", "name": "File 4", "time": start.Format(time.RFC3339)},
+	}
+	err := persistence.Reload("")
+	assert.Nil(t, err)
+
+	names := map[string]string{}
+	for _, item := range persistence.Items {
+		names[item.(Event).Id] = item.(Event).Name
+	}
+	assert.Equal(t, map[string]string{
+		"1": "Memory 1",
+		"2": "File 2",
+		"3": "Memory 3",
+		"4": "File 4",
+	}, names)
+}
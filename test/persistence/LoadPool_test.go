@@ -0,0 +1,51 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPoolStats(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Loader = &staticLoader{items: []interface{}{
+		map[string]interface{}{"id": "1", "key": "Key 1"},
+		map[string]interface{}{"id": "2", "key": "Key 2"},
+		map[string]interface{}{"id": "3", "key": "Key 3"},
+	}}
+
+	before := cpersist.GetLoadPoolStats()
+	assert.Nil(t, persistence.Open(""))
+	after := cpersist.GetLoadPoolStats()
+
+	assert.Equal(t, int64(3), after.Gets-before.Gets)
+	assert.Equal(t, int64(3), after.Puts-before.Puts)
+	assert.True(t, after.Allocations-before.Allocations <= 3)
+
+	item, _ := persistence.GetOneById("", "2")
+	assert.Equal(t, "Key 2", item.Key)
+}
+
+func TestLoadStructItems(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Event{}))
+	persistence.Loader = &staticLoader{items: []interface{}{
+		Event{Id: "1", Name: "A", Time: start},
+		&Event{Id: "2", Name: "B", Time: start.Add(time.Hour)},
+		"not an object",
+		nil,
+	}}
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.load_errors", "skip"))
+	assert.Nil(t, persistence.Open(""))
+	defer persistence.Close("")
+
+	// Struct fields with their own encoding are loaded by their json names
+	assert.Len(t, persistence.Items, 2)
+	item, _ := persistence.GetOneById("", "2")
+	assert.True(t, start.Add(time.Hour).Equal(item.(Event).Time))
+}
@@ -0,0 +1,89 @@
+package test_persistence
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// Logger that captures warnings
+type warningLogger struct {
+	captureLogger
+}
+
+func (c *warningLogger) Log(level int, correlationId string, err error, message string, args ...interface{}) {
+	if level != clog.Warn {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messages = append(c.messages, fmt.Sprintf(message, args...))
+}
+
+func (c *warningLogger) warnings() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string{}, c.messages...)
+}
+
+func TestLongLockDetection(t *testing.T) {
+	logger := &warningLogger{}
+	persistence := NewDummyMemoryPersistence()
+	persistence.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "logger", "capture", "default", "1.0"), logger,
+	))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.long_lock_threshold", 20))
+
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Len(t, logger.warnings(), 0)
+
+	// The write lock is held by a slow filter of a delete
+	holding := make(chan struct{})
+	deleted := make(chan struct{})
+	go func() {
+		persistence.IdentifiableMemoryPersistence.DeleteByFilter("", func(item interface{}) bool {
+			close(holding)
+			time.Sleep(50 * time.Millisecond)
+			return false
+		})
+		close(deleted)
+	}()
+	<-holding
+	persistence.GetOneById("", "1")
+	<-deleted
+
+	warnings := logger.warnings()
+	assert.Len(t, warnings, 2)
+	assert.True(t, strings.HasPrefix(warnings[0], "Waiting for read lock"))
+	assert.Contains(t, warnings[0], "DeleteByFilter")
+	assert.True(t, strings.HasPrefix(warnings[1], "Write lock was held for"))
+	assert.Contains(t, warnings[1], "TestLongLockDetection")
+
+	// Reconfiguration keeps the threshold
+	slowDelete := func() {
+		persistence.IdentifiableMemoryPersistence.DeleteByFilter("", func(item interface{}) bool {
+			time.Sleep(30 * time.Millisecond)
+			return false
+		})
+	}
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.max_page_size", 10))
+	slowDelete()
+	assert.Len(t, logger.warnings(), 3)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.long_lock_threshold", 0))
+	slowDelete()
+	assert.Len(t, logger.warnings(), 3)
+
+	// Locks taken directly through the Lock field are not tracked
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.long_lock_threshold", 20))
+	persistence.Lock.Lock()
+	time.Sleep(30 * time.Millisecond)
+	persistence.Lock.Unlock()
+	assert.Len(t, logger.warnings(), 3)
+}
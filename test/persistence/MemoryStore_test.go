@@ -0,0 +1,60 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedMemoryPersistence(t *testing.T) {
+	defer cpersist.RemoveMemoryStore("shared_dummies")
+
+	readModel, err := cpersist.NewSharedMemoryPersistence("shared_dummies", reflect.TypeOf(Dummy{}))
+	assert.Nil(t, err)
+	adminModel, err := cpersist.NewSharedMemoryPersistence("shared_dummies", reflect.TypeOf(Dummy{}))
+	assert.Nil(t, err)
+	assert.Equal(t, readModel.Store(), adminModel.Store())
+
+	saver := &countingSaver{}
+	store := readModel.Store().Persistence()
+	store.Loader = &staticLoader{items: []interface{}{map[string]interface{}{"id": "1", "key": "Key 1"}}}
+	store.Saver = saver
+	readModel.Configure(cconf.NewConfigParamsFromTuples("options.durability", "none"))
+
+	assert.Nil(t, readModel.Open(""))
+	assert.Nil(t, adminModel.Open(""))
+	assert.Equal(t, 2, readModel.Store().OpenCount())
+
+	// Items changed by one component are seen by the other without duplicating them
+	_, err = adminModel.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	item, err := readModel.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.(Dummy).Key)
+	count, _ := readModel.GetCountByFilter("", func(interface{}) bool { return true })
+	assert.Equal(t, int64(2), count)
+
+	// The store is saved when the last component is closed
+	assert.Nil(t, adminModel.Close(""))
+	assert.False(t, adminModel.IsOpen())
+	assert.True(t, readModel.IsOpen())
+	assert.Equal(t, 0, saver.saves)
+	assert.False(t, cpersist.RemoveMemoryStore("shared_dummies"))
+
+	assert.Nil(t, readModel.Close(""))
+	assert.Nil(t, readModel.Close(""))
+	assert.Equal(t, 1, saver.saves)
+	assert.Equal(t, 2, saver.items)
+	assert.Equal(t, 0, readModel.Store().OpenCount())
+
+	_, err = cpersist.NewSharedMemoryPersistence("shared_dummies", reflect.TypeOf(Place{}))
+	assert.NotNil(t, err)
+
+	assert.True(t, cpersist.RemoveMemoryStore("shared_dummies"))
+	other, err := cpersist.NewSharedMemoryPersistence("shared_dummies", reflect.TypeOf(Place{}))
+	assert.Nil(t, err)
+	assert.NotEqual(t, readModel.Store(), other.Store())
+}
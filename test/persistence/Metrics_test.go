@@ -0,0 +1,74 @@
+package test_persistence
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingSaver struct{}
+
+func (c *failingSaver) Save(correlationId string, items []interface{}) error {
+	return errors.New("disk is full")
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	metrics := persistence.EnableMetrics("dummies")
+	persistence.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+	persistence.Create("", Dummy{Key: "Key 2", Content: "Content 2"})
+
+	persistence.Saver = &failingSaver{}
+	persistence.Create("", Dummy{Key: "Key 3", Content: "Content 3"})
+
+	handler, err := cpersist.NewMetricsHandler(metrics)
+	assert.Nil(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/metrics")
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	text := string(body)
+
+	assert.Contains(t, response.Header.Get("Content-Type"), "text/plain")
+	assert.Contains(t, text, `pip_persistence_items{persistence="dummies"} 3`)
+	assert.Contains(t, text, `pip_persistence_save_duration_seconds_count{persistence="dummies"} 1`)
+	assert.Contains(t, text, `pip_persistence_save_duration_seconds_bucket{persistence="dummies",le="+Inf"} 1`)
+	assert.Contains(t, text, `pip_persistence_save_duration_seconds_bucket{persistence="dummies",le="10"} 1`)
+	assert.Contains(t, text, `pip_persistence_errors_total{operation="save",persistence="dummies"} 1`)
+	assert.Contains(t, text, `pip_persistence_load_buffers_total{source="allocated"}`)
+}
+
+func TestPrometheusMetricsRegistry(t *testing.T) {
+	first := NewDummyMemoryPersistence()
+	second := NewDummyMemoryPersistence()
+	first.Create("", Dummy{Key: "Key 1", Content: "Content 1"})
+
+	// Metrics of several persistences are registered together, and names are escaped
+	registry := prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(first.EnableMetrics(`dummies "main"`)))
+	assert.Nil(t, registry.Register(second.EnableMetrics("dummies\\copy")))
+	assert.Nil(t, registry.Register(cpersist.LoadPoolCollector()))
+
+	expected := `
+# HELP pip_persistence_items Number of items stored in the persistence.
+# TYPE pip_persistence_items gauge
+pip_persistence_items{persistence="dummies \"main\""} 1
+pip_persistence_items{persistence="dummies\\copy"} 0
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "pip_persistence_items"))
+
+	// The same persistence can't be registered twice
+	assert.NotNil(t, registry.Register(first.Metrics()))
+	_, err := cpersist.NewMetricsHandler(first.Metrics(), first.Metrics())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,101 @@
+package test_persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkFilePersister(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "share", "dummies.json")
+	persister := cpersist.NewNetworkFilePersister(reflect.TypeOf(Dummy{}), path)
+
+	items, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Nil(t, items)
+
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Loader = persister
+	persistence.Saver = persister
+	assert.Nil(t, persistence.Open(""))
+	_, err = persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.Nil(t, persistence.Close(""))
+
+	// The lock file is removed after every operation
+	_, err = os.Stat(path + ".lock")
+	assert.True(t, os.IsNotExist(err))
+
+	items, err = cpersist.NewNetworkFilePersister(reflect.TypeOf(Dummy{}), path).Load("")
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+}
+
+func TestNetworkFilePersisterLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dummies.json")
+	persister := cpersist.NewNetworkFilePersister(reflect.TypeOf(Dummy{}), path)
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"options.lock_timeout", 100,
+		"options.lock_stale_timeout", 60000,
+		"options.retry_delay", 10,
+	))
+
+	// Lock held by another host
+	assert.Nil(t, ioutil.WriteFile(path+".lock", []byte("other 1"), 0644))
+	err := persister.Save("", []interface{}{Dummy{Id: "1"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, "LOCK_TIMEOUT", err.(*errors.ApplicationError).Code)
+
+	// Lock left by a crashed process
+	old := time.Now().Add(-2 * time.Minute)
+	assert.Nil(t, os.Chtimes(path+".lock", old, old))
+	assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "1"}}))
+}
+
+func TestNetworkFilePersisterConflicts(t *testing.T) {
+	for _, detection := range []string{cpersist.ConflictDetectionMtime, cpersist.ConflictDetectionEtag} {
+		path := filepath.Join(t.TempDir(), "dummies.json")
+		persister := cpersist.NewNetworkFilePersister(reflect.TypeOf(Dummy{}), path)
+		persister.ConflictDetection = detection
+		assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "1", Key: "Key 1"}}))
+		assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "1", Key: "Key 2"}}))
+
+		// Another writer replaces the file keeping its size and modification time
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Nil(t, ioutil.WriteFile(path, []byte(`[{"id":"1","key":"Key 3","content":""}]`), 0644))
+		os.Chtimes(path, info.ModTime(), info.ModTime())
+		if detection == cpersist.ConflictDetectionMtime {
+			later := info.ModTime().Add(time.Second)
+			os.Chtimes(path, later, later)
+		}
+
+		err = persister.Save("", []interface{}{Dummy{Id: "1", Key: "Key 4"}})
+		assert.NotNil(t, err, detection)
+		assert.Equal(t, "DATA_FILE_CONFLICT", err.(*errors.ApplicationError).Code)
+
+		// After reload changes are saved again
+		items, err := persister.Load("")
+		assert.Nil(t, err)
+		assert.Equal(t, "Key 3", items[0].(map[string]interface{})["key"])
+		assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "1", Key: "Key 4"}}))
+	}
+
+	// Without detection the last save wins
+	path := filepath.Join(t.TempDir(), "dummies.json")
+	persister := cpersist.NewNetworkFilePersister(reflect.TypeOf(Dummy{}), path)
+	persister.Configure(cconf.NewConfigParamsFromTuples("options.conflict_detection", "none"))
+	assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "1"}}))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`[]`), 0644))
+	assert.Nil(t, persister.Save("", []interface{}{Dummy{Id: "2"}}))
+}
@@ -0,0 +1,67 @@
+package test_persistence
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type captureLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (c *captureLogger) Level() int         { return clog.Trace }
+func (c *captureLogger) SetLevel(value int) {}
+func (c *captureLogger) Log(level int, correlationId string, err error, message string, args ...interface{}) {
+	if level != clog.Debug {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messages = append(c.messages, correlationId+" "+fmt.Sprintf(message, args...))
+}
+func (c *captureLogger) Fatal(correlationId string, err error, message string, args ...interface{}) {
+	c.Log(clog.Fatal, correlationId, err, message, args...)
+}
+func (c *captureLogger) Error(correlationId string, err error, message string, args ...interface{}) {
+	c.Log(clog.Error, correlationId, err, message, args...)
+}
+func (c *captureLogger) Warn(correlationId string, message string, args ...interface{}) {
+	c.Log(clog.Warn, correlationId, nil, message, args...)
+}
+func (c *captureLogger) Info(correlationId string, message string, args ...interface{}) {
+	c.Log(clog.Info, correlationId, nil, message, args...)
+}
+func (c *captureLogger) Debug(correlationId string, message string, args ...interface{}) {
+	c.Log(clog.Debug, correlationId, nil, message, args...)
+}
+func (c *captureLogger) Trace(correlationId string, message string, args ...interface{}) {
+	c.Log(clog.Trace, correlationId, nil, message, args...)
+}
+
+func TestOperationLogging(t *testing.T) {
+	logger := &captureLogger{}
+	persistence := NewDummyMemoryPersistence()
+	persistence.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("pip-services", "logger", "capture", "default", "1.0"), logger,
+	))
+
+	persistence.Create("123", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Len(t, logger.messages, 0)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.log_operations", true))
+	persistence.Create("123", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	persistence.GetOneById("456", "2")
+	persistence.Create("789", Dummy{Id: "2", Key: "Key 3"})
+
+	assert.Len(t, logger.messages, 3)
+	assert.Regexp(t, `^123 operation=create id=2 duration=[0-9.]+ms result=ok$`, logger.messages[0])
+	assert.Regexp(t, `^456 operation=get_one_by_id id=2 duration=[0-9.]+ms result=ok$`, logger.messages[1])
+	assert.Regexp(t, `^789 operation=create id=2 duration=[0-9.]+ms result=error error=".+"$`, logger.messages[2])
+}
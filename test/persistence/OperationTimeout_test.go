@@ -0,0 +1,131 @@
+package test_persistence
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Persister that blocks until it is released or its context is cancelled
+type hangingPersister struct {
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func (c *hangingPersister) Load(correlationId string) ([]interface{}, error) {
+	<-c.release
+	return []interface{}{}, nil
+}
+
+func (c *hangingPersister) LoadWithContext(ctx context.Context, correlationId string) ([]interface{}, error) {
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		close(c.cancelled)
+	}
+	return []interface{}{}, ctx.Err()
+}
+
+func (c *hangingPersister) Save(correlationId string, items []interface{}) error {
+	<-c.release
+	return nil
+}
+
+func TestOperationTimeout(t *testing.T) {
+	persister := &hangingPersister{release: make(chan struct{}), cancelled: make(chan struct{})}
+	defer close(persister.release)
+
+	dummies := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	dummies.Configure(cconf.NewConfigParamsFromTuples("options.operation_timeout", 50))
+	dummies.Loader = persister
+	dummies.Saver = persister
+
+	err := dummies.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "OPERATION_TIMEOUT", err.(*errors.ApplicationError).Code)
+	<-persister.cancelled
+
+	start := time.Now()
+	_, err = dummies.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "OPERATION_TIMEOUT", err.(*errors.ApplicationError).Code)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// The lock is not held by the abandoned save
+	item, err := dummies.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.NotNil(t, item)
+}
+
+// Saver that blocks until it is released and counts concurrent calls
+type concurrentSaver struct {
+	release chan struct{}
+	running int32
+	maxRuns int32
+	saved   int32
+}
+
+func (c *concurrentSaver) Save(correlationId string, items []interface{}) error {
+	running := atomic.AddInt32(&c.running, 1)
+	defer atomic.AddInt32(&c.running, -1)
+	for {
+		max := atomic.LoadInt32(&c.maxRuns)
+		if running <= max || atomic.CompareAndSwapInt32(&c.maxRuns, max, running) {
+			break
+		}
+	}
+	<-c.release
+	atomic.AddInt32(&c.saved, 1)
+	return nil
+}
+
+func TestOperationTimeoutSerializesSaves(t *testing.T) {
+	saver := &concurrentSaver{release: make(chan struct{})}
+	dummies := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	dummies.Configure(cconf.NewConfigParamsFromTuples("options.operation_timeout", 50))
+	dummies.Saver = saver
+
+	_, err := dummies.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "OPERATION_TIMEOUT", err.(*errors.ApplicationError).Code)
+
+	// The next save doesn't start while the abandoned one is running
+	_, err = dummies.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "OPERATION_TIMEOUT", err.(*errors.ApplicationError).Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&saver.running))
+
+	close(saver.release)
+	_, err = dummies.Create("", Dummy{Id: "3", Key: "Key 3"})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&saver.maxRuns))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&saver.saved))
+}
+
+func TestFilterTimeout(t *testing.T) {
+	dummies := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	dummies.Configure(cconf.NewConfigParamsFromTuples("options.operation_timeout", 20))
+	items := make([]interface{}, 5000)
+	for i := range items {
+		items[i] = Dummy{}
+	}
+	assert.Nil(t, dummies.LoadAll("", items))
+
+	_, err := dummies.GetListByFilter("", func(item interface{}) bool {
+		time.Sleep(10 * time.Microsecond)
+		return true
+	}, nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "OPERATION_TIMEOUT", err.(*errors.ApplicationError).Code)
+
+	list, err := dummies.GetListByFilter("", func(item interface{}) bool { return true }, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, list, 5000)
+}
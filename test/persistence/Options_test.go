@@ -0,0 +1,65 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Tagged struct {
+	Id   string   `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+func TestPersistenceWithOptions(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	loader := &staticLoader{items: []interface{}{
+		Event{Id: "1", Name: "A", Time: start},
+		Event{Id: "2", Name: "B", Time: start.Add(48 * time.Hour)},
+	}}
+
+	persistence := cpersist.NewIdentifiableMemoryPersistenceWithOptions(reflect.TypeOf(Event{}),
+		cpersist.WithLoader(loader),
+		cpersist.WithMaxPageSize(1),
+		cpersist.WithIndexes(cpersist.TimeIndex("time", 0)),
+	)
+	assert.Equal(t, "time", persistence.TimestampField)
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+
+	page, err := persistence.GetPageByDateRange("", start, start.Add(time.Hour), nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "1", page.Data[0].(Event).Id)
+
+	page, err = persistence.MemoryPersistence.GetPageByFilter("", nil, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+}
+
+func TestCloneStrategies(t *testing.T) {
+	deep := cpersist.NewIdentifiableMemoryPersistenceWithOptions(reflect.TypeOf(Tagged{}),
+		cpersist.WithCloneStrategy(cpersist.CloneStrategyDeep))
+	tags := []string{"a"}
+	_, err := deep.Create("", Tagged{Id: "1", Tags: tags})
+	assert.Nil(t, err)
+	tags[0] = "b"
+
+	item, err := deep.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, item.(Tagged).Tags)
+
+	none := cpersist.NewIdentifiableMemoryPersistenceWithOptions(reflect.TypeOf(Tagged{}),
+		cpersist.WithCloneStrategy(cpersist.CloneStrategyNone))
+	_, err = none.Create("", Tagged{Id: "1", Tags: []string{"a"}})
+	assert.Nil(t, err)
+	page, err := none.GetPageByFilter("", nil, cdata.NewEmptyPagingParams(), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, page.Data[0].(Tagged).Tags)
+}
@@ -0,0 +1,34 @@
+package test_persistence
+
+import (
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagingLimits(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.max_skip", 10,
+		"options.max_take", 5,
+	))
+	for _, id := range []string{"1", "2", "3"} {
+		persistence.Create("", Dummy{Id: id, Key: "Key " + id})
+	}
+
+	page, err := persistence.IdentifiableMemoryPersistence.GetPageByFilter("", nil, cdata.NewPagingParams(10, 5, false), nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 0)
+
+	_, err = persistence.IdentifiableMemoryPersistence.GetPageByFilter("123", nil, cdata.NewPagingParams(11, 5, false), nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "SKIP_TOO_LARGE", err.(*cerr.ApplicationError).Code)
+	assert.Equal(t, "123", err.(*cerr.ApplicationError).CorrelationId)
+
+	_, err = persistence.GetPageByFilterParams("", nil, cdata.NewPagingParams(0, 6, false), nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "TAKE_TOO_LARGE", err.(*cerr.ApplicationError).Code)
+}
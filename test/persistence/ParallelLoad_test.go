@@ -0,0 +1,38 @@
+package test_persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelLoad(t *testing.T) {
+	path := "../../data/dummies_parallel.json"
+	defer os.Remove(path)
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), path)
+	items := make([]interface{}, 2500)
+	for i := range items {
+		items[i] = Dummy{Id: strconv.Itoa(i), Key: "Key " + strconv.Itoa(i)}
+	}
+	assert.Nil(t, persister.Save("", items))
+
+	expected, err := persister.Load("")
+	assert.Nil(t, err)
+
+	persister.Configure(cconf.NewConfigParamsFromTuples("options.load_parallelism", 4))
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 2500)
+	assert.Equal(t, expected, loaded)
+
+	ioutil.WriteFile(path, []byte(`[{"id":"1"},{"id":2,]`), 0777)
+	_, err = persister.Load("")
+	assert.NotNil(t, err)
+}
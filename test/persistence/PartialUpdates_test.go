@@ -0,0 +1,39 @@
+package test_persistence
+
+import (
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdatePartiallyWithMask(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+
+	item, err := persistence.IdentifiableMemoryPersistence.UpdatePartiallyWithMask("", "1",
+		Dummy{Key: "Key 2"}, []string{"key", "content"})
+	assert.Nil(t, err)
+	assert.Equal(t, Dummy{Id: "1", Key: "Key 2", Content: ""}, item)
+
+	item, err = persistence.IdentifiableMemoryPersistence.UpdatePartiallyWithMask("", "1",
+		map[string]interface{}{"content": "Content 3", "key": ""}, []string{"content"})
+	assert.Nil(t, err)
+	assert.Equal(t, Dummy{Id: "1", Key: "Key 2", Content: "Content 3"}, item)
+
+	_, err = persistence.IdentifiableMemoryPersistence.UpdatePartiallyWithMask("", "1",
+		Dummy{}, []string{"color"})
+	assert.NotNil(t, err)
+}
+
+func TestOmitEmptyUpdates(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.omit_empty_updates", true))
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+
+	item, err := persistence.UpdatePartially("", "1", cdata.NewAnyValueMapFromTuples("key", "", "content", "Content 2"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", item.Key)
+	assert.Equal(t, "Content 2", item.Content)
+}
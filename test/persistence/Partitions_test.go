@@ -0,0 +1,47 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Event struct {
+	Id   string    `json:"id"`
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+func TestDateRangePartitions(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Event{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.timestamp_field", "time",
+		"options.partition_period", 60*60*1000,
+	))
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		persistence.Create("", Event{Name: "Event", Time: start.Add(time.Duration(i) * 30 * time.Minute)})
+	}
+
+	page, err := persistence.GetPageByDateRange("", start.Add(time.Hour), start.Add(2*time.Hour), nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 3)
+	assert.Equal(t, start.Add(time.Hour), page.Data[0].(Event).Time)
+
+	page, err = persistence.GetPageByDateRange("", start.Add(4*time.Hour), time.Time{}, nil, nil,
+		func(a, b interface{}) bool { return a.(Event).Time.After(b.(Event).Time) })
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, start.Add(270*time.Minute), page.Data[0].(Event).Time)
+
+	persistence.Create("", Event{Name: "Late", Time: start.Add(5 * time.Hour)})
+	page, err = persistence.GetPageByDateRange("", start.Add(5*time.Hour), time.Time{}, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "Late", page.Data[0].(Event).Name)
+}
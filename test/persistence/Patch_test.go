@@ -0,0 +1,62 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateByJsonPatch(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Customer{}))
+	_, err := persistence.Create("", Customer{Id: "1", Name: "John", Address: Address{City: "Boston"}, Tags: []string{"vip"}})
+	assert.Nil(t, err)
+
+	result, err := persistence.UpdateByPatch("", "1", []cpersist.PatchOperation{
+		{Op: cpersist.PatchReplace, Path: "/address/city", Value: "Austin"},
+		{Op: cpersist.PatchAdd, Path: "/tags/-", Value: "new"},
+		{Op: cpersist.PatchCopy, From: "/name", Path: "/address/zip"},
+	})
+	assert.Nil(t, err)
+	customer := result.(Customer)
+	assert.Equal(t, "Austin", customer.Address.City)
+	assert.Equal(t, "John", customer.Address.Zip)
+	assert.Equal(t, []string{"vip", "new"}, customer.Tags)
+
+	// Failed test operation leaves the item unchanged
+	_, err = persistence.UpdateByPatch("", "1", `[
+		{"op": "remove", "path": "/tags/0"},
+		{"op": "test", "path": "/name", "value": "Bill"}
+	]`)
+	assert.NotNil(t, err)
+	result, _ = persistence.GetOneById("", "1")
+	assert.Equal(t, []string{"vip", "new"}, result.(Customer).Tags)
+
+	_, err = persistence.UpdateByPatch("", "1", []cpersist.PatchOperation{
+		{Op: cpersist.PatchReplace, Path: "/id", Value: "2"},
+	})
+	assert.NotNil(t, err)
+
+	_, err = persistence.UpdateByPatch("", "1", []cpersist.PatchOperation{
+		{Op: cpersist.PatchRemove, Path: "/unknown"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestUpdateByMergePatch(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Customer{}))
+	_, err := persistence.Create("", Customer{Id: "1", Name: "John", Address: Address{City: "Boston", Zip: "02101"}, Tags: []string{"vip"}})
+	assert.Nil(t, err)
+
+	result, err := persistence.UpdateByPatch("", "1", map[string]interface{}{
+		"address": map[string]interface{}{"city": "Austin"},
+		"tags":    nil,
+	})
+	assert.Nil(t, err)
+	customer := result.(Customer)
+	assert.Equal(t, "Austin", customer.Address.City)
+	assert.Equal(t, "02101", customer.Address.Zip)
+	assert.Nil(t, customer.Tags)
+	assert.Equal(t, "John", customer.Name)
+}
@@ -0,0 +1,66 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type auditPersistence struct {
+	persistence cpersist.IWriter
+	log         *[]string
+}
+
+func (c *auditPersistence) Create(correlationId string, item interface{}) (interface{}, error) {
+	*c.log = append(*c.log, "create "+item.(Dummy).Id)
+	return c.persistence.Create(correlationId, item)
+}
+
+func (c *auditPersistence) Update(correlationId string, item interface{}) (interface{}, error) {
+	return c.persistence.Update(correlationId, item)
+}
+
+func (c *auditPersistence) DeleteById(correlationId string, id interface{}) (interface{}, error) {
+	return c.persistence.DeleteById(correlationId, id)
+}
+
+func TestPersistenceBuilder(t *testing.T) {
+	log := []string{}
+	base := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence := cpersist.NewPersistenceBuilder(base).
+		WithRateLimit().
+		With(func(p interface{}) interface{} {
+			return &auditPersistence{persistence: p.(cpersist.IWriter), log: &log}
+		}).
+		Build()
+	assert.Len(t, persistence.Layers(), 3)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.rate", 1,
+		"options.burst", 1,
+		"options.max_page_size", 5,
+	))
+	assert.Equal(t, 5, base.MaxPageSize)
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	assert.True(t, base.IsOpen())
+
+	_, err = persistence.Create("client1", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	// Audit is outside of the rate limit and records rejected calls too
+	_, err = persistence.Create("client1", Dummy{Id: "2", Key: "Key 2"})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"create 1", "create 2"}, log)
+
+	// Audit decorator doesn't support reads
+	_, err = persistence.GetOneById("client2", "1")
+	assert.NotNil(t, err)
+
+	err = persistence.Close("")
+	assert.Nil(t, err)
+	assert.False(t, base.IsOpen())
+}
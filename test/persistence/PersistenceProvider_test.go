@@ -0,0 +1,58 @@
+package test_persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvideFilePersistence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dummies.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`[{"id":"1","key":"Key 1"}]`), 0644))
+	defer cpersist.ReleaseFilePersistence(path)
+
+	first, err := cpersist.ProvideFilePersistence(reflect.TypeOf(Dummy{}), path)
+	assert.Nil(t, err)
+	wd, _ := os.Getwd()
+	relative, _ := filepath.Rel(wd, path)
+	second, err := cpersist.ProvideFilePersistence(reflect.TypeOf(Dummy{}), relative)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Store(), second.Store())
+
+	first.Configure(cconf.NewConfigParamsFromTuples("options.durability", "none"))
+	assert.Nil(t, first.Open(""))
+	assert.Nil(t, second.Open(""))
+
+	// Changes of both components are kept, instead of the last saved file clobbering the other
+	_, err = first.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	_, err = second.Create("", Dummy{Id: "3", Key: "Key 3"})
+	assert.Nil(t, err)
+
+	assert.Nil(t, first.Close(""))
+	content, _ := ioutil.ReadFile(path)
+	assert.NotContains(t, string(content), "Key 2")
+
+	assert.Nil(t, second.Close(""))
+	loaded, err := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), path).Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 3)
+
+	// The data file can't be shared by items of different types
+	_, err = cpersist.ProvideFilePersistence(reflect.TypeOf(Place{}), path)
+	assert.NotNil(t, err)
+	_, err = cpersist.ProvideFilePersistence(reflect.TypeOf(Dummy{}), "")
+	assert.NotNil(t, err)
+
+	assert.True(t, cpersist.ReleaseFilePersistence(path))
+	third, err := cpersist.ProvideFilePersistence(reflect.TypeOf(Dummy{}), path)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Store(), third.Store())
+}
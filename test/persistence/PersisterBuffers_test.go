@@ -0,0 +1,70 @@
+package test_persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersisterEncoderOptions(t *testing.T) {
+	path := "../../data/dummies_encoder.json"
+	defer os.Remove(path)
+
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), path)
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"options.read_buffer_size", 16,
+		"options.write_buffer_size", 16,
+		"options.indent", "  ",
+		"options.escape_html", false,
+	))
+	assert.Equal(t, 16, persister.WriteBufferSize)
+	assert.Equal(t, 0, persister.ChunkSize)
+	items := []interface{}{
+		Dummy{Id: "1", Key: "<a>", Content: "a & b"},
+		Dummy{Id: "2", Key: "Key 2", Content: "Content 2"},
+	}
+	assert.Nil(t, persister.Save("", items))
+
+	buffer, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "[\n  {\n    \"id\": \"1\",\n    \"key\": \"<a>\",\n    \"content\": \"a & b\"\n  },\n"+
+		"  {\n    \"id\": \"2\",\n    \"key\": \"Key 2\",\n    \"content\": \"Content 2\"\n  }\n]", string(buffer))
+
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, "<a>", loaded[0].(map[string]interface{})["key"])
+
+	persister.Configure(cconf.NewConfigParamsFromTuples("options.indent", "", "options.escape_html", true))
+	assert.Nil(t, persister.Save("", items[:1]))
+	buffer, _ = ioutil.ReadFile(path)
+	assert.Equal(t, `[{"id":"1","key":"\u003ca\u003e","content":"a \u0026 b"}]`, string(buffer))
+}
+
+func TestPersisterChunkAndBufferSizes(t *testing.T) {
+	path := "../../data/dummies_chunks.json"
+	defer os.Remove(path)
+
+	// Both options are kept regardless of their order
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), path)
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"options.write_buffer_size", 64,
+		"options.save_chunk_size", 8,
+	))
+	assert.Equal(t, 8, persister.ChunkSize)
+	assert.Equal(t, 64, persister.WriteBufferSize)
+
+	items := []interface{}{
+		Dummy{Id: "1", Key: "Key 1", Content: "Content 1"},
+		Dummy{Id: "2", Key: "Key 2", Content: "Content 2"},
+	}
+	assert.Nil(t, persister.Save("", items))
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 2)
+}
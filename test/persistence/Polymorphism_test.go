@@ -0,0 +1,65 @@
+package test_persistence
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Notification interface {
+	Recipient() string
+}
+
+type EmailNotification struct {
+	Id      string `json:"id"`
+	Email   string `json:"email"`
+	Subject string `json:"subject"`
+}
+
+func (c EmailNotification) Recipient() string { return c.Email }
+
+type SmsNotification struct {
+	Id    string `json:"id"`
+	Phone string `json:"phone"`
+}
+
+func (c SmsNotification) Recipient() string { return c.Phone }
+
+func newNotificationPersistence(filename string) *cpersist.IdentifiableFilePersistence {
+	prototype := reflect.TypeOf((*Notification)(nil)).Elem()
+	persistence := cpersist.NewIdentifiableFilePersistence(prototype, cpersist.NewJsonFilePersister(prototype, filename))
+	persistence.RegisterSubtype("email", reflect.TypeOf(EmailNotification{}))
+	persistence.RegisterSubtype("sms", reflect.TypeOf(SmsNotification{}))
+	return persistence
+}
+
+func TestPolymorphicItems(t *testing.T) {
+	filename := "../../data/notifications.json"
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	persistence := newNotificationPersistence(filename)
+	persistence.Open("")
+	_, err := persistence.Create("", EmailNotification{Id: "1", Email: "user@example.com", Subject: "Hello"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("", SmsNotification{Id: "2", Phone: "+1555"})
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	persistence = newNotificationPersistence(filename)
+	err = persistence.Open("")
+	assert.Nil(t, err)
+
+	item, err := persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	email, ok := item.(EmailNotification)
+	assert.True(t, ok)
+	assert.Equal(t, "Hello", email.Subject)
+
+	item, err = persistence.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "+1555", item.(Notification).Recipient())
+}
@@ -0,0 +1,34 @@
+package test_persistence
+
+import (
+	"testing"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPageByFilterEx(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.MaxPageSize = 2
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		persistence.Create("", Dummy{Id: id, Key: "Key " + id})
+	}
+
+	page, err := persistence.GetPageByFilterEx("", func(item interface{}) bool {
+		return item.(Dummy).Id != "5"
+	}, cdata.NewPagingParams(1, 10, true), nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, int64(4), *page.Total)
+	assert.Equal(t, int64(5), page.Metadata.ScannedItems)
+	assert.Equal(t, int64(4), page.Metadata.MatchedItems)
+	assert.True(t, page.Metadata.TakeTruncated)
+	assert.True(t, page.Metadata.HasMore)
+	assert.False(t, page.Metadata.IndexUsed)
+
+	page, err = persistence.GetPageByFilterEx("", nil, cdata.NewPagingParams(3, 2, false), nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 2)
+	assert.False(t, page.Metadata.TakeTruncated)
+	assert.False(t, page.Metadata.HasMore)
+}
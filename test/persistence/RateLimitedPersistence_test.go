@@ -0,0 +1,38 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedPersistence(t *testing.T) {
+	persistence := cpersist.NewRateLimitedPersistence(
+		cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{})))
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.rate", 1,
+		"options.burst", 2,
+	))
+
+	_, err := persistence.Create("client1", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	_, err = persistence.GetOneById("client1", "1")
+	assert.Nil(t, err)
+	_, err = persistence.GetOneById("client1", "1")
+	assert.NotNil(t, err)
+
+	// Other callers have their own limits
+	item, err := persistence.GetOneById("client2", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", item.(Dummy).Key)
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.rate", 50,
+		"options.wait_timeout", 100,
+	))
+	_, err = persistence.GetOneById("client1", "1")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,69 @@
+package test_persistence
+
+import (
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSnapshot(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	assert.Nil(t, persistence.GetReadSnapshot())
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.read_snapshot", true,
+		"options.read_snapshot_indexes", "key",
+	))
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	persistence.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+
+	snapshot := persistence.GetReadSnapshot()
+	assert.Equal(t, 2, snapshot.Len())
+	assert.Equal(t, Dummy{Id: "1", Key: "Key 1", Content: "Content 1"}, snapshot.GetOneById("1"))
+	assert.Nil(t, snapshot.GetOneById("3"))
+	assert.Equal(t, []interface{}{Dummy{Id: "2", Key: "Key 2", Content: "Content 2"}},
+		snapshot.GetListByField("key", "Key 2"))
+	assert.Len(t, snapshot.GetListByField("content", "Content 1"), 1)
+
+	page, err := snapshot.GetPageByFilter(nil, cdata.NewPagingParams(1, 1, true), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), *page.Total)
+	assert.Equal(t, "2", page.Data[0].(Dummy).Id)
+
+	// Snapshots don't change
+	persistence.DeleteById("", "1")
+	assert.Equal(t, 2, snapshot.Len())
+	assert.Equal(t, 1, persistence.GetReadSnapshot().Len())
+
+	// The lock is not taken by readers
+	persistence.Lock.Lock()
+	assert.Equal(t, 1, persistence.GetReadSnapshot().Len())
+	persistence.Lock.Unlock()
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.read_snapshot", false))
+	assert.Nil(t, persistence.GetReadSnapshot())
+}
+
+func TestReadSnapshotInterval(t *testing.T) {
+	clock := cpersist.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	persistence := NewDummyMemoryPersistence()
+	persistence.Clock = clock
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"options.read_snapshot", true,
+		"options.read_snapshot_interval", 1000,
+	))
+
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Equal(t, 0, persistence.GetReadSnapshot().Len())
+
+	clock.Advance(time.Second)
+	// The outdated snapshot is returned while the new one is built
+	persistence.GetReadSnapshot()
+	assert.Eventually(t, func() bool {
+		return persistence.GetReadSnapshot().Len() == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,275 @@
+package test_persistence
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// In-process FTP server with files kept in memory
+type ftpServer struct {
+	listener net.Listener
+	lock     sync.Mutex
+	files    map[string][]byte
+	commands []string
+}
+
+func startFtpServer(t *testing.T) *ftpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &ftpServer{listener: listener, files: map[string][]byte{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (c *ftpServer) port() int {
+	return c.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (c *ftpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) { fmt.Fprintf(conn, format+"\r\n", args...) }
+	var data net.Listener
+	var renameFrom string
+
+	reply("220 ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		command, arg := parts[0], ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+		c.lock.Lock()
+		c.commands = append(c.commands, command)
+		c.lock.Unlock()
+
+		switch command {
+		case "USER":
+			reply("331 password required")
+		case "PASS":
+			if arg != "secret" {
+				reply("530 login incorrect")
+			} else {
+				reply("230 logged in")
+			}
+		case "TYPE":
+			reply("200 binary")
+		case "EPSV":
+			// Old servers don't support EPSV, so the client falls back to PASV
+			reply("500 unknown command")
+		case "PASV":
+			data, _ = net.Listen("tcp", "127.0.0.1:0")
+			port := data.Addr().(*net.TCPAddr).Port
+			reply("227 Entering Passive Mode (10,0,0,1,%d,%d)", port>>8, port&255)
+		case "RETR":
+			c.lock.Lock()
+			content, ok := c.files[arg]
+			c.lock.Unlock()
+			if !ok {
+				data.Close()
+				reply("550 not found")
+				continue
+			}
+			reply("150 opening")
+			dataConn, _ := data.Accept()
+			dataConn.Write(content)
+			dataConn.Close()
+			data.Close()
+			reply("226 done")
+		case "STOR":
+			reply("150 opening")
+			dataConn, _ := data.Accept()
+			content, _ := ioutil.ReadAll(dataConn)
+			dataConn.Close()
+			data.Close()
+			c.lock.Lock()
+			c.files[arg] = content
+			c.lock.Unlock()
+			reply("226 done")
+		case "RNFR":
+			renameFrom = arg
+			reply("350 ready")
+		case "RNTO":
+			c.lock.Lock()
+			c.files[arg] = c.files[renameFrom]
+			delete(c.files, renameFrom)
+			c.lock.Unlock()
+			reply("250 renamed")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestRemoteFilePersisterOverFtp(t *testing.T) {
+	server := startFtpServer(t)
+	server.files["/exchange/dummies.json"] = []byte(`[{"id":"1","key":"Key 1","content":"Content 1"}]`)
+
+	persister := cpersist.NewRemoteFilePersister(reflect.TypeOf(Dummy{}), "")
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.uri", fmt.Sprintf("ftp://127.0.0.1:%d/exchange/dummies.json", server.port()),
+		"credential.username", "exchange",
+		"credential.password", "secret",
+	))
+
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Loader = persister
+	persistence.Saver = persister
+	assert.Nil(t, persistence.Open(""))
+	item, err := persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", item.(Dummy).Key)
+
+	_, err = persistence.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.Nil(t, persistence.Close(""))
+
+	server.lock.Lock()
+	content := server.files["/exchange/dummies.json"]
+	_, tempExists := server.files["/exchange/dummies.json.tmp"]
+	server.lock.Unlock()
+	assert.Contains(t, string(content), `"key":"Key 2"`)
+	assert.False(t, tempExists)
+
+	// Missing data file loads no items
+	missing := cpersist.NewRemoteFilePersister(reflect.TypeOf(Dummy{}), "/exchange/missing.json")
+	missing.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.protocol", "ftp",
+		"connection.host", "127.0.0.1",
+		"connection.port", server.port(),
+		"credential.username", "exchange",
+		"credential.password", "secret",
+	))
+	items, err := missing.Load("")
+	assert.Nil(t, err)
+	assert.Nil(t, items)
+
+	// Wrong password fails to connect
+	wrong := cpersist.NewRemoteFilePersister(reflect.TypeOf(Dummy{}), "/exchange/dummies.json")
+	wrong.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.uri", fmt.Sprintf("ftp://127.0.0.1:%d", server.port()),
+		"credential.username", "exchange",
+		"credential.password", "wrong",
+	))
+	_, err = wrong.Load("")
+	assert.NotNil(t, err)
+}
+
+// Remote file client over a local directory
+type localFileClient struct {
+	dir string
+}
+
+func (c *localFileClient) Retrieve(path string) (io.ReadCloser, error) {
+	return os.Open(c.dir + path)
+}
+
+func (c *localFileClient) Store(path string, reader io.Reader) error {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.dir+path, content, 0644)
+}
+
+func (c *localFileClient) Rename(from string, to string) error {
+	return os.Rename(c.dir+from, c.dir+to)
+}
+
+func (c *localFileClient) Close() error {
+	return nil
+}
+
+func TestRemoteFilePersisterDialers(t *testing.T) {
+	persister := cpersist.NewRemoteFilePersister(reflect.TypeOf(Dummy{}), "/dummies.json")
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.protocol", "sftp",
+		"connection.host", "files.example.com",
+	))
+
+	// SFTP requires a referenced dialer
+	_, err := persister.Load("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "UNSUPPORTED_PROTOCOL", err.(*cerr.ApplicationError).Code)
+
+	dir := t.TempDir()
+	var dialedPort int
+	dialer := cpersist.RemoteFileDialer(func(host string, port int, username string, password string,
+		timeout time.Duration) (cpersist.IRemoteFileClient, error) {
+		dialedPort = port
+		return &localFileClient{dir: dir}, nil
+	})
+	persister.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("test", "file-dialer", "sftp", "default", "1.0"), dialer,
+	))
+	items := []interface{}{Dummy{Id: "1", Key: "Key 1"}}
+	assert.Nil(t, persister.Save("", items))
+	assert.Equal(t, 22, dialedPort)
+
+	content, err := ioutil.ReadFile(dir + "/dummies.json")
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte(`[{"id":"1"`)))
+
+	loaded, err := persister.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 1)
+
+	// Dialers are not shared between instances
+	other := cpersist.NewRemoteFilePersister(reflect.TypeOf(Dummy{}), "/dummies.json")
+	other.Configure(cconf.NewConfigParamsFromTuples("connection.uri", "sftp://files.example.com"))
+	_, err = other.Load("")
+	assert.NotNil(t, err)
+
+	// Dialer field replaces referenced dialers
+	other.Dialer = dialer
+	dialedPort = 0
+	loaded, err = other.Load("")
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 1)
+	assert.Equal(t, 22, dialedPort)
+
+	// A referenced component that is not a dialer
+	other.Dialer = nil
+	other.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("test", "file-dialer", "sftp", "default", "1.0"), "not a dialer",
+	))
+	_, err = other.Load("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_DIALER", err.(*cerr.ApplicationError).Code)
+
+	// Missing connection is a configuration error
+	_, err = cpersist.NewRemoteFilePersister(reflect.TypeOf(Dummy{}), "/dummies.json").Load("")
+	assert.NotNil(t, err)
+}
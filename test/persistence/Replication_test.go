@@ -0,0 +1,61 @@
+package test_persistence
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReplication(t *testing.T, primary *DummyMemoryPersistence, replica *DummyMemoryPersistence,
+	source cpersist.IReplicationSource) {
+	primary.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+
+	replication := cpersist.NewReplicationReplica(&replica.MemoryPersistence, source)
+	err := replication.Sync("")
+	assert.Nil(t, err)
+	dummy, _ := replica.GetOneById("", "1")
+	assert.Equal(t, "Content 1", dummy.Content)
+
+	primary.Create("", Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	primary.Update("", Dummy{Id: "1", Key: "Key 1", Content: "Content 11"})
+	primary.DeleteById("", "2")
+
+	err = replication.Sync("")
+	assert.Nil(t, err)
+	dummy, _ = replica.GetOneById("", "1")
+	assert.Equal(t, "Content 11", dummy.Content)
+	dummy, _ = replica.GetOneById("", "2")
+	assert.Equal(t, "", dummy.Id)
+
+	// Events beyond the log trigger full resync
+	for i := 0; i < 5; i++ {
+		primary.Update("", Dummy{Id: "1", Key: "Key 1", Content: "Content 111"})
+	}
+	primary.Create("", Dummy{Id: "3", Key: "Key 3", Content: "Content 3"})
+
+	err = replication.Sync("")
+	assert.Nil(t, err)
+	dummy, _ = replica.GetOneById("", "3")
+	assert.Equal(t, "Content 3", dummy.Content)
+	dummy, _ = replica.GetOneById("", "1")
+	assert.Equal(t, "Content 111", dummy.Content)
+}
+
+func TestReplication(t *testing.T) {
+	primary := NewDummyMemoryPersistence()
+	replica := NewDummyMemoryPersistence()
+	source := cpersist.NewReplicationPrimary(&primary.MemoryPersistence, 3)
+
+	testReplication(t, primary, replica, source)
+}
+
+func TestHttpReplication(t *testing.T) {
+	primary := NewDummyMemoryPersistence()
+	replica := NewDummyMemoryPersistence()
+	server := httptest.NewServer(cpersist.NewReplicationPrimary(&primary.MemoryPersistence, 3))
+	defer server.Close()
+
+	testReplication(t, primary, replica, cpersist.NewHttpReplicationSource(server.URL))
+}
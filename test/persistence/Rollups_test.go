@@ -0,0 +1,51 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Sale struct {
+	Id     string  `json:"id"`
+	Region string  `json:"region"`
+	Amount float64 `json:"amount"`
+}
+
+func TestRollups(t *testing.T) {
+	sales := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Sale{}))
+	sales.Configure(config.NewConfigParamsFromTuples(
+		"rollups.by_region.group_by", "region",
+		"rollups.by_region.sum", "amount",
+	))
+	assert.Nil(t, sales.Open(""))
+	defer sales.Close("")
+
+	sales.Create("", Sale{Id: "1", Region: "east", Amount: 10})
+	sales.Create("", Sale{Id: "2", Region: "east", Amount: 5})
+	sales.Create("", Sale{Id: "3", Region: "west", Amount: 7})
+	assert.Nil(t, sales.RegisterRollup("", "total", "", []string{"amount"}))
+
+	sales.UpdatePartially("", "2", cdata.NewAnyValueMapFromTuples("region", "west"))
+	sales.DeleteById("", "3")
+
+	rollup, err := sales.GetRollup("", "by_region")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]persistence.RollupGroup{
+		"east": {Count: 1, Sums: map[string]float64{"amount": 10}},
+		"west": {Count: 1, Sums: map[string]float64{"amount": 5}},
+	}, rollup)
+
+	rollup, err = sales.GetRollup("", "total")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), rollup[""].Count)
+	assert.Equal(t, float64(15), rollup[""].Sums["amount"])
+
+	sales.UnregisterRollup("", "total")
+	_, err = sales.GetRollup("", "total")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,50 @@
+package test_persistence
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPageAfter(t *testing.T) {
+	sales := persistence.NewIdentifiableMemoryPersistence(reflect.TypeOf(Sale{}))
+	for i := 0; i < 25; i++ {
+		sales.Create("", Sale{Id: strconv.Itoa(100 + i), Region: []string{"east", "west"}[i%2], Amount: float64(i % 3)})
+	}
+	sort := cdata.NewSortParams([]cdata.SortField{cdata.NewSortField("region", false), cdata.NewSortField("amount", true)})
+
+	ids := []string{}
+	var after []interface{}
+	for pages := 0; ; pages++ {
+		page, err := sales.GetPageAfter("", nil, sort, after, 7)
+		assert.Nil(t, err)
+		for _, item := range page.Data {
+			ids = append(ids, item.(Sale).Id)
+		}
+		if pages == 0 {
+			// Items added before the current position don't shift next pages
+			sales.Create("", Sale{Id: "000", Region: "west", Amount: 0})
+		}
+		if page.After == nil {
+			break
+		}
+		after = page.After
+	}
+
+	assert.Len(t, ids, 25)
+	seen := map[string]bool{}
+	for _, id := range ids {
+		assert.False(t, seen[id])
+		seen[id] = true
+	}
+	assert.Equal(t, "103", ids[0])
+	assert.Equal(t, "109", ids[1])
+	assert.Equal(t, "120", ids[len(ids)-1])
+
+	_, err := sales.GetPageAfter("", nil, sort, []interface{}{"west"}, 7)
+	assert.NotNil(t, err)
+}
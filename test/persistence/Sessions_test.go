@@ -0,0 +1,72 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	clock := persistence.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	sessions := persistence.NewSessionFilePersistence(path)
+	sessions.Clock = clock
+	sessions.Configure(config.NewConfigParamsFromTuples("options.session_timeout", 60000))
+	assert.Nil(t, sessions.Open(""))
+	defer sessions.Close("")
+
+	session1, err := sessions.OpenSession("", "user1", map[string]interface{}{"role": "admin"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", session1.Id)
+	clock.Advance(time.Second)
+	session2, err := sessions.OpenSession("", "user1", nil)
+	assert.Nil(t, err)
+	_, err = sessions.OpenSession("", "user2", nil)
+	assert.Nil(t, err)
+
+	// Using the session extends its expiration
+	clock.Advance(40 * time.Second)
+	session, err := sessions.GetSessionById("", session1.Id)
+	assert.Nil(t, err)
+	assert.NotNil(t, session)
+	assert.Equal(t, "admin", session.Data["role"])
+	assert.Equal(t, clock.Now().Add(time.Minute), session.ExpireTime)
+
+	userSessions, err := sessions.GetByUser("", "user1")
+	assert.Nil(t, err)
+	assert.Len(t, userSessions, 2)
+	assert.Equal(t, session1.Id, userSessions[0].Id)
+	assert.Equal(t, session2.Id, userSessions[1].Id)
+
+	// Other sessions expire
+	clock.Advance(30 * time.Second)
+	session, err = sessions.GetSessionById("", session2.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, session)
+	userSessions, err = sessions.GetByUser("", "user1")
+	assert.Nil(t, err)
+	assert.Len(t, userSessions, 1)
+
+	assert.Nil(t, sessions.CloseExpired(""))
+	count, err := sessions.GetCountByFilterParams("", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Sessions are kept in the file
+	reloaded := persistence.NewSessionFilePersistence(path)
+	reloaded.Clock = clock
+	assert.Nil(t, reloaded.Open(""))
+	session, err = reloaded.GetSessionById("", session1.Id)
+	assert.Nil(t, err)
+	assert.NotNil(t, session)
+	assert.Nil(t, reloaded.Close(""))
+
+	assert.Nil(t, sessions.CloseSession("", session1.Id))
+	session, err = sessions.GetSessionById("", session1.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, session)
+}
@@ -0,0 +1,56 @@
+package test_persistence
+
+import (
+	"path/filepath"
+	"testing"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingsFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	settings := persistence.NewSettingsFilePersistence(path)
+	changed := []string{}
+	settings.AddListener(func(correlationId string, id string, parameters *cdata.AnyValueMap) {
+		changed = append(changed, id)
+	})
+	assert.Nil(t, settings.Open(""))
+
+	_, err := settings.SetSection("", "features", cdata.NewAnyValueMapFromTuples("dark_mode", true, "theme", "blue"))
+	assert.Nil(t, err)
+	_, err = settings.ModifySection("", "stats", nil, cdata.NewAnyValueMapFromTuples("logins", 2))
+	assert.Nil(t, err)
+	stats, err := settings.ModifySection("", "stats",
+		cdata.NewAnyValueMapFromTuples("last", "admin"), cdata.NewAnyValueMapFromTuples("logins", 3))
+	assert.Nil(t, err)
+	assert.Equal(t, float64(5), stats.Get("logins"))
+	assert.Equal(t, []string{"features", "stats", "stats"}, changed)
+
+	// Returned settings are copies
+	stats.Put("logins", 100)
+	assert.Nil(t, settings.Close(""))
+
+	settings = persistence.NewSettingsFilePersistence(path)
+	assert.Nil(t, settings.Open(""))
+	defer settings.Close("")
+
+	ids, err := settings.GetSectionIds("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"features", "stats"}, ids)
+
+	features, err := settings.GetSection("", "features")
+	assert.Nil(t, err)
+	assert.Equal(t, true, features.Get("dark_mode"))
+	assert.Equal(t, "blue", features.GetAsString("theme"))
+
+	stats, err = settings.GetSection("", "stats")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(5), stats.Get("logins"))
+	assert.Equal(t, "admin", stats.GetAsString("last"))
+
+	missing, err := settings.GetSection("", "missing")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, missing.Len())
+}
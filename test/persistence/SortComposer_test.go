@@ -0,0 +1,64 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeSort(t *testing.T) {
+	persistence := NewDummyMemoryPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.collation", "ignore_case, numeric"))
+	persistence.Create("", Dummy{Id: "1", Key: "b", Content: "item10"})
+	persistence.Create("", Dummy{Id: "2", Key: "A", Content: "item2"})
+	persistence.Create("", Dummy{Id: "3", Key: "B", Content: "Item2"})
+	persistence.Create("", Dummy{Id: "4", Key: "a", Content: "item1"})
+
+	sortFunc, err := persistence.ComposeSort(cdata.NewSortParams([]cdata.SortField{
+		cdata.NewSortField("key", false),
+		cdata.NewSortField("Content", true),
+	}))
+	assert.Nil(t, err)
+
+	items, err := persistence.IdentifiableMemoryPersistence.GetListByFilter("", nil, sortFunc, nil)
+	assert.Nil(t, err)
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.(Dummy).Id)
+	}
+	// Equal items keep their original order
+	assert.Equal(t, []string{"3", "1", "4", "2"}, ids)
+
+	_, err = cpersist.ComposeSort(reflect.TypeOf(Dummy{}), cdata.NewSortParams([]cdata.SortField{
+		cdata.NewSortField("color", true),
+	}), nil)
+	assert.NotNil(t, err)
+
+	sortFunc, err = cpersist.ComposeSort(reflect.TypeOf(Dummy{}), nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, sortFunc)
+}
+
+func TestCollation(t *testing.T) {
+	collation := cpersist.ParseCollation("numeric")
+	assert.Equal(t, -1, collation.Compare("file2.txt", "file10.txt"))
+	assert.Equal(t, 0, collation.Compare("file002", "file2"))
+	assert.Equal(t, -1, collation.Compare("B", "a"))
+
+	collation = cpersist.ParseCollation("ignore_case")
+	assert.Equal(t, 1, collation.Compare("B", "a"))
+	assert.Equal(t, 1, collation.Compare("file2", "file10"))
+	assert.Nil(t, cpersist.ParseCollation(""))
+
+	collation = cpersist.ParseCollation("ignore_case, ignore_diacritics")
+	assert.Equal(t, 0, collation.Compare("José", "JOSE"))
+	assert.Equal(t, 0, collation.Compare("Jose\u0301", "josé"))
+	assert.Equal(t, 0, collation.Compare("Straße", "STRASSE"))
+	assert.Equal(t, 0, collation.Compare("Ἀθῆναι", "ΑΘΗΝΑΙ"))
+	assert.Equal(t, "creme brulee", collation.Normalize("Crème Brûlée"))
+	assert.Equal(t, -1, collation.Compare("Émile", "Fabien"))
+}
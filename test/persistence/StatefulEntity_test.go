@@ -0,0 +1,51 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type Ticket struct {
+	Id    string `json:"id"`
+	State string `json:"state"`
+}
+
+func TestStatefulEntityPersistence(t *testing.T) {
+	tickets := persistence.NewStatefulEntityPersistence(reflect.TypeOf(Ticket{}))
+	tickets.Configure(config.NewConfigParamsFromTuples(
+		"transitions.open", "in_progress,closed",
+		"transitions.in_progress", "open, closed",
+	))
+	assert.Nil(t, tickets.Open(""))
+	defer tickets.Close("")
+
+	_, err := tickets.Create("", Ticket{Id: "1", State: "open"})
+	assert.Nil(t, err)
+	_, err = tickets.UpdatePartially("", "1", cdata.NewAnyValueMapFromTuples("state", "in_progress"))
+	assert.Nil(t, err)
+	_, err = tickets.Update("", Ticket{Id: "1", State: "closed"})
+	assert.Nil(t, err)
+
+	// Closed tickets can't be reopened
+	_, err = tickets.Update("", Ticket{Id: "1", State: "open"})
+	assert.NotNil(t, err)
+	_, err = tickets.Set("", Ticket{Id: "1", State: "in_progress"})
+	assert.NotNil(t, err)
+	item, err := tickets.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "closed", item.(Ticket).State)
+
+	history, err := tickets.GetTransitionHistory("", "1")
+	assert.Nil(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, "", history[0].From)
+	assert.Equal(t, "open", history[0].To)
+	assert.Equal(t, "in_progress", history[1].To)
+	assert.Equal(t, "in_progress", history[2].From)
+	assert.Equal(t, "closed", history[2].To)
+}
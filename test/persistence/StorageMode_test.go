@@ -0,0 +1,110 @@
+package test_persistence
+
+import (
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointerStorageMode(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(&Dummy{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.storage_mode", "pointer"))
+	assert.Nil(t, persistence.Open(""))
+	defer persistence.Close("")
+
+	created, err := persistence.Create("", &Dummy{Key: "Key 1"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.(*Dummy).Id)
+	_, err = persistence.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.IsType(t, &Dummy{}, persistence.Items[0])
+	assert.IsType(t, &Dummy{}, persistence.Items[1])
+
+	// Filters and sorts receive stored pointers
+	page, err := persistence.GetPageByFilter("", func(item interface{}) bool {
+		return item.(*Dummy).Key == "Key 2"
+	}, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "2", page.Data[0].(*Dummy).Id)
+
+	// Returned items are copies by default
+	created.(*Dummy).Key = "Changed"
+	item, _ := persistence.GetOneById("", created.(*Dummy).Id)
+	assert.Equal(t, "Key 1", item.(*Dummy).Key)
+
+	updated, err := persistence.Update("", &Dummy{Id: "2", Key: "Key 3"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 3", updated.(*Dummy).Key)
+	updated, err = persistence.UpdatePartially("", "2", cdata.NewAnyValueMapFromTuples("content", "Content 3"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 3", updated.(*Dummy).Key)
+	assert.Equal(t, "Content 3", updated.(*Dummy).Content)
+
+	deleted, err := persistence.DeleteById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", deleted.(*Dummy).Id)
+	assert.Len(t, persistence.Items, 1)
+}
+
+func TestPointerStorageModeWithoutCopies(t *testing.T) {
+	persister := NewDummyRefMemoryPersistence()
+	persister.Configure(cconf.NewConfigParamsFromTuples(
+		"options.storage_mode", "pointer",
+		"options.clone_strategy", "none",
+	))
+	assert.Nil(t, persister.Open(""))
+
+	dummy := &Dummy{Id: "1", Key: "Key 1"}
+	created, err := persister.Create("", dummy)
+	assert.Nil(t, err)
+	assert.True(t, created == dummy)
+
+	item, _ := persister.GetOneById("", "1")
+	assert.True(t, item == dummy)
+
+	// Partial updates don't change the shared item in place
+	data := cdata.NewAnyValueMapFromTuples("key", "Key 2")
+	updated, err := persister.UpdatePartially("", "1", data)
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", updated.Key)
+	assert.Equal(t, "Key 1", dummy.Key)
+}
+
+func TestStorageModeLoad(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(&Dummy{}))
+	persistence.Loader = &staticLoader{items: []interface{}{
+		map[string]interface{}{"id": "1", "key": "Key 1"},
+	}}
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.storage_mode", "pointer"))
+	assert.Nil(t, persistence.Open(""))
+	assert.IsType(t, &Dummy{}, persistence.Items[0])
+	persistence.Close("")
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.storage_mode", "value"))
+	assert.Nil(t, persistence.Open(""))
+	assert.IsType(t, Dummy{}, persistence.Items[0])
+	item, _ := persistence.GetOneById("", "1")
+	assert.Equal(t, "Key 1", item.(*Dummy).Key)
+	persistence.Close("")
+}
+
+func TestStorageModeMismatch(t *testing.T) {
+	persistence := cpersist.NewIdentifiableMemoryPersistence(reflect.TypeOf(Dummy{}))
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.storage_mode", "pointer"))
+	err := persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "pointer to struct")
+	assert.False(t, persistence.IsOpen())
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.storage_mode", "reference"))
+	assert.NotNil(t, persistence.Open(""))
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.storage_mode", "value"))
+	assert.Nil(t, persistence.Open(""))
+	persistence.Close("")
+}
@@ -0,0 +1,63 @@
+package test_persistence
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPersister(t *testing.T) {
+	persister := cpersist.NewJsonFilePersister(reflect.TypeOf(Dummy{}), "")
+	items := []interface{}{
+		Dummy{Id: "1", Key: "Key 1", Content: "Content 1"},
+		Dummy{Id: "2", Key: "Key 2", Content: "Content 2"},
+	}
+
+	// Items are saved and loaded through compression wrappers
+	buffer := &bytes.Buffer{}
+	zipper := gzip.NewWriter(buffer)
+	assert.Nil(t, persister.SaveTo("", zipper, items))
+	assert.Nil(t, zipper.Close())
+
+	unzipper, err := gzip.NewReader(buffer)
+	assert.Nil(t, err)
+	loaded, err := persister.LoadFrom("", unzipper)
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, "Key 2", loaded[1].(map[string]interface{})["key"])
+
+	loaded, err = persister.LoadFrom("", strings.NewReader("  "))
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	loaded, err = persister.LoadFrom("", strings.NewReader(` {"id": "1"}`))
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 1)
+
+	_, err = persister.LoadFrom("", strings.NewReader(`[{"id": "1"`))
+	assert.NotNil(t, err)
+}
+
+func TestStdioPersister(t *testing.T) {
+	prototype := reflect.TypeOf(Dummy{})
+	persister := cpersist.NewJsonFilePersister(prototype, cpersist.StdioPath)
+	persister.Stdin = strings.NewReader(`[{"id":"1","key":"Key 1"},{"id":"2","key":"Key 2"}]`)
+	output := &bytes.Buffer{}
+	persister.Stdout = output
+
+	persistence := cpersist.NewIdentifiableFilePersistence(prototype, persister)
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.durability", "none"))
+	assert.Nil(t, persistence.Open(""))
+	persistence.DeleteById("", "1")
+	persistence.Create("", Dummy{Id: "3", Key: "Key 3"})
+	assert.Equal(t, 0, output.Len())
+	assert.Nil(t, persistence.Close(""))
+
+	assert.Equal(t, `[{"id":"2","key":"Key 2","content":""},{"id":"3","key":"Key 3","content":""}]`, output.String())
+}
@@ -0,0 +1,47 @@
+package test_persistence
+
+import (
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeletedSince(t *testing.T) {
+	clock := cpersist.NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	persistence := NewDummyMemoryPersistence()
+	persistence.Clock = clock
+	var _ cpersist.ITraceable = persistence
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.tombstone_window", 60000))
+
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	persistence.Create("", Dummy{Id: "2", Key: "Key 2"})
+	persistence.Create("", Dummy{Id: "3", Key: "Key 3"})
+
+	start := clock.Now()
+	clock.Advance(time.Millisecond)
+	persistence.DeleteById("", "1")
+	persistence.DeleteById("", "2")
+
+	tombstones, err := persistence.GetDeletedSince("", start)
+	assert.Nil(t, err)
+	assert.Len(t, tombstones, 2)
+	assert.Equal(t, "1", tombstones[0].Id)
+	assert.Equal(t, "2", tombstones[1].Id)
+
+	tombstones, _ = persistence.GetDeletedSince("", clock.Now())
+	assert.Len(t, tombstones, 0)
+
+	// Created again items are not reported as deleted
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	tombstones, _ = persistence.GetDeletedSince("", start)
+	assert.Len(t, tombstones, 1)
+	assert.Equal(t, "2", tombstones[0].Id)
+
+	// Tombstones expire after the window
+	clock.Advance(2 * time.Minute)
+	tombstones, _ = persistence.GetDeletedSince("", start)
+	assert.Len(t, tombstones, 0)
+}
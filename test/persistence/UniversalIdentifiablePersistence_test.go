@@ -0,0 +1,219 @@
+package test_persistence
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	ccache "github.com/pip-services3-go/pip-services3-components-go/cache"
+	cpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Minimal database/sql driver that understands statements of SqlPersister
+type fakeSqlDriver struct {
+	lock   sync.Mutex
+	tables map[string]map[string]string
+}
+
+var fakeSql = &fakeSqlDriver{tables: map[string]map[string]string{}}
+
+func init() {
+	sql.Register("fakesql", fakeSql)
+}
+
+func (d *fakeSqlDriver) Open(name string) (driver.Conn, error) {
+	return &fakeSqlConn{driver: d, db: name}, nil
+}
+
+type fakeSqlConn struct {
+	driver *fakeSqlDriver
+	db     string
+}
+
+func (c *fakeSqlConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSqlStmt{conn: c, query: query}, nil
+}
+func (c *fakeSqlConn) Close() error              { return nil }
+func (c *fakeSqlConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeSqlConn) Commit() error             { return nil }
+func (c *fakeSqlConn) Rollback() error           { return nil }
+
+type fakeSqlStmt struct {
+	conn  *fakeSqlConn
+	query string
+}
+
+func (s *fakeSqlStmt) Close() error  { return nil }
+func (s *fakeSqlStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+// Gets rows of the table named after the given keyword of the query
+func (s *fakeSqlStmt) table(keyword string) map[string]string {
+	name := strings.Fields(strings.SplitN(s.query, keyword, 2)[1])[0]
+	key := s.conn.db + "/" + name
+	if s.conn.driver.tables[key] == nil {
+		s.conn.driver.tables[key] = map[string]string{}
+	}
+	return s.conn.driver.tables[key]
+}
+
+func (s *fakeSqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.lock.Lock()
+	defer s.conn.driver.lock.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS"):
+		s.table("EXISTS")
+	case strings.HasPrefix(s.query, "DELETE FROM"):
+		table := s.table("FROM")
+		for id := range table {
+			delete(table, id)
+		}
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		s.table("INTO")[args[0].(string)] = args[1].(string)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.driver.lock.Lock()
+	defer s.conn.driver.lock.Unlock()
+
+	table := s.table("FROM")
+	ids := make([]string, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rows := &fakeSqlRows{}
+	for _, id := range ids {
+		rows.data = append(rows.data, table[id])
+	}
+	return rows, nil
+}
+
+type fakeSqlRows struct {
+	data []string
+}
+
+func (r *fakeSqlRows) Columns() []string { return []string{"data"} }
+func (r *fakeSqlRows) Close() error      { return nil }
+func (r *fakeSqlRows) Next(dest []driver.Value) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	dest[0], r.data = r.data[0], r.data[1:]
+	return nil
+}
+
+// Creates items in a new persistence with the backend and loads them in another one
+func testUniversalBackend(t *testing.T, config *cconf.ConfigParams, references cref.IReferences) {
+	persistence := cpersist.NewUniversalIdentifiablePersistence(reflect.TypeOf(Dummy{}))
+	persistence.Configure(config)
+	if references != nil {
+		persistence.SetReferences(references)
+	}
+	assert.Nil(t, persistence.Open(""))
+	assert.Equal(t, config.GetAsString("options.backend"), persistence.OpenedBackend())
+	_, err := persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("", Dummy{Id: "2", Key: "Key 2"})
+	assert.Nil(t, err)
+	assert.Nil(t, persistence.Close(""))
+	assert.Equal(t, "", persistence.OpenedBackend())
+
+	persistence = cpersist.NewUniversalIdentifiablePersistence(reflect.TypeOf(Dummy{}))
+	persistence.Configure(config)
+	if references != nil {
+		persistence.SetReferences(references)
+	}
+	assert.Nil(t, persistence.Open(""))
+	defer persistence.Close("")
+	item, err := persistence.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 2", item.(Dummy).Key)
+}
+
+func TestUniversalPersistenceBackends(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(dir, "dummies.json")
+		testUniversalBackend(t, cconf.NewConfigParamsFromTuples("options.backend", "file", "path", path), nil)
+		content, _ := ioutil.ReadFile(path)
+		assert.Contains(t, string(content), "Key 2")
+	})
+
+	t.Run("Sqlite", func(t *testing.T) {
+		testUniversalBackend(t, cconf.NewConfigParamsFromTuples(
+			"options.backend", "sqlite",
+			"options.driver", "fakesql",
+			"path", filepath.Join(dir, "dummies.db"),
+		), nil)
+		assert.Len(t, fakeSql.tables[filepath.Join(dir, "dummies.db")+"/dummy"], 2)
+	})
+
+	t.Run("KeyValue", func(t *testing.T) {
+		store := ccache.NewMemoryCache()
+		references := cref.NewReferencesFromTuples(cref.NewDescriptor("pip-services", "cache", "memory", "default", "1.0"), store)
+		testUniversalBackend(t, cconf.NewConfigParamsFromTuples("options.backend", "keyvalue", "options.key", "dummies"), references)
+		value, err := store.Retrieve("", "dummies")
+		assert.Nil(t, err)
+		assert.Contains(t, value, "Key 2")
+	})
+}
+
+func TestUniversalPersistenceSwitchBackend(t *testing.T) {
+	persistence := cpersist.NewUniversalIdentifiablePersistence(reflect.TypeOf(Dummy{}))
+	assert.Nil(t, persistence.Open(""))
+	assert.Equal(t, cpersist.BackendMemory, persistence.OpenedBackend())
+	persistence.Create("", Dummy{Id: "1", Key: "Key 1"})
+	assert.Nil(t, persistence.Close(""))
+
+	// The backend is selected again at Open
+	path := filepath.Join(t.TempDir(), "dummies.json")
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.backend", "file", "path", path))
+	assert.Nil(t, persistence.Open(""))
+	assert.Equal(t, cpersist.BackendFile, persistence.OpenedBackend())
+	assert.Nil(t, persistence.Close(""))
+}
+
+func TestUniversalPersistenceInvalidBackends(t *testing.T) {
+	persistence := cpersist.NewUniversalIdentifiablePersistence(reflect.TypeOf(Dummy{}))
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.backend", "mongodb"))
+	err := persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "mongodb")
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.backend", "file"))
+	assert.NotNil(t, persistence.Open(""))
+
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.backend", "keyvalue"))
+	assert.NotNil(t, persistence.Open(""))
+
+	// A referenced component that is not a key-value store
+	persistence.SetReferences(cref.NewReferencesFromTuples(
+		cref.NewDescriptor("test", "cache", "fake", "default", "1.0"), "not a cache"))
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_STORE", err.(*cerr.ApplicationError).Code)
+	assert.False(t, persistence.IsOpen())
+
+	// The default sqlite3 driver is not imported by tests
+	persistence.Configure(cconf.NewConfigParamsFromTuples("options.backend", "sqlite", "path", "dummies.db"))
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "sqlite3")
+	assert.False(t, persistence.IsOpen())
+}
@@ -0,0 +1,67 @@
+package test_persistence
+
+import (
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisitById(t *testing.T) {
+	dummies := NewDummyMemoryPersistence()
+	dummies.Create("", Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+
+	key := ""
+	found, err := dummies.VisitById("", "1", func(item interface{}) {
+		key = item.(Dummy).Key
+	})
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Key 1", key)
+
+	found, err = dummies.VisitById("", "2", func(item interface{}) {
+		t.Fail()
+	})
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	dummies.Configure(cconf.NewConfigParamsFromTuples("options.not_found_error", true))
+	_, err = dummies.VisitById("", "2", func(item interface{}) {})
+	assert.NotNil(t, err)
+
+	found, err = dummies.VisitById("", "1", func(item interface{}) {
+		panic("failed")
+	})
+	assert.False(t, found)
+	assert.NotNil(t, err)
+}
+
+func TestVisitByFilter(t *testing.T) {
+	dummies := NewDummyMemoryPersistence()
+	for _, key := range []string{"a", "b", "a", "c"} {
+		dummies.Create("", Dummy{Key: key, Content: key})
+	}
+	isA := func(item interface{}) bool { return item.(Dummy).Key == "a" }
+
+	count, err := dummies.VisitByFilter("", isA, func(item interface{}) bool { return true })
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = dummies.VisitByFilter("", nil, func(item interface{}) bool { return false })
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// Read snapshots are visited without lock
+	dummies.Configure(cconf.NewConfigParamsFromTuples("options.read_snapshot", true))
+	keys := ""
+	count, err = dummies.VisitByFilter("", nil, func(item interface{}) bool {
+		keys += item.(Dummy).Key
+		return true
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, "abac", keys)
+
+	_, err = dummies.VisitByFilter("", nil, func(item interface{}) bool { panic("failed") })
+	assert.NotNil(t, err)
+}